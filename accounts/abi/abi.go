@@ -20,8 +20,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
 	"strings"
 
+	"github.com/pbfcoin/go-pbfcoin/common"
 	"github.com/pbfcoin/go-pbfcoin/crypto"
 )
 
@@ -32,12 +34,13 @@ import (
 // from the storage and therefor requires no Tx to be send to the
 // network. A method such as `Transact` does require a Tx and thus will
 // be flagged `true`.
-// Input specifies the required input parameters for this gives method.
-type method struct {
-	Name   string
-	Const  bool
-	Inputs []Argument
-	Return Type // not yet implemented
+// Input specifies the required input parameters for this gives method,
+// Outputs the values it returns.
+type Method struct {
+	Name    string
+	Const   bool
+	Inputs  []Argument
+	Outputs []Argument
 }
 
 // Returns the methods string signature according to the ABI spec.
@@ -47,7 +50,7 @@ type method struct {
 //     function foo(uint32 a, int b)    =    "foo(uint32,int256)"
 //
 // Please note that "int" is substitute for its canonical representation "int256"
-func (m method) String() (out string) {
+func (m Method) String() (out string) {
 	out += m.Name
 	types := make([]string, len(m.Inputs))
 	i := 0
@@ -60,21 +63,24 @@ func (m method) String() (out string) {
 	return
 }
 
-func (m method) Id() []byte {
+func (m Method) Id() []byte {
 	return crypto.Sha3([]byte(m.String()))[:4]
 }
 
 // Argument holds the name of the argument and the corresponding type.
-// Types are used when packing and testing arguments.
+// Types are used when packing and testing arguments. Indexed is only
+// used by event arguments.
 type Argument struct {
-	Name string
-	Type Type
+	Name    string
+	Type    Type
+	Indexed bool
 }
 
 func (a *Argument) UnmarshalJSON(data []byte) error {
 	var extarg struct {
-		Name string
-		Type string
+		Name    string
+		Type    string
+		Indexed bool
 	}
 	err := json.Unmarshal(data, &extarg)
 	if err != nil {
@@ -86,33 +92,48 @@ func (a *Argument) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	a.Name = extarg.Name
+	a.Indexed = extarg.Indexed
 
 	return nil
 }
 
 // The ABI holds information about a contract's context and available
 // invokable methods. It will allow you to type check function calls and
-// packs data accordingly.
+// packs data accordingly. Return values and event logs can be decoded
+// using Unpack and UnpackLog.
 type ABI struct {
-	methods map[string]method
+	Constructor Method
+	methods     map[string]Method
+	Events      map[string]Event
 }
 
 // tests, tests whpbfer the given input would result in a successful
 // call. Checks argument list count and matches input to `input`.
-func (abi ABI) pack(name string, args ...interface{}) ([]byte, error) {
-	method := abi.methods[name]
+// Dynamic arguments are appended after the fixed size ones and
+// referenced by their offset.
+func (abi ABI) pack(method Method, args ...interface{}) ([]byte, error) {
+	headSize := 0
+	for _, input := range method.Inputs {
+		headSize += input.Type.headSize()
+	}
 
-	var ret []byte
+	var ret, variableInput []byte
 	for i, a := range args {
 		input := method.Inputs[i]
 
 		packed, err := input.Type.pack(a)
 		if err != nil {
-			return nil, fmt.Errorf("`%s` %v", name, err)
+			return nil, fmt.Errorf("`%s` %v", method.Name, err)
+		}
+		if input.Type.isDynamic() {
+			offset := big.NewInt(int64(headSize + len(variableInput)))
+			ret = append(ret, U256(offset)...)
+			variableInput = append(variableInput, packed...)
+		} else {
+			ret = append(ret, packed...)
 		}
-		ret = append(ret, packed...)
-
 	}
+	ret = append(ret, variableInput...)
 
 	return ret, nil
 }
@@ -122,8 +143,14 @@ func (abi ABI) pack(name string, args ...interface{}) ([]byte, error) {
 // of 4 bytes and arguments are all 32 bytes.
 // method ids are created from the first 4 bytes of the hash of the
 // methods string signature. (signature = baz(uint32,string32))
+//
+// An empty name packs the constructor arguments, which are appended to
+// the contract code on deployment and don't have a method id.
 func (abi ABI) Pack(name string, args ...interface{}) ([]byte, error) {
 	method, exist := abi.methods[name]
+	if name == "" {
+		method, exist = abi.Constructor, true
+	}
 	if !exist {
 		return nil, fmt.Errorf("method '%s' not found", name)
 	}
@@ -133,27 +160,126 @@ func (abi ABI) Pack(name string, args ...interface{}) ([]byte, error) {
 		return nil, fmt.Errorf("argument count mismatch: %d for %d", len(args), len(method.Inputs))
 	}
 
-	arguments, err := abi.pack(name, args...)
+	arguments, err := abi.pack(method, args...)
 	if err != nil {
 		return nil, err
 	}
+	if name == "" {
+		return arguments, nil
+	}
 
 	// Set function id
-	packed := abi.methods[name].Id()
+	packed := method.Id()
 	packed = append(packed, arguments...)
 
 	return packed, nil
 }
 
+// Functions returns the callable methods of the contract keyed by name.
+func (abi ABI) Functions() map[string]Method {
+	return abi.methods
+}
+
+// Unpack decodes the return values of the named method into v. A method with
+// a single output accepts a pointer to a value of the output type, methods
+// with several outputs accept a pointer to a struct, whose fields are matched
+// by the capitalised output names, or a pointer to a []interface{}.
+func (abi ABI) Unpack(v interface{}, name string, output []byte) error {
+	method, exist := abi.methods[name]
+	if !exist {
+		return fmt.Errorf("method '%s' not found", name)
+	}
+	values, err := unpackArguments(method.Outputs, output)
+	if err != nil {
+		return fmt.Errorf("`%s` %v", name, err)
+	}
+	return setArguments(v, method.Outputs, values)
+}
+
+// UnpackLog decodes the arguments of the named event from the topics and data
+// of a log into v, using the same destinations as Unpack. Indexed arguments of
+// a dynamic or array type are only stored as a hash of their value in the log,
+// they are decoded as a common.Hash.
+func (abi ABI) UnpackLog(v interface{}, name string, topics []common.Hash, data []byte) error {
+	event, exist := abi.Events[name]
+	if !exist {
+		return fmt.Errorf("event '%s' not found", name)
+	}
+	if !event.Anonymous {
+		if len(topics) == 0 || topics[0] != event.Id() {
+			return fmt.Errorf("`%s` log signature mismatch", name)
+		}
+		topics = topics[1:]
+	}
+
+	var plain []Argument
+	for _, input := range event.Inputs {
+		if !input.Indexed {
+			plain = append(plain, input)
+		}
+	}
+	plainValues, err := unpackArguments(plain, data)
+	if err != nil {
+		return fmt.Errorf("`%s` %v", name, err)
+	}
+
+	values := make([]interface{}, len(event.Inputs))
+	for i, input := range event.Inputs {
+		if !input.Indexed {
+			values[i], plainValues = plainValues[0], plainValues[1:]
+			continue
+		}
+		if len(topics) == 0 {
+			return fmt.Errorf("`%s` missing topic for indexed argument %d", name, i)
+		}
+		topic := topics[0]
+		topics = topics[1:]
+
+		if input.Type.isDynamic() || input.Type.isArray() {
+			values[i] = topic
+		} else if values[i], err = input.Type.unpack(topic[:], 0); err != nil {
+			return fmt.Errorf("`%s` %v", name, err)
+		}
+	}
+	return setArguments(v, event.Inputs, values)
+}
+
 func (abi *ABI) UnmarshalJSON(data []byte) error {
-	var methods []method
-	if err := json.Unmarshal(data, &methods); err != nil {
+	var fields []struct {
+		Type      string
+		Name      string
+		Const     bool
+		Constant  bool
+		Anonymous bool
+		Inputs    []Argument
+		Outputs   []Argument
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
 		return err
 	}
 
-	abi.methods = make(map[string]method)
-	for _, method := range methods {
-		abi.methods[method.Name] = method
+	abi.methods = make(map[string]Method)
+	abi.Events = make(map[string]Event)
+	for _, field := range fields {
+		switch field.Type {
+		case "constructor":
+			abi.Constructor = Method{
+				Inputs: field.Inputs,
+			}
+		case "function", "":
+			abi.methods[field.Name] = Method{
+				Name:    field.Name,
+				Const:   field.Const || field.Constant,
+				Inputs:  field.Inputs,
+				Outputs: field.Outputs,
+			}
+		case "event":
+			abi.Events[field.Name] = Event{
+				Name:      field.Name,
+				Anonymous: field.Anonymous,
+				Inputs:    field.Inputs,
+			}
+		}
 	}
 
 	return nil
@@ -90,14 +90,14 @@ func TestType(t *testing.T) {
 func TestReader(t *testing.T) {
 	Uint256, _ := NewType("uint256")
 	exp := ABI{
-		methods: map[string]method{
-			"balance": method{
-				"balance", true, nil, Type{},
+		methods: map[string]Method{
+			"balance": Method{
+				"balance", true, nil, nil,
 			},
-			"send": method{
+			"send": Method{
 				"send", false, []Argument{
-					Argument{"amount", Uint256},
-				}, Type{},
+					Argument{"amount", Uint256, false},
+				}, nil,
 			},
 		},
 	}
@@ -238,7 +238,7 @@ func TestTestAddress(t *testing.T) {
 func TestmethodSignature(t *testing.T) {
 	String, _ := NewType("string")
 	String32, _ := NewType("string32")
-	m := method{"foo", false, []Argument{Argument{"bar", String32}, Argument{"baz", String}}, Type{}}
+	m := Method{"foo", false, []Argument{Argument{"bar", String32, false}, Argument{"baz", String, false}}, nil}
 	exp := "foo(string32,string)"
 	if m.String() != exp {
 		t.Error("signature mismatch", exp, "!=", m.String())
@@ -250,7 +250,7 @@ func TestmethodSignature(t *testing.T) {
 	}
 
 	uintt, _ := NewType("uint")
-	m = method{"foo", false, []Argument{Argument{"bar", uintt}}, Type{}}
+	m = Method{"foo", false, []Argument{Argument{"bar", uintt, false}}, nil}
 	exp = "foo(uint256)"
 	if m.String() != exp {
 		t.Error("signature mismatch", exp, "!=", m.String())
@@ -393,3 +393,252 @@ func TestBytes(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+const unpackdata = `
+[
+	{ "name" : "balance", "type" : "function", "constant" : true, "outputs" : [ { "name" : "", "type" : "uint256" } ] },
+	{ "name" : "delta", "type" : "function", "constant" : true, "outputs" : [ { "name" : "", "type" : "int256" } ] },
+	{ "name" : "owner", "type" : "function", "constant" : true, "outputs" : [ { "name" : "", "type" : "address" } ] },
+	{ "name" : "hash", "type" : "function", "constant" : true, "outputs" : [ { "name" : "", "type" : "bytes32" } ] },
+	{ "name" : "info", "type" : "function", "constant" : true, "outputs" : [
+		{ "name" : "name", "type" : "string" },
+		{ "name" : "fixed", "type" : "uint256[2]" },
+		{ "name" : "data", "type" : "bytes" },
+		{ "name" : "values", "type" : "uint256[]" },
+		{ "name" : "_active", "type" : "bool" }
+	] },
+	{ "name" : "Transfer", "type" : "event", "inputs" : [
+		{ "name" : "from", "type" : "address", "indexed" : true },
+		{ "name" : "to", "type" : "address", "indexed" : true },
+		{ "name" : "memo", "type" : "string", "indexed" : true },
+		{ "name" : "value", "type" : "uint256", "indexed" : false }
+	] },
+	{ "name" : "Anon", "type" : "event", "anonymous" : true, "inputs" : [
+		{ "name" : "id", "type" : "uint256", "indexed" : true },
+		{ "name" : "note", "type" : "string", "indexed" : false }
+	] }
+]`
+
+// words concatenates the given values left padded to 32 bytes.
+func words(values ...[]byte) []byte {
+	var out []byte
+	for _, v := range values {
+		out = append(out, common.LeftPadBytes(v, 32)...)
+	}
+	return out
+}
+
+func TestUnpack(t *testing.T) {
+	abi, err := JSON(strings.NewReader(unpackdata))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var balance *big.Int
+	if err := abi.Unpack(&balance, "balance", words([]byte{0x01, 0x00})); err != nil {
+		t.Fatal(err)
+	}
+	if balance.Cmp(big.NewInt(256)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 256", balance)
+	}
+	var small uint8
+	if err := abi.Unpack(&small, "balance", words([]byte{0x01, 0x00})); err == nil {
+		t.Errorf("expected overflow error for uint8, got %v", small)
+	}
+	var native uint64
+	if err := abi.Unpack(&native, "balance", words([]byte{0x01, 0x00})); err != nil || native != 256 {
+		t.Errorf("native balance mismatch: have %v (%v), want 256", native, err)
+	}
+
+	var delta *big.Int
+	if err := abi.Unpack(&delta, "delta", common.Hex2Bytes(strings.Repeat("ff", 32))); err != nil {
+		t.Fatal(err)
+	}
+	if delta.Cmp(big.NewInt(-1)) != 0 {
+		t.Errorf("delta mismatch: have %v, want -1", delta)
+	}
+
+	var owner common.Address
+	if err := abi.Unpack(&owner, "owner", words([]byte{0x01})); err != nil {
+		t.Fatal(err)
+	}
+	if owner != common.HexToAddress("01") {
+		t.Errorf("owner mismatch: have %x", owner)
+	}
+
+	var hash common.Hash
+	if err := abi.Unpack(&hash, "hash", words([]byte{0x02})); err != nil {
+		t.Fatal(err)
+	}
+	if hash != common.BytesToHash([]byte{0x02}) {
+		t.Errorf("hash mismatch: have %x", hash)
+	}
+
+	if err := abi.Unpack(&balance, "balance", nil); err == nil {
+		t.Error("expected error for empty output")
+	}
+	if err := abi.Unpack(balance, "balance", words([]byte{0x01})); err == nil {
+		t.Error("expected error for non pointer destination")
+	}
+}
+
+func TestUnpackDynamic(t *testing.T) {
+	abi, err := JSON(strings.NewReader(unpackdata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// head: string offset, 2 inline array elements, bytes offset, array offset, bool
+	output := words(
+		[]byte{0xc0}, []byte{0x07}, []byte{0x08}, []byte{0x01, 0x00}, []byte{0x01, 0x40}, []byte{0x01},
+		[]byte{0x05}, common.RightPadBytes([]byte("hello"), 32),
+		[]byte{0x02}, common.RightPadBytes([]byte{0xca, 0xfe}, 32),
+		[]byte{0x03}, []byte{0x01}, []byte{0x02}, []byte{0x03},
+	)
+
+	var values []interface{}
+	if err := abi.Unpack(&values, "info", output); err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 5 {
+		t.Fatalf("value count mismatch: have %d, want 5", len(values))
+	}
+	if name := values[0].(string); name != "hello" {
+		t.Errorf("string mismatch: have %q, want %q", name, "hello")
+	}
+	if fixed := values[1].([]*big.Int); len(fixed) != 2 || fixed[0].Int64() != 7 || fixed[1].Int64() != 8 {
+		t.Errorf("fixed array mismatch: have %v", fixed)
+	}
+	if data := values[2].([]byte); !bytes.Equal(data, []byte{0xca, 0xfe}) {
+		t.Errorf("bytes mismatch: have %x", data)
+	}
+	if dynamic := values[3].([]*big.Int); len(dynamic) != 3 || dynamic[2].Int64() != 3 {
+		t.Errorf("dynamic array mismatch: have %v", dynamic)
+	}
+	if active := values[4].(bool); !active {
+		t.Error("bool mismatch: have false, want true")
+	}
+
+	// struct fields are matched by the capitalised output names
+	var res struct {
+		Name   string
+		Fixed  []*big.Int
+		Data   []byte
+		Values []*big.Int
+		Active bool
+	}
+	if err := abi.Unpack(&res, "info", output); err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != "hello" || !res.Active || len(res.Values) != 3 {
+		t.Errorf("struct mismatch: have %+v", res)
+	}
+
+	// a length pointing beyond the output must not panic
+	corrupt := common.CopyBytes(output)
+	corrupt[6*32+31] = 0xff
+	if err := abi.Unpack(&res, "info", corrupt); err == nil {
+		t.Error("expected error for out of bound string length")
+	}
+}
+
+func TestUnpackLog(t *testing.T) {
+	abi, err := JSON(strings.NewReader(unpackdata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := abi.Events["Transfer"]
+	if event.String() != "Transfer(address,address,string,uint256)" {
+		t.Fatalf("signature mismatch: have %s", event.String())
+	}
+	if event.Id() != crypto.Sha3Hash([]byte(event.String())) {
+		t.Fatalf("event id mismatch: have %x", event.Id())
+	}
+
+	memo := crypto.Sha3Hash([]byte("rent"))
+	topics := []common.Hash{
+		event.Id(),
+		common.BytesToHash([]byte{0x01}),
+		common.BytesToHash([]byte{0x02}),
+		memo,
+	}
+	data := words([]byte{0x2a})
+
+	var transfer struct {
+		From  common.Address
+		To    common.Address
+		Memo  common.Hash
+		Value *big.Int
+	}
+	if err := abi.UnpackLog(&transfer, "Transfer", topics, data); err != nil {
+		t.Fatal(err)
+	}
+	if transfer.From != common.HexToAddress("01") || transfer.To != common.HexToAddress("02") {
+		t.Errorf("indexed address mismatch: have %x, %x", transfer.From, transfer.To)
+	}
+	if transfer.Memo != memo {
+		t.Errorf("indexed string hash mismatch: have %x, want %x", transfer.Memo, memo)
+	}
+	if transfer.Value.Int64() != 42 {
+		t.Errorf("value mismatch: have %v, want 42", transfer.Value)
+	}
+
+	topics[0] = common.Hash{}
+	if err := abi.UnpackLog(&transfer, "Transfer", topics, data); err == nil {
+		t.Error("expected error for mismatching event signature")
+	}
+
+	// anonymous events don't store the signature as first topic
+	topics = []common.Hash{common.BytesToHash([]byte{0x07})}
+	data = append(words([]byte{0x20}, []byte{0x02}), common.RightPadBytes([]byte("hi"), 32)...)
+
+	var values []interface{}
+	if err := abi.UnpackLog(&values, "Anon", topics, data); err != nil {
+		t.Fatal(err)
+	}
+	if values[0].(*big.Int).Int64() != 7 || values[1].(string) != "hi" {
+		t.Errorf("anonymous event mismatch: have %v", values)
+	}
+}
+
+func TestPackDynamic(t *testing.T) {
+	const definition = `[
+	{ "type" : "constructor", "inputs" : [ { "name" : "name", "type" : "string" }, { "name" : "supply", "type" : "uint256" } ] },
+	{ "type" : "function", "name" : "set", "inputs" : [ { "name" : "data", "type" : "bytes" }, { "name" : "values", "type" : "uint256[]" }, { "name" : "key", "type" : "bytes32" } ] }
+]`
+	abi, err := JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The constructor arguments are packed without a method id
+	packed, err := abi.Pack("", "cafe", big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := append(words([]byte{64}, []byte{10}, []byte{4}), common.RightPadBytes([]byte("cafe"), 32)...)
+	if !bytes.Equal(packed, exp) {
+		t.Errorf("constructor mismatch:\nhave %x\nwant %x", packed, exp)
+	}
+	// Dynamic arguments are referenced by offset and appended in order
+	packed, err = abi.Pack("set", []byte{0xca, 0xfe}, []*big.Int{big.NewInt(1), big.NewInt(2)}, []byte{0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp = abi.Functions()["set"].Id()
+	exp = append(exp, words([]byte{96}, []byte{160})...)
+	exp = append(exp, common.RightPadBytes([]byte{0xff}, 32)...)
+	exp = append(exp, words([]byte{2})...)
+	exp = append(exp, common.RightPadBytes([]byte{0xca, 0xfe}, 32)...)
+	exp = append(exp, words([]byte{2}, []byte{1}, []byte{2})...)
+	if !bytes.Equal(packed, exp) {
+		t.Errorf("method mismatch:\nhave %x\nwant %x", packed, exp)
+	}
+	// The unpacked values round trip
+	abi.methods["set"] = Method{Name: "set", Outputs: abi.methods["set"].Inputs}
+	var out []interface{}
+	if err := abi.Unpack(&out, "set", packed[4:]); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out[0].([]byte), []byte{0xca, 0xfe}) || out[1].([]*big.Int)[1].Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("round trip mismatch: have %v", out)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"math/big"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/core/vm"
+)
+
+// CallMsg contains the parameters of a contract call which is executed
+// locally and not mined into the chain.
+type CallMsg struct {
+	From     common.Address  // the sender of the call
+	To       *common.Address // the destination contract, nil for contract creation
+	Gas      *big.Int        // gas allowance of the call, nil for the backend default
+	GasPrice *big.Int        // price of a unit of gas, nil for the backend default
+	Value    *big.Int        // amount of wei sent along with the call
+	Data     []byte          // input data, usually an ABI-encoded contract method call
+}
+
+// FilterQuery contains the options for a contract log filtering. Topics are
+// matched by position, an empty list at a position matches any topic.
+type FilterQuery struct {
+	FromBlock int64 // first block of the range, negative for the latest block
+	ToBlock   int64 // last block of the range, negative for the latest block
+	Addresses []common.Address
+	Topics    [][]common.Hash
+}
+
+// ContractCaller defines the methods needed to allow operating with contracts
+// on a read only basis.
+type ContractCaller interface {
+	// CallContract executes a call against the latest block, or against the
+	// pending state if pending is set, and returns the output data.
+	CallContract(call CallMsg, pending bool) ([]byte, error)
+}
+
+// ContractTransactor defines the methods needed to allow operating with
+// contracts on a write only basis.
+type ContractTransactor interface {
+	// PendingNonceAt retrieves the nonce the next transaction of the account
+	// should use, taking pending transactions into account.
+	PendingNonceAt(account common.Address) (uint64, error)
+
+	// SuggestGasPrice retrieves the currently suggested gas price.
+	SuggestGasPrice() (*big.Int, error)
+
+	// EstimateGas estimates the gas needed to execute the call against the
+	// pending state.
+	EstimateGas(call CallMsg) (*big.Int, error)
+
+	// SendTransaction injects a signed transaction into the pending pool.
+	SendTransaction(tx *types.Transaction) error
+}
+
+// ContractFilterer defines the methods needed to access the logs emitted by
+// contracts.
+type ContractFilterer interface {
+	// FilterLogs returns the logs matching the given query.
+	FilterLogs(query FilterQuery) (vm.Logs, error)
+}
+
+// ContractBackend defines the methods needed to work with contracts on a
+// read-write basis. It is implemented by the RPC backend and by the simulated
+// chain used in tests.
+type ContractBackend interface {
+	ContractCaller
+	ContractTransactor
+	ContractFilterer
+}
@@ -0,0 +1,228 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package backends contains the contract backends the generated bindings can
+// operate on.
+package backends
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+
+	"github.com/pbfcoin/go-pbfcoin/accounts/abi/bind"
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/core/vm"
+	"github.com/pbfcoin/go-pbfcoin/rlp"
+	"github.com/pbfcoin/go-pbfcoin/rpc/shared"
+)
+
+// RPCClient is the connection to a node the RPC backend sends its requests
+// through, e.g. an IPC, HTTP or WebSocket client of the rpc/comms package.
+type RPCClient interface {
+	Send(req interface{}) error
+	Recv() (interface{}, error)
+	Close()
+}
+
+// rpcBackend implements bind.ContractBackend, and acts as the data provider to
+// pbfcoin contracts bound to Go structs. It uses an RPC connection to delegate
+// all its functionality.
+type rpcBackend struct {
+	client RPCClient // RPC client connection to interact with an API server
+	autoid uint32    // ID number to use for the next API request
+	lock   sync.Mutex
+}
+
+// NewRPCBackend creates a new binding backend to an RPC provider that can be
+// used to interact with remote contracts.
+func NewRPCBackend(client RPCClient) bind.ContractBackend {
+	return &rpcBackend{
+		client: client,
+	}
+}
+
+// request forwards an API request to the RPC server, and decodes the result of
+// the response into result.
+func (b *rpcBackend) request(result interface{}, method string, params ...interface{}) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.autoid++
+	req := map[string]interface{}{
+		"id":      b.autoid,
+		"jsonrpc": shared.JsonRpcVersion,
+		"method":  method,
+		"params":  params,
+	}
+	if err := b.client.Send(req); err != nil {
+		return err
+	}
+	res, err := b.client.Recv()
+	if err != nil {
+		return err
+	}
+	success, ok := res.(*shared.SuccessResponse)
+	if !ok {
+		return fmt.Errorf("invalid response to %s: %v", method, res)
+	}
+	// Results are decoded generically by the codec, convert them to the requested type
+	blob, err := json.Marshal(success.Result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(blob, result)
+}
+
+// callArgs converts a call message to the JSON parameters of eth_call and
+// eth_estimateGas.
+func callArgs(call bind.CallMsg) map[string]interface{} {
+	args := map[string]interface{}{
+		"from": call.From.Hex(),
+		"data": "0x" + common.Bytes2Hex(call.Data),
+	}
+	if call.To != nil {
+		args["to"] = call.To.Hex()
+	}
+	if call.Gas != nil {
+		args["gas"] = call.Gas.String()
+	}
+	if call.GasPrice != nil {
+		args["gasPrice"] = call.GasPrice.String()
+	}
+	if call.Value != nil {
+		args["value"] = call.Value.String()
+	}
+	return args
+}
+
+// CallContract implements bind.ContractCaller, executing a call via eth_call.
+func (b *rpcBackend) CallContract(call bind.CallMsg, pending bool) ([]byte, error) {
+	block := "latest"
+	if pending {
+		block = "pending"
+	}
+	var output string
+	if err := b.request(&output, "eth_call", callArgs(call), block); err != nil {
+		return nil, err
+	}
+	return common.FromHex(output), nil
+}
+
+// PendingNonceAt implements bind.ContractTransactor, retrieving the current
+// pending nonce associated with an account.
+func (b *rpcBackend) PendingNonceAt(account common.Address) (uint64, error) {
+	var nonce string
+	if err := b.request(&nonce, "eth_getTransactionCount", account.Hex(), "pending"); err != nil {
+		return 0, err
+	}
+	return common.String2Big(nonce).Uint64(), nil
+}
+
+// SuggestGasPrice implements bind.ContractTransactor, retrieving the currently
+// suggested gas price to allow a timely execution of a transaction.
+func (b *rpcBackend) SuggestGasPrice() (*big.Int, error) {
+	var price string
+	if err := b.request(&price, "eth_gasPrice"); err != nil {
+		return nil, err
+	}
+	return common.String2Big(price), nil
+}
+
+// EstimateGas implements bind.ContractTransactor, trying to estimate the gas
+// needed to execute a specific transaction based on the current pending state
+// of the backend blockchain.
+func (b *rpcBackend) EstimateGas(call bind.CallMsg) (*big.Int, error) {
+	var gas string
+	if err := b.request(&gas, "eth_estimateGas", callArgs(call)); err != nil {
+		return nil, err
+	}
+	return common.String2Big(gas), nil
+}
+
+// SendTransaction implements bind.ContractTransactor, injecting a signed
+// transaction into the pending pool for execution.
+func (b *rpcBackend) SendTransaction(tx *types.Transaction) error {
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return err
+	}
+	var hash string
+	return b.request(&hash, "eth_sendRawTransaction", "0x"+common.Bytes2Hex(data))
+}
+
+// FilterLogs implements bind.ContractFilterer, retrieving the matching logs
+// via eth_getLogs.
+func (b *rpcBackend) FilterLogs(query bind.FilterQuery) (vm.Logs, error) {
+	args := map[string]interface{}{
+		"fromBlock": blockArg(query.FromBlock),
+		"toBlock":   blockArg(query.ToBlock),
+		"limit":     math.MaxInt32,
+	}
+	addresses := make([]string, len(query.Addresses))
+	for i, address := range query.Addresses {
+		addresses[i] = address.Hex()
+	}
+	args["address"] = addresses
+
+	// An empty position has to be sent as null to act as a wildcard
+	topics := make([]interface{}, len(query.Topics))
+	for i, rule := range query.Topics {
+		if len(rule) == 0 {
+			continue
+		}
+		hashes := make([]string, len(rule))
+		for j, topic := range rule {
+			hashes[j] = topic.Hex()
+		}
+		topics[i] = hashes
+	}
+	args["topics"] = topics
+
+	var results []shared.LogRes
+	if err := b.request(&results, "eth_getLogs", args); err != nil {
+		return nil, err
+	}
+	logs := make(vm.Logs, len(results))
+	for i, res := range results {
+		log := &vm.Log{
+			Address:     common.HexToAddress(res.Address),
+			Data:        common.FromHex(res.Data),
+			BlockNumber: common.String2Big(res.BlockNumber).Uint64(),
+			TxHash:      common.HexToHash(res.TransactionHash),
+			TxIndex:     uint(common.String2Big(res.TransactionIndex).Uint64()),
+			BlockHash:   common.HexToHash(res.BlockHash),
+			Index:       uint(common.String2Big(res.LogIndex).Uint64()),
+		}
+		for _, topic := range res.Topics {
+			log.Topics = append(log.Topics, common.HexToHash(topic))
+		}
+		logs[i] = log
+	}
+	return logs, nil
+}
+
+// blockArg converts a block number of a filter query to its JSON parameter,
+// negative numbers denote the latest block.
+func blockArg(number int64) string {
+	if number < 0 {
+		return "latest"
+	}
+	return fmt.Sprintf("%#x", number)
+}
@@ -0,0 +1,216 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/pbfcoin/go-pbfcoin/accounts/abi/bind"
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core"
+	"github.com/pbfcoin/go-pbfcoin/core/state"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/core/vm"
+	"github.com/pbfcoin/go-pbfcoin/event"
+	"github.com/pbfcoin/go-pbfcoin/pbf/filters"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+)
+
+// Gas allowance of calls and gas estimations which don't specify one.
+var defaultCallGas = big.NewInt(50000000)
+
+// SimulatedBackend implements bind.ContractBackend, simulating a blockchain in
+// the background. Its main purpose is to allow easily testing contract bindings.
+//
+// Sent transactions are executed on a pending block right away, which is only
+// mined into the chain when Commit is called.
+type SimulatedBackend struct {
+	database   pbfdb.Database   // In memory database to store our testing data
+	blockchain *core.BlockChain // pbfcoin blockchain to handle the consensus
+
+	mu           sync.Mutex
+	pendingBlock *types.Block   // Currently pending block that will be imported on request
+	pendingState *state.StateDB // Currently pending state that will be the active one on request
+}
+
+// NewSimulatedBackend creates a new binding backend using a simulated blockchain
+// for testing purposes. The accounts are funded in the genesis block.
+func NewSimulatedBackend(accounts ...core.GenesisAccount) *SimulatedBackend {
+	database, _ := pbfdb.NewMemDatabase()
+	core.WriteGenesisBlockForTesting(database, accounts...)
+	blockchain, _ := core.NewBlockChain(database, core.NewPowEngine(new(core.FakePow)), new(event.TypeMux))
+
+	backend := &SimulatedBackend{database: database, blockchain: blockchain}
+	backend.rollback()
+	return backend
+}
+
+// BlockChain returns the simulated chain, e.g. to inspect the mined blocks.
+func (b *SimulatedBackend) BlockChain() *core.BlockChain {
+	return b.blockchain
+}
+
+// Commit imports all the pending transactions as a single block and starts a
+// fresh new state.
+func (b *SimulatedBackend) Commit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.blockchain.InsertChain([]*types.Block{b.pendingBlock}); err != nil {
+		panic(err) // This cannot happen unless the simulator is wrong, fail in that case
+	}
+	b.rollback()
+}
+
+// Rollback aborts all pending transactions, reverting to the last committed state.
+func (b *SimulatedBackend) Rollback() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rollback()
+}
+
+func (b *SimulatedBackend) rollback() {
+	blocks, _ := core.GenerateChain(b.blockchain.CurrentBlock(), b.database, 1, func(int, *core.BlockGen) {})
+	b.pendingBlock = blocks[0]
+	b.pendingState, _ = state.New(b.pendingBlock.Root(), b.database)
+}
+
+// CallContract implements bind.ContractCaller, executing the call against the
+// pending state or the head of the chain.
+func (b *SimulatedBackend) CallContract(call bind.CallMsg, pending bool) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if pending {
+		out, _, err := b.callContract(call, b.pendingBlock.Header(), b.pendingState.Copy())
+		return out, err
+	}
+	block := b.blockchain.CurrentBlock()
+	statedb, err := state.New(block.Root(), b.database)
+	if err != nil {
+		return nil, err
+	}
+	out, _, err := b.callContract(call, block.Header(), statedb)
+	return out, err
+}
+
+// PendingNonceAt implements bind.ContractTransactor, retrieving the nonce of
+// the account in the pending state.
+func (b *SimulatedBackend) PendingNonceAt(account common.Address) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.pendingState.GetNonce(account), nil
+}
+
+// SuggestGasPrice implements bind.ContractTransactor. Since the simulated chain
+// doesn't have miners, we just return a gas price of 1 for any call.
+func (b *SimulatedBackend) SuggestGasPrice() (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+// EstimateGas implements bind.ContractTransactor, executing the call against
+// the pending state and returning the gas it used.
+func (b *SimulatedBackend) EstimateGas(call bind.CallMsg) (*big.Int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, gas, err := b.callContract(call, b.pendingBlock.Header(), b.pendingState.Copy())
+	return gas, err
+}
+
+// callContract executes a call on statedb, which is modified in the process.
+// The sender is given an unlimited balance to pay for it.
+func (b *SimulatedBackend) callContract(call bind.CallMsg, header *types.Header, statedb *state.StateDB) ([]byte, *big.Int, error) {
+	if call.Gas == nil || call.Gas.BitLen() == 0 {
+		call.Gas = defaultCallGas
+	}
+	if call.GasPrice == nil {
+		call.GasPrice = big.NewInt(1)
+	}
+	if call.Value == nil {
+		call.Value = new(big.Int)
+	}
+	from := statedb.GetOrNewStateObject(call.From)
+	from.SetBalance(common.MaxBig)
+
+	msg := callmsg{CallMsg: call, nonce: from.Nonce()}
+	vmenv := core.NewEnv(statedb, b.blockchain, msg, header)
+	gaspool := new(core.GasPool).AddGas(common.MaxBig)
+
+	return core.ApplyMessage(vmenv, msg, gaspool)
+}
+
+// SendTransaction implements bind.ContractTransactor, executing the transaction
+// on the pending block. Transactions which can't be executed are rejected.
+func (b *SimulatedBackend) SendTransaction(tx *types.Transaction) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Dry run the transaction first, block generation panics on failures
+	header := b.pendingBlock.Header()
+	vmenv := core.NewEnv(b.pendingState.Copy(), b.blockchain, tx, header)
+	gaspool := new(core.GasPool).AddGas(new(big.Int).Sub(header.GasLimit, header.GasUsed))
+	if _, _, err := core.ApplyMessage(vmenv, tx, gaspool); err != nil {
+		return err
+	}
+	blocks, _ := core.GenerateChain(b.blockchain.CurrentBlock(), b.database, 1, func(number int, block *core.BlockGen) {
+		for _, pending := range b.pendingBlock.Transactions() {
+			block.AddTx(pending)
+		}
+		block.AddTx(tx)
+	})
+	b.pendingBlock = blocks[0]
+	b.pendingState, _ = state.New(b.pendingBlock.Root(), b.database)
+	return nil
+}
+
+// FilterLogs implements bind.ContractFilterer, searching the logs of the
+// committed blocks.
+func (b *SimulatedBackend) FilterLogs(query bind.FilterQuery) (vm.Logs, error) {
+	// The filter never matches an empty topic list, use a wildcard instead
+	topics := make([][]common.Hash, len(query.Topics))
+	for i, rule := range query.Topics {
+		if len(rule) == 0 {
+			rule = []common.Hash{{}}
+		}
+		topics[i] = rule
+	}
+	filter := filters.New(b.database)
+	filter.SetBeginBlock(query.FromBlock)
+	filter.SetEndBlock(query.ToBlock)
+	filter.SetAddresses(query.Addresses)
+	filter.SetTopics(topics)
+
+	return filter.Find(), nil
+}
+
+// callmsg implements core.Message to allow passing it as a transaction simulator.
+type callmsg struct {
+	bind.CallMsg
+	nonce uint64
+}
+
+func (m callmsg) From() (common.Address, error) { return m.CallMsg.From, nil }
+func (m callmsg) Nonce() uint64                 { return m.nonce }
+func (m callmsg) To() *common.Address           { return m.CallMsg.To }
+func (m callmsg) GasPrice() *big.Int            { return m.CallMsg.GasPrice }
+func (m callmsg) Gas() *big.Int                 { return m.CallMsg.Gas }
+func (m callmsg) Value() *big.Int               { return m.CallMsg.Value }
+func (m callmsg) Data() []byte                  { return m.CallMsg.Data }
@@ -0,0 +1,104 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pbfcoin/go-pbfcoin/accounts/abi"
+	"github.com/pbfcoin/go-pbfcoin/accounts/abi/bind"
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+)
+
+// storeFixture returns the ABI and bytecode of the store contract shared with
+// the binding generator tests.
+func storeFixture(t *testing.T) (abi.ABI, []byte) {
+	abiJSON, err := ioutil.ReadFile(filepath.Join("..", "testdata", "store.abi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := ioutil.ReadFile(filepath.Join("..", "testdata", "store.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := abi.JSON(bytes.NewReader(abiJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed, common.FromHex(strings.TrimSpace(string(code)))
+}
+
+func TestSimulatedBackend(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	auth := bind.NewKeyedTransactor(key)
+	sim := NewSimulatedBackend(core.GenesisAccount{Address: auth.From, Balance: big.NewInt(10000000000)})
+
+	parsed, code := storeFixture(t)
+	address, _, store, err := bind.DeployContract(auth, parsed, code, sim)
+	if err != nil {
+		t.Fatalf("failed to deploy contract: %v", err)
+	}
+	// The contract only exists in the pending state until committed
+	var value *big.Int
+	if err := store.Call(&bind.CallOpts{Pending: true}, &value, "get"); err != nil {
+		t.Fatalf("failed to call pending contract: %v", err)
+	}
+	if code, _ := sim.CallContract(bind.CallMsg{To: &address, Data: parsed.Functions()["get"].Id()}, false); len(code) != 0 {
+		t.Fatalf("contract callable before commit: %x", code)
+	}
+	sim.Commit()
+
+	if _, err := store.Transact(auth, "set", big.NewInt(42)); err != nil {
+		t.Fatalf("failed to transact: %v", err)
+	}
+	if err := store.Call(nil, &value, "get"); err != nil || value.Sign() != 0 {
+		t.Fatalf("committed value mismatch: have %v, want 0 (err %v)", value, err)
+	}
+	if err := store.Call(&bind.CallOpts{Pending: true}, &value, "get"); err != nil || value.Cmp(big.NewInt(42)) != 0 {
+		t.Fatalf("pending value mismatch: have %v, want 42 (err %v)", value, err)
+	}
+	sim.Commit()
+
+	if err := store.Call(nil, &value, "get"); err != nil || value.Cmp(big.NewInt(42)) != 0 {
+		t.Fatalf("committed value mismatch: have %v, want 42 (err %v)", value, err)
+	}
+	// The event can be filtered by its indexed value
+	for _, tt := range []struct {
+		rule []interface{}
+		logs int
+	}{{nil, 1}, {[]interface{}{big.NewInt(42)}, 1}, {[]interface{}{big.NewInt(43)}, 0}} {
+		logs, err := store.FilterLogs(&bind.FilterOpts{}, "Set", tt.rule)
+		if err != nil {
+			t.Fatalf("failed to filter logs: %v", err)
+		}
+		if len(logs) != tt.logs {
+			t.Fatalf("rule %v: log count mismatch: have %d, want %d", tt.rule, len(logs), tt.logs)
+		}
+	}
+	// Transactions which can't be executed are rejected
+	auth.Nonce = big.NewInt(0)
+	if _, err := store.Transact(auth, "set", big.NewInt(1)); err == nil {
+		t.Fatalf("transaction with stale nonce accepted")
+	}
+}
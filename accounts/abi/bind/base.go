@@ -0,0 +1,272 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/pbfcoin/go-pbfcoin/accounts/abi"
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/core/vm"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+)
+
+// SignerFn is a signer function callback when a contract requires a method to
+// sign the transaction before submission.
+type SignerFn func(from common.Address, tx *types.Transaction) (*types.Transaction, error)
+
+// CallOpts is the collection of options to fine tune a contract call request.
+type CallOpts struct {
+	Pending bool // Whpbfer to operate on the pending state or the last known one
+}
+
+// TransactOpts is the collection of authorization data required to create a
+// valid transaction.
+type TransactOpts struct {
+	From   common.Address // Sender of the transaction
+	Nonce  *big.Int       // Nonce to use for the transaction execution (nil = use pending state)
+	Signer SignerFn       // Method to use for signing the transaction (mandatory)
+
+	Value    *big.Int // Funds to transfer along the transaction (nil = 0 = no funds)
+	GasPrice *big.Int // Gas price to use for the transaction execution (nil = gas price oracle)
+	GasLimit *big.Int // Gas limit to set for the transaction execution (nil = estimate)
+}
+
+// FilterOpts is the collection of options to fine tune the filtering of the
+// logs of a bound contract.
+type FilterOpts struct {
+	Start uint64  // Start of the queried range
+	End   *uint64 // End of the range (nil = latest)
+}
+
+// NewKeyedTransactor is a utility method to easily create a transaction signer
+// from a plain private key.
+func NewKeyedTransactor(key *ecdsa.PrivateKey) *TransactOpts {
+	keyAddr := crypto.PubkeyToAddress(key.PublicKey)
+	return &TransactOpts{
+		From: keyAddr,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != keyAddr {
+				return nil, errors.New("not authorized to sign this account")
+			}
+			return tx.SignECDSA(key)
+		},
+	}
+}
+
+// BoundContract is the base wrapper object that reflects a contract on the
+// chain. It contains a collection of methods that are used by the higher level
+// contract bindings to operate.
+type BoundContract struct {
+	address common.Address  // Deployment address of the contract on the chain
+	abi     abi.ABI         // Reflect based ABI to access the correct methods
+	backend ContractBackend // Backend to use to interact with the chain
+}
+
+// NewBoundContract creates a low level contract interface through which calls
+// and transactions may be made through.
+func NewBoundContract(address common.Address, abi abi.ABI, backend ContractBackend) *BoundContract {
+	return &BoundContract{
+		address: address,
+		abi:     abi,
+		backend: backend,
+	}
+}
+
+// DeployContract deploys a contract onto the chain and wraps the API around it.
+// The contract address is derived from the sender and the nonce, the contract
+// only exists once the returned transaction is mined.
+func DeployContract(opts *TransactOpts, abi abi.ABI, bytecode []byte, backend ContractBackend, params ...interface{}) (common.Address, *types.Transaction, *BoundContract, error) {
+	// Otherwise try to deploy the contract
+	c := NewBoundContract(common.Address{}, abi, backend)
+
+	input, err := c.abi.Pack("", params...)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	tx, err := c.transact(opts, nil, append(common.CopyBytes(bytecode), input...))
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	c.address = crypto.CreateAddress(opts.From, tx.Nonce())
+	return c.address, tx, c, nil
+}
+
+// Address returns the deployment address of the contract.
+func (c *BoundContract) Address() common.Address {
+	return c.address
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a struct for named returns or a slice of interfaces for anonymous
+// returns.
+func (c *BoundContract) Call(opts *CallOpts, result interface{}, method string, params ...interface{}) error {
+	// Don't crash on a lazy user
+	if opts == nil {
+		opts = new(CallOpts)
+	}
+	input, err := c.abi.Pack(method, params...)
+	if err != nil {
+		return err
+	}
+	output, err := c.backend.CallContract(CallMsg{To: &c.address, Data: input}, opts.Pending)
+	if err != nil {
+		return err
+	}
+	return c.abi.Unpack(result, method, output)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (c *BoundContract) Transact(opts *TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	input, err := c.abi.Pack(method, params...)
+	if err != nil {
+		return nil, err
+	}
+	return c.transact(opts, &c.address, input)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (c *BoundContract) Transfer(opts *TransactOpts) (*types.Transaction, error) {
+	return c.transact(opts, &c.address, nil)
+}
+
+// transact executes an actual transaction invocation, first deriving any missing
+// authorization fields, and then scheduling the transaction for execution.
+func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, input []byte) (*types.Transaction, error) {
+	if opts.Signer == nil {
+		return nil, errors.New("no signer to authorize the transaction with")
+	}
+	// Ensure a valid value field and resolve the account nonce
+	value := opts.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	var nonce uint64
+	if opts.Nonce == nil {
+		var err error
+		if nonce, err = c.backend.PendingNonceAt(opts.From); err != nil {
+			return nil, fmt.Errorf("failed to retrieve account nonce: %v", err)
+		}
+	} else {
+		nonce = opts.Nonce.Uint64()
+	}
+	// Figure out the gas allowance and gas price values
+	gasPrice := opts.GasPrice
+	if gasPrice == nil {
+		var err error
+		if gasPrice, err = c.backend.SuggestGasPrice(); err != nil {
+			return nil, fmt.Errorf("failed to suggest gas price: %v", err)
+		}
+	}
+	gasLimit := opts.GasLimit
+	if gasLimit == nil {
+		msg := CallMsg{From: opts.From, To: contract, Value: value, Data: input}
+		var err error
+		if gasLimit, err = c.backend.EstimateGas(msg); err != nil {
+			return nil, fmt.Errorf("failed to estimate gas needed: %v", err)
+		}
+	}
+	// Create the transaction, sign it and schedule it for execution
+	var rawTx *types.Transaction
+	if contract == nil {
+		rawTx = types.NewContractCreation(nonce, value, gasLimit, gasPrice, input)
+	} else {
+		rawTx = types.NewTransaction(nonce, c.address, value, gasLimit, gasPrice, input)
+	}
+	signedTx, err := opts.Signer(opts.From, rawTx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.backend.SendTransaction(signedTx); err != nil {
+		return nil, err
+	}
+	return signedTx, nil
+}
+
+// FilterLogs retrieves the logs of the named event emitted by the contract.
+// Each query lists the accepted values of an indexed event argument in order,
+// an empty query accepts any value.
+func (c *BoundContract) FilterLogs(opts *FilterOpts, name string, query ...[]interface{}) (vm.Logs, error) {
+	// Don't crash on a lazy user
+	if opts == nil {
+		opts = new(FilterOpts)
+	}
+	event, exist := c.abi.Events[name]
+	if !exist {
+		return nil, fmt.Errorf("event '%s' not found", name)
+	}
+	var topics [][]common.Hash
+	if !event.Anonymous {
+		topics = append(topics, []common.Hash{event.Id()})
+	}
+	for i, rule := range query {
+		hashes := make([]common.Hash, len(rule))
+		for j, value := range rule {
+			topic, err := makeTopic(value)
+			if err != nil {
+				return nil, fmt.Errorf("`%s` topic %d: %v", name, i, err)
+			}
+			hashes[j] = topic
+		}
+		topics = append(topics, hashes)
+	}
+	end := int64(-1)
+	if opts.End != nil {
+		end = int64(*opts.End)
+	}
+	return c.backend.FilterLogs(FilterQuery{
+		FromBlock: int64(opts.Start),
+		ToBlock:   end,
+		Addresses: []common.Address{c.address},
+		Topics:    topics,
+	})
+}
+
+// UnpackLog decodes the arguments of the named event from log into out.
+func (c *BoundContract) UnpackLog(out interface{}, name string, log *vm.Log) error {
+	return c.abi.UnpackLog(out, name, log.Topics, log.Data)
+}
+
+// makeTopic converts the value of an indexed event argument to the topic it
+// is stored as. Strings are stored as the hash of their contents, fixed size
+// byte arrays as is.
+func makeTopic(value interface{}) (common.Hash, error) {
+	switch value := value.(type) {
+	case common.Hash:
+		return value, nil
+	case common.Address:
+		return common.BytesToHash(value.Bytes()), nil
+	case *big.Int:
+		return common.BytesToHash(abi.U256(new(big.Int).Set(value))), nil
+	case bool:
+		if value {
+			return common.BigToHash(common.Big1), nil
+		}
+		return common.Hash{}, nil
+	case string:
+		return crypto.Sha3Hash([]byte(value)), nil
+	case []byte:
+		return common.BytesToHash(common.RightPadBytes(value, 32)), nil
+	}
+	return common.Hash{}, fmt.Errorf("unsupported indexed type %T", value)
+}
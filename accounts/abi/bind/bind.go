@@ -0,0 +1,234 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bind generates Go bindings for pbfcoin contracts.
+//
+// The generated code wraps a BoundContract, which packs the calls and
+// transactions using the contract ABI and sends them through a
+// ContractBackend, e.g. a node reached via RPC or a simulated chain.
+package bind
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/pbfcoin/go-pbfcoin/accounts/abi"
+)
+
+// Bind generates a Go wrapper around a contract ABI. This wrapper isn't meant
+// to be used as is in client code, but rather as an intermediate struct which
+// enforces compile time type safety and naming convention opposed to having to
+// manually maintain hard coded strings that break on runtime.
+//
+// The types, abis and bytecodes slices describe one contract each, bytecodes
+// may be empty in which case no deployment method is generated.
+func Bind(types []string, abis []string, bytecodes []string, pkg string) (string, error) {
+	if len(types) != len(abis) || len(types) != len(bytecodes) {
+		return "", fmt.Errorf("contract count mismatch: %d types, %d abis, %d bytecodes", len(types), len(abis), len(bytecodes))
+	}
+	contracts := make([]*tmplContract, len(types))
+	for i, typ := range types {
+		evmABI, err := abi.JSON(strings.NewReader(abis[i]))
+		if err != nil {
+			return "", err
+		}
+		constructor, err := bindArguments(evmABI.Constructor.Inputs, "arg")
+		if err != nil {
+			return "", fmt.Errorf("%s constructor: %v", typ, err)
+		}
+		contract := &tmplContract{
+			Type:        capitalise(typ),
+			InputABI:    strings.Replace(strings.TrimSpace(abis[i]), "`", "", -1),
+			InputBin:    strings.TrimPrefix(strings.TrimSpace(bytecodes[i]), "0x"),
+			Constructor: constructor,
+		}
+		functions := evmABI.Functions()
+		for _, name := range sortedKeys(functions) {
+			original := functions[name]
+			inputs, err := bindArguments(original.Inputs, "arg")
+			if err != nil {
+				return "", fmt.Errorf("%s.%s: %v", typ, original.Name, err)
+			}
+			outputs, err := bindArguments(original.Outputs, "ret")
+			if err != nil {
+				return "", fmt.Errorf("%s.%s: %v", typ, original.Name, err)
+			}
+			m := &tmplFunction{
+				Original:   original.Name,
+				Name:       capitalise(original.Name),
+				Inputs:     inputs,
+				Outputs:    outputs,
+				Structured: len(original.Outputs) > 1,
+			}
+			if original.Const {
+				contract.Calls = append(contract.Calls, m)
+			} else {
+				contract.Transacts = append(contract.Transacts, m)
+			}
+		}
+		for _, name := range sortedKeys(evmABI.Events) {
+			original := evmABI.Events[name]
+			fields, err := bindArguments(original.Inputs, "arg")
+			if err != nil {
+				return "", fmt.Errorf("%s.%s: %v", typ, original.Name, err)
+			}
+			e := &tmplEvent{
+				Original: original.Name,
+				Name:     capitalise(original.Name),
+				Fields:   fields,
+			}
+			for i, input := range original.Inputs {
+				if !input.Indexed {
+					continue
+				}
+				e.Fields[i].Indexed = true
+				if bindTopicType(input.Type) != e.Fields[i].Type {
+					e.Fields[i].Type = "common.Hash"
+				}
+				e.Indexed = append(e.Indexed, e.Fields[i])
+			}
+			contract.Events = append(contract.Events, e)
+		}
+		contracts[i] = contract
+	}
+	// Generate the contract template data content and render it
+	data := &tmplData{
+		Package:   pkg,
+		Contracts: contracts,
+	}
+	buffer := new(bytes.Buffer)
+
+	tmpl := template.Must(template.New("").Parse(tmplSource))
+	if err := tmpl.Execute(buffer, data); err != nil {
+		return "", err
+	}
+	// Pass the code through goimports to clean it up and double check
+	code, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("%v\n%s", err, buffer)
+	}
+	return string(code), nil
+}
+
+// bindArguments converts the arguments of a method or event to their Go
+// counterparts, naming anonymous ones after their position.
+func bindArguments(args []abi.Argument, prefix string) ([]*tmplArgument, error) {
+	bound := make([]*tmplArgument, len(args))
+	for i, arg := range args {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("%s%d", prefix, i)
+		}
+		typ, err := bindType(arg.Type)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %v", name, err)
+		}
+		bound[i] = &tmplArgument{
+			Name:  decapitalise(name),
+			Field: capitalise(name),
+			Type:  typ,
+		}
+	}
+	return bound, nil
+}
+
+// bindType converts an ABI type to the Go type used in the bindings, which is
+// also the type returned when unpacking a value of it. Only number arrays can
+// be unpacked, arrays of other types are refused rather than bound to a Go type
+// the generated code would fail to unpack into at runtime.
+func bindType(t abi.Type) (string, error) {
+	switch {
+	case strings.HasSuffix(t.String(), "]"):
+		if strings.HasPrefix(t.String(), "int") || strings.HasPrefix(t.String(), "uint") {
+			return "[]*big.Int", nil
+		}
+		return "", fmt.Errorf("unsupported array type %s", t)
+	case t.T == abi.AddressTy:
+		return "common.Address", nil
+	case strings.HasPrefix(t.String(), "bytes"):
+		return "[]byte", nil
+	case strings.HasPrefix(t.String(), "int"), strings.HasPrefix(t.String(), "uint"):
+		return "*big.Int", nil
+	case t.String() == "bool":
+		return "bool", nil
+	case strings.HasPrefix(t.String(), "string"):
+		return "string", nil
+	}
+	return "interface{}", nil
+}
+
+// bindTopicType returns the Go type of an indexed event argument. Only the
+// hash of dynamic and array values is stored in the log topics.
+func bindTopicType(t abi.Type) string {
+	if t.String() == "bytes" || t.String() == "string" || strings.HasSuffix(t.String(), "]") {
+		return "common.Hash"
+	}
+	typ, _ := bindType(t) // only arrays are refused, see above
+	return typ
+}
+
+// sortedKeys returns the keys of a method or event map in order, so that the
+// generated code is deterministic.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// capitalise makes the first character of a string upper case, also removing
+// any prefixing underscores from the variable names.
+func capitalise(input string) string {
+	input = strings.TrimLeft(input, "_")
+	if len(input) == 0 {
+		return input
+	}
+	return strings.ToUpper(input[:1]) + input[1:]
+}
+
+// decapitalise makes the first character of a string lower case, prefixing
+// names clashing with Go keywords or the generated code.
+func decapitalise(input string) string {
+	input = strings.TrimLeft(input, "_")
+	if len(input) == 0 {
+		return input
+	}
+	input = string(unicode.ToLower(rune(input[0]))) + input[1:]
+	if reserved[input] {
+		input = "_" + input
+	}
+	return input
+}
+
+var reserved = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true,
+	"func": true, "go": true, "goto": true, "if": true, "import": true,
+	"interface": true, "map": true, "package": true, "range": true, "return": true,
+	"select": true, "struct": true, "switch": true, "type": true, "var": true,
+	"opts": true, "out": true, "err": true, "ret": true, "auth": true, "backend": true,
+	"parsed": true, "address": true, "tx": true, "contract": true,
+	"logs": true, "log": true, "event": true, "events": true,
+	"bind": true, "common": true, "big": true, "abi": true, "vm": true, "types": true,
+}
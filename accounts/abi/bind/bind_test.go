@@ -0,0 +1,214 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var bindTests = []struct {
+	name     string
+	abi      string
+	bytecode string
+	expect   []string
+	tester   string
+}{
+	// A token contract with calls, transactions, a constructor and events
+	{
+		"Token",
+		`[
+			{"type":"constructor","inputs":[{"name":"supply","type":"uint256"},{"name":"name","type":"string"}]},
+			{"type":"function","name":"balanceOf","constant":true,"inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+			{"type":"function","name":"info","constant":true,"inputs":[],"outputs":[{"name":"name","type":"string"},{"name":"decimals","type":"uint8"},{"name":"","type":"bytes32"}]},
+			{"type":"function","name":"transfer","constant":false,"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+			{"type":"function","name":"approve","constant":false,"inputs":[{"name":"_spender","type":"address"},{"name":"type","type":"uint256"}],"outputs":[]},
+			{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"memo","type":"string","indexed":true},{"name":"value","type":"uint256","indexed":false}]}
+		]`,
+		"0x6060",
+		[]string{
+			"const TokenBin = `0x6060`",
+			"func DeployToken(auth *bind.TransactOpts, backend bind.ContractBackend, supply *big.Int, name string)",
+			"func NewToken(address common.Address, backend bind.ContractBackend) (*Token, error)",
+			"func (_Token *Token) BalanceOf(opts *bind.CallOpts, owner common.Address) (ret0 *big.Int, err error)",
+			"type TokenInfoResult struct",
+			"func (_Token *Token) Info(opts *bind.CallOpts) (ret TokenInfoResult, err error)",
+			"func (_Token *Token) Transfer(opts *bind.TransactOpts, to common.Address, value *big.Int) (*types.Transaction, error)",
+			"func (_Token *Token) Approve(opts *bind.TransactOpts, spender common.Address, _type *big.Int) (*types.Transaction, error)",
+			"type TokenTransfer struct",
+			"Memo  common.Hash",
+			"func (_Token *Token) FilterTransfer(opts *bind.FilterOpts, from []common.Address, to []common.Address, memo []common.Hash) ([]*TokenTransfer, error)",
+		},
+		"",
+	},
+	// A contract without bytecode doesn't get a deploy method
+	{
+		"empty",
+		`[]`,
+		"",
+		[]string{
+			"type Empty struct",
+			"func NewEmpty(",
+		},
+		"",
+	},
+	// A contract storing a number, deployed and used through the simulated backend
+	{
+		"Store",
+		readFixture("store.abi"),
+		readFixture("store.bin"),
+		[]string{
+			"func DeployStore(auth *bind.TransactOpts, backend bind.ContractBackend)",
+			"func (_Store *Store) Get(opts *bind.CallOpts) (ret0 *big.Int, err error)",
+			"func (_Store *Store) Set(opts *bind.TransactOpts, value *big.Int) (*types.Transaction, error)",
+		},
+		`
+			key, _ := crypto.GenerateKey()
+			auth := bind.NewKeyedTransactor(key)
+			sim := backends.NewSimulatedBackend(core.GenesisAccount{Address: auth.From, Balance: big.NewInt(10000000000)})
+
+			_, _, store, err := DeployStore(auth, sim)
+			if err != nil {
+				t.Fatalf("failed to deploy contract: %v", err)
+			}
+			sim.Commit()
+
+			if _, err := store.Set(auth, big.NewInt(42)); err != nil {
+				t.Fatalf("failed to set value: %v", err)
+			}
+			sim.Commit()
+
+			if value, err := store.Get(nil); err != nil || value.Cmp(big.NewInt(42)) != 0 {
+				t.Fatalf("value mismatch: have %v, want 42 (err %v)", value, err)
+			}
+			events, err := store.FilterSet(&bind.FilterOpts{}, []*big.Int{big.NewInt(42)})
+			if err != nil {
+				t.Fatalf("failed to filter events: %v", err)
+			}
+			if len(events) != 1 || events[0].Value.Cmp(big.NewInt(42)) != 0 {
+				t.Fatalf("event mismatch: have %v", events)
+			}
+		`,
+	},
+}
+
+// readFixture returns the contents of a file in the testdata directory. The
+// store contract in there is also used by the simulated backend tests. Calls
+// to it with a 4 byte input return the stored number, all others store the
+// first argument and log it as the Set event.
+func readFixture(name string) string {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+// bindTestImports are the imports available to the binding testers.
+const bindTestImports = `
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pbfcoin/go-pbfcoin/accounts/abi/bind"
+	"github.com/pbfcoin/go-pbfcoin/accounts/abi/bind/backends"
+	"github.com/pbfcoin/go-pbfcoin/core"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+)
+
+var (
+	_ = big.NewInt
+	_ = bind.NewKeyedTransactor
+	_ = backends.NewSimulatedBackend
+	_ = core.GenesisAccount{}
+	_ = crypto.GenerateKey
+)
+`
+
+// Tests that the generated bindings are valid Go code and contain the expected
+// methods. The bindings are then compiled and their testers run against the
+// simulated backend.
+func TestBindings(t *testing.T) {
+	// Create a temporary package to compile and test the bindings in
+	ws, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary workspace: %v", err)
+	}
+	defer os.RemoveAll(ws)
+
+	pkg := filepath.Join(ws, "bindtest")
+	if err := os.MkdirAll(pkg, 0700); err != nil {
+		t.Fatalf("failed to create package: %v", err)
+	}
+	for i, tt := range bindTests {
+		code, err := Bind([]string{tt.name}, []string{tt.abi}, []string{tt.bytecode}, "bindtest")
+		if err != nil {
+			t.Errorf("test %d: failed to generate binding: %v", i, err)
+			continue
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), "", code, 0); err != nil {
+			t.Errorf("test %d: generated binding doesn't parse: %v\n%s", i, err, code)
+			continue
+		}
+		for _, expect := range tt.expect {
+			if !strings.Contains(code, expect) {
+				t.Errorf("test %d: binding misses %q\n%s", i, expect, code)
+			}
+		}
+		if tt.bytecode == "" && strings.Contains(code, "func Deploy") {
+			t.Errorf("test %d: deploy method generated without bytecode", i)
+		}
+		if err := ioutil.WriteFile(filepath.Join(pkg, strings.ToLower(tt.name)+".go"), []byte(code), 0600); err != nil {
+			t.Fatalf("test %d: failed to write binding: %v", i, err)
+		}
+		tester := fmt.Sprintf("package bindtest\n%s\nfunc Test%s(t *testing.T) {\n%s\n}\n", bindTestImports, tt.name, tt.tester)
+		if err := ioutil.WriteFile(filepath.Join(pkg, strings.ToLower(tt.name)+"_test.go"), []byte(tester), 0600); err != nil {
+			t.Fatalf("test %d: failed to write tester: %v", i, err)
+		}
+	}
+	if t.Failed() {
+		return
+	}
+	// Compile the bindings and run their testers
+	gocmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not available, bindings not compiled")
+	}
+	cmd := exec.Command(gocmd, "test", "-v")
+	cmd.Dir = pkg
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to run binding tests: %v\n%s", err, out)
+	}
+}
+
+// Tests that arrays which can't be unpacked are refused instead of being bound
+// to Go types the generated code would fail to unpack into.
+func TestBindUnsupportedArrays(t *testing.T) {
+	for _, typ := range []string{"address[]", "bool[]", "string[]"} {
+		abi := fmt.Sprintf(`[{"constant":true,"inputs":[],"name":"get","outputs":[{"name":"","type":"%s"}],"type":"function"}]`, typ)
+		if _, err := Bind([]string{"Unsupported"}, []string{abi}, []string{""}, "bindtest"); err == nil {
+			t.Errorf("%s: binding generated", typ)
+		}
+	}
+}
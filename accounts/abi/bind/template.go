@@ -0,0 +1,182 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+// tmplData is the data structure required to fill the binding template.
+type tmplData struct {
+	Package   string          // Name of the package to place the generated file in
+	Contracts []*tmplContract // List of contracts to generate into this file
+}
+
+// tmplContract contains the data needed to generate an individual contract
+// binding.
+type tmplContract struct {
+	Type        string          // Type name of the main contract binding
+	InputABI    string          // JSON ABI used as the input to generate the binding from
+	InputBin    string          // Optional EVM bytecode used to generate deploy code from
+	Constructor []*tmplArgument // Arguments of the contract constructor
+	Calls       []*tmplFunction // Contract calls that only read state data
+	Transacts   []*tmplFunction // Contract calls that write state data
+	Events      []*tmplEvent    // Contract events accessors
+}
+
+// tmplFunction is a wrapper around an abi method, containing the Go names
+// of its arguments.
+type tmplFunction struct {
+	Original   string          // Original method name in the ABI
+	Name       string          // Capitalised method name of the binding
+	Inputs     []*tmplArgument // Method parameters
+	Outputs    []*tmplArgument // Method return values
+	Structured bool            // Whpbfer the returns should be accumulated into a struct
+}
+
+// tmplEvent is a wrapper around an abi event, containing the Go names of its
+// arguments.
+type tmplEvent struct {
+	Original string          // Original event name in the ABI
+	Name     string          // Capitalised event name of the binding
+	Fields   []*tmplArgument // All the event arguments in declaration order
+	Indexed  []*tmplArgument // The indexed arguments, which the logs can be filtered by
+}
+
+// tmplArgument is a single method or event argument.
+type tmplArgument struct {
+	Name    string // Parameter name in the generated code
+	Field   string // Struct field name in the generated code
+	Type    string // Go type of the argument
+	Indexed bool   // Whpbfer the event argument is stored in the log topics
+}
+
+// tmplSource is the Go source template use to generate the contract binding
+// based on.
+const tmplSource = `
+// This file is an automatically generated Go binding. Do not modify as any
+// change will likely be lost upon the next re-generation!
+
+package {{.Package}}
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/pbfcoin/go-pbfcoin/accounts/abi"
+	"github.com/pbfcoin/go-pbfcoin/accounts/abi/bind"
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/core/vm"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = big.NewInt
+	_ = types.NewTransaction
+	_ = vm.NewLog
+)
+{{range $contract := .Contracts}}
+// {{.Type}}ABI is the input ABI used to generate the binding from.
+const {{.Type}}ABI = ` + "`" + `{{.InputABI}}` + "`" + `
+
+{{if .InputBin}}
+// {{.Type}}Bin is the compiled bytecode used for deploying new contracts.
+const {{.Type}}Bin = ` + "`" + `0x{{.InputBin}}` + "`" + `
+
+// Deploy{{.Type}} deploys a new pbfcoin contract, binding an instance of {{.Type}} to it.
+func Deploy{{.Type}}(auth *bind.TransactOpts, backend bind.ContractBackend{{range .Constructor}}, {{.Name}} {{.Type}}{{end}}) (common.Address, *types.Transaction, *{{.Type}}, error) {
+	parsed, err := abi.JSON(strings.NewReader({{.Type}}ABI))
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	address, tx, contract, err := bind.DeployContract(auth, parsed, common.FromHex({{.Type}}Bin), backend{{range .Constructor}}, {{.Name}}{{end}})
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return address, tx, &{{.Type}}{contract: contract}, nil
+}
+{{end}}
+
+// {{.Type}} is an auto generated Go binding around a pbfcoin contract.
+type {{.Type}} struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// New{{.Type}} creates a new instance of {{.Type}}, bound to a specific deployed contract.
+func New{{.Type}}(address common.Address, backend bind.ContractBackend) (*{{.Type}}, error) {
+	parsed, err := abi.JSON(strings.NewReader({{.Type}}ABI))
+	if err != nil {
+		return nil, err
+	}
+	return &{{.Type}}{contract: bind.NewBoundContract(address, parsed, backend)}, nil
+}
+{{range .Calls}}
+{{if .Structured}}
+// {{$contract.Type}}{{.Name}}Result is the return value of the {{.Original}} call.
+type {{$contract.Type}}{{.Name}}Result struct {
+	{{range .Outputs}}{{.Field}} {{.Type}}
+	{{end}}
+}
+{{end}}
+// {{.Name}} is a free data retrieval call binding the contract method {{.Original}}.
+func (_{{$contract.Type}} *{{$contract.Type}}) {{.Name}}(opts *bind.CallOpts{{range .Inputs}}, {{.Name}} {{.Type}}{{end}}) ({{if .Structured}}ret {{$contract.Type}}{{.Name}}Result, {{else}}{{range $i, $_ := .Outputs}}ret{{$i}} {{.Type}}, {{end}}{{end}}err error) {
+	var out []interface{}
+	if err = _{{$contract.Type}}.contract.Call(opts, &out, "{{.Original}}"{{range .Inputs}}, {{.Name}}{{end}}); err != nil {
+		return
+	}
+	{{if .Structured}}{{range $i, $_ := .Outputs}}ret.{{.Field}} = out[{{$i}}].({{.Type}})
+	{{end}}{{else}}{{range $i, $_ := .Outputs}}ret{{$i}} = out[{{$i}}].({{.Type}})
+	{{end}}{{end}}return
+}
+{{end}}
+{{range .Transacts}}
+// {{.Name}} is a paid mutator transaction binding the contract method {{.Original}}.
+func (_{{$contract.Type}} *{{$contract.Type}}) {{.Name}}(opts *bind.TransactOpts{{range .Inputs}}, {{.Name}} {{.Type}}{{end}}) (*types.Transaction, error) {
+	return _{{$contract.Type}}.contract.Transact(opts, "{{.Original}}"{{range .Inputs}}, {{.Name}}{{end}})
+}
+{{end}}
+{{range .Events}}
+// {{$contract.Type}}{{.Name}} represents a {{.Original}} event raised by the {{$contract.Type}} contract.
+type {{$contract.Type}}{{.Name}} struct {
+	{{range .Fields}}{{.Field}} {{.Type}}
+	{{end}}
+	Raw *vm.Log // Blockchain specific contextual infos
+}
+
+// Filter{{.Name}} retrieves the {{.Original}} events raised by the {{$contract.Type}} contract.
+// The indexed arguments restrict the results to the given values, nil accepts any value.
+func (_{{$contract.Type}} *{{$contract.Type}}) Filter{{.Name}}(opts *bind.FilterOpts{{range .Indexed}}, {{.Name}} []{{.Type}}{{end}}) ([]*{{$contract.Type}}{{.Name}}, error) {
+	{{range .Indexed}}var {{.Name}}Rule []interface{}
+	for _, {{.Name}}Item := range {{.Name}} {
+		{{.Name}}Rule = append({{.Name}}Rule, {{.Name}}Item)
+	}
+	{{end}}logs, err := _{{$contract.Type}}.contract.FilterLogs(opts, "{{.Original}}"{{range .Indexed}}, {{.Name}}Rule{{end}})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*{{$contract.Type}}{{.Name}}, 0, len(logs))
+	for _, log := range logs {
+		var out []interface{}
+		if err := _{{$contract.Type}}.contract.UnpackLog(&out, "{{.Original}}", log); err != nil {
+			return nil, err
+		}
+		event := &{{$contract.Type}}{{.Name}}{Raw: log}
+		{{range $i, $_ := .Fields}}event.{{.Field}} = out[{{$i}}].({{.Type}})
+		{{end}}events = append(events, event)
+	}
+	return events, nil
+}
+{{end}}
+{{end}}
+`
@@ -0,0 +1,52 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"strings"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+)
+
+// Event is an event potentially triggered by the EVM's LOG mechanism. The
+// Inputs list the arguments of the event, indexed arguments are stored in the
+// log's topics, all others in its data.
+type Event struct {
+	Name      string
+	Anonymous bool
+	Inputs    []Argument
+}
+
+// Returns the events string signature according to the ABI spec.
+//
+// Example
+//
+//	event Transfer(address indexed from, address indexed to, uint value)    =    "Transfer(address,address,uint256)"
+func (e Event) String() string {
+	types := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		types[i] = input.Type.String()
+	}
+	return e.Name + "(" + strings.Join(types, ",") + ")"
+}
+
+// Id returns the hash of the event signature, which is stored as the first
+// topic of the logs of non anonymous events.
+func (e Event) Id() common.Hash {
+	return crypto.Sha3Hash([]byte(e.String()))
+}
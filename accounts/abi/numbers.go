@@ -82,6 +82,13 @@ func S2S256(n int64) []byte {
 	return S256(big.NewInt(n))
 }
 
+// packBytesSlice packs a dynamic byte array as its length followed by the
+// bytes, right padded to a multiple of 32 bytes.
+func packBytesSlice(bytes []byte) []byte {
+	padded := common.RightPadBytes(bytes, (len(bytes)+31)/32*32)
+	return append(U2U256(uint64(len(bytes))), padded...)
+}
+
 // packNum packs the given number (using the reflect value) and will cast it to appropriate number representation
 func packNum(value reflect.Value, to byte) []byte {
 	switch kind := value.Kind(); kind {
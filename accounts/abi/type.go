@@ -177,9 +177,12 @@ func (t Type) pack(v interface{}) ([]byte, error) {
 		if t.Size > -1 && value.Len() > t.Size {
 			return nil, fmt.Errorf("%v out of bound. %d for %d", value.Kind(), value.Len(), t.Size)
 		}
-		return []byte(common.LeftPadString(t.String(), 32)), nil
+		if t.isDynamic() {
+			return packBytesSlice([]byte(value.String())), nil
+		}
+		return common.RightPadBytes([]byte(value.String()), 32), nil
 	case reflect.Slice:
-		if t.Size > -1 && value.Len() > t.Size {
+		if t.Size > -1 && !t.isDynamic() && value.Len() > t.Size {
 			return nil, fmt.Errorf("%v out of bound. %d for %d", value.Kind(), value.Len(), t.Size)
 		}
 
@@ -187,6 +190,13 @@ func (t Type) pack(v interface{}) ([]byte, error) {
 		if t.T == AddressTy {
 			return common.LeftPadBytes(v.([]byte), 32), nil
 		}
+		// So are bytes, which are padded on the right
+		if t.isBytes() && value.Type().Elem().Kind() == reflect.Uint8 {
+			if t.isDynamic() {
+				return packBytesSlice(value.Bytes()), nil
+			}
+			return common.RightPadBytes(value.Bytes(), 32), nil
+		}
 
 		// Signed / Unsigned check
 		if (t.T != IntTy && isSigned(value)) || (t.T == UintTy && isSigned(value)) {
@@ -194,6 +204,9 @@ func (t Type) pack(v interface{}) ([]byte, error) {
 		}
 
 		var packed []byte
+		if t.isDynamic() {
+			packed = U2U256(uint64(value.Len()))
+		}
 		for i := 0; i < value.Len(); i++ {
 			packed = append(packed, packNum(value.Index(i), t.T)...)
 		}
@@ -0,0 +1,260 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+)
+
+// isBytes reports whpbfer the type is a fixed (bytesN) or dynamic byte array.
+func (t Type) isBytes() bool {
+	return t.Kind == reflect.Slice && t.Type == byte_ts && t.T != AddressTy
+}
+
+// isArray reports whpbfer the type is a fixed (T[N]) or dynamic (T[]) number array.
+func (t Type) isArray() bool {
+	return t.Kind == reflect.Slice && t.Type != byte_ts
+}
+
+// isDynamic reports whpbfer the value is stored in the tail of the encoding,
+// referenced by an offset in the head.
+func (t Type) isDynamic() bool {
+	switch {
+	case t.Kind == reflect.String:
+		return t.Size < 0
+	case t.isBytes():
+		return t.Size == 0
+	case t.isArray():
+		return t.Size < 0
+	}
+	return false
+}
+
+// headSize returns the number of bytes the type occupies in the head of the
+// encoding. Fixed arrays are stored inline, dynamic values as an offset.
+func (t Type) headSize() int {
+	if t.isArray() && t.Size > 0 {
+		return 32 * t.Size
+	}
+	return 32
+}
+
+// unpack decodes the value of type t stored at offset of output. Numbers are
+// returned as *big.Int, addresses as common.Address, bytes as []byte.
+func (t Type) unpack(output []byte, offset int) (interface{}, error) {
+	if t.isDynamic() {
+		ptr, err := readWord(output, offset)
+		if err != nil {
+			return nil, err
+		}
+		start := new(big.Int).SetBytes(ptr)
+		if start.BitLen() > 31 {
+			return nil, fmt.Errorf("offset %v out of bound", start)
+		}
+		size, err := readLength(output, int(start.Int64()))
+		if err != nil {
+			return nil, err
+		}
+		start.Add(start, big.NewInt(32))
+
+		if t.isArray() {
+			return t.unpackNumbers(output, int(start.Int64()), size)
+		}
+		data, err := readBytes(output, int(start.Int64()), size)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind == reflect.String {
+			return string(data), nil
+		}
+		return data, nil
+	}
+
+	switch {
+	case t.isArray():
+		return t.unpackNumbers(output, offset, t.Size)
+	case t.isBytes():
+		return readBytes(output, offset, t.Size)
+	case t.Kind == reflect.String:
+		data, err := readBytes(output, offset, t.Size)
+		if err != nil {
+			return nil, err
+		}
+		return strings.TrimRight(string(data), "\x00"), nil
+	}
+
+	word, err := readWord(output, offset)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t.T == AddressTy:
+		return common.BytesToAddress(word), nil
+	case t.Kind == reflect.Bool:
+		return word[31] == 1, nil
+	case t.Kind == reflect.Ptr:
+		return t.unpackNumber(word), nil
+	}
+	return nil, fmt.Errorf("cannot unpack type %s", t)
+}
+
+// unpackNumber decodes a single 32 byte word according to the signedness of t.
+func (t Type) unpackNumber(word []byte) *big.Int {
+	n := new(big.Int).SetBytes(word)
+	if strings.HasPrefix(t.stringKind, "int") {
+		return common.S256(n)
+	}
+	return n
+}
+
+func (t Type) unpackNumbers(output []byte, offset, size int) ([]*big.Int, error) {
+	numbers := make([]*big.Int, size)
+	for i := range numbers {
+		word, err := readWord(output, offset+32*i)
+		if err != nil {
+			return nil, err
+		}
+		numbers[i] = t.unpackNumber(word)
+	}
+	return numbers, nil
+}
+
+func readWord(output []byte, offset int) ([]byte, error) {
+	return readBytes(output, offset, 32)
+}
+
+func readLength(output []byte, offset int) (int, error) {
+	word, err := readWord(output, offset)
+	if err != nil {
+		return 0, err
+	}
+	size := new(big.Int).SetBytes(word)
+	if size.BitLen() > 31 || int(size.Int64()) > len(output) {
+		return 0, fmt.Errorf("length %v out of bound", size)
+	}
+	return int(size.Int64()), nil
+}
+
+func readBytes(output []byte, offset, size int) ([]byte, error) {
+	if offset < 0 || offset+size > len(output) {
+		return nil, fmt.Errorf("output out of bound. %d for %d", offset+size, len(output))
+	}
+	return common.CopyBytes(output[offset : offset+size]), nil
+}
+
+// unpackArguments decodes a list of arguments encoded as a tuple.
+func unpackArguments(args []Argument, output []byte) ([]interface{}, error) {
+	values := make([]interface{}, len(args))
+
+	offset := 0
+	for i, arg := range args {
+		value, err := arg.Type.unpack(output, offset)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+		offset += arg.Type.headSize()
+	}
+	return values, nil
+}
+
+// setArguments stores the decoded values of args into v, see ABI.Unpack for
+// the accepted destinations.
+func setArguments(v interface{}, args []Argument, values []interface{}) error {
+	dst := reflect.ValueOf(v)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return fmt.Errorf("abi: unpack into non-pointer %T", v)
+	}
+	dst = dst.Elem()
+
+	if dst.Type() == reflect.TypeOf([]interface{}(nil)) {
+		dst.Set(reflect.ValueOf(values))
+		return nil
+	}
+	if len(args) == 1 && dst.Kind() != reflect.Struct {
+		return setValue(dst, values[0])
+	}
+	if dst.Kind() != reflect.Struct {
+		return fmt.Errorf("abi: unpack %d values into %v", len(values), dst.Type())
+	}
+	for i, arg := range args {
+		field := dst.FieldByName(capitalise(arg.Name))
+		if !field.IsValid() {
+			return fmt.Errorf("abi: field %s not found in %v", capitalise(arg.Name), dst.Type())
+		}
+		if err := setValue(field, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setValue assigns a decoded value to dst, converting numbers and byte arrays
+// to the destination type if needed.
+func setValue(dst reflect.Value, value interface{}) error {
+	src := reflect.ValueOf(value)
+	switch {
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+		return nil
+
+	case src.Type() == big_t:
+		n := value.(*big.Int)
+		switch dst.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n.BitLen() >= dst.Type().Bits() {
+				return fmt.Errorf("abi: %v overflows %v", n, dst.Type())
+			}
+			dst.SetInt(n.Int64())
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n.Sign() < 0 || n.BitLen() > dst.Type().Bits() {
+				return fmt.Errorf("abi: %v overflows %v", n, dst.Type())
+			}
+			dst.SetUint(n.Uint64())
+			return nil
+		}
+
+	case src.Type() == byte_ts:
+		if dst.Kind() == reflect.Array && dst.Type().Elem() == byte_t && dst.Len() == src.Len() {
+			reflect.Copy(dst, src)
+			return nil
+		}
+
+	case src.Type() == reflect.TypeOf(common.Address{}):
+		if dst.Type() == byte_ts {
+			dst.SetBytes(common.CopyBytes(value.(common.Address).Bytes()))
+			return nil
+		}
+	}
+	return fmt.Errorf("abi: cannot unpack %T into %v", value, dst.Type())
+}
+
+// capitalise makes the first character of an argument name upper case, it is
+// used to find the struct field receiving the argument.
+func capitalise(name string) string {
+	name = strings.TrimLeft(name, "_")
+	if len(name) == 0 {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,132 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of go-pbfcoin.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// go-pbfcoin is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-pbfcoin is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-pbfcoin. If not, see <http://www.gnu.org/licenses/>.
+
+// abigen generates Go bindings for pbfcoin contracts.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/pbfcoin/go-pbfcoin/accounts/abi/bind"
+	"github.com/pbfcoin/go-pbfcoin/common/compiler"
+)
+
+var (
+	abiFlag = flag.String("abi", "", "Path to the pbfcoin contract ABI json to bind")
+	binFlag = flag.String("bin", "", "Path to the pbfcoin contract bytecode (generate deploy method)")
+	typFlag = flag.String("type", "", "Go struct name for the binding (default = package name)")
+
+	solFlag  = flag.String("sol", "", "Path to the pbfcoin contract Solidity source to build and bind")
+	solcFlag = flag.String("solc", "solc", "Solidity compiler to use if source builds are requested")
+
+	pkgFlag = flag.String("pkg", "", "Go package name to generate the binding into")
+	outFlag = flag.String("out", "", "Output file for the generated binding (default = stdout)")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:", os.Args[0], "(-abi <file> [-bin <file>] [-type <name>] | -sol <file> [-solc <path>]) -pkg <name> [-out <file>]")
+		flag.PrintDefaults()
+		fmt.Fprintln(os.Stderr, `
+Generates Go bindings for the given contract ABI, or for all the contracts
+of the given Solidity source.`)
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	if *abiFlag == "" && *solFlag == "" {
+		die("No contract ABI (--abi) or Solidity source (--sol) specified")
+	} else if *abiFlag != "" && *solFlag != "" {
+		die("Contract ABI (--abi) and Solidity source (--sol) flags are mutually exclusive")
+	}
+	if *pkgFlag == "" {
+		die("No destination Go package specified (--pkg)")
+	}
+	// Gather the contracts to bind, either from the flags or by compiling them
+	var abis, bins, types []string
+
+	if *solFlag != "" {
+		source, err := ioutil.ReadFile(*solFlag)
+		if err != nil {
+			die("Failed to read Solidity source:", err)
+		}
+		solc, err := compiler.New(*solcFlag)
+		if err != nil {
+			die("Failed to locate Solidity compiler:", err)
+		}
+		contracts, err := solc.Compile(string(source))
+		if err != nil {
+			die("Failed to build Solidity contract:", err)
+		}
+		names := make([]string, 0, len(contracts))
+		for name := range contracts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			abi, _ := json.Marshal(contracts[name].Info.AbiDefinition) // Flatten the compiler parse
+			abis = append(abis, string(abi))
+			bins = append(bins, contracts[name].Code)
+			types = append(types, name)
+		}
+	} else {
+		abi, err := ioutil.ReadFile(*abiFlag)
+		if err != nil {
+			die("Failed to read input ABI:", err)
+		}
+		abis = append(abis, string(abi))
+
+		bin := []byte{}
+		if *binFlag != "" {
+			if bin, err = ioutil.ReadFile(*binFlag); err != nil {
+				die("Failed to read input bytecode:", err)
+			}
+		}
+		bins = append(bins, string(bin))
+
+		kind := *typFlag
+		if kind == "" {
+			kind = *pkgFlag
+		}
+		types = append(types, kind)
+	}
+	// Generate the contract binding
+	code, err := bind.Bind(types, abis, bins, *pkgFlag)
+	if err != nil {
+		die("Failed to generate ABI binding:", err)
+	}
+	// Either flush it out to a file or display on the standard output
+	if *outFlag == "" {
+		fmt.Printf("%s\n", code)
+		return
+	}
+	if err := ioutil.WriteFile(*outFlag, []byte(code), 0600); err != nil {
+		die("Failed to write ABI binding:", err)
+	}
+}
+
+func die(args ...interface{}) {
+	fmt.Fprintln(os.Stderr, args...)
+	os.Exit(1)
+}
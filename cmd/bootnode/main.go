@@ -30,6 +30,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/p2p/discover"
 	"github.com/pbfcoin/go-pbfcoin/p2p/nat"
+	"github.com/pbfcoin/go-pbfcoin/p2p/netutil"
 )
 
 func main() {
@@ -39,9 +40,13 @@ func main() {
 		nodeKeyFile = flag.String("nodekey", "", "private key filename")
 		nodeKeyHex  = flag.String("nodekeyhex", "", "private key as hex (for testing)")
 		natdesc     = flag.String("nat", "none", "port mapping mechanism (any|none|upnp|pmp|extip:<IP>)")
+		netrestrict = flag.String("netrestrict", "", "restrict network communication to the given IP networks (CIDR masks)")
+		netsecret   = flag.String("netkey", "", "pre-shared secret of a private network")
 
-		nodeKey *ecdsa.PrivateKey
-		err     error
+		nodeKey      *ecdsa.PrivateKey
+		restrictList *netutil.Netlist
+		netKey       []byte
+		err          error
 	)
 	flag.Parse()
 	logger.AddLogSystem(logger.NewStdLogSystem(os.Stdout, log.LstdFlags, logger.DebugLevel))
@@ -55,6 +60,14 @@ func main() {
 	if err != nil {
 		log.Fatalf("-nat: %v", err)
 	}
+	if *netrestrict != "" {
+		if restrictList, err = netutil.ParseNetlist(*netrestrict); err != nil {
+			log.Fatalf("-netrestrict: %v", err)
+		}
+	}
+	if *netsecret != "" {
+		netKey = crypto.Sha3([]byte(*netsecret))
+	}
 	switch {
 	case *nodeKeyFile == "" && *nodeKeyHex == "":
 		log.Fatal("Use -nodekey or -nodekeyhex to specify a private key")
@@ -70,7 +83,7 @@ func main() {
 		}
 	}
 
-	if _, err := discover.ListenUDP(nodeKey, *listenAddr, natm, ""); err != nil {
+	if _, err := discover.ListenUDP(nodeKey, *listenAddr, natm, "", restrictList, nil, netKey); err != nil {
 		log.Fatal(err)
 	}
 	select {}
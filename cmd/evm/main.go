@@ -32,6 +32,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/core/vm"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/params"
 	"github.com/pbfcoin/go-pbfcoin/pbfdb"
 )
 
@@ -190,6 +191,7 @@ func (self *VMEnv) MakeSnapshot() vm.Database  { return self.state.Copy() }
 func (self *VMEnv) SetSnapshot(db vm.Database) { self.state.Set(db.(*state.StateDB)) }
 func (self *VMEnv) Origin() common.Address     { return *self.transactor }
 func (self *VMEnv) BlockNumber() *big.Int      { return common.Big0 }
+func (self *VMEnv) Rules() *params.Rules       { return params.DefaultRules() }
 func (self *VMEnv) Coinbase() common.Address   { return *self.transactor }
 func (self *VMEnv) Time() *big.Int             { return self.time }
 func (self *VMEnv) Difficulty() *big.Int       { return common.Big1 }
@@ -211,6 +213,9 @@ func (self *VMEnv) AddStructLog(log vm.StructLog) {
 func (self *VMEnv) StructLogs() []vm.StructLog {
 	return self.logs
 }
+func (self *VMEnv) Tracer() vm.Tracer {
+	return nil
+}
 func (self *VMEnv) AddLog(log *vm.Log) {
 	self.state.AddLog(log)
 }
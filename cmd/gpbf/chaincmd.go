@@ -34,6 +34,17 @@ import (
 )
 
 var (
+	initCommand = cli.Command{
+		Action: initGenesis,
+		Name:   "init",
+		Usage:  "initialize a new chain database from a genesis file",
+		Description: `
+Writes the genesis block described by the JSON file given as the first argument
+into the chain database of the data directory and prints its hash and state root.
+Malformed genesis values are reported along with their field. A database which
+already contains a different genesis block is left untouched.
+`,
+	}
 	importCommand = cli.Command{
 		Action: importChain,
 		Name:   "import",
@@ -60,6 +71,17 @@ if already existing.
 		Name:   "removedb",
 		Usage:  "Remove blockchain and state databases",
 	}
+	pruneCommand = cli.Command{
+		Action: pruneState,
+		Name:   "prune",
+		Usage:  "delete the state of old blocks",
+		Description: `
+Deletes the state of all canonical blocks except for the most recent ones
+and the checkpoints, as configured by --pruneretain and --prunecheckpoint.
+The node must not be running. Pruning is incremental, only the blocks which
+fell out of the retention window since the last run are processed.
+`,
+	}
 	dumpCommand = cli.Command{
 		Action: dump,
 		Name:   "dump",
@@ -71,6 +93,25 @@ Use "pbfcoin dump 0" to dump the genesis block.
 	}
 )
 
+func initGenesis(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	file, err := os.Open(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Could not open genesis file: %v", err)
+	}
+	defer file.Close()
+
+	chainDb := utils.MakeChainDatabase(ctx)
+	block, err := core.InitGenesisBlock(chainDb, file)
+	chainDb.Close()
+	if err != nil {
+		utils.Fatalf("Could not write genesis block: %v", err)
+	}
+	fmt.Printf("Genesis block written\nhash:       %x\nstate root: %x\n", block.Hash(), block.Root())
+}
+
 func importChain(ctx *cli.Context) {
 	if len(ctx.Args()) != 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -165,6 +206,26 @@ func upgradeDB(ctx *cli.Context) {
 	}
 }
 
+func pruneState(ctx *cli.Context) {
+	chain, chainDb := utils.MakeChain(ctx)
+	defer chainDb.Close()
+
+	head := chain.CurrentBlock().NumberU64()
+	start := time.Now()
+	deleted, err := core.PruneState(chainDb, head, utils.MakePruneConfig(ctx), nil)
+	if err != nil {
+		utils.Fatalf("Prune error: %v", err)
+	}
+	fmt.Printf("Pruned %d state entries in %v\n", deleted, time.Since(start))
+
+	// Trie nodes are keyed by hash and spread over the whole key space
+	start = time.Now()
+	if err := chainDb.Compact(nil, nil); err != nil {
+		utils.Fatalf("Compaction error: %v", err)
+	}
+	fmt.Printf("Compacted database in %v\n", time.Since(start))
+}
+
 func dump(ctx *cli.Context) {
 	chain, chainDb := utils.MakeChain(ctx)
 	for _, arg := range ctx.Args() {
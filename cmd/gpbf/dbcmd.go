@@ -0,0 +1,71 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of go-pbfcoin.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// go-pbfcoin is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-pbfcoin is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-pbfcoin. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/pbfcoin/go-pbfcoin/cmd/utils"
+	"github.com/pbfcoin/go-pbfcoin/core"
+)
+
+var dbCommand = cli.Command{
+	Name:  "db",
+	Usage: "low level chain database operations",
+	Subcommands: []cli.Command{
+		{
+			Action: inspectDB,
+			Name:   "inspect",
+			Usage:  "report the size of the chain database per data type",
+			Description: `
+Iterates over the whole chain database and prints the number of entries and
+their total size for every kind of stored data. Data not needed by the
+canonical chain, such as side chain blocks and entries left over from old
+database versions, is listed separately. The node must not be running.
+`,
+		},
+	},
+}
+
+func inspectDB(ctx *cli.Context) {
+	chainDb := utils.MakeChainDatabase(ctx)
+	defer chainDb.Close()
+
+	start := time.Now()
+	stats, err := core.InspectDatabase(chainDb)
+	if err != nil {
+		utils.Fatalf("Inspection error: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Category\tEntries\tSize\t")
+	for _, stat := range stats.Categories {
+		fmt.Fprintf(w, "%s\t%d\t%v\t\n", stat.Name, stat.Count, stat.Size)
+	}
+	fmt.Fprintf(w, "Total\t%d\t%v\t\n", stats.Total.Count, stats.Total.Size)
+	fmt.Fprintln(w, "\t\t\t")
+	fmt.Fprintln(w, "Orphaned data\tEntries\tSize\t")
+	for _, stat := range stats.Orphans {
+		fmt.Fprintf(w, "%s\t%d\t%v\t\n", stat.Name, stat.Count, stat.Size)
+	}
+	w.Flush()
+
+	fmt.Printf("\nInspected database in %v\n", time.Since(start))
+}
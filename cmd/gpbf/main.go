@@ -84,11 +84,14 @@ recover <hex> recovers by hash
 `,
 		},
 		blocktestCommand,
+		initCommand,
 		importCommand,
 		exportCommand,
 		upgradedbCommand,
 		removedbCommand,
 		dumpCommand,
+		pruneCommand,
+		dbCommand,
 		monitorCommand,
 		{
 			Action: makedag,
@@ -304,12 +307,25 @@ JavaScript API. See https://github.com/pbfcoin/go-pbfcoin/wiki/Javascipt-Console
 		utils.BlockchainVersionFlag,
 		utils.OlympicFlag,
 		utils.FastSyncFlag,
+		utils.LightModeFlag,
+		utils.LightServFlag,
+		utils.CheckpointFlag,
+		utils.PruneFlag,
+		utils.PruneRetainFlag,
+		utils.PruneCheckpointFlag,
+		utils.AuthorityFlag,
+		utils.AuthorityPeriodFlag,
+		utils.AuthorityEpochFlag,
 		utils.CacheFlag,
 		utils.LightKDFFlag,
 		utils.JSpathFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
+		utils.MaxPeersPerSubnetFlag,
+		utils.NetrestrictFlag,
+		utils.NetdenyFlag,
+		utils.NetworkKeyFlag,
 		utils.pbferbaseFlag,
 		utils.GasPriceFlag,
 		utils.MinerThreadsFlag,
@@ -325,12 +341,18 @@ JavaScript API. See https://github.com/pbfcoin/go-pbfcoin/wiki/Javascipt-Console
 		utils.RPCListenAddrFlag,
 		utils.RPCPortFlag,
 		utils.RpcApiFlag,
+		utils.WSEnabledFlag,
+		utils.WSListenAddrFlag,
+		utils.WSPortFlag,
+		utils.WSApiFlag,
+		utils.WSAllowedOriginsFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCApiFlag,
 		utils.IPCPathFlag,
 		utils.ExecFlag,
 		utils.WhisperEnabledFlag,
 		utils.DevModeFlag,
+		utils.DevPeriodFlag,
 		utils.TestNetFlag,
 		utils.VMDebugFlag,
 		utils.VMForceJitFlag,
@@ -346,6 +368,11 @@ JavaScript API. See https://github.com/pbfcoin/go-pbfcoin/wiki/Javascipt-Console
 		utils.PProfPortFlag,
 		utils.MetricsEnabledFlag,
 		utils.SolcPathFlag,
+		utils.TxPoolPriceBumpFlag,
+		utils.TxPoolGlobalSlotsFlag,
+		utils.TxPoolGlobalQueueFlag,
+		utils.TxPoolAccountSlotsFlag,
+		utils.TxPoolJournalFlag,
 		utils.GpoMinGasPriceFlag,
 		utils.GpoMaxGasPriceFlag,
 		utils.GpoFullBlockRatioFlag,
@@ -586,7 +613,13 @@ func startpbf(ctx *cli.Context, pbf *pbf.pbfcoin) {
 			utils.Fatalf("Error starting RPC: %v", err)
 		}
 	}
-	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) {
+	if ctx.GlobalBool(utils.WSEnabledFlag.Name) {
+		if err := utils.StartWS(pbf, ctx); err != nil {
+			utils.Fatalf("Error starting WS-RPC: %v", err)
+		}
+	}
+	// Dev mode is the only block producer of its chain, so it always seals
+	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) || ctx.GlobalBool(utils.DevModeFlag.Name) {
 		err := pbf.StartMining(
 			ctx.GlobalInt(utils.MinerThreadsFlag.Name),
 			ctx.GlobalString(utils.MiningGPUFlag.Name))
@@ -66,9 +66,19 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.OlympicFlag,
 			utils.TestNetFlag,
 			utils.DevModeFlag,
+			utils.DevPeriodFlag,
 			utils.GenesisFileFlag,
 			utils.IdentityFlag,
 			utils.FastSyncFlag,
+			utils.LightModeFlag,
+			utils.LightServFlag,
+			utils.CheckpointFlag,
+			utils.PruneFlag,
+			utils.PruneRetainFlag,
+			utils.PruneCheckpointFlag,
+			utils.AuthorityFlag,
+			utils.AuthorityPeriodFlag,
+			utils.AuthorityEpochFlag,
 			utils.LightKDFFlag,
 			utils.CacheFlag,
 			utils.BlockchainVersionFlag,
@@ -88,6 +98,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCListenAddrFlag,
 			utils.RPCPortFlag,
 			utils.RpcApiFlag,
+			utils.WSEnabledFlag,
+			utils.WSListenAddrFlag,
+			utils.WSPortFlag,
+			utils.WSApiFlag,
+			utils.WSAllowedOriginsFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCApiFlag,
 			utils.IPCPathFlag,
@@ -103,6 +118,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ListenPortFlag,
 			utils.MaxPeersFlag,
 			utils.MaxPendingPeersFlag,
+			utils.MaxPeersPerSubnetFlag,
+			utils.NetrestrictFlag,
+			utils.NetdenyFlag,
+			utils.NetworkKeyFlag,
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
 			utils.NodeKeyFileFlag,
@@ -121,6 +140,16 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ExtraDataFlag,
 		},
 	},
+	{
+		Name: "TRANSACTION POOL",
+		Flags: []cli.Flag{
+			utils.TxPoolPriceBumpFlag,
+			utils.TxPoolGlobalSlotsFlag,
+			utils.TxPoolGlobalQueueFlag,
+			utils.TxPoolAccountSlotsFlag,
+			utils.TxPoolJournalFlag,
+		},
+	},
 	{
 		Name: "GAS PRICE ORACLE",
 		Flags: []cli.Flag{
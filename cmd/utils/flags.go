@@ -28,10 +28,13 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 
 	"github.com/codegangsta/cli"
 	"github.com/pbfcoin/go-pbfcoin/accounts"
 	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus"
+	"github.com/pbfcoin/go-pbfcoin/consensus/poa"
 	"github.com/pbfcoin/go-pbfcoin/core"
 	"github.com/pbfcoin/go-pbfcoin/core/vm"
 	"github.com/pbfcoin/go-pbfcoin/crypto"
@@ -40,6 +43,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
 	"github.com/pbfcoin/go-pbfcoin/metrics"
 	"github.com/pbfcoin/go-pbfcoin/p2p/nat"
+	"github.com/pbfcoin/go-pbfcoin/p2p/netutil"
 	"github.com/pbfcoin/go-pbfcoin/params"
 	"github.com/pbfcoin/go-pbfcoin/pbf"
 	"github.com/pbfcoin/go-pbfcoin/pbfdb"
@@ -121,6 +125,10 @@ var (
 		Name:  "dev",
 		Usage: "Developer mode: pre-configured private network with several debugging flags",
 	}
+	DevPeriodFlag = cli.IntFlag{
+		Name:  "devperiod",
+		Usage: "Block period of developer mode in seconds (0 = seal on every transaction)",
+	}
 	GenesisFileFlag = cli.StringFlag{
 		Name:  "genesis",
 		Usage: "Insert/overwrite the genesis block (JSON format)",
@@ -152,10 +160,77 @@ var (
 		Name:  "fast",
 		Usage: "Enable fast syncing through state downloads",
 	}
+	LightModeFlag = cli.BoolFlag{
+		Name:  "light",
+		Usage: "Run as a light client, syncing only headers and retrieving state on demand",
+	}
+	LightServFlag = cli.IntFlag{
+		Name:  "lightserv",
+		Usage: "Maximum number of light clients to serve (serving disabled if set to 0)",
+		Value: 0,
+	}
+	CheckpointFlag = cli.StringFlag{
+		Name:  "checkpoint",
+		Usage: "Trusted block to fast or light sync from, as <number>:<hash>:<total difficulty>",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
 	}
+	PruneFlag = cli.BoolFlag{
+		Name:  "prune",
+		Usage: "Delete the state of old blocks, keeping only recent and checkpoint states",
+	}
+	PruneRetainFlag = cli.IntFlag{
+		Name:  "pruneretain",
+		Usage: "Number of recent blocks whose state is kept when pruning",
+		Value: 256,
+	}
+	PruneCheckpointFlag = cli.IntFlag{
+		Name:  "prunecheckpoint",
+		Usage: "Keep the state of every block divisible by this number when pruning (0 = genesis only)",
+		Value: 10000,
+	}
+	AuthorityFlag = cli.BoolFlag{
+		Name:  "authority",
+		Usage: "Use proof-of-authority consensus, the genesis extra-data must list the initial signers",
+	}
+	AuthorityPeriodFlag = cli.IntFlag{
+		Name:  "authorityperiod",
+		Usage: "Minimum number of seconds between proof-of-authority blocks (0 = seal only blocks with transactions)",
+		Value: int(poa.DefaultPeriod),
+	}
+	AuthorityEpochFlag = cli.IntFlag{
+		Name:  "authorityepoch",
+		Usage: "Number of blocks after which proof-of-authority votes are reset and the signers checkpointed",
+		Value: int(poa.DefaultEpoch),
+	}
+	// Transaction pool settings
+	TxPoolPriceBumpFlag = cli.IntFlag{
+		Name:  "txpricebump",
+		Usage: "Minimum gas price bump percentage to replace a pending transaction",
+		Value: int(core.DefaultTxPoolConfig.PriceBump),
+	}
+	TxPoolGlobalSlotsFlag = cli.IntFlag{
+		Name:  "txslots",
+		Usage: "Maximum number of processable transactions in the pool",
+		Value: int(core.DefaultTxPoolConfig.GlobalSlots),
+	}
+	TxPoolGlobalQueueFlag = cli.IntFlag{
+		Name:  "txqueue",
+		Usage: "Maximum number of future (non-processable) transactions in the pool",
+		Value: int(core.DefaultTxPoolConfig.GlobalQueue),
+	}
+	TxPoolAccountSlotsFlag = cli.IntFlag{
+		Name:  "txaccountslots",
+		Usage: "Number of processable transactions per account protected from eviction",
+		Value: int(core.DefaultTxPoolConfig.AccountSlots),
+	}
+	TxPoolJournalFlag = cli.StringFlag{
+		Name:  "txjournal",
+		Usage: "Disk journal for local transactions to survive node restarts, relative to the data dir (empty disables)",
+		Value: core.DefaultTxPoolConfig.Journal,
+	}
 	// Miner settings
 	// TODO: refactor CPU vs GPU mining flags
 	MiningEnabledFlag = cli.BoolFlag{
@@ -280,6 +355,30 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: comms.DefaultHttpRpcApis,
 	}
+	WSEnabledFlag = cli.BoolFlag{
+		Name:  "ws",
+		Usage: "Enable the WS-RPC server",
+	}
+	WSListenAddrFlag = cli.StringFlag{
+		Name:  "wsaddr",
+		Usage: "WS-RPC server listening interface",
+		Value: "127.0.0.1",
+	}
+	WSPortFlag = cli.IntFlag{
+		Name:  "wsport",
+		Usage: "WS-RPC server listening port",
+		Value: 7576,
+	}
+	WSApiFlag = cli.StringFlag{
+		Name:  "wsapi",
+		Usage: "API's offered over the WS-RPC interface",
+		Value: comms.DefaultHttpRpcApis,
+	}
+	WSAllowedOriginsFlag = cli.StringFlag{
+		Name:  "wsorigins",
+		Usage: "Origins from which to accept websockets requests (space separated, * for any)",
+		Value: comms.DefaultWsOrigin,
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -332,6 +431,23 @@ var (
 		Usage: "NAT port mapping mechanism (any|none|upnp|pmp|extip:<IP>)",
 		Value: "any",
 	}
+	NetrestrictFlag = cli.StringFlag{
+		Name:  "netrestrict",
+		Usage: "Restricts network communication to the given IP networks (comma-separated CIDR masks)",
+	}
+	NetdenyFlag = cli.StringFlag{
+		Name:  "netdeny",
+		Usage: "Refuses network communication with the given IP networks (comma-separated CIDR masks)",
+	}
+	MaxPeersPerSubnetFlag = cli.IntFlag{
+		Name:  "maxsubnetpeers",
+		Usage: "Maximum number of peers from a single /24 (IPv4) or /64 (IPv6) subnet (no limit if set to 0)",
+		Value: 0,
+	}
+	NetworkKeyFlag = cli.StringFlag{
+		Name:  "netkey",
+		Usage: "Pre-shared secret of a private network, nodes not holding it can't connect or discover",
+	}
 	NoDiscoverFlag = cli.BoolFlag{
 		Name:  "nodiscover",
 		Usage: "Disables the peer discovery mechanism (manual peer addition)",
@@ -394,6 +510,30 @@ func MakeNAT(ctx *cli.Context) nat.Interface {
 	return natif
 }
 
+// MakeNetlist creates an IP network list from the given command line flag, or
+// returns nil if the flag is not set.
+func MakeNetlist(ctx *cli.Context, flag cli.StringFlag) *netutil.Netlist {
+	masks := ctx.GlobalString(flag.Name)
+	if masks == "" {
+		return nil
+	}
+	list, err := netutil.ParseNetlist(masks)
+	if err != nil {
+		Fatalf("Option %s: %v", flag.Name, err)
+	}
+	return list
+}
+
+// MakeNetworkKey derives the private network key from the shared secret set on
+// the command line, or returns nil if the network is public.
+func MakeNetworkKey(ctx *cli.Context) []byte {
+	secret := ctx.GlobalString(NetworkKeyFlag.Name)
+	if secret == "" {
+		return nil
+	}
+	return crypto.Sha3([]byte(secret))
+}
+
 // MakeNodeKey creates a node key from set command line flags.
 func MakeNodeKey(ctx *cli.Context) (key *ecdsa.PrivateKey) {
 	hex, file := ctx.GlobalString(NodeKeyHexFlag.Name), ctx.GlobalString(NodeKeyFileFlag.Name)
@@ -413,6 +553,59 @@ func MakeNodeKey(ctx *cli.Context) (key *ecdsa.PrivateKey) {
 	return key
 }
 
+// MakePruneConfig creates the state pruning options from set command line flags.
+func MakePruneConfig(ctx *cli.Context) *core.PruneConfig {
+	if retain := ctx.GlobalInt(PruneRetainFlag.Name); retain < core.MinPruneRetain {
+		Fatalf("Option %q: must be at least %d, have %d", PruneRetainFlag.Name, core.MinPruneRetain, retain)
+	}
+	return &core.PruneConfig{
+		Retain:     uint64(ctx.GlobalInt(PruneRetainFlag.Name)),
+		Checkpoint: uint64(ctx.GlobalInt(PruneCheckpointFlag.Name)),
+	}
+}
+
+// MakeAuthorityConfig creates the proof-of-authority consensus options from set
+// command line flags, or nil if proof-of-work is used.
+func MakeAuthorityConfig(ctx *cli.Context) *poa.Config {
+	if !ctx.GlobalBool(AuthorityFlag.Name) {
+		return nil
+	}
+	return &poa.Config{
+		Period: uint64(ctx.GlobalInt(AuthorityPeriodFlag.Name)),
+		Epoch:  uint64(ctx.GlobalInt(AuthorityEpochFlag.Name)),
+	}
+}
+
+// MakeCheckpoint creates the trusted sync checkpoint from the command line flag,
+// or nil if none was given.
+func MakeCheckpoint(ctx *cli.Context) *params.Checkpoint {
+	value := ctx.GlobalString(CheckpointFlag.Name)
+	if value == "" {
+		return nil
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		Fatalf("Option %q: want <number>:<hash>:<total difficulty>", CheckpointFlag.Name)
+	}
+	number, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		Fatalf("Option %q: invalid number: %v", CheckpointFlag.Name, err)
+	}
+	hash := common.FromHex(parts[1])
+	if len(hash) != len(common.Hash{}) {
+		Fatalf("Option %q: invalid hash length %d, want %d bytes", CheckpointFlag.Name, len(hash), len(common.Hash{}))
+	}
+	td, ok := new(big.Int).SetString(parts[2], 0)
+	if !ok {
+		Fatalf("Option %q: invalid total difficulty %q", CheckpointFlag.Name, parts[2])
+	}
+	checkpoint := &params.Checkpoint{Number: number, Hash: common.BytesToHash(hash), TD: td}
+	if err := checkpoint.Validate(); err != nil {
+		Fatalf("Option %q: %v", CheckpointFlag.Name, err)
+	}
+	return checkpoint
+}
+
 // MakepbfConfig creates pbfcoin options from set command line flags.
 func MakepbfConfig(clientID, version string, ctx *cli.Context) *pbf.Config {
 	customName := ctx.GlobalString(IdentityFlag.Name)
@@ -430,6 +623,10 @@ func MakepbfConfig(clientID, version string, ctx *cli.Context) *pbf.Config {
 		DataDir:                 MustDataDir(ctx),
 		GenesisFile:             ctx.GlobalString(GenesisFileFlag.Name),
 		FastSync:                ctx.GlobalBool(FastSyncFlag.Name),
+		LightMode:               ctx.GlobalBool(LightModeFlag.Name),
+		LightServ:               ctx.GlobalInt(LightServFlag.Name),
+		Authority:               MakeAuthorityConfig(ctx),
+		Checkpoint:              MakeCheckpoint(ctx),
 		BlockChainVersion:       ctx.GlobalInt(BlockchainVersionFlag.Name),
 		DatabaseCache:           ctx.GlobalInt(CacheFlag.Name),
 		SkipBcVersionCheck:      false,
@@ -442,6 +639,10 @@ func MakepbfConfig(clientID, version string, ctx *cli.Context) *pbf.Config {
 		VmDebug:                 ctx.GlobalBool(VMDebugFlag.Name),
 		MaxPeers:                ctx.GlobalInt(MaxPeersFlag.Name),
 		MaxPendingPeers:         ctx.GlobalInt(MaxPendingPeersFlag.Name),
+		MaxPeersPerSubnet:       ctx.GlobalInt(MaxPeersPerSubnetFlag.Name),
+		NetRestrict:             MakeNetlist(ctx, NetrestrictFlag),
+		NetDeny:                 MakeNetlist(ctx, NetdenyFlag),
+		NetworkKey:              MakeNetworkKey(ctx),
 		Port:                    ctx.GlobalString(ListenPortFlag.Name),
 		Olympic:                 ctx.GlobalBool(OlympicFlag.Name),
 		NAT:                     MakeNAT(ctx),
@@ -461,11 +662,33 @@ func MakepbfConfig(clientID, version string, ctx *cli.Context) *pbf.Config {
 		GpobaseCorrectionFactor: ctx.GlobalInt(GpobaseCorrectionFactorFlag.Name),
 		SolcPath:                ctx.GlobalString(SolcPathFlag.Name),
 		AutoDAG:                 ctx.GlobalBool(AutoDAGFlag.Name) || ctx.GlobalBool(MiningEnabledFlag.Name),
+		TxPool: core.TxPoolConfig{
+			PriceBump:    uint64(ctx.GlobalInt(TxPoolPriceBumpFlag.Name)),
+			GlobalSlots:  uint64(ctx.GlobalInt(TxPoolGlobalSlotsFlag.Name)),
+			GlobalQueue:  uint64(ctx.GlobalInt(TxPoolGlobalQueueFlag.Name)),
+			AccountSlots: uint64(ctx.GlobalInt(TxPoolAccountSlotsFlag.Name)),
+			Journal:      ctx.GlobalString(TxPoolJournalFlag.Name),
+			Rejournal:    core.DefaultTxPoolConfig.Rejournal,
+		},
+	}
+
+	if ctx.GlobalBool(PruneFlag.Name) {
+		cfg.StatePrune = MakePruneConfig(ctx)
 	}
 
 	if ctx.GlobalBool(DevModeFlag.Name) && ctx.GlobalBool(TestNetFlag.Name) {
 		glog.Fatalf("%s and %s are mutually exclusive\n", DevModeFlag.Name, TestNetFlag.Name)
 	}
+	if ctx.GlobalBool(LightModeFlag.Name) {
+		for _, flag := range []string{MiningEnabledFlag.Name, DevModeFlag.Name, FastSyncFlag.Name} {
+			if ctx.GlobalBool(flag) {
+				glog.Fatalf("%s and %s are mutually exclusive\n", LightModeFlag.Name, flag)
+			}
+		}
+		if ctx.GlobalInt(LightServFlag.Name) > 0 {
+			glog.Fatalf("%s and %s are mutually exclusive\n", LightModeFlag.Name, LightServFlag.Name)
+		}
+	}
 
 	if ctx.GlobalBool(TestNetFlag.Name) {
 		// testnet is always stored in the testnet folder
@@ -494,10 +717,17 @@ func MakepbfConfig(clientID, version string, ctx *cli.Context) *pbf.Config {
 			cfg.Shh = true
 		}
 		if !ctx.GlobalIsSet(DataDirFlag.Name) {
-			cfg.DataDir = os.TempDir() + "/pbfcoin_dev_mode"
+			cfg.DataDir = devDataDir()
 		}
+		developer := MakeDevAccount(am)
+		if !ctx.GlobalIsSet(pbferbaseFlag.Name) {
+			cfg.pbferbase = developer.Address
+		}
+		cfg.AutoDAG = false
 		cfg.PowTest = true
 		cfg.DevMode = true
+		cfg.DevPeriod = uint64(ctx.GlobalInt(DevPeriodFlag.Name))
+		cfg.DevAccount = developer.Address
 
 		glog.V(logger.Info).Infoln("dev mode enabled")
 	}
@@ -521,8 +751,8 @@ func SetupNetwork(ctx *cli.Context) {
 		params.MinGasLimit = big.NewInt(125000)
 		params.MaximumExtraDataSize = big.NewInt(1024)
 		NetworkIdFlag.Value = 0
-		core.BlockReward = big.NewInt(1.5e+18)
-		core.ExpDiffPeriod = big.NewInt(math.MaxInt64)
+		params.BlockReward = big.NewInt(1.5e+18)
+		params.ExpDiffPeriod = big.NewInt(math.MaxInt64)
 	}
 }
 
@@ -533,15 +763,22 @@ func SetupVM(ctx *cli.Context) {
 	vm.SetJITCacheSize(ctx.GlobalInt(VMJitCacheFlag.Name))
 }
 
-// MakeChain creates a chain manager from set command line flags.
-func MakeChain(ctx *cli.Context) (chain *core.BlockChain, chainDb pbfdb.Database) {
+// MakeChainDatabase opens the chain database from set command line flags.
+func MakeChainDatabase(ctx *cli.Context) pbfdb.Database {
 	datadir := MustDataDir(ctx)
 	cache := ctx.GlobalInt(CacheFlag.Name)
 
-	var err error
-	if chainDb, err = pbfdb.NewLDBDatabase(filepath.Join(datadir, "chaindata"), cache); err != nil {
+	chainDb, err := pbfdb.NewLDBDatabase(filepath.Join(datadir, "chaindata"), cache)
+	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
+	return chainDb
+}
+
+// MakeChain creates a chain manager from set command line flags.
+func MakeChain(ctx *cli.Context) (chain *core.BlockChain, chainDb pbfdb.Database) {
+	var err error
+	chainDb = MakeChainDatabase(ctx)
 	if ctx.GlobalBool(OlympicFlag.Name) {
 		_, err := core.WriteTestNetGenesisBlock(chainDb, 42)
 		if err != nil {
@@ -550,9 +787,12 @@ func MakeChain(ctx *cli.Context) (chain *core.BlockChain, chainDb pbfdb.Database
 	}
 
 	eventMux := new(event.TypeMux)
-	pow := pbfash.New()
+	var engine consensus.Engine = core.NewPowEngine(pbfash.New())
+	if config := MakeAuthorityConfig(ctx); config != nil {
+		engine = poa.New(config)
+	}
 	//genesis := core.GenesisBlock(uint64(ctx.GlobalInt(GenesisNonceFlag.Name)), blockDB)
-	chain, err = core.NewBlockChain(chainDb, pow, eventMux)
+	chain, err = core.NewBlockChain(chainDb, engine, eventMux)
 	if err != nil {
 		Fatalf("Could not start chainmanager: %v", err)
 	}
@@ -566,6 +806,9 @@ func MakeAccountManager(ctx *cli.Context) *accounts.Manager {
 	if ctx.GlobalBool(TestNetFlag.Name) {
 		dataDir += "/testnet"
 	}
+	if ctx.GlobalBool(DevModeFlag.Name) && !ctx.GlobalIsSet(DataDirFlag.Name) {
+		dataDir = devDataDir()
+	}
 	scryptN := crypto.StandardScryptN
 	scryptP := crypto.StandardScryptP
 	if ctx.GlobalBool(LightKDFFlag.Name) {
@@ -576,6 +819,32 @@ func MakeAccountManager(ctx *cli.Context) *accounts.Manager {
 	return accounts.NewManager(ks)
 }
 
+// MakeDevAccount retrieves the developer account of dev mode, which is the first
+// account of the keystore or a new one with an empty password if there is none,
+// and unlocks it so transactions can be sent without a passphrase.
+func MakeDevAccount(am *accounts.Manager) accounts.Account {
+	accs, err := am.Accounts()
+	if err != nil && err != accounts.ErrNoKeys {
+		Fatalf("Could not list accounts: %v", err)
+	}
+	var developer accounts.Account
+	if len(accs) > 0 {
+		developer = accs[0]
+	} else if developer, err = am.NewAccount(""); err != nil {
+		Fatalf("Could not create developer account: %v", err)
+	}
+	if err := am.Unlock(developer.Address, ""); err != nil {
+		Fatalf("Could not unlock developer account %x (it needs an empty password): %v", developer.Address, err)
+	}
+	glog.V(logger.Info).Infof("Using developer account %x", developer.Address)
+	return developer
+}
+
+// devDataDir is the data directory of dev mode if none is set explicitly.
+func devDataDir() string {
+	return filepath.Join(os.TempDir(), "pbfcoin_dev_mode")
+}
+
 // MustDataDir retrieves the currently requested data directory, terminating if
 // none (or the empty string) is specified.
 func MustDataDir(ctx *cli.Context) string {
@@ -641,6 +910,32 @@ func StartRPC(pbf *pbf.pbfcoin, ctx *cli.Context) error {
 	return comms.StartHttp(config, codec, api.Merge(apis...))
 }
 
+func StartWS(pbf *pbf.pbfcoin, ctx *cli.Context) error {
+	config := comms.WsConfig{
+		ListenAddress: ctx.GlobalString(WSListenAddrFlag.Name),
+		ListenPort:    uint(ctx.GlobalInt(WSPortFlag.Name)),
+		Origins:       ctx.GlobalString(WSAllowedOriginsFlag.Name),
+	}
+
+	xpbf := xpbf.New(pbf, nil)
+	codec := codec.JSON
+
+	apis, err := api.ParseApiString(ctx.GlobalString(WSApiFlag.Name), codec, xpbf, pbf)
+	if err != nil {
+		return err
+	}
+
+	if err := comms.StartWs(config, codec, api.Merge(apis...), pbf.EventMux()); err != nil {
+		return err
+	}
+	// hijacked WebSocket connections outlive the listener, close them explicitly
+	go func() {
+		pbf.WaitForShutdown()
+		comms.StopWs()
+	}()
+	return nil
+}
+
 func StartPProf(ctx *cli.Context) {
 	address := fmt.Sprintf("localhost:%d", ctx.GlobalInt(PProfPortFlag.Name))
 	go func() {
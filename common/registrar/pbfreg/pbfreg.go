@@ -42,7 +42,10 @@ func (self *pbfReg) Registry() *registrar.Registrar {
 func (self *pbfReg) Resolver(n *big.Int) *registrar.Registrar {
 	xe := self.backend
 	if n != nil {
-		xe = self.backend.AtStateNum(n.Int64())
+		// Resolve against the current state if the requested one is unavailable
+		if st, err := self.backend.AtStateNum(n.Int64()); err == nil {
+			xe = st
+		}
 	}
 	return registrar.New(xe)
 }
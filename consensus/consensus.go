@@ -0,0 +1,97 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package consensus defines the interface a consensus engine has to implement
+// to be able to validate and seal blocks for the block chain.
+package consensus
+
+import (
+	"math/big"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/state"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/params"
+)
+
+// ChainReader defines the small collection of methods needed to access the local
+// block chain during header verification and sealing.
+type ChainReader interface {
+	// Config retrieves the hard-fork schedule of the local chain, nil if the
+	// chain runs with the default rules.
+	Config() *params.ChainConfig
+
+	// Gpbfeader retrieves a block header from the local chain by hash.
+	Gpbfeader(hash common.Hash) *types.Header
+}
+
+// Engine is an algorithm agnostic consensus engine.
+type Engine interface {
+	// Author retrieves the address of the account that minted the given block,
+	// which may be different from the header's coinbase if the engine is based
+	// on signatures.
+	Author(header *types.Header) (common.Address, error)
+
+	// VerifyHeader checks the engine specific fields of a header (extra-data,
+	// timestamp against the parent and difficulty). The generic fields are
+	// verified by the caller, the seal is verified separately via VerifySeal.
+	VerifyHeader(chain ChainReader, header, parent *types.Header, uncle bool) error
+
+	// VerifySeal checks whpbfer the cryptographic seal on a header is valid
+	// according to the consensus rules of the given engine.
+	VerifySeal(chain ChainReader, header *types.Header) error
+
+	// CalcDifficulty returns the difficulty that a new block created at the
+	// given time on top of parent should have.
+	CalcDifficulty(chain ChainReader, time uint64, parent *types.Header) *big.Int
+
+	// Prepare initializes the consensus fields of a block header according to
+	// the rules of the engine. The changes are executed inline.
+	Prepare(chain ChainReader, header *types.Header) error
+
+	// Finalize runs any post-transaction state modifications (e.g. block
+	// rewards). The header's state root is not updated, that is left to the
+	// caller.
+	Finalize(chain ChainReader, state *state.StateDB, header *types.Header, uncles []*types.Header) error
+
+	// Seal generates a new block for the given input block with the local
+	// miner's seal placed on top. It blocks until the seal is found or stop is
+	// closed, in which case a nil block is returned.
+	Seal(chain ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error)
+}
+
+// PoW is a consensus engine based on proof-of-work. Only proof-of-work engines
+// allow uncles to be included in blocks.
+type PoW interface {
+	Engine
+
+	// SealThread is like Seal, but searches as the given mining thread so that
+	// concurrently mining threads don't duplicate each other's work.
+	SealThread(chain ChainReader, block *types.Block, stop <-chan struct{}, thread int) (*types.Block, error)
+
+	// Hashrate returns the current mining hashrate of the engine.
+	Hashrate() int64
+}
+
+// CheckpointVerifier is implemented by consensus engines which can't verify the
+// chain above every header, because they rebuild their state from particular
+// blocks. Syncs from a trusted checkpoint are only started at headers passing
+// VerifyCheckpoint.
+type CheckpointVerifier interface {
+	// VerifyCheckpoint checks whpbfer the chain above the header can be
+	// verified without any of the header's ancestors.
+	VerifyCheckpoint(header *types.Header) error
+}
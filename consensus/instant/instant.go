@@ -0,0 +1,145 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package instant implements a developer mode consensus engine that seals
+// blocks without any proof, either as soon as they contain transactions or on
+// a fixed period.
+package instant
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus"
+	"github.com/pbfcoin/go-pbfcoin/core/state"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+)
+
+// difficulty is the constant difficulty of every block, so the total difficulty
+// of the chain is simply its length.
+var difficulty = big.NewInt(1)
+
+var (
+	// errUnknownBlock is returned when the parent of a header being prepared
+	// is not part of the local chain.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errInvalidTimestamp is returned if the timestamp of a block is lower
+	// than the previous block's timestamp plus the sealing period.
+	errInvalidTimestamp = errors.New("invalid timestamp")
+
+	// errInvalidDifficulty is returned if the difficulty of a block is not 1.
+	errInvalidDifficulty = errors.New("invalid difficulty")
+
+	// errUnclesNotAllowed is returned if a block contains uncles.
+	errUnclesNotAllowed = errors.New("uncles not allowed")
+)
+
+// Instant is the developer mode consensus engine. Blocks carry no seal at all,
+// so it must only be used on private chains where the local node is the only
+// block producer.
+//
+// With a zero period a block is sealed as soon as it contains a transaction and
+// several blocks may share the same timestamp; otherwise a block, possibly an
+// empty one, is sealed every period seconds.
+type Instant struct {
+	period uint64
+}
+
+// New creates an instant sealing engine sealing blocks at most every period
+// seconds, or on every transaction if period is zero.
+func New(period uint64) *Instant {
+	return &Instant{period: period}
+}
+
+// Author returns the coinbase of the header.
+func (e *Instant) Author(header *types.Header) (common.Address, error) {
+	return header.Coinbase, nil
+}
+
+// VerifyHeader checks the extra-data size, the timestamp spacing and the
+// constant difficulty.
+func (e *Instant) VerifyHeader(chain consensus.ChainReader, header, parent *types.Header, uncle bool) error {
+	if uncle {
+		return errUnclesNotAllowed
+	}
+	if big.NewInt(int64(len(header.Extra))).Cmp(chain.Config().Rules(header.Number).MaximumExtraDataSize) == 1 {
+		return fmt.Errorf("Header extra data too long (%d)", len(header.Extra))
+	}
+	if header.Time.Uint64() < parent.Time.Uint64()+e.period {
+		return errInvalidTimestamp
+	}
+	if header.Difficulty == nil || header.Difficulty.Cmp(difficulty) != 0 {
+		return errInvalidDifficulty
+	}
+	return nil
+}
+
+// VerifySeal accepts every header, blocks are not sealed by this engine.
+func (e *Instant) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
+	return nil
+}
+
+// CalcDifficulty returns the constant difficulty of all blocks.
+func (e *Instant) CalcDifficulty(chain consensus.ChainReader, time uint64, parent *types.Header) *big.Int {
+	return new(big.Int).Set(difficulty)
+}
+
+// Prepare sets the difficulty and moves the timestamp to the earliest moment the
+// block may be sealed, which is never before the current time.
+func (e *Instant) Prepare(chain consensus.ChainReader, header *types.Header) error {
+	parent := chain.Gpbfeader(header.ParentHash)
+	if parent == nil {
+		return errUnknownBlock
+	}
+	header.Difficulty = new(big.Int).Set(difficulty)
+
+	timestamp := parent.Time.Uint64() + e.period
+	if now := uint64(time.Now().Unix()); timestamp < now {
+		timestamp = now
+	}
+	header.Time = new(big.Int).SetUint64(timestamp)
+	return nil
+}
+
+// Finalize does not reward anyone, the developer account is funded in the
+// genesis block instead.
+func (e *Instant) Finalize(chain consensus.ChainReader, statedb *state.StateDB, header *types.Header, uncles []*types.Header) error {
+	if len(uncles) > 0 {
+		return errUnclesNotAllowed
+	}
+	return nil
+}
+
+// Seal returns the block unchanged once its timestamp is reached. With a zero
+// period, empty blocks are never sealed: Seal waits for stop to be closed, which
+// happens when the miner commits new work for an arriving transaction.
+func (e *Instant) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
+	if e.period == 0 && len(block.Transactions()) == 0 {
+		<-stop
+		return nil, nil
+	}
+	delay := time.Unix(block.Time().Int64(), 0).Sub(time.Now())
+	select {
+	case <-stop:
+		return nil, nil
+	case <-time.After(delay):
+	}
+	return block, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package instant
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/params"
+)
+
+// testerChain is a consensus.ChainReader backed by an in-memory header set.
+type testerChain map[common.Hash]*types.Header
+
+func (c testerChain) Config() *params.ChainConfig              { return nil }
+func (c testerChain) Gpbfeader(hash common.Hash) *types.Header { return c[hash] }
+
+// newTesterChain creates a chain containing a single header with the given
+// timestamp.
+func newTesterChain(time int64) (testerChain, *types.Header) {
+	parent := &types.Header{
+		Number:     big.NewInt(1),
+		Time:       big.NewInt(time),
+		Difficulty: big.NewInt(1),
+		GasLimit:   new(big.Int),
+		GasUsed:    new(big.Int),
+	}
+	return testerChain{parent.Hash(): parent}, parent
+}
+
+// Tests that prepared headers pass verification and that headers breaking the
+// timestamp spacing or the difficulty are rejected.
+func TestVerifyHeader(t *testing.T) {
+	tests := []struct {
+		period uint64
+		parent int64
+	}{
+		{0, time.Now().Unix()},       // instant sealing within the parent's second
+		{0, time.Now().Unix() - 100}, // instant sealing long after the parent
+		{5, time.Now().Unix()},       // periodic sealing right after the parent
+		{5, time.Now().Unix() - 100}, // periodic sealing long after the parent
+	}
+	for i, tt := range tests {
+		engine := New(tt.period)
+		chain, parent := newTesterChain(tt.parent)
+
+		header := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(2), Time: new(big.Int)}
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Errorf("test %d: failed to prepare header: %v", i, err)
+			continue
+		}
+		if min := uint64(tt.parent) + tt.period; header.Time.Uint64() < min {
+			t.Errorf("test %d: timestamp mismatch: have %v, want at least %v", i, header.Time, min)
+		}
+		if err := engine.VerifyHeader(chain, header, parent, false); err != nil {
+			t.Errorf("test %d: prepared header rejected: %v", i, err)
+		}
+		if err := engine.VerifyHeader(chain, header, parent, true); err != errUnclesNotAllowed {
+			t.Errorf("test %d: uncle verification error mismatch: have %v, want %v", i, err, errUnclesNotAllowed)
+		}
+		early := types.CopyHeader(header)
+		early.Time = new(big.Int).Sub(parent.Time, big.NewInt(1))
+		if err := engine.VerifyHeader(chain, early, parent, false); err != errInvalidTimestamp {
+			t.Errorf("test %d: early header error mismatch: have %v, want %v", i, err, errInvalidTimestamp)
+		}
+		hard := types.CopyHeader(header)
+		hard.Difficulty = big.NewInt(2)
+		if err := engine.VerifyHeader(chain, hard, parent, false); err != errInvalidDifficulty {
+			t.Errorf("test %d: difficulty error mismatch: have %v, want %v", i, err, errInvalidDifficulty)
+		}
+	}
+}
+
+// Tests that instant sealing only seals blocks with transactions, while empty
+// blocks wait for new work.
+func TestInstantSeal(t *testing.T) {
+	engine := New(0)
+	chain, parent := newTesterChain(time.Now().Unix())
+
+	header := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(2), Time: new(big.Int)}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	// Empty blocks must not be sealed until the work is aborted
+	stop := make(chan struct{})
+	done := make(chan *types.Block)
+	go func() {
+		block, _ := engine.Seal(chain, types.NewBlockWithHeader(header), stop)
+		done <- block
+	}()
+	select {
+	case block := <-done:
+		t.Fatalf("empty block sealed: %v", block)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(stop)
+	select {
+	case block := <-done:
+		if block != nil {
+			t.Fatalf("aborted seal returned block: %v", block)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("seal not aborted")
+	}
+	// Blocks with transactions must be sealed right away
+	tx := types.NewTransaction(0, common.Address{}, new(big.Int), big.NewInt(21000), new(big.Int), nil)
+	block := types.NewBlock(header, []*types.Transaction{tx}, nil, nil)
+
+	go func() {
+		sealed, _ := engine.Seal(chain, block, make(chan struct{}))
+		done <- sealed
+	}()
+	select {
+	case sealed := <-done:
+		if sealed == nil || sealed.Hash() != block.Hash() {
+			t.Fatalf("sealed block mismatch: have %v, want %v", sealed, block)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("block with transactions not sealed")
+	}
+}
+
+// Tests that periodic sealing seals empty blocks once their timestamp is reached.
+func TestPeriodicSeal(t *testing.T) {
+	engine := New(1)
+	chain, parent := newTesterChain(time.Now().Unix())
+
+	header := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(2), Time: new(big.Int)}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	block, err := engine.Seal(chain, types.NewBlockWithHeader(header), make(chan struct{}))
+	if err != nil || block == nil {
+		t.Fatalf("failed to seal empty block: %v", err)
+	}
+	if now := time.Now().Unix(); now < header.Time.Int64() {
+		t.Errorf("block sealed too early: now %d, timestamp %v", now, header.Time)
+	}
+}
@@ -0,0 +1,586 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package poa implements a proof-of-authority consensus engine, where a set of
+// authorized signers takes turns sealing blocks and votes signers in or out of
+// the set through the header extra-data.
+package poa
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus"
+	"github.com/pbfcoin/go-pbfcoin/core/state"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/crypto/sha3"
+	"github.com/pbfcoin/go-pbfcoin/logger"
+	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/rlp"
+)
+
+const (
+	inmemorySnapshots  = 128  // Number of recent vote snapshots to keep in memory
+	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
+
+	wiggleTime = 500 * time.Millisecond // Random delay (per signer) to allow concurrent signers
+
+	// The extra-data of every header is laid out as vanity | payload | seal,
+	// where the payload is the signer list on checkpoint blocks and an optional
+	// vote on every other block.
+	extraVanity = 32                // Fixed number of extra-data prefix bytes reserved for signer vanity
+	extraSeal   = 65                // Fixed number of extra-data suffix bytes reserved for signer seal
+	extraVote   = addressLength + 1 // Size of a vote: the candidate and the vote kind
+
+	addressLength = len(common.Address{}) // Size of a signer address within the extra-data
+
+	voteAdd  byte = 0x01 // Vote flag to add a new signer
+	voteDrop byte = 0x00 // Vote flag to remove an existing signer
+)
+
+var (
+	DefaultPeriod uint64 = 15    // Default minimum difference between two consecutive block's timestamps
+	DefaultEpoch  uint64 = 30000 // Default number of blocks after which to checkpoint and reset the pending votes
+
+	diffInTurn = big.NewInt(2) // Block difficulty for in-turn signatures
+	diffNoTurn = big.NewInt(1) // Block difficulty for out-of-turn signatures
+)
+
+var (
+	// errUnknownBlock is returned when the list of signers is requested for a
+	// block that is not part of the local blockchain.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errMissingVanity is returned if a block's extra-data section is shorter than
+	// 32 bytes, which is required to store the signer vanity.
+	errMissingVanity = errors.New("extra-data 32 byte vanity prefix missing")
+
+	// errMissingSignature is returned if a block's extra-data section doesn't seem
+	// to contain a 65 byte secp256k1 signature.
+	errMissingSignature = errors.New("extra-data 65 byte signature suffix missing")
+
+	// errInvalidCheckpointSigners is returned if a checkpoint block contains an
+	// invalid list of signers (i.e. non divisible by 20 bytes, or not the list
+	// of currently authorized signers).
+	errInvalidCheckpointSigners = errors.New("invalid signer list on checkpoint block")
+
+	// errInvalidVote is returned if a non-checkpoint block's extra-data payload
+	// is not a single well formed vote.
+	errInvalidVote = errors.New("invalid vote in extra-data")
+
+	// errInvalidTimestamp is returned if the timestamp of a block is lower than
+	// the previous block's timestamp + the minimum block period.
+	errInvalidTimestamp = errors.New("invalid timestamp")
+
+	// errInvalidDifficulty is returned if the difficulty of a block is neither 1
+	// nor 2, or it doesn't match the turn of the signer.
+	errInvalidDifficulty = errors.New("invalid difficulty")
+
+	// errUnclesNotAllowed is returned if a block contains uncles, which are
+	// meaningless in proof-of-authority.
+	errUnclesNotAllowed = errors.New("uncles not allowed")
+
+	// errUnauthorized is returned if a header is signed by a non-authorized entity.
+	errUnauthorized = errors.New("unauthorized signer")
+
+	// errRecentlySigned is returned if a header is signed by an authorized entity
+	// that already signed a header recently, thus is temporarily not allowed to.
+	errRecentlySigned = errors.New("signed recently, must wait for others")
+
+	// errNoSigner is returned if sealing is attempted without a local signer.
+	errNoSigner = errors.New("no signer authorized for sealing")
+
+	// errCheckpointNotEpoch is returned if a sync is started from a checkpoint
+	// which isn't an epoch block, above which no signer set can be rebuilt.
+	errCheckpointNotEpoch = errors.New("checkpoint is not an epoch block")
+)
+
+// Config is the consensus configuration of a proof-of-authority chain.
+type Config struct {
+	Period uint64 // Number of seconds between blocks to enforce, 0 seals blocks only when there are transactions
+	Epoch  uint64 // Epoch length to checkpoint the signers and reset the pending votes
+}
+
+// SignerFn is a signer callback function to request a hash to be signed by a
+// backing account.
+type SignerFn func(signer common.Address, hash []byte) ([]byte, error)
+
+// PoA is the proof-of-authority consensus engine.
+//
+// PoA implements consensus.Engine.
+type PoA struct {
+	config *Config
+
+	recents    *lru.Cache // Snapshots for recent blocks to speed up reorgs
+	signatures *lru.Cache // Signatures of recent blocks to speed up sealing
+
+	proposals map[common.Address]bool // Current list of proposals we are pushing
+
+	signer common.Address // pbfcoin address of the signing key
+	signFn SignerFn       // Signer function to authorize hashes with
+	lock   sync.RWMutex   // Protects the signer and proposal fields
+}
+
+// New creates a proof-of-authority consensus engine. A zero epoch is replaced by
+// its default, a zero period seals blocks on demand.
+func New(config *Config) *PoA {
+	conf := *config
+	if conf.Epoch == 0 {
+		conf.Epoch = DefaultEpoch
+	}
+	recents, _ := lru.New(inmemorySnapshots)
+	signatures, _ := lru.New(inmemorySignatures)
+
+	return &PoA{
+		config:     &conf,
+		recents:    recents,
+		signatures: signatures,
+		proposals:  make(map[common.Address]bool),
+	}
+}
+
+// Authorize injects the account and signing function the engine seals new
+// blocks with.
+func (p *PoA) Authorize(signer common.Address, signFn SignerFn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.signer = signer
+	p.signFn = signFn
+}
+
+// Propose injects a new vote to add (or remove) the given signer that the local
+// signer will cast in the blocks it seals, until the vote passes or is
+// discarded.
+func (p *PoA) Propose(address common.Address, authorize bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.proposals[address] = authorize
+}
+
+// Discard drops a currently running proposal.
+func (p *PoA) Discard(address common.Address) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.proposals, address)
+}
+
+// Proposals returns the currently running proposals of the local signer.
+func (p *PoA) Proposals() map[common.Address]bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	proposals := make(map[common.Address]bool, len(p.proposals))
+	for address, authorize := range p.proposals {
+		proposals[address] = authorize
+	}
+	return proposals
+}
+
+// Signers retrieves the list of authorized signers at the given block.
+func (p *PoA) Signers(chain consensus.ChainReader, header *types.Header) ([]common.Address, error) {
+	snap, err := p.snapshot(chain, header.Number.Uint64(), header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return snap.signers(), nil
+}
+
+// Author retrieves the account that signed the header.
+func (p *PoA) Author(header *types.Header) (common.Address, error) {
+	return p.ecrecover(header)
+}
+
+// VerifyHeader checks the layout of the extra-data, the timestamp spacing and
+// the range of the difficulty. Whpbfer the signer was allowed to seal the header
+// is checked by VerifySeal.
+func (p *PoA) VerifyHeader(chain consensus.ChainReader, header, parent *types.Header, uncle bool) error {
+	if uncle {
+		return errUnclesNotAllowed
+	}
+	if len(header.Extra) < extraVanity {
+		return errMissingVanity
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return errMissingSignature
+	}
+	payload := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+	if header.Number.Uint64()%p.config.Epoch == 0 {
+		if len(payload) == 0 || len(payload)%addressLength != 0 {
+			return errInvalidCheckpointSigners
+		}
+	} else {
+		if _, _, ok := parseVote(payload); !ok {
+			return errInvalidVote
+		}
+	}
+	if new(big.Int).Add(parent.Time, new(big.Int).SetUint64(p.period())).Cmp(header.Time) > 0 {
+		return errInvalidTimestamp
+	}
+	if header.Difficulty == nil || (header.Difficulty.Cmp(diffInTurn) != 0 && header.Difficulty.Cmp(diffNoTurn) != 0) {
+		return errInvalidDifficulty
+	}
+	return nil
+}
+
+// VerifySeal checks that the header was signed by an authorized signer that
+// didn't sign too recently, that the difficulty matches the signer's turn and
+// that checkpoint headers carry the current signer set.
+func (p *PoA) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return errUnknownBlock
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return errMissingSignature
+	}
+	snap, err := p.snapshot(chain, number-1, header.ParentHash)
+	if err != nil {
+		return err
+	}
+	signer, err := p.ecrecover(header)
+	if err != nil {
+		return err
+	}
+	if err := snap.verify(number, signer); err != nil {
+		return err
+	}
+	if expd := snap.difficulty(number, signer); header.Difficulty.Cmp(expd) != 0 {
+		return errInvalidDifficulty
+	}
+	if number%p.config.Epoch == 0 {
+		signers := snap.signers()
+		payload := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+		if !bytes.Equal(payload, encodeSigners(signers)) {
+			return errInvalidCheckpointSigners
+		}
+	}
+	return nil
+}
+
+// VerifyCheckpoint checks that a sync checkpoint is an epoch block carrying a
+// signer list, as the signer snapshots above it are rebuilt from that list.
+//
+// PoA implements consensus.CheckpointVerifier.
+func (p *PoA) VerifyCheckpoint(header *types.Header) error {
+	if header.Number.Uint64()%p.config.Epoch != 0 {
+		return errCheckpointNotEpoch
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return errMissingSignature
+	}
+	payload := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+	if len(payload) == 0 || len(payload)%addressLength != 0 {
+		return errInvalidCheckpointSigners
+	}
+	return nil
+}
+
+// CalcDifficulty returns 2 if the local signer is in turn to seal the block
+// following parent, 1 otherwise.
+func (p *PoA) CalcDifficulty(chain consensus.ChainReader, time uint64, parent *types.Header) *big.Int {
+	snap, err := p.snapshot(chain, parent.Number.Uint64(), parent.Hash())
+	if err != nil {
+		return new(big.Int).Set(diffNoTurn)
+	}
+	p.lock.RLock()
+	signer := p.signer
+	p.lock.RUnlock()
+
+	return snap.difficulty(parent.Number.Uint64()+1, signer)
+}
+
+// Prepare fills in the difficulty, the timestamp and the extra-data of the
+// header: the signer list on checkpoints, or one of the local proposals
+// otherwise.
+func (p *PoA) Prepare(chain consensus.ChainReader, header *types.Header) error {
+	parent := chain.Gpbfeader(header.ParentHash)
+	if parent == nil {
+		return errUnknownBlock
+	}
+	number := header.Number.Uint64()
+
+	snap, err := p.snapshot(chain, number-1, header.ParentHash)
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	if number%p.config.Epoch == 0 {
+		payload = encodeSigners(snap.signers())
+	} else {
+		p.lock.RLock()
+		var candidates []common.Address
+		for address, authorize := range p.proposals {
+			if snap.validVote(address, authorize) {
+				candidates = append(candidates, address)
+			}
+		}
+		if len(candidates) > 0 {
+			address := candidates[rand.Intn(len(candidates))]
+			payload = encodeVote(address, p.proposals[address])
+		}
+		p.lock.RUnlock()
+	}
+	extra := make([]byte, extraVanity, extraVanity+len(payload)+extraSeal)
+	copy(extra, header.Extra)
+	extra = append(extra, payload...)
+	header.Extra = append(extra, make([]byte, extraSeal)...)
+
+	header.Difficulty = p.CalcDifficulty(chain, header.Time.Uint64(), parent)
+
+	if min := new(big.Int).Add(parent.Time, new(big.Int).SetUint64(p.period())); header.Time.Cmp(min) < 0 {
+		header.Time = min
+	}
+	return nil
+}
+
+// Finalize doesn't modify the state, there are no block rewards in
+// proof-of-authority. Blocks containing uncles are rejected.
+func (p *PoA) Finalize(chain consensus.ChainReader, statedb *state.StateDB, header *types.Header, uncles []*types.Header) error {
+	if len(uncles) > 0 {
+		return errUnclesNotAllowed
+	}
+	return nil
+}
+
+// Seal signs the block with the local signer once its timestamp is reached. It
+// returns a nil block if the local signer signed too recently or stop is closed
+// in the meantime.
+func (p *PoA) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
+	header := block.Header()
+
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil, errUnknownBlock
+	}
+	p.lock.RLock()
+	signer, signFn := p.signer, p.signFn
+	p.lock.RUnlock()
+
+	if signFn == nil {
+		return nil, errNoSigner
+	}
+	// Blocks are sealed on demand without a period, don't seal empty ones
+	if p.config.Period == 0 && len(block.Transactions()) == 0 {
+		<-stop
+		return nil, nil
+	}
+	snap, err := p.snapshot(chain, number-1, header.ParentHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := snap.verify(number, signer); err != nil {
+		if err == errRecentlySigned {
+			glog.V(logger.Debug).Infof("signed recently, waiting for other signers")
+			<-stop
+			return nil, nil
+		}
+		return nil, err
+	}
+	// Sweet, the protocol permits us to sign the block, wait for our time
+	delay := time.Unix(header.Time.Int64(), 0).Sub(time.Now())
+	if header.Difficulty.Cmp(diffNoTurn) == 0 {
+		// It's not our turn explicitly to sign, delay it a bit
+		wiggle := time.Duration(len(snap.Signers)/2+1) * wiggleTime
+		delay += time.Duration(rand.Int63n(int64(wiggle)))
+	}
+	select {
+	case <-stop:
+		return nil, nil
+	case <-time.After(delay):
+	}
+	sig, err := signFn(signer, sigHash(header).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+
+	return types.NewBlockWithHeader(header).WithBody(block.Transactions(), block.Uncles()), nil
+}
+
+// snapshot retrieves the signer set at the given block, replaying the headers
+// since the closest cached snapshot or checkpoint.
+func (p *PoA) snapshot(chain consensus.ChainReader, number uint64, hash common.Hash) (*Snapshot, error) {
+	var (
+		headers []*types.Header
+		snap    *Snapshot
+	)
+	for snap == nil {
+		if s, ok := p.recents.Get(hash); ok {
+			snap = s.(*Snapshot)
+			break
+		}
+		header := chain.Gpbfeader(hash)
+		if header == nil || header.Number.Uint64() != number {
+			return nil, errUnknownBlock
+		}
+		if number%p.config.Epoch == 0 {
+			s, err := newCheckpointSnapshot(p.config, header)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.checkpointRecents(chain, s, header); err != nil {
+				return nil, err
+			}
+			p.recents.Add(hash, s)
+			snap = s
+			break
+		}
+		headers = append(headers, header)
+		number, hash = number-1, header.ParentHash
+	}
+	if len(headers) == 0 {
+		return snap, nil
+	}
+	// Replay the collected headers in chain order on top of the snapshot
+	for i := 0; i < len(headers)/2; i++ {
+		headers[i], headers[len(headers)-1-i] = headers[len(headers)-1-i], headers[i]
+	}
+	snap, err := snap.apply(headers, p.ecrecover)
+	if err != nil {
+		return nil, err
+	}
+	p.recents.Add(snap.Hash, snap)
+	return snap, nil
+}
+
+// checkpointRecents fills in the recent signers of a snapshot created at the
+// given checkpoint, which carry over from the previous epoch. Headers missing
+// below a sync checkpoint are skipped.
+func (p *PoA) checkpointRecents(chain consensus.ChainReader, snap *Snapshot, header *types.Header) error {
+	limit := uint64(len(snap.Signers)/2 + 1)
+	for i := uint64(0); i < limit && header != nil && header.Number.Uint64() > 0; i++ {
+		signer, err := p.ecrecover(header)
+		if err != nil {
+			return err
+		}
+		snap.Recents[header.Number.Uint64()] = signer
+		header = chain.Gpbfeader(header.ParentHash)
+	}
+	return nil
+}
+
+// period returns the minimum number of seconds between two blocks. Blocks
+// sealed on demand still need increasing timestamps.
+func (p *PoA) period() uint64 {
+	if p.config.Period == 0 {
+		return 1
+	}
+	return p.config.Period
+}
+
+// ecrecover extracts the pbfcoin account address from a signed header.
+func (p *PoA) ecrecover(header *types.Header) (common.Address, error) {
+	hash := header.Hash()
+	if address, ok := p.signatures.Get(hash); ok {
+		return address.(common.Address), nil
+	}
+	if len(header.Extra) < extraSeal {
+		return common.Address{}, errMissingSignature
+	}
+	signature := header.Extra[len(header.Extra)-extraSeal:]
+
+	pubkey, err := crypto.SigToPub(sigHash(header).Bytes(), signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	signer := crypto.PubkeyToAddress(*pubkey)
+
+	p.signatures.Add(hash, signer)
+	return signer, nil
+}
+
+// sigHash returns the hash which is used as input for the proof-of-authority
+// signing. It is the hash of the entire header apart from the 65 byte signature
+// contained at the end of the extra-data.
+func sigHash(header *types.Header) (hash common.Hash) {
+	hasher := sha3.NewKeccak256()
+
+	rlp.Encode(hasher, []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-extraSeal],
+		header.MixDigest,
+		header.Nonce,
+	})
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// parseVote decodes the extra-data payload of a non-checkpoint block. An empty
+// payload is valid and casts no vote.
+func parseVote(payload []byte) (address common.Address, authorize bool, ok bool) {
+	switch {
+	case len(payload) == 0:
+		return common.Address{}, false, true
+	case len(payload) != extraVote:
+		return common.Address{}, false, false
+	}
+	switch payload[addressLength] {
+	case voteAdd:
+		authorize = true
+	case voteDrop:
+	default:
+		return common.Address{}, false, false
+	}
+	return common.BytesToAddress(payload[:addressLength]), authorize, true
+}
+
+// encodeVote creates the extra-data payload of a vote.
+func encodeVote(address common.Address, authorize bool) []byte {
+	payload := append(address.Bytes(), voteDrop)
+	if authorize {
+		payload[addressLength] = voteAdd
+	}
+	return payload
+}
+
+// encodeSigners creates the extra-data payload of a checkpoint block.
+func encodeSigners(signers []common.Address) []byte {
+	payload := make([]byte, 0, len(signers)*addressLength)
+	for _, signer := range signers {
+		payload = append(payload, signer.Bytes()...)
+	}
+	return payload
+}
+
+// GenesisExtra assembles the extra-data of a proof-of-authority genesis block
+// authorizing the given initial signers.
+func GenesisExtra(vanity []byte, signers []common.Address) []byte {
+	extra := make([]byte, extraVanity)
+	copy(extra, vanity)
+	extra = append(extra, encodeSigners(sortSigners(signers))...)
+	return append(extra, make([]byte, extraSeal)...)
+}
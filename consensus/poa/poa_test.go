@@ -0,0 +1,368 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package poa
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/params"
+)
+
+// testerChain is a consensus.ChainReader backed by an in-memory header set.
+type testerChain map[common.Hash]*types.Header
+
+func (c testerChain) Config() *params.ChainConfig              { return nil }
+func (c testerChain) Gpbfeader(hash common.Hash) *types.Header { return c[hash] }
+
+// testerAccounts is a pool of named private keys to sign test headers with.
+type testerAccounts map[string]*ecdsa.PrivateKey
+
+func (ap testerAccounts) address(name string) common.Address {
+	if name == "" {
+		return common.Address{}
+	}
+	if ap[name] == nil {
+		ap[name], _ = crypto.GenerateKey()
+	}
+	return crypto.PubkeyToAddress(ap[name].PublicKey)
+}
+
+func (ap testerAccounts) sign(header *types.Header, name string) {
+	if ap[name] == nil {
+		ap[name], _ = crypto.GenerateKey()
+	}
+	sig, _ := crypto.Sign(sigHash(header).Bytes(), ap[name])
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+}
+
+// newTesterChain creates a chain containing only a genesis header authorizing
+// the given signers.
+func newTesterChain(accounts testerAccounts, signers ...string) (testerChain, *types.Header) {
+	addresses := make([]common.Address, len(signers))
+	for i, signer := range signers {
+		addresses[i] = accounts.address(signer)
+	}
+	genesis := &types.Header{
+		Number:     new(big.Int),
+		Time:       new(big.Int),
+		Difficulty: new(big.Int).Set(diffInTurn),
+		GasLimit:   new(big.Int),
+		GasUsed:    new(big.Int),
+		Extra:      GenesisExtra(nil, addresses),
+	}
+	return testerChain{genesis.Hash(): genesis}, genesis
+}
+
+// Tests that signers can be voted in and out of the authorized set.
+func TestVoting(t *testing.T) {
+	type testerVote struct {
+		signer    string
+		voted     string
+		authorize bool
+	}
+	tests := []struct {
+		signers []string
+		votes   []testerVote
+		results []string
+		failure error
+	}{
+		{
+			// Single signer, no votes cast
+			signers: []string{"A"},
+			votes:   []testerVote{{signer: "A"}},
+			results: []string{"A"},
+		}, {
+			// Single signer, voting to add another
+			signers: []string{"A"},
+			votes:   []testerVote{{signer: "A", voted: "B", authorize: true}},
+			results: []string{"A", "B"},
+		}, {
+			// Two signers, a single vote to add a third doesn't pass
+			signers: []string{"A", "B"},
+			votes:   []testerVote{{signer: "A", voted: "C", authorize: true}},
+			results: []string{"A", "B"},
+		}, {
+			// Three signers, adding one and then dropping another
+			signers: []string{"A", "B", "C"},
+			votes: []testerVote{
+				{signer: "A", voted: "D", authorize: true},
+				{signer: "B", voted: "D", authorize: true},
+				{signer: "C"},
+				{signer: "D", voted: "C"},
+				{signer: "A", voted: "C"},
+				{signer: "B", voted: "C"},
+			},
+			results: []string{"A", "B", "D"},
+		}, {
+			// Votes of a dropped signer are discarded
+			signers: []string{"A", "B", "C", "D"},
+			votes: []testerVote{
+				{signer: "C", voted: "E", authorize: true},
+				{signer: "A", voted: "C"},
+				{signer: "B", voted: "C"},
+				{signer: "D", voted: "C"},
+				{signer: "A", voted: "E", authorize: true},
+			},
+			results: []string{"A", "B", "D"},
+		}, {
+			// Pointless votes (adding an existing signer) are ignored
+			signers: []string{"A", "B"},
+			votes: []testerVote{
+				{signer: "A", voted: "B", authorize: true},
+				{signer: "B", voted: "A", authorize: true},
+			},
+			results: []string{"A", "B"},
+		}, {
+			// Unauthorized signers are rejected
+			signers: []string{"A"},
+			votes:   []testerVote{{signer: "B"}},
+			failure: errUnauthorized,
+		}, {
+			// Signers can't sign again before the others had their turn
+			signers: []string{"A", "B"},
+			votes:   []testerVote{{signer: "A"}, {signer: "A"}},
+			failure: errRecentlySigned,
+		},
+	}
+	for i, tt := range tests {
+		accounts := make(testerAccounts)
+		chain, parent := newTesterChain(accounts, tt.signers...)
+
+		for _, vote := range tt.votes {
+			var payload []byte
+			if vote.voted != "" {
+				payload = encodeVote(accounts.address(vote.voted), vote.authorize)
+			}
+			header := &types.Header{
+				ParentHash: parent.Hash(),
+				Number:     new(big.Int).Add(parent.Number, common.Big1),
+				Time:       new(big.Int).Add(parent.Time, common.Big1),
+				Difficulty: new(big.Int).Set(diffNoTurn),
+				GasLimit:   new(big.Int),
+				GasUsed:    new(big.Int),
+				Extra:      append(append(make([]byte, extraVanity), payload...), make([]byte, extraSeal)...),
+			}
+			accounts.sign(header, vote.signer)
+			chain[header.Hash()] = header
+			parent = header
+		}
+		engine := New(&Config{Epoch: 30000})
+		snap, err := engine.snapshot(chain, parent.Number.Uint64(), parent.Hash())
+		if err != tt.failure {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, err, tt.failure)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		want := make([]common.Address, len(tt.results))
+		for j, name := range tt.results {
+			want[j] = accounts.address(name)
+		}
+		want = sortSigners(want)
+
+		have := snap.signers()
+		if len(have) != len(want) {
+			t.Errorf("test %d: signer count mismatch: have %d, want %d", i, len(have), len(want))
+			continue
+		}
+		for j := range have {
+			if have[j] != want[j] {
+				t.Errorf("test %d, signer %d: signer mismatch: have %x, want %x", i, j, have[j], want[j])
+			}
+		}
+	}
+}
+
+// Tests that a block prepared and sealed by the local signer passes verification,
+// and that tampering with it invalidates the seal.
+func TestSealVerification(t *testing.T) {
+	accounts := make(testerAccounts)
+	chain, genesis := newTesterChain(accounts, "A")
+
+	engine := New(&Config{Period: 1, Epoch: 30000})
+	engine.Authorize(accounts.address("A"), func(signer common.Address, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, accounts["A"])
+	})
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		Time:       big.NewInt(1),
+		GasLimit:   new(big.Int),
+		GasUsed:    new(big.Int),
+		Extra:      []byte("vanity"),
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	if header.Difficulty.Cmp(diffInTurn) != 0 {
+		t.Errorf("difficulty mismatch: have %v, want %v", header.Difficulty, diffInTurn)
+	}
+	block, err := engine.Seal(chain, types.NewBlockWithHeader(header), make(chan struct{}))
+	if err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	sealed := block.Header()
+	if err := engine.VerifyHeader(chain, sealed, genesis, false); err != nil {
+		t.Errorf("failed to verify header: %v", err)
+	}
+	if err := engine.VerifySeal(chain, sealed); err != nil {
+		t.Errorf("failed to verify seal: %v", err)
+	}
+	if author, err := engine.Author(sealed); err != nil || author != accounts.address("A") {
+		t.Errorf("author mismatch: have %x (%v), want %x", author, err, accounts.address("A"))
+	}
+	// Tamper with the vanity and make sure the seal no longer verifies
+	sealed.Extra = common.CopyBytes(sealed.Extra)
+	sealed.Extra[0]++
+	if err := engine.VerifySeal(chain, sealed); err != errUnauthorized {
+		t.Errorf("tampered seal error mismatch: have %v, want %v", err, errUnauthorized)
+	}
+	// Out of turn difficulty must be rejected for an in-turn signer
+	header = block.Header()
+	header.Difficulty = new(big.Int).Set(diffNoTurn)
+	accounts.sign(header, "A")
+	if err := engine.VerifySeal(chain, header); err != errInvalidDifficulty {
+		t.Errorf("difficulty error mismatch: have %v, want %v", err, errInvalidDifficulty)
+	}
+}
+
+// Tests that the extra-data layout is enforced on headers.
+func TestVerifyHeaderExtra(t *testing.T) {
+	accounts := make(testerAccounts)
+	chain, genesis := newTesterChain(accounts, "A")
+	engine := New(&Config{Period: 5, Epoch: 2})
+
+	tests := []struct {
+		number  int64
+		time    int64
+		payload []byte
+		short   bool
+		failure error
+	}{
+		{number: 1, time: 5, failure: nil},
+		{number: 1, time: 4, failure: errInvalidTimestamp},
+		{number: 1, time: 5, short: true, failure: errMissingSignature},
+		{number: 1, time: 5, payload: make([]byte, 5), failure: errInvalidVote},
+		{number: 1, time: 5, payload: append(accounts.address("B").Bytes(), 0x02), failure: errInvalidVote},
+		{number: 1, time: 5, payload: encodeVote(accounts.address("B"), true), failure: nil},
+		{number: 2, time: 5, failure: errInvalidCheckpointSigners},
+		{number: 2, time: 5, payload: encodeSigners([]common.Address{accounts.address("A")}), failure: nil},
+	}
+	for i, tt := range tests {
+		extra := append(make([]byte, extraVanity), tt.payload...)
+		if !tt.short {
+			extra = append(extra, make([]byte, extraSeal)...)
+		}
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			Number:     big.NewInt(tt.number),
+			Time:       big.NewInt(tt.time),
+			Difficulty: new(big.Int).Set(diffInTurn),
+			Extra:      extra,
+		}
+		if err := engine.VerifyHeader(chain, header, genesis, false); err != tt.failure {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, err, tt.failure)
+		}
+	}
+}
+
+// Tests that the recent signers carry over into a new epoch, whether the
+// snapshot is replayed across the checkpoint or created at it.
+func TestRecentsAcrossEpoch(t *testing.T) {
+	accounts := make(testerAccounts)
+	chain, parent := newTesterChain(accounts, "A", "B")
+
+	signers := encodeSigners(sortSigners([]common.Address{accounts.address("A"), accounts.address("B")}))
+	for i, signer := range []string{"A", "B", "B"} {
+		var payload []byte
+		if i == 1 {
+			payload = signers
+		}
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Time:       new(big.Int).Add(parent.Time, common.Big1),
+			Difficulty: new(big.Int).Set(diffNoTurn),
+			GasLimit:   new(big.Int),
+			GasUsed:    new(big.Int),
+			Extra:      append(append(make([]byte, extraVanity), payload...), make([]byte, extraSeal)...),
+		}
+		accounts.sign(header, signer)
+		chain[header.Hash()] = header
+		parent = header
+	}
+	checkpoint := chain[parent.ParentHash]
+
+	// Replay the headers from before the checkpoint across it
+	engine := New(&Config{Epoch: 2})
+	snap, err := engine.snapshot(chain, 1, checkpoint.ParentHash)
+	if err != nil {
+		t.Fatalf("failed to create snapshot before the checkpoint: %v", err)
+	}
+	if _, err := snap.apply([]*types.Header{checkpoint, parent}, engine.ecrecover); err != errRecentlySigned {
+		t.Errorf("replayed snapshot: failure mismatch: have %v, want %v", err, errRecentlySigned)
+	}
+	// Start from the snapshot created at the checkpoint
+	engine = New(&Config{Epoch: 2})
+	if _, err := engine.snapshot(chain, 3, parent.Hash()); err != errRecentlySigned {
+		t.Errorf("checkpoint snapshot: failure mismatch: have %v, want %v", err, errRecentlySigned)
+	}
+}
+
+// Tests that a zero period is kept, sealing only blocks with transactions but
+// still requiring increasing timestamps.
+func TestZeroPeriod(t *testing.T) {
+	accounts := make(testerAccounts)
+	chain, genesis := newTesterChain(accounts, "A")
+
+	engine := New(&Config{Epoch: 30000})
+	if engine.config.Period != 0 {
+		t.Fatalf("period mismatch: have %d, want 0", engine.config.Period)
+	}
+	engine.Authorize(accounts.address("A"), func(signer common.Address, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, accounts["A"])
+	})
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		Time:       new(big.Int),
+		GasLimit:   new(big.Int),
+		GasUsed:    new(big.Int),
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	if header.Time.Cmp(common.Big1) != 0 {
+		t.Errorf("timestamp mismatch: have %v, want 1", header.Time)
+	}
+	stop := make(chan struct{})
+	close(stop)
+	if block, err := engine.Seal(chain, types.NewBlockWithHeader(header), stop); block != nil || err != nil {
+		t.Errorf("empty block sealed: %v, %v", block, err)
+	}
+	header.Time = new(big.Int).Set(genesis.Time)
+	accounts.sign(header, "A")
+	if err := engine.VerifyHeader(chain, header, genesis, false); err != errInvalidTimestamp {
+		t.Errorf("failure mismatch: have %v, want %v", err, errInvalidTimestamp)
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package poa
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+)
+
+// Vote represents a single vote that an authorized signer made to modify the
+// list of authorizations.
+type Vote struct {
+	Signer    common.Address // Authorized signer that cast this vote
+	Block     uint64         // Block number the vote was cast in (expire old votes)
+	Address   common.Address // Account being voted on to change its authorization
+	Authorize bool           // Whpbfer to authorize or deauthorize the voted account
+}
+
+// Tally is a simple vote tally to keep the current score of votes. Votes that
+// go against the proposal aren't counted since it's equivalent to not voting.
+type Tally struct {
+	Authorize bool // Whpbfer the vote is about authorizing or kicking someone
+	Votes     int  // Number of votes until now wanting to pass the proposal
+}
+
+// Snapshot is the state of the authorization voting at a given point in time.
+type Snapshot struct {
+	config *Config
+
+	Number  uint64                      // Block number where the snapshot was created
+	Hash    common.Hash                 // Block hash where the snapshot was created
+	Signers map[common.Address]struct{} // Set of authorized signers at this moment
+	Recents map[uint64]common.Address   // Set of recent signers for spam protections
+	Votes   []*Vote                     // List of votes cast in chronological order
+	Tally   map[common.Address]Tally    // Current vote tally to avoid recalculating
+}
+
+// newCheckpointSnapshot creates the snapshot at a checkpoint block from the
+// signer list stored in its extra-data. Pending votes are reset at every
+// checkpoint, the recent signers are left for the caller to fill in.
+func newCheckpointSnapshot(config *Config, header *types.Header) (*Snapshot, error) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	payload := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+	if len(payload) == 0 || len(payload)%addressLength != 0 {
+		return nil, errInvalidCheckpointSigners
+	}
+	snap := &Snapshot{
+		config:  config,
+		Number:  header.Number.Uint64(),
+		Hash:    header.Hash(),
+		Signers: make(map[common.Address]struct{}),
+		Recents: make(map[uint64]common.Address),
+		Tally:   make(map[common.Address]Tally),
+	}
+	for i := 0; i < len(payload); i += addressLength {
+		snap.Signers[common.BytesToAddress(payload[i:i+addressLength])] = struct{}{}
+	}
+	return snap, nil
+}
+
+// copy creates a deep copy of the snapshot, though not the individual votes.
+func (s *Snapshot) copy() *Snapshot {
+	cpy := &Snapshot{
+		config:  s.config,
+		Number:  s.Number,
+		Hash:    s.Hash,
+		Signers: make(map[common.Address]struct{}),
+		Recents: make(map[uint64]common.Address),
+		Votes:   make([]*Vote, len(s.Votes)),
+		Tally:   make(map[common.Address]Tally),
+	}
+	for signer := range s.Signers {
+		cpy.Signers[signer] = struct{}{}
+	}
+	for block, signer := range s.Recents {
+		cpy.Recents[block] = signer
+	}
+	for address, tally := range s.Tally {
+		cpy.Tally[address] = tally
+	}
+	copy(cpy.Votes, s.Votes)
+
+	return cpy
+}
+
+// validVote returns whpbfer it makes sense to cast the specified vote in the
+// given snapshot context (e.g. don't try to add an already authorized signer).
+func (s *Snapshot) validVote(address common.Address, authorize bool) bool {
+	_, signer := s.Signers[address]
+	return (signer && !authorize) || (!signer && authorize)
+}
+
+// cast adds a new vote into the tally.
+func (s *Snapshot) cast(address common.Address, authorize bool) bool {
+	if !s.validVote(address, authorize) {
+		return false
+	}
+	if old, ok := s.Tally[address]; ok {
+		old.Votes++
+		s.Tally[address] = old
+	} else {
+		s.Tally[address] = Tally{Authorize: authorize, Votes: 1}
+	}
+	return true
+}
+
+// uncast removes a previously cast vote from the tally.
+func (s *Snapshot) uncast(address common.Address, authorize bool) bool {
+	tally, ok := s.Tally[address]
+	if !ok {
+		return false
+	}
+	// Ensure we only revert counted votes
+	if tally.Authorize != authorize {
+		return false
+	}
+	if tally.Votes > 1 {
+		tally.Votes--
+		s.Tally[address] = tally
+	} else {
+		delete(s.Tally, address)
+	}
+	return true
+}
+
+// verify checks whpbfer signer may seal the block with the given number on top
+// of the snapshot.
+func (s *Snapshot) verify(number uint64, signer common.Address) error {
+	if _, ok := s.Signers[signer]; !ok {
+		return errUnauthorized
+	}
+	for seen, recent := range s.Recents {
+		if recent == signer {
+			// Signer is among recents, only fail if the current block doesn't shift it out
+			if limit := uint64(len(s.Signers)/2 + 1); seen+limit > number {
+				return errRecentlySigned
+			}
+		}
+	}
+	return nil
+}
+
+// apply creates a new authorization snapshot by applying the given headers to
+// the original one.
+func (s *Snapshot) apply(headers []*types.Header, ecrecover func(*types.Header) (common.Address, error)) (*Snapshot, error) {
+	if len(headers) == 0 {
+		return s, nil
+	}
+	// Sanity check that the headers can be applied
+	for i := 0; i < len(headers)-1; i++ {
+		if headers[i+1].Number.Uint64() != headers[i].Number.Uint64()+1 {
+			return nil, errUnknownBlock
+		}
+	}
+	if headers[0].Number.Uint64() != s.Number+1 {
+		return nil, errUnknownBlock
+	}
+	snap := s.copy()
+
+	for _, header := range headers {
+		number := header.Number.Uint64()
+		if len(header.Extra) < extraVanity+extraSeal {
+			return nil, errMissingSignature
+		}
+		// Resolve the authorization key and check against signers
+		signer, err := ecrecover(header)
+		if err != nil {
+			return nil, err
+		}
+		if err := snap.verify(number, signer); err != nil {
+			return nil, err
+		}
+		// Delete the oldest signer from the recent list to allow it signing again
+		if limit := uint64(len(snap.Signers)/2 + 1); number >= limit {
+			delete(snap.Recents, number-limit)
+		}
+		snap.Recents[number] = signer
+
+		// Checkpoints reset the pending votes and carry the signers, but the
+		// recent signers stay, or one could sign again right after the epoch
+		if number%s.config.Epoch == 0 {
+			recents := snap.Recents
+			if snap, err = newCheckpointSnapshot(s.config, header); err != nil {
+				return nil, err
+			}
+			snap.Recents = recents
+			continue
+		}
+
+		// Blocks without a payload don't cast any vote
+		payload := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+		if len(payload) == 0 {
+			continue
+		}
+		address, authorize, ok := parseVote(payload)
+		if !ok {
+			return nil, errInvalidVote
+		}
+		// Discard any previous votes from the signer on the same account
+		for i, vote := range snap.Votes {
+			if vote.Signer == signer && vote.Address == address {
+				// Uncast the vote from the cached tally
+				snap.uncast(vote.Address, vote.Authorize)
+
+				// Uncast the vote from the chronological list
+				snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
+				break // only one vote allowed
+			}
+		}
+		// Tally up the new vote from the signer
+		if snap.cast(address, authorize) {
+			snap.Votes = append(snap.Votes, &Vote{
+				Signer:    signer,
+				Block:     number,
+				Address:   address,
+				Authorize: authorize,
+			})
+		}
+		// If the vote passed, update the list of signers
+		if tally := snap.Tally[address]; tally.Votes > len(snap.Signers)/2 {
+			if tally.Authorize {
+				snap.Signers[address] = struct{}{}
+			} else {
+				delete(snap.Signers, address)
+
+				// Signer list shrunk, delete any leftover recent caches
+				if limit := uint64(len(snap.Signers)/2 + 1); number >= limit {
+					delete(snap.Recents, number-limit)
+				}
+				// Discard any previous votes the deauthorized signer cast
+				for i := 0; i < len(snap.Votes); i++ {
+					if snap.Votes[i].Signer == address {
+						snap.uncast(snap.Votes[i].Address, snap.Votes[i].Authorize)
+						snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
+						i--
+					}
+				}
+			}
+			// Discard any previous votes around the just changed account
+			for i := 0; i < len(snap.Votes); i++ {
+				if snap.Votes[i].Address == address {
+					snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
+					i--
+				}
+			}
+			delete(snap.Tally, address)
+		}
+	}
+	snap.Number = headers[len(headers)-1].Number.Uint64()
+	snap.Hash = headers[len(headers)-1].Hash()
+
+	return snap, nil
+}
+
+// signers retrieves the list of authorized signers in ascending order.
+func (s *Snapshot) signers() []common.Address {
+	signers := make([]common.Address, 0, len(s.Signers))
+	for signer := range s.Signers {
+		signers = append(signers, signer)
+	}
+	return sortSigners(signers)
+}
+
+// inturn returns if a signer at a given block height is in-turn or not.
+func (s *Snapshot) inturn(number uint64, signer common.Address) bool {
+	signers := s.signers()
+	if len(signers) == 0 {
+		return false
+	}
+	return signers[number%uint64(len(signers))] == signer
+}
+
+// difficulty returns the difficulty a block sealed by signer at the given
+// height must have.
+func (s *Snapshot) difficulty(number uint64, signer common.Address) *big.Int {
+	if s.inturn(number, signer) {
+		return new(big.Int).Set(diffInTurn)
+	}
+	return new(big.Int).Set(diffNoTurn)
+}
+
+// sortSigners sorts a list of signer addresses in ascending byte order.
+func sortSigners(signers []common.Address) []common.Address {
+	sort.Sort(signersAscending(signers))
+	return signers
+}
+
+// signersAscending implements the sort interface to allow sorting a list of addresses
+type signersAscending []common.Address
+
+func (s signersAscending) Len() int           { return len(s) }
+func (s signersAscending) Less(i, j int) bool { return bytes.Compare(s[i][:], s[j][:]) < 0 }
+func (s signersAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -22,11 +22,10 @@ import (
 	"time"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus"
 	"github.com/pbfcoin/go-pbfcoin/core/state"
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
-	"github.com/pbfcoin/go-pbfcoin/params"
-	"github.com/pbfcoin/go-pbfcoin/pow"
 	"gopkg.in/fatih/set.v0"
 )
 
@@ -35,15 +34,15 @@ import (
 //
 // BlockValidator implements Validator.
 type BlockValidator struct {
-	bc  *BlockChain // Canonical block chain
-	Pow pow.PoW     // Proof of work used for validating
+	bc     *BlockChain      // Canonical block chain
+	engine consensus.Engine // Consensus engine used for validating
 }
 
 // NewBlockValidator returns a new block validator which is safe for re-use
-func NewBlockValidator(blockchain *BlockChain, pow pow.PoW) *BlockValidator {
+func NewBlockValidator(blockchain *BlockChain, engine consensus.Engine) *BlockValidator {
 	validator := &BlockValidator{
-		Pow: pow,
-		bc:  blockchain,
+		engine: engine,
+		bc:     blockchain,
 	}
 	return validator
 }
@@ -51,7 +50,7 @@ func NewBlockValidator(blockchain *BlockChain, pow pow.PoW) *BlockValidator {
 // ValidateBlock validates the given block's header and uncles and verifies the
 // the block header's transaction and uncle roots.
 //
-// ValidateBlock does not validate the header's pow. The pow work validated
+// ValidateBlock does not validate the header's seal. The seals are validated
 // seperately so we can process them in paralel.
 //
 // ValidateBlock also validates and makes sure that any previous state (or present)
@@ -74,7 +73,7 @@ func (v *BlockValidator) ValidateBlock(block *types.Block) error {
 
 	header := block.Header()
 	// validate the block header
-	if err := ValidateHeader(v.Pow, header, parent.Header(), false, false); err != nil {
+	if err := ValidateHeader(v.engine, v.bc, header, parent.Header(), false, false); err != nil {
 		return err
 	}
 	// verify the uncles are correctly rewarded
@@ -131,6 +130,10 @@ func (v *BlockValidator) ValidateState(block, parent *types.Block, statedb *stat
 // error if any of the included uncle headers were invalid. It returns an error
 // if the validation failed.
 func (v *BlockValidator) VerifyUncles(block, parent *types.Block) error {
+	// only proof-of-work engines reward uncles, others must not include any
+	if _, ok := v.engine.(consensus.PoW); !ok && len(block.Uncles()) > 0 {
+		return ValidationError("Block can not contain uncles (contained %v)", len(block.Uncles()))
+	}
 	// validate that there at most 2 uncles included in this block
 	if len(block.Uncles()) > 2 {
 		return ValidationError("Block can only contain maximum 2 uncles (contained %v)", len(block.Uncles()))
@@ -169,7 +172,7 @@ func (v *BlockValidator) VerifyUncles(block, parent *types.Block) error {
 			return UncleError("uncle[%d](%x)'s parent is not ancestor (%x)", i, hash[:4], uncle.ParentHash[0:4])
 		}
 
-		if err := ValidateHeader(v.Pow, uncle, ancestors[uncle.ParentHash].Header(), true, true); err != nil {
+		if err := ValidateHeader(v.engine, v.bc, uncle, ancestors[uncle.ParentHash].Header(), true, true); err != nil {
 			return ValidationError(fmt.Sprintf("uncle[%d](%x) header invalid: %v", i, hash[:4], err))
 		}
 	}
@@ -178,8 +181,8 @@ func (v *BlockValidator) VerifyUncles(block, parent *types.Block) error {
 }
 
 // ValidateHeader validates the given header and, depending on the pow arg,
-// checks the proof of work of the given header. Returns an error if the
-// validation failed.
+// checks the seal of the given header. Returns an error if the validation
+// failed.
 func (v *BlockValidator) ValidateHeader(header, parent *types.Header, checkPow bool) error {
 	// Short circuit if the parent is missing.
 	if parent == nil {
@@ -189,17 +192,15 @@ func (v *BlockValidator) ValidateHeader(header, parent *types.Header, checkPow b
 	if v.bc.HasHeader(header.Hash()) {
 		return nil
 	}
-	return ValidateHeader(v.Pow, header, parent, checkPow, false)
+	return ValidateHeader(v.engine, v.bc, header, parent, checkPow, false)
 }
 
-// Validates a header. Returns an error if the header is invalid.
+// Validates a header. The generic fields are checked here, the consensus
+// specific ones (extra-data, timestamp spacing, difficulty and seal) by the
+// given engine. Returns an error if the header is invalid.
 //
 // See YP section 4.3.4. "Block Header Validity"
-func ValidateHeader(pow pow.PoW, header *types.Header, parent *types.Header, checkPow, uncle bool) error {
-	if big.NewInt(int64(len(header.Extra))).Cmp(params.MaximumExtraDataSize) == 1 {
-		return fmt.Errorf("Header extra data too long (%d)", len(header.Extra))
-	}
-
+func ValidateHeader(engine consensus.Engine, chain consensus.ChainReader, header *types.Header, parent *types.Header, checkSeal, uncle bool) error {
 	if uncle {
 		if header.Time.Cmp(common.MaxBig) == 1 {
 			return BlockTSTooBigErr
@@ -209,21 +210,18 @@ func ValidateHeader(pow pow.PoW, header *types.Header, parent *types.Header, che
 			return BlockFutureErr
 		}
 	}
-	if header.Time.Cmp(parent.Time) != 1 {
-		return BlockEqualTSErr
+	if err := engine.VerifyHeader(chain, header, parent, uncle); err != nil {
+		return err
 	}
 
-	expd := CalcDifficulty(header.Time.Uint64(), parent.Time.Uint64(), parent.Number, parent.Difficulty)
-	if expd.Cmp(header.Difficulty) != 0 {
-		return fmt.Errorf("Difficulty check failed for header %v, %v", header.Difficulty, expd)
-	}
+	rules := chain.Config().Rules(header.Number)
 
 	a := new(big.Int).Set(parent.GasLimit)
 	a = a.Sub(a, header.GasLimit)
 	a.Abs(a)
 	b := new(big.Int).Set(parent.GasLimit)
-	b = b.Div(b, params.GasLimitBoundDivisor)
-	if !(a.Cmp(b) < 0) || (header.GasLimit.Cmp(params.MinGasLimit) == -1) {
+	b = b.Div(b, rules.GasLimitBoundDivisor)
+	if !(a.Cmp(b) < 0) || (header.GasLimit.Cmp(rules.MinGasLimit) == -1) {
 		return fmt.Errorf("GasLimit check failed for header %v (%v > %v)", header.GasLimit, a, b)
 	}
 
@@ -233,11 +231,9 @@ func ValidateHeader(pow pow.PoW, header *types.Header, parent *types.Header, che
 		return BlockNumberErr
 	}
 
-	if checkPow {
-		// Verify the nonce of the header. Return an error if it's not valid
-		if !pow.Verify(types.NewBlockWithHeader(header)) {
-			return &BlockNonceErr{header.Number, header.Hash(), header.Nonce.Uint64()}
-		}
+	if checkSeal {
+		// Verify the seal of the header. Return an error if it's not valid
+		return engine.VerifySeal(chain, header)
 	}
 	return nil
 }
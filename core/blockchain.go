@@ -31,6 +31,7 @@ import (
 	"time"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus"
 	"github.com/pbfcoin/go-pbfcoin/core/state"
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/core/vm"
@@ -40,7 +41,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
 	"github.com/pbfcoin/go-pbfcoin/metrics"
-	"github.com/pbfcoin/go-pbfcoin/pow"
+	"github.com/pbfcoin/go-pbfcoin/params"
 	"github.com/pbfcoin/go-pbfcoin/rlp"
 	"github.com/pbfcoin/go-pbfcoin/trie"
 	"github.com/hashicorp/golang-lru"
@@ -109,16 +110,20 @@ type BlockChain struct {
 	procInterrupt int32 // interrupt signaler for block processing
 	wg            sync.WaitGroup
 
-	pow       pow.PoW
+	engine    consensus.Engine
+	config    *params.ChainConfig // hard-fork schedule of the chain, nil for the default rules
 	rand      *mrand.Rand
 	processor Processor
 	validator Validator
+
+	pruneCfg *PruneConfig // state pruning options, nil if pruning is disabled
+	pruning  int32        // whpbfer the state is being pruned, accessed atomically
 }
 
 // NewBlockChain returns a fully initialised block chain using information
 // available in the database. It initialiser the default pbfcoin Validator and
 // Processor.
-func NewBlockChain(chainDb pbfdb.Database, pow pow.PoW, mux *event.TypeMux) (*BlockChain, error) {
+func NewBlockChain(chainDb pbfdb.Database, engine consensus.Engine, mux *event.TypeMux) (*BlockChain, error) {
 	headerCache, _ := lru.New(headerCacheLimit)
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
@@ -136,7 +141,7 @@ func NewBlockChain(chainDb pbfdb.Database, pow pow.PoW, mux *event.TypeMux) (*Bl
 		tdCache:      tdCache,
 		blockCache:   blockCache,
 		futureBlocks: futureBlocks,
-		pow:          pow,
+		engine:       engine,
 	}
 	// Seed a fast but crypto originating random generator
 	seed, err := crand.Int(crand.Reader, big.NewInt(math.MaxInt64))
@@ -144,7 +149,7 @@ func NewBlockChain(chainDb pbfdb.Database, pow pow.PoW, mux *event.TypeMux) (*Bl
 		return nil, err
 	}
 	bc.rand = mrand.New(mrand.NewSource(seed.Int64()))
-	bc.SetValidator(NewBlockValidator(bc, pow))
+	bc.SetValidator(NewBlockValidator(bc, engine))
 	bc.SetProcessor(NewStateProcessor(bc))
 
 	bc.genesisBlock = bc.GetBlockByNumber(0)
@@ -159,6 +164,9 @@ func NewBlockChain(chainDb pbfdb.Database, pow pow.PoW, mux *event.TypeMux) (*Bl
 		}
 		glog.V(logger.Info).Infoln("WARNING: Wrote default pbfcoin genesis block")
 	}
+	if bc.config, err = GetChainConfig(chainDb, bc.genesisBlock.Hash()); err != nil {
+		return nil, err
+	}
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
@@ -317,6 +325,64 @@ func (self *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	return nil
 }
 
+// InsertCheckpoint injects a trusted header with the total difficulty of its
+// chain without the ancestors, to serve as the starting point of a sync from a
+// checkpoint. It becomes the head header if its total difficulty is higher than
+// that of the current one, in which case the canonical number assignments of a
+// previous chain it doesn't extend are removed, the ones below the checkpoint
+// stay unknown. Engines which can't verify the chain above every header may
+// refuse the checkpoint.
+func (self *BlockChain) InsertCheckpoint(header *types.Header, td *big.Int) error {
+	if v, ok := self.engine.(consensus.CheckpointVerifier); ok {
+		if err := v.VerifyCheckpoint(header); err != nil {
+			return fmt.Errorf("checkpoint #%d: %v", header.Number, err)
+		}
+	}
+	self.wg.Add(1)
+	defer self.wg.Done()
+
+	self.chainmu.Lock()
+	defer self.chainmu.Unlock()
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	hash, number := header.Hash(), header.Number.Uint64()
+	if err := WriteTd(self.chainDb, hash, td); err != nil {
+		return err
+	}
+	if err := WriteHeader(self.chainDb, header); err != nil {
+		return err
+	}
+	if td.Cmp(self.GetTd(self.currentHeader.Hash())) > 0 {
+		// Delete any canonical number assignments above the checkpoint
+		for i := number + 1; GetCanonicalHash(self.chainDb, i) != (common.Hash{}); i++ {
+			DeleteCanonicalHash(self.chainDb, i)
+		}
+		// Delete the ones below too unless the checkpoint links up to them
+		if number > 0 && GetCanonicalHash(self.chainDb, number-1) != header.ParentHash {
+			deleteCanonicalHashesBelow(self.chainDb, number)
+		}
+		if err := WriteCanonicalHash(self.chainDb, hash, number); err != nil {
+			return err
+		}
+		if err := WriteHeadHeaderHash(self.chainDb, hash); err != nil {
+			return err
+		}
+		self.currentHeader = types.CopyHeader(header)
+	}
+	glog.V(logger.Info).Infof("inserted checkpoint header #%d [%x…]", number, hash[:4])
+	return nil
+}
+
+// deleteCanonicalHashesBelow removes the canonical number assignments of the
+// blocks below the given number, down to but excluding the genesis block.
+func deleteCanonicalHashesBelow(db pbfdb.Database, number uint64) {
+	for i := number - 1; i > 0 && GetCanonicalHash(db, i) != (common.Hash{}); i-- {
+		DeleteCanonicalHash(db, i)
+	}
+}
+
 // GasLimit returns the gas limit of the current HEAD block.
 func (self *BlockChain) GasLimit() *big.Int {
 	self.mu.RLock()
@@ -383,6 +449,40 @@ func (self *BlockChain) SetValidator(validator Validator) {
 	self.validator = validator
 }
 
+// SetPruning enables pruning the state of the blocks falling out of the
+// retention window as new blocks are written, nil disables pruning.
+func (self *BlockChain) SetPruning(cfg *PruneConfig) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.pruneCfg = cfg
+}
+
+// maybePrune starts pruning the state in the background once a full retention
+// window of states became prunable, unless pruning is already running. The
+// caller must hold self.mu.
+func (self *BlockChain) maybePrune(head uint64) {
+	cfg := self.pruneCfg
+	if cfg == nil || head < GetLastPrunedNumber(self.chainDb)+2*cfg.Retain {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&self.pruning, 0, 1) {
+		return
+	}
+	self.wg.Add(1)
+	go func() {
+		defer self.wg.Done()
+		defer atomic.StoreInt32(&self.pruning, 0)
+
+		start := time.Now()
+		deleted, err := PruneState(self.chainDb, head, cfg, self.quit)
+		if err != nil {
+			glog.V(logger.Error).Infof("state pruning failed: %v", err)
+			return
+		}
+		glog.V(logger.Info).Infof("pruned %d state entries in %v", deleted, time.Since(start))
+	}()
+}
+
 // Validator returns the current validator.
 func (self *BlockChain) Validator() Validator {
 	self.procmu.RLock()
@@ -397,8 +497,18 @@ func (self *BlockChain) Processor() Processor {
 	return self.processor
 }
 
-// AuxValidator returns the auxiliary validator (Proof of work atm)
-func (self *BlockChain) AuxValidator() pow.PoW { return self.pow }
+// Engine returns the consensus engine used to validate and seal blocks.
+func (self *BlockChain) Engine() consensus.Engine { return self.engine }
+
+// Config returns the chain config of the chain, nil if the genesis block didn't
+// specify one.
+func (self *BlockChain) Config() *params.ChainConfig { return self.config }
+
+// PendingRules returns the protocol rules in force at the block following the
+// current head block.
+func (self *BlockChain) PendingRules() *params.Rules {
+	return self.config.Rules(new(big.Int).Add(self.CurrentBlock().Number(), common.Big1))
+}
 
 // State returns a new mutable state based on the current HEAD block.
 func (self *BlockChain) State() (*state.StateDB, error) {
@@ -742,10 +852,18 @@ func (self *BlockChain) writeHeader(header *types.Header) error {
 			DeleteCanonicalHash(self.chainDb, i)
 		}
 		// Overwrite any stale canonical number assignments
-		head := self.Gpbfeader(header.ParentHash)
-		for GetCanonicalHash(self.chainDb, head.Number.Uint64()) != head.Hash() {
+		var (
+			head  = self.Gpbfeader(header.ParentHash)
+			first = header
+		)
+		for head != nil && GetCanonicalHash(self.chainDb, head.Number.Uint64()) != head.Hash() {
 			WriteCanonicalHash(self.chainDb, head.Hash(), head.Number.Uint64())
-			head = self.Gpbfeader(head.ParentHash)
+			first, head = head, self.Gpbfeader(head.ParentHash)
+		}
+		// Chains synced from a checkpoint have no ancestors, drop the stale
+		// assignments of the previous chain below its start
+		if head == nil {
+			deleteCanonicalHashesBelow(self.chainDb, first.Number.Uint64())
 		}
 		// Extend the canonical chain with the new header
 		if err := WriteCanonicalHash(self.chainDb, header.Hash(), header.Number.Uint64()); err != nil {
@@ -825,14 +943,13 @@ func (self *BlockChain) InsertHeaderChain(chain []*types.Header, checkFreq int)
 			if self.HasHeader(hash) {
 				continue
 			}
-			// Verify that the header honors the chain parameters
-			checkPow := verify[index]
-
+			// Verify that the header honors the chain parameters, seals are
+			// checked afterwards against the whole batch
 			var err error
 			if index == 0 {
-				err = self.Validator().ValidateHeader(header, self.Gpbfeader(header.ParentHash), checkPow)
+				err = self.Validator().ValidateHeader(header, self.Gpbfeader(header.ParentHash), false)
 			} else {
-				err = self.Validator().ValidateHeader(header, chain[index-1], checkPow)
+				err = self.Validator().ValidateHeader(header, chain[index-1], false)
 			}
 			if err != nil {
 				errs[index] = err
@@ -860,6 +977,32 @@ func (self *BlockChain) InsertHeaderChain(chain []*types.Header, checkFreq int)
 			}
 		}
 	}
+	// Verify the seals of the selected headers. The engine might need ancestors
+	// from the batch itself, so resolve those before hitting the database.
+	var (
+		sealed  []*types.Header
+		indices []int
+	)
+	for i, header := range chain {
+		if verify[i] && !self.HasHeader(header.Hash()) {
+			sealed = append(sealed, header)
+			indices = append(indices, i)
+		}
+	}
+	if len(sealed) > 0 {
+		abort, results := verifyNoncesFromHeaders(self.engine, newBatchChainReader(self, chain), sealed)
+		defer close(abort)
+
+		failure, err := -1, error(nil)
+		for i := 0; i < len(sealed); i++ {
+			if r := <-results; !r.valid && (failure == -1 || indices[r.index] < failure) {
+				failure, err = indices[r.index], r.err
+			}
+		}
+		if failure != -1 {
+			return failure, err
+		}
+	}
 	// All headers passed verification, import them into the database
 	for i, header := range chain {
 		// Short circuit insertion if shutting down
@@ -911,6 +1054,37 @@ func (self *BlockChain) Rollback(chain []common.Hash) {
 	}
 }
 
+// SetReceiptsData computes all the non-consensus fields of the receipts of a
+// block, which are not transferred over the network.
+func SetReceiptsData(block *types.Block, receipts types.Receipts) {
+	transactions, logIndex := block.Transactions(), uint(0)
+	for j := 0; j < len(receipts); j++ {
+		// The transaction hash can be retrieved from the transaction itself
+		receipts[j].TxHash = transactions[j].Hash()
+
+		// The contract address can be derived from the transaction itself
+		if MessageCreatesContract(transactions[j]) {
+			from, _ := transactions[j].From()
+			receipts[j].ContractAddress = crypto.CreateAddress(from, transactions[j].Nonce())
+		}
+		// The used gas can be calculated based on previous receipts
+		if j == 0 {
+			receipts[j].GasUsed = new(big.Int).Set(receipts[j].CumulativeGasUsed)
+		} else {
+			receipts[j].GasUsed = new(big.Int).Sub(receipts[j].CumulativeGasUsed, receipts[j-1].CumulativeGasUsed)
+		}
+		// The derived log fields can simply be set from the block and transaction
+		for k := 0; k < len(receipts[j].Logs); k++ {
+			receipts[j].Logs[k].BlockNumber = block.NumberU64()
+			receipts[j].Logs[k].BlockHash = block.Hash()
+			receipts[j].Logs[k].TxHash = receipts[j].TxHash
+			receipts[j].Logs[k].TxIndex = uint(j)
+			receipts[j].Logs[k].Index = logIndex
+			logIndex++
+		}
+	}
+}
+
 // InsertReceiptChain attempts to complete an already existing header chain with
 // transaction and receipt data.
 func (self *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain []types.Receipts) (int, error) {
@@ -952,32 +1126,8 @@ func (self *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain
 				continue
 			}
 			// Compute all the non-consensus fields of the receipts
-			transactions, logIndex := block.Transactions(), uint(0)
-			for j := 0; j < len(receipts); j++ {
-				// The transaction hash can be retrieved from the transaction itself
-				receipts[j].TxHash = transactions[j].Hash()
-
-				// The contract address can be derived from the transaction itself
-				if MessageCreatesContract(transactions[j]) {
-					from, _ := transactions[j].From()
-					receipts[j].ContractAddress = crypto.CreateAddress(from, transactions[j].Nonce())
-				}
-				// The used gas can be calculated based on previous receipts
-				if j == 0 {
-					receipts[j].GasUsed = new(big.Int).Set(receipts[j].CumulativeGasUsed)
-				} else {
-					receipts[j].GasUsed = new(big.Int).Sub(receipts[j].CumulativeGasUsed, receipts[j-1].CumulativeGasUsed)
-				}
-				// The derived log fields can simply be set from the block and transaction
-				for k := 0; k < len(receipts[j].Logs); k++ {
-					receipts[j].Logs[k].BlockNumber = block.NumberU64()
-					receipts[j].Logs[k].BlockHash = block.Hash()
-					receipts[j].Logs[k].TxHash = receipts[j].TxHash
-					receipts[j].Logs[k].TxIndex = uint(j)
-					receipts[j].Logs[k].Index = logIndex
-					logIndex++
-				}
-			}
+			SetReceiptsData(block, receipts)
+
 			// Write all the data out into the database
 			if err := WriteBody(self.chainDb, block.Hash(), &types.Body{block.Transactions(), block.Uncles()}); err != nil {
 				errs[index] = fmt.Errorf("failed to write block body: %v", err)
@@ -1081,6 +1231,10 @@ func (self *BlockChain) WriteBlock(block *types.Block) (status writeStatus, err
 	}
 	self.futureBlocks.Remove(block.Hash())
 
+	if status == CanonStatTy {
+		self.maybePrune(block.NumberU64())
+	}
+
 	return
 }
 
@@ -1105,8 +1259,12 @@ func (self *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 		nonceChecked = make([]bool, len(chain))
 	)
 
-	// Start the parallel nonce verifier.
-	nonceAbort, nonceResults := verifyNoncesFromBlocks(self.pow, chain)
+	// Start the parallel seal verifier.
+	headers := make([]*types.Header, len(chain))
+	for i, block := range chain {
+		headers[i] = block.Header()
+	}
+	nonceAbort, nonceResults := verifyNoncesFromHeaders(self.engine, newBatchChainReader(self, headers), headers)
 	defer close(nonceAbort)
 
 	txcount := 0
@@ -1123,8 +1281,7 @@ func (self *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 			r := <-nonceResults
 			nonceChecked[r.index] = true
 			if !r.valid {
-				block := chain[r.index]
-				return r.index, &BlockNonceErr{Hash: block.Hash(), Number: block.Number(), Nonce: block.Nonce()}
+				return r.index, r.err
 			}
 		}
 
@@ -24,10 +24,12 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/golang-lru"
 	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus/poa"
 	"github.com/pbfcoin/go-pbfcoin/core/state"
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/core/vm"
@@ -52,7 +54,7 @@ func thePow() pow.PoW {
 func theBlockChain(db pbfdb.Database, t *testing.T) *BlockChain {
 	var eventMux event.TypeMux
 	WriteTestNetGenesisBlock(db, 0)
-	blockchain, err := NewBlockChain(db, thePow(), &eventMux)
+	blockchain, err := NewBlockChain(db, NewPowEngine(thePow()), &eventMux)
 	if err != nil {
 		t.Error("failed creating blockchain:", err)
 		t.FailNow()
@@ -471,7 +473,7 @@ func makeBlockChainWithDiff(genesis *types.Block, d []int, seed byte) []*types.B
 
 func chm(genesis *types.Block, db pbfdb.Database) *BlockChain {
 	var eventMux event.TypeMux
-	bc := &BlockChain{chainDb: db, genesisBlock: genesis, eventMux: &eventMux, pow: FakePow{}, rand: rand.New(rand.NewSource(0))}
+	bc := &BlockChain{chainDb: db, genesisBlock: genesis, eventMux: &eventMux, engine: NewPowEngine(FakePow{}), rand: rand.New(rand.NewSource(0))}
 	bc.headerCache, _ = lru.New(100)
 	bc.bodyCache, _ = lru.New(100)
 	bc.bodyRLPCache, _ = lru.New(100)
@@ -546,6 +548,124 @@ func testReorg(t *testing.T, first, second []int, td int64, full bool) {
 	}
 }
 
+// Tests that inserting a checkpoint drops the canonical numbers of the previous
+// chain, whether it becomes the head right away or only once the chain synced
+// from it overtakes the previous one, and that the chain extends from it.
+func TestInsertCheckpoint(t *testing.T) {
+	testInsertCheckpoint(t, []int{1, 1, 1}, 11)
+	testInsertCheckpoint(t, []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, 15)
+}
+
+func testInsertCheckpoint(t *testing.T, local []int, localTd int64) {
+	db, _ := pbfdb.NewMemDatabase()
+	genesis, _ := WriteTestNetGenesisBlock(db, 0)
+	bc := chm(genesis, db)
+
+	if _, err := bc.InsertHeaderChain(makeHeaderChainWithDiff(genesis, local, 11), 1); err != nil {
+		t.Fatalf("failed to insert local chain: %v", err)
+	}
+	// Insert the checkpoint of a remote chain and sync it from there
+	remote := makeHeaderChainWithDiff(genesis, []int{1, 2, 3, 4, 5, 6}, 22)
+	checkpoint := remote[3]
+	td := new(big.Int).Add(genesis.Difficulty(), big.NewInt(1+2+3+4))
+	if err := bc.InsertCheckpoint(checkpoint, td); err != nil {
+		t.Fatalf("failed to insert checkpoint: %v", err)
+	}
+	if _, err := bc.InsertHeaderChain(remote[4:], 1); err != nil {
+		t.Fatalf("failed to insert headers after checkpoint: %v", err)
+	}
+	if head := bc.CurrentHeader(); head.Hash() != remote[len(remote)-1].Hash() {
+		t.Fatalf("local td %d: head mismatch: have #%d, want #%d", localTd, head.Number, len(remote))
+	}
+	for i := uint64(1); i < checkpoint.Number.Uint64(); i++ {
+		if header := bc.GpbfeaderByNumber(i); header != nil {
+			t.Errorf("local td %d: stale header #%d still canonical", localTd, i)
+		}
+	}
+	for _, header := range remote[3:] {
+		if have := bc.GpbfeaderByNumber(header.Number.Uint64()); have == nil || have.Hash() != header.Hash() {
+			t.Errorf("local td %d: header #%d not canonical", localTd, header.Number)
+		}
+	}
+	if header := bc.GpbfeaderByNumber(uint64(len(local))); len(local) > len(remote) && header != nil {
+		t.Errorf("local td %d: stale header #%d above the head still canonical", localTd, len(local))
+	}
+}
+
+// Tests that a proof-of-authority chain can be synced from a checkpoint at an
+// epoch block, whose signer list the snapshots above it are rebuilt from, but
+// not from any other block.
+func TestInsertCheckpointPoA(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	newChain := func() (*BlockChain, *poa.PoA) {
+		db, _ := pbfdb.NewMemDatabase()
+		genesis := fmt.Sprintf(`{"nonce":"0x%x","gasLimit":"0x%x","difficulty":"0x1","extraData":"0x%x","alloc":{}}`,
+			types.EncodeNonce(0), params.GenesisGasLimit.Bytes(), poa.GenesisExtra(nil, []common.Address{signer}))
+		if _, err := WriteGenesisBlock(db, strings.NewReader(genesis)); err != nil {
+			t.Fatalf("failed to write genesis block: %v", err)
+		}
+		engine := poa.New(&poa.Config{Period: 1, Epoch: 4})
+		engine.Authorize(signer, func(account common.Address, hash []byte) ([]byte, error) {
+			return crypto.Sign(hash, key)
+		})
+		bc, err := NewBlockChain(db, engine, new(event.TypeMux))
+		if err != nil {
+			t.Fatalf("failed to create block chain: %v", err)
+		}
+		return bc, engine
+	}
+	// Sign a header chain spanning a few epochs
+	source, engine := newChain()
+	var headers []*types.Header
+	for i := 0; i < 10; i++ {
+		parent := source.CurrentHeader()
+		header := &types.Header{
+			ParentHash:  parent.Hash(),
+			Number:      new(big.Int).Add(parent.Number, common.Big1),
+			Time:        new(big.Int).Add(parent.Time, common.Big1),
+			GasLimit:    parent.GasLimit,
+			GasUsed:     new(big.Int),
+			Root:        parent.Root,
+			UncleHash:   types.EmptyUncleHash,
+			TxHash:      types.EmptyRootHash,
+			ReceiptHash: types.EmptyRootHash,
+		}
+		if err := engine.Prepare(source, header); err != nil {
+			t.Fatalf("failed to prepare header #%d: %v", header.Number, err)
+		}
+		block, err := engine.Seal(source, types.NewBlockWithHeader(header), nil)
+		if err != nil {
+			t.Fatalf("failed to seal header #%d: %v", header.Number, err)
+		}
+		if _, err := source.InsertHeaderChain([]*types.Header{block.Header()}, 1); err != nil {
+			t.Fatalf("failed to insert header #%d: %v", header.Number, err)
+		}
+		headers = append(headers, block.Header())
+	}
+	td := func(checkpoint *types.Header) *big.Int {
+		return source.GetTd(checkpoint.Hash())
+	}
+	// Sync from the checkpoint at the second epoch block
+	bc, _ := newChain()
+	checkpoint := headers[7]
+	if err := bc.InsertCheckpoint(checkpoint, td(checkpoint)); err != nil {
+		t.Fatalf("failed to insert checkpoint: %v", err)
+	}
+	if n, err := bc.InsertHeaderChain(headers[8:], 1); err != nil {
+		t.Fatalf("failed to insert header #%d after checkpoint: %v", headers[8+n].Number, err)
+	}
+	if head := bc.CurrentHeader(); head.Hash() != headers[9].Hash() {
+		t.Errorf("head mismatch: have #%d, want #%d", head.Number, headers[9].Number)
+	}
+	// Checkpoints which aren't epoch blocks must be refused
+	bc, _ = newChain()
+	if err := bc.InsertCheckpoint(headers[6], td(headers[6])); err == nil {
+		t.Errorf("checkpoint #%d within an epoch accepted", headers[6].Number)
+	}
+}
+
 // Tests that the insertion functions detect banned hashes.
 func TestBadHeaderHashes(t *testing.T) { testBadHashes(t, false) }
 func TestBadBlockHashes(t *testing.T)  { testBadHashes(t, true) }
@@ -607,7 +727,7 @@ func testReorgBadHashes(t *testing.T, full bool) {
 		defer func() { delete(BadHashes, headers[3].Hash()) }()
 	}
 	// Create a new chain manager and check it rolled back the state
-	ncm, err := NewBlockChain(db, FakePow{}, new(event.TypeMux))
+	ncm, err := NewBlockChain(db, NewPowEngine(FakePow{}), new(event.TypeMux))
 	if err != nil {
 		t.Fatalf("failed to create new chain manager: %v", err)
 	}
@@ -650,7 +770,7 @@ func testInsertNonceError(t *testing.T, full bool) {
 			failNum = blocks[failAt].NumberU64()
 			failHash = blocks[failAt].Hash()
 
-			blockchain.pow = failPow{failNum}
+			blockchain.engine = NewPowEngine(failPow{failNum})
 
 			failRes, err = blockchain.InsertChain(blocks)
 		} else {
@@ -660,8 +780,8 @@ func testInsertNonceError(t *testing.T, full bool) {
 			failNum = headers[failAt].Number.Uint64()
 			failHash = headers[failAt].Hash()
 
-			blockchain.pow = failPow{failNum}
-			blockchain.validator = NewBlockValidator(blockchain, failPow{failNum})
+			blockchain.engine = NewPowEngine(failPow{failNum})
+			blockchain.validator = NewBlockValidator(blockchain, NewPowEngine(failPow{failNum}))
 
 			failRes, err = blockchain.InsertHeaderChain(headers, 1)
 		}
@@ -727,7 +847,7 @@ func TestFastVsFullChains(t *testing.T) {
 	archiveDb, _ := pbfdb.NewMemDatabase()
 	WriteGenesisBlockForTesting(archiveDb, GenesisAccount{address, funds})
 
-	archive, _ := NewBlockChain(archiveDb, FakePow{}, new(event.TypeMux))
+	archive, _ := NewBlockChain(archiveDb, NewPowEngine(FakePow{}), new(event.TypeMux))
 
 	if n, err := archive.InsertChain(blocks); err != nil {
 		t.Fatalf("failed to process block %d: %v", n, err)
@@ -735,7 +855,7 @@ func TestFastVsFullChains(t *testing.T) {
 	// Fast import the chain as a non-archive node to test
 	fastDb, _ := pbfdb.NewMemDatabase()
 	WriteGenesisBlockForTesting(fastDb, GenesisAccount{address, funds})
-	fast, _ := NewBlockChain(fastDb, FakePow{}, new(event.TypeMux))
+	fast, _ := NewBlockChain(fastDb, NewPowEngine(FakePow{}), new(event.TypeMux))
 
 	headers := make([]*types.Header, len(blocks))
 	for i, block := range blocks {
@@ -811,7 +931,7 @@ func TestLightVsFastVsFullChainHeads(t *testing.T) {
 	archiveDb, _ := pbfdb.NewMemDatabase()
 	WriteGenesisBlockForTesting(archiveDb, GenesisAccount{address, funds})
 
-	archive, _ := NewBlockChain(archiveDb, FakePow{}, new(event.TypeMux))
+	archive, _ := NewBlockChain(archiveDb, NewPowEngine(FakePow{}), new(event.TypeMux))
 
 	if n, err := archive.InsertChain(blocks); err != nil {
 		t.Fatalf("failed to process block %d: %v", n, err)
@@ -823,7 +943,7 @@ func TestLightVsFastVsFullChainHeads(t *testing.T) {
 	// Import the chain as a non-archive node and ensure all pointers are updated
 	fastDb, _ := pbfdb.NewMemDatabase()
 	WriteGenesisBlockForTesting(fastDb, GenesisAccount{address, funds})
-	fast, _ := NewBlockChain(fastDb, FakePow{}, new(event.TypeMux))
+	fast, _ := NewBlockChain(fastDb, NewPowEngine(FakePow{}), new(event.TypeMux))
 
 	headers := make([]*types.Header, len(blocks))
 	for i, block := range blocks {
@@ -842,7 +962,7 @@ func TestLightVsFastVsFullChainHeads(t *testing.T) {
 	// Import the chain as a light node and ensure all pointers are updated
 	lightDb, _ := pbfdb.NewMemDatabase()
 	WriteGenesisBlockForTesting(lightDb, GenesisAccount{address, funds})
-	light, _ := NewBlockChain(lightDb, FakePow{}, new(event.TypeMux))
+	light, _ := NewBlockChain(lightDb, NewPowEngine(FakePow{}), new(event.TypeMux))
 
 	if n, err := light.InsertHeaderChain(headers, 1); err != nil {
 		t.Fatalf("failed to insert header %d: %v", n, err)
@@ -907,7 +1027,7 @@ func TestChainTxReorgs(t *testing.T) {
 	})
 	// Import the chain. This runs all block validation rules.
 	evmux := &event.TypeMux{}
-	blockchain, _ := NewBlockChain(db, FakePow{}, evmux)
+	blockchain, _ := NewBlockChain(db, NewPowEngine(FakePow{}), evmux)
 	if i, err := blockchain.InsertChain(chain); err != nil {
 		t.Fatalf("failed to insert original chain[%d]: %v", i, err)
 	}
@@ -19,53 +19,53 @@ package core
 import (
 	"runtime"
 
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus"
 	"github.com/pbfcoin/go-pbfcoin/core/types"
-	"github.com/pbfcoin/go-pbfcoin/pow"
+	"github.com/pbfcoin/go-pbfcoin/params"
 )
 
-// nonceCheckResult contains the result of a nonce verification.
+// nonceCheckResult contains the result of a seal verification.
 type nonceCheckResult struct {
-	index int  // Index of the item verified from an input array
-	valid bool // Result of the nonce verification
+	index int   // Index of the item verified from an input array
+	valid bool  // Result of the seal verification
+	err   error // Reason of the failure if the seal is invalid
 }
 
-// verifyNoncesFromHeaders starts a concurrent header nonce verification,
+// verifyNoncesFromHeaders starts a concurrent header seal verification,
 // returning a quit channel to abort the operations and a results channel
 // to retrieve the async verifications.
-func verifyNoncesFromHeaders(checker pow.PoW, headers []*types.Header) (chan<- struct{}, <-chan nonceCheckResult) {
-	items := make([]pow.Block, len(headers))
-	for i, header := range headers {
-		items[i] = types.NewBlockWithHeader(header)
-	}
-	return verifyNonces(checker, items)
+func verifyNoncesFromHeaders(engine consensus.Engine, chain consensus.ChainReader, headers []*types.Header) (chan<- struct{}, <-chan nonceCheckResult) {
+	return verifyNonces(engine, chain, headers)
 }
 
-// verifyNoncesFromBlocks starts a concurrent block nonce verification,
+// verifyNoncesFromBlocks starts a concurrent block seal verification,
 // returning a quit channel to abort the operations and a results channel
 // to retrieve the async verifications.
-func verifyNoncesFromBlocks(checker pow.PoW, blocks []*types.Block) (chan<- struct{}, <-chan nonceCheckResult) {
-	items := make([]pow.Block, len(blocks))
+func verifyNoncesFromBlocks(engine consensus.Engine, chain consensus.ChainReader, blocks []*types.Block) (chan<- struct{}, <-chan nonceCheckResult) {
+	headers := make([]*types.Header, len(blocks))
 	for i, block := range blocks {
-		items[i] = block
+		headers[i] = block.Header()
 	}
-	return verifyNonces(checker, items)
+	return verifyNonces(engine, chain, headers)
 }
 
-// verifyNonces starts a concurrent nonce verification, returning a quit channel
+// verifyNonces starts a concurrent seal verification, returning a quit channel
 // to abort the operations and a results channel to retrieve the async checks.
-func verifyNonces(checker pow.PoW, items []pow.Block) (chan<- struct{}, <-chan nonceCheckResult) {
+func verifyNonces(engine consensus.Engine, chain consensus.ChainReader, headers []*types.Header) (chan<- struct{}, <-chan nonceCheckResult) {
 	// Spawn as many workers as allowed threads
 	workers := runtime.GOMAXPROCS(0)
-	if len(items) < workers {
-		workers = len(items)
+	if len(headers) < workers {
+		workers = len(headers)
 	}
 	// Create a task channel and spawn the verifiers
 	tasks := make(chan int, workers)
-	results := make(chan nonceCheckResult, len(items)) // Buffered to make sure all workers stop
+	results := make(chan nonceCheckResult, len(headers)) // Buffered to make sure all workers stop
 	for i := 0; i < workers; i++ {
 		go func() {
 			for index := range tasks {
-				results <- nonceCheckResult{index: index, valid: checker.Verify(items[index])}
+				err := engine.VerifySeal(chain, headers[index])
+				results <- nonceCheckResult{index: index, valid: err == nil, err: err}
 			}
 		}()
 	}
@@ -74,7 +74,7 @@ func verifyNonces(checker pow.PoW, items []pow.Block) (chan<- struct{}, <-chan n
 	go func() {
 		defer close(tasks)
 
-		for i := range items {
+		for i := range headers {
 			select {
 			case tasks <- i:
 				continue
@@ -85,3 +85,38 @@ func verifyNonces(checker pow.PoW, items []pow.Block) (chan<- struct{}, <-chan n
 	}()
 	return abort, results
 }
+
+// batchChainReader is a consensus.ChainReader which resolves headers from a
+// batch being imported before falling back to the local chain, allowing the
+// seals of a batch to be verified before any of its headers is written.
+type batchChainReader struct {
+	chain   consensus.ChainReader
+	headers map[common.Hash]*types.Header
+}
+
+// newBatchChainReader creates a chain reader overlaying the given headers on top
+// of the local chain.
+func newBatchChainReader(chain consensus.ChainReader, headers []*types.Header) *batchChainReader {
+	batch := &batchChainReader{
+		chain:   chain,
+		headers: make(map[common.Hash]*types.Header, len(headers)),
+	}
+	for _, header := range headers {
+		batch.headers[header.Hash()] = header
+	}
+	return batch
+}
+
+// Gpbfeader retrieves a header from the batch, or from the local chain if it's
+// not part of the batch.
+func (r *batchChainReader) Gpbfeader(hash common.Hash) *types.Header {
+	if header, ok := r.headers[hash]; ok {
+		return header
+	}
+	return r.chain.Gpbfeader(hash)
+}
+
+// Config returns the chain config of the local chain.
+func (r *batchChainReader) Config() *params.ChainConfig {
+	return r.chain.Config()
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+)
+
+// DatabaseStat is the number and total size (keys and values) of the entries
+// of a single category in the chain database.
+type DatabaseStat struct {
+	Name  string
+	Count uint64
+	Size  common.StorageSize
+}
+
+func (s *DatabaseStat) add(key, value []byte) {
+	s.Count++
+	s.Size += common.StorageSize(len(key) + len(value))
+}
+
+// DatabaseStats is the result of inspecting a chain database. Orphans lists
+// the data which is not needed by the canonical chain, these entries are also
+// counted in their respective categories.
+type DatabaseStats struct {
+	Categories []*DatabaseStat
+	Orphans    []*DatabaseStat
+	Total      DatabaseStat
+}
+
+var metadataKeys = [][]byte{
+	headHeaderKey, headBlockKey, headFastKey, lastPrunedKey,
+	[]byte("BlockchainVersion"), []byte("setting-mipmap-version"),
+}
+
+// hashLength is the length of the bare hash keys of trie nodes, codes and
+// transactions.
+const hashLength = len(common.Hash{})
+
+// preimagePrefix is the key prefix of the hash preimages of secure tries.
+var preimagePrefix = []byte("secure-key-")
+
+// InspectDatabase iterates over the whole chain database and collects the
+// number and size of the entries of every known key schema.
+//
+// Transactions and state entries (trie nodes and contract codes) are both
+// stored under bare 32 byte hashes, transactions are told apart by their
+// lookup metadata which immediately follows them in key order.
+func InspectDatabase(db pbfdb.Database) (*DatabaseStats, error) {
+	var (
+		headers      = &DatabaseStat{Name: "Headers"}
+		bodies       = &DatabaseStat{Name: "Bodies"}
+		tds          = &DatabaseStat{Name: "Total difficulties"}
+		canonical    = &DatabaseStat{Name: "Canonical hashes"}
+		blockRcpts   = &DatabaseStat{Name: "Block receipts"}
+		txRcpts      = &DatabaseStat{Name: "Transaction receipts"}
+		txs          = &DatabaseStat{Name: "Transactions"}
+		blooms       = &DatabaseStat{Name: "Log bloom bins"}
+		stateEntries = &DatabaseStat{Name: "State trie nodes and codes"}
+		preimages    = &DatabaseStat{Name: "Trie preimages"}
+		metadata     = &DatabaseStat{Name: "Metadata"}
+		unknown      = &DatabaseStat{Name: "Unknown"}
+
+		sideHeaders = &DatabaseStat{Name: "Non-canonical headers"}
+		sideBodies  = &DatabaseStat{Name: "Non-canonical bodies"}
+		sideTds     = &DatabaseStat{Name: "Non-canonical total difficulties"}
+		sideRcpts   = &DatabaseStat{Name: "Non-canonical block receipts"}
+		legacy      = &DatabaseStat{Name: "Deprecated block-hash- entries"}
+	)
+	stats := &DatabaseStats{
+		Categories: []*DatabaseStat{headers, bodies, tds, canonical, blockRcpts, txRcpts, txs, blooms, stateEntries, preimages, metadata, legacy, unknown},
+		Orphans:    []*DatabaseStat{sideHeaders, sideBodies, sideTds, sideRcpts, legacy},
+	}
+	// A bare hash key is only classified once the next key is known
+	var pendingKey, pendingValue []byte
+	flush := func(next []byte) {
+		if pendingKey == nil {
+			return
+		}
+		if len(next) == hashLength+1 && bytes.HasPrefix(next, pendingKey) && next[hashLength] == txMetaSuffix[0] {
+			txs.add(pendingKey, pendingValue)
+		} else {
+			stateEntries.add(pendingKey, pendingValue)
+		}
+		pendingKey, pendingValue = nil, nil
+	}
+	it := db.NewRangeIterator(nil, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key, value := it.Key(), it.Value()
+		flush(key)
+		stats.Total.add(key, value)
+
+		switch {
+		case len(key) == hashLength:
+			pendingKey, pendingValue = common.CopyBytes(key), common.CopyBytes(value)
+
+		case len(key) == hashLength+1 && key[hashLength] == txMetaSuffix[0]:
+			txs.add(key, value)
+
+		case isMetadataKey(key), bytes.HasPrefix(key, configPrefix) && len(key) == len(configPrefix)+hashLength:
+			metadata.add(key, value)
+
+		case bytes.HasPrefix(key, blockHashPrefix) && len(key) == len(blockHashPrefix)+hashLength:
+			legacy.add(key, value)
+
+		case bytes.HasPrefix(key, blockNumPrefix) && len(key) <= len(blockNumPrefix)+8:
+			canonical.add(key, value)
+
+		case isBlockKey(key, headerSuffix):
+			headers.add(key, value)
+			if hash := common.BytesToHash(key[len(blockPrefix) : len(blockPrefix)+hashLength]); !isCanonical(db, hash) {
+				sideHeaders.add(key, value)
+			}
+		case isBlockKey(key, bodySuffix):
+			bodies.add(key, value)
+			if hash := common.BytesToHash(key[len(blockPrefix) : len(blockPrefix)+hashLength]); !isCanonical(db, hash) {
+				sideBodies.add(key, value)
+			}
+		case isBlockKey(key, tdSuffix):
+			tds.add(key, value)
+			if hash := common.BytesToHash(key[len(blockPrefix) : len(blockPrefix)+hashLength]); !isCanonical(db, hash) {
+				sideTds.add(key, value)
+			}
+		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == len(blockReceiptsPrefix)+hashLength:
+			blockRcpts.add(key, value)
+			if hash := common.BytesToHash(key[len(blockReceiptsPrefix):]); !isCanonical(db, hash) {
+				sideRcpts.add(key, value)
+			}
+		case bytes.HasPrefix(key, receiptsPrefix) && len(key) == len(receiptsPrefix)+hashLength:
+			txRcpts.add(key, value)
+
+		case bytes.HasPrefix(key, mipmapPre):
+			blooms.add(key, value)
+
+		case bytes.HasPrefix(key, preimagePrefix) && len(key) == len(preimagePrefix)+hashLength:
+			preimages.add(key, value)
+
+		default:
+			unknown.add(key, value)
+		}
+	}
+	flush(nil)
+	return stats, it.Error()
+}
+
+// isMetadataKey reports whpbfer key is one of the single entry database keys.
+func isMetadataKey(key []byte) bool {
+	for _, meta := range metadataKeys {
+		if bytes.Equal(key, meta) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBlockKey reports whpbfer key is a block entry with the given suffix.
+func isBlockKey(key, suffix []byte) bool {
+	return len(key) == len(blockPrefix)+hashLength+len(suffix) && bytes.HasPrefix(key, blockPrefix) && bytes.HasSuffix(key, suffix)
+}
+
+// isCanonical reports whpbfer the block with the given hash is part of the
+// canonical chain. Blocks without a header are never canonical.
+func isCanonical(db pbfdb.Database, hash common.Hash) bool {
+	header := Gpbfeader(db, hash)
+	return header != nil && GetCanonicalHash(db, header.Number.Uint64()) == hash
+}
@@ -0,0 +1,94 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+)
+
+// Tests that database inspection classifies all entries and detects the data
+// not belonging to the canonical chain.
+func TestInspectDatabase(t *testing.T) {
+	db, _ := pbfdb.NewMemDatabase()
+
+	tx := types.NewTransaction(1, common.BytesToAddress([]byte{0x11}), big.NewInt(111), big.NewInt(1111), big.NewInt(11111), nil)
+	canon := types.NewBlock(&types.Header{Number: big.NewInt(1), Extra: []byte("canon")}, []*types.Transaction{tx}, nil, nil)
+	side := types.NewBlock(&types.Header{Number: big.NewInt(1), Extra: []byte("side")}, nil, nil, nil)
+
+	for _, block := range []*types.Block{canon, side} {
+		if err := WriteBlock(db, block); err != nil {
+			t.Fatalf("failed to write block: %v", err)
+		}
+		if err := WriteTd(db, block.Hash(), big.NewInt(1)); err != nil {
+			t.Fatalf("failed to write td: %v", err)
+		}
+		if err := WriteBlockReceipts(db, block.Hash(), nil); err != nil {
+			t.Fatalf("failed to write block receipts: %v", err)
+		}
+	}
+	WriteCanonicalHash(db, canon.Hash(), 1)
+	WriteHeadBlockHash(db, canon.Hash())
+	WriteTransactions(db, canon)
+
+	// A state entry and a left over combined block
+	code := []byte{0x60, 0x00}
+	db.Put(crypto.Sha3(code), code)
+	db.Put(append(blockHashPrefix, side.Hash().Bytes()...), []byte{0xc0})
+
+	stats, err := InspectDatabase(db)
+	if err != nil {
+		t.Fatalf("failed to inspect database: %v", err)
+	}
+	counts := make(map[string]uint64)
+	for _, stat := range append(stats.Categories, stats.Orphans...) {
+		counts[stat.Name] = stat.Count
+	}
+	want := map[string]uint64{
+		"Headers":                          2,
+		"Bodies":                           2,
+		"Total difficulties":               2,
+		"Block receipts":                   2,
+		"Canonical hashes":                 1,
+		"Transactions":                     2,
+		"State trie nodes and codes":       1,
+		"Metadata":                         1,
+		"Unknown":                          0,
+		"Non-canonical headers":            1,
+		"Non-canonical bodies":             1,
+		"Non-canonical total difficulties": 1,
+		"Non-canonical block receipts":     1,
+		"Deprecated block-hash- entries":   1,
+	}
+	for name, count := range want {
+		if counts[name] != count {
+			t.Errorf("%s: count mismatch: have %d, want %d", name, counts[name], count)
+		}
+	}
+	var total uint64
+	for _, stat := range stats.Categories {
+		total += stat.Count
+	}
+	if total != stats.Total.Count || total != 14 {
+		t.Errorf("total count mismatch: have %d (categories %d), want %d", stats.Total.Count, total, 14)
+	}
+}
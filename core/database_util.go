@@ -19,6 +19,7 @@ package core
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"math/big"
 
@@ -35,6 +36,7 @@ var (
 	headHeaderKey = []byte("LastHeader")
 	headBlockKey  = []byte("LastBlock")
 	headFastKey   = []byte("LastFast")
+	lastPrunedKey = []byte("LastPruned")
 
 	blockPrefix    = []byte("block-")
 	blockNumPrefix = []byte("block-num-")
@@ -50,39 +52,43 @@ var (
 	mipmapPre    = []byte("mipmap-log-bloom-")
 	MIPMapLevels = []uint64{1000000, 500000, 100000, 50000, 1000}
 
-	ExpDiffPeriod   = big.NewInt(100000)
+	configPrefix = []byte("pbfcoin-config-") // config prefix for the db
+
 	blockHashPrefix = []byte("block-hash-") // [deprecated by the header/block split, remove eventually]
 )
 
 // CalcDifficulty is the difficulty adjustment algorithm. It returns
 // the difficulty that a new block b should have when created at time
-// given the parent block's time and difficulty.
-func CalcDifficulty(time, parentTime uint64, parentNumber, parentDiff *big.Int) *big.Int {
+// given the parent block's time and difficulty, following the rules of
+// the chain config in force at the new block.
+func CalcDifficulty(config *params.ChainConfig, time, parentTime uint64, parentNumber, parentDiff *big.Int) *big.Int {
+	number := new(big.Int).Add(parentNumber, common.Big1)
+	rules := config.Rules(number)
+
 	diff := new(big.Int)
-	adjust := new(big.Int).Div(parentDiff, params.DifficultyBoundDivisor)
+	adjust := new(big.Int).Div(parentDiff, rules.DifficultyBoundDivisor)
 	bigTime := new(big.Int)
 	bigParentTime := new(big.Int)
 
 	bigTime.SetUint64(time)
 	bigParentTime.SetUint64(parentTime)
 
-	if bigTime.Sub(bigTime, bigParentTime).Cmp(params.DurationLimit) < 0 {
+	if bigTime.Sub(bigTime, bigParentTime).Cmp(rules.DurationLimit) < 0 {
 		diff.Add(parentDiff, adjust)
 	} else {
 		diff.Sub(parentDiff, adjust)
 	}
-	if diff.Cmp(params.MinimumDifficulty) < 0 {
-		diff = params.MinimumDifficulty
+	if diff.Cmp(rules.MinimumDifficulty) < 0 {
+		diff.Set(rules.MinimumDifficulty)
 	}
 
-	periodCount := new(big.Int).Add(parentNumber, common.Big1)
-	periodCount.Div(periodCount, ExpDiffPeriod)
+	periodCount := new(big.Int).Div(number, rules.ExpDiffPeriod)
 	if periodCount.Cmp(common.Big1) > 0 {
 		// diff = diff + 2^(periodCount - 2)
 		expDiff := periodCount.Sub(periodCount, common.Big2)
 		expDiff.Exp(common.Big2, expDiff, nil)
 		diff.Add(diff, expDiff)
-		diff = common.BigMax(diff, params.MinimumDifficulty)
+		diff = common.BigMax(diff, rules.MinimumDifficulty)
 	}
 
 	return diff
@@ -90,15 +96,18 @@ func CalcDifficulty(time, parentTime uint64, parentNumber, parentDiff *big.Int)
 
 // CalcGasLimit computes the gas limit of the next block after parent.
 // The result may be modified by the caller.
-// This is miner strategy, not consensus protocol.
-func CalcGasLimit(parent *types.Block) *big.Int {
+// This is miner strategy, not consensus protocol, but the limit stays
+// within the bounds of the chain config in force at the next block.
+func CalcGasLimit(config *params.ChainConfig, parent *types.Block) *big.Int {
+	rules := config.Rules(new(big.Int).Add(parent.Number(), common.Big1))
+
 	// contrib = (parentGasUsed * 3 / 2) / 1024
 	contrib := new(big.Int).Mul(parent.GasUsed(), big.NewInt(3))
 	contrib = contrib.Div(contrib, big.NewInt(2))
-	contrib = contrib.Div(contrib, params.GasLimitBoundDivisor)
+	contrib = contrib.Div(contrib, rules.GasLimitBoundDivisor)
 
 	// decay = parentGasLimit / 1024 -1
-	decay := new(big.Int).Div(parent.GasLimit(), params.GasLimitBoundDivisor)
+	decay := new(big.Int).Div(parent.GasLimit(), rules.GasLimitBoundDivisor)
 	decay.Sub(decay, big.NewInt(1))
 
 	/*
@@ -110,7 +119,7 @@ func CalcGasLimit(parent *types.Block) *big.Int {
 	*/
 	gl := new(big.Int).Sub(parent.GasLimit(), decay)
 	gl = gl.Add(gl, contrib)
-	gl.Set(common.BigMax(gl, params.MinGasLimit))
+	gl.Set(common.BigMax(gl, rules.MinGasLimit))
 
 	// however, if we're now below the target (GenesisGasLimit) we increase the
 	// limit as much as we can (parentGasLimit / 1024 -1)
@@ -327,6 +336,26 @@ func WriteHeadBlockHash(db pbfdb.Database, hash common.Hash) error {
 	return nil
 }
 
+// GetLastPrunedNumber retrieves the number of the last block whose state was
+// considered for pruning, zero if the state was never pruned.
+func GetLastPrunedNumber(db pbfdb.Database) uint64 {
+	data, _ := db.Get(lastPrunedKey)
+	if len(data) == 0 {
+		return 0
+	}
+	return new(big.Int).SetBytes(data).Uint64()
+}
+
+// WriteLastPrunedNumber stores the number of the last block whose state was
+// considered for pruning.
+func WriteLastPrunedNumber(db pbfdb.Database, number uint64) error {
+	if err := db.Put(lastPrunedKey, new(big.Int).SetUint64(number).Bytes()); err != nil {
+		glog.Fatalf("failed to store last pruned block number into database: %v", err)
+		return err
+	}
+	return nil
+}
+
 // WriteHeadFastBlockHash stores the fast head block's hash.
 func WriteHeadFastBlockHash(db pbfdb.Database, hash common.Hash) error {
 	if err := db.Put(headFastKey, hash.Bytes()); err != nil {
@@ -582,3 +611,31 @@ func GetMipmapBloom(db pbfdb.Database, number, level uint64) types.Bloom {
 	bloomDat, _ := db.Get(mipmapKey(number, level))
 	return types.BytesToBloom(bloomDat)
 }
+
+// GetChainConfig retrieves the chain config of the chain with the given genesis
+// hash, nil if the genesis block didn't specify one.
+func GetChainConfig(db pbfdb.Database, hash common.Hash) (*params.ChainConfig, error) {
+	data, _ := db.Get(append(configPrefix, hash[:]...))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	config := new(params.ChainConfig)
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid chain config: %v", err)
+	}
+	return config, nil
+}
+
+// WriteChainConfig stores the chain config of the chain with the given genesis
+// hash.
+func WriteChainConfig(db pbfdb.Database, hash common.Hash, config *params.ChainConfig) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(append(configPrefix, hash[:]...), data); err != nil {
+		glog.Fatalf("failed to store chain config into database: %v", err)
+		return err
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
@@ -77,7 +78,7 @@ func TestDifficulty(t *testing.T) {
 
 	for name, test := range tests {
 		number := new(big.Int).Sub(test.CurrentBlocknumber, big.NewInt(1))
-		diff := CalcDifficulty(test.CurrentTimestamp, test.ParentTimestamp, number, test.ParentDifficulty)
+		diff := CalcDifficulty(nil, test.CurrentTimestamp, test.ParentTimestamp, number, test.ParentDifficulty)
 		if diff.Cmp(test.CurrentDifficulty) != 0 {
 			t.Error(name, "failed. Expected", test.CurrentDifficulty, "and calculated", diff)
 		}
@@ -302,6 +303,68 @@ func TestCanonicalMappingStorage(t *testing.T) {
 	}
 }
 
+// Tests that the chain config of a genesis file is stored along the genesis block
+// and that malformed fork schedules are rejected.
+func TestChainConfigStorage(t *testing.T) {
+	db, _ := pbfdb.NewMemDatabase()
+
+	genesis := `{
+	"difficulty": "0x20000",
+	"gasLimit": "0x2fefd8",
+	"config": {
+		"forks": [
+			{"block": 0, "blockReward": 3000000000000000000},
+			{"block": 100, "txGas": 25000, "sstoreSetGas": 30000}
+		],
+		"checkpoint": {
+			"number": 30000,
+			"hash": "0x0102030405060708091011121314151617181920212223242526272829303132",
+			"td": 4000000000
+		}
+	}
+}`
+	block, err := WriteGenesisBlock(db, strings.NewReader(genesis))
+	if err != nil {
+		t.Fatalf("failed to write genesis block: %v", err)
+	}
+	config, err := GetChainConfig(db, block.Hash())
+	if err != nil || config == nil {
+		t.Fatalf("failed to retrieve chain config: %v", err)
+	}
+	tests := []struct {
+		number                int64
+		reward, txGas, sstore int64
+	}{
+		{0, 3e18, 21000, 20000},
+		{99, 3e18, 21000, 20000},
+		{100, 3e18, 25000, 30000},
+		{1000, 3e18, 25000, 30000},
+	}
+	for i, tt := range tests {
+		rules := config.Rules(big.NewInt(tt.number))
+		if rules.BlockReward.Int64() != tt.reward || rules.TxGas.Int64() != tt.txGas || rules.SstoreSetGas.Int64() != tt.sstore {
+			t.Errorf("test %d: rules mismatch at block %d: have reward %v, txgas %v, sstore %v; want %d, %d, %d",
+				i, tt.number, rules.BlockReward, rules.TxGas, rules.SstoreSetGas, tt.reward, tt.txGas, tt.sstore)
+		}
+	}
+	checkpoint := config.Checkpoint
+	if checkpoint == nil {
+		t.Fatalf("checkpoint missing from chain config")
+	}
+	if checkpoint.Number != 30000 || checkpoint.Hash != common.HexToHash("0x0102030405060708091011121314151617181920212223242526272829303132") || checkpoint.TD.Int64() != 4e9 {
+		t.Errorf("checkpoint mismatch: have #%d [%x] td %v", checkpoint.Number, checkpoint.Hash, checkpoint.TD)
+	}
+	// Genesis blocks without a config run with the default rules
+	if config, err := GetChainConfig(db, common.Hash{1}); config != nil || err != nil {
+		t.Errorf("non existent chain config returned: %v, %v", config, err)
+	}
+	// Forks must be ordered by their activation block
+	invalid := `{"difficulty": "0x20000", "gasLimit": "0x2fefd8", "config": {"forks": [{"block": 10, "txGas": 1}, {"block": 5, "txGas": 2}]}}`
+	if _, err := WriteGenesisBlock(db, strings.NewReader(invalid)); err == nil {
+		t.Errorf("unordered forks accepted")
+	}
+}
+
 // Tests that head headers and head blocks can be assigned, individually.
 func TestHeadStorage(t *testing.T) {
 	db, _ := pbfdb.NewMemDatabase()
@@ -201,3 +201,23 @@ func IsGasLimitErr(err error) bool {
 func (err *GasLimitErr) Error() string {
 	return fmt.Sprintf("GasLimit reached. Have %d gas, transaction requires %d", err.Have, err.Want)
 }
+
+// GenesisErr indicates a malformed genesis specification, identifying the offending
+// value by its JSON path (e.g. alloc.<address>.balance).
+type GenesisErr struct {
+	Field  string
+	Reason string
+}
+
+func (err *GenesisErr) Error() string {
+	if err.Field == "" {
+		return fmt.Sprintf("invalid genesis: %s", err.Reason)
+	}
+	return fmt.Sprintf("invalid genesis: %s: %s", err.Field, err.Reason)
+}
+
+// IsGenesisErr returns true for malformed genesis specification errors.
+func IsGenesisErr(err error) bool {
+	_, ok := err.(*GenesisErr)
+	return ok
+}
@@ -27,20 +27,20 @@ import (
 
 // Call executes within the given contract
 func Call(env vm.Environment, caller vm.ContractRef, addr common.Address, input []byte, gas, gasPrice, value *big.Int) (ret []byte, err error) {
-	ret, _, err = exec(env, caller, &addr, &addr, input, env.Db().GetCode(addr), gas, gasPrice, value)
+	ret, _, err = exec(env, vm.CALL, caller, &addr, &addr, input, env.Db().GetCode(addr), gas, gasPrice, value)
 	return ret, err
 }
 
 // CallCode executes the given address' code as the given contract address
 func CallCode(env vm.Environment, caller vm.ContractRef, addr common.Address, input []byte, gas, gasPrice, value *big.Int) (ret []byte, err error) {
 	prev := caller.Address()
-	ret, _, err = exec(env, caller, &prev, &addr, input, env.Db().GetCode(addr), gas, gasPrice, value)
+	ret, _, err = exec(env, vm.CALLCODE, caller, &prev, &addr, input, env.Db().GetCode(addr), gas, gasPrice, value)
 	return ret, err
 }
 
 // Create creates a new contract with the given code
 func Create(env vm.Environment, caller vm.ContractRef, code []byte, gas, gasPrice, value *big.Int) (ret []byte, address common.Address, err error) {
-	ret, address, err = exec(env, caller, nil, nil, nil, code, gas, gasPrice, value)
+	ret, address, err = exec(env, vm.CREATE, caller, nil, nil, nil, code, gas, gasPrice, value)
 	// Here we get an error if we run into maximum stack depth,
 	// See: https://github.com/pbfcoin/yellowpaper/pull/131
 	// and YP definitions for CREATE instruction
@@ -50,7 +50,7 @@ func Create(env vm.Environment, caller vm.ContractRef, code []byte, gas, gasPric
 	return ret, address, err
 }
 
-func exec(env vm.Environment, caller vm.ContractRef, address, codeAddr *common.Address, input, code []byte, gas, gasPrice, value *big.Int) (ret []byte, addr common.Address, err error) {
+func exec(env vm.Environment, typ vm.OpCode, caller vm.ContractRef, address, codeAddr *common.Address, input, code []byte, gas, gasPrice, value *big.Int) (ret []byte, addr common.Address, err error) {
 	evm := vm.NewVm(env)
 
 	// Depth check execution. Fail if we're trying to execute above the
@@ -98,6 +98,20 @@ func exec(env vm.Environment, caller vm.ContractRef, address, codeAddr *common.A
 	contract := vm.NewContract(caller, to, value, gas, gasPrice)
 	contract.SetCallCode(codeAddr, code)
 
+	if tracer, ok := env.Tracer().(vm.CallTracer); ok {
+		target := *address
+		if codeAddr != nil {
+			target = *codeAddr
+		}
+		// Contract creations report their init code as the call input
+		traced := input
+		if createAccount {
+			traced = code
+		}
+		tracer.CaptureEnter(typ, caller.Address(), target, traced, new(big.Int).Set(gas), new(big.Int).Set(value))
+		defer func() { tracer.CaptureExit(ret, new(big.Int).Set(contract.Gas), err) }()
+	}
+
 	ret, err = evm.Run(contract, input)
 	if err != nil {
 		env.SetSnapshot(snapshot) //env.Db().Set(snapshot)
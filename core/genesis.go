@@ -17,11 +17,14 @@
 package core
 
 import (
+	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math/big"
+	"sort"
 	"strings"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
@@ -39,52 +42,67 @@ func WriteGenesisBlock(chainDb pbfdb.Database, reader io.Reader) (*types.Block,
 	if err != nil {
 		return nil, err
 	}
-
-	var genesis struct {
-		Nonce      string
-		Timestamp  string
-		ParentHash string
-		ExtraData  string
-		GasLimit   string
-		Difficulty string
-		Mixhash    string
-		Coinbase   string
-		Alloc      map[string]struct {
-			Code    string
-			Storage map[string]string
-			Balance string
-		}
-	}
-
-	if err := json.Unmarshal(contents, &genesis); err != nil {
+	var raw json.RawMessage
+	if err := json.Unmarshal(contents, &raw); err != nil {
 		return nil, err
 	}
+	p := new(genesisParser)
+	genesis := p.object("", raw, genesisFields...)
 
 	// creating with empty hash always works
 	statedb, _ := state.New(common.Hash{}, chainDb)
-	for addr, account := range genesis.Alloc {
-		address := common.HexToAddress(addr)
-		statedb.AddBalance(address, common.String2Big(account.Balance))
-		statedb.SetCode(address, common.Hex2Bytes(account.Code))
-		for key, value := range account.Storage {
-			statedb.SetState(address, common.HexToHash(key), common.HexToHash(value))
+	alloc := p.object("alloc", genesis["alloc"])
+	for _, addr := range sortedKeys(alloc) {
+		field := "alloc." + addr
+		account := p.object(field, alloc[addr], genesisAccountFields...)
+
+		address := p.address(field, addr)
+		balance := p.number(field+".balance", account["balance"], false)
+		code := p.bytes(field+".code", account["code"])
+		storage := p.object(field+".storage", account["storage"])
+		if p.err != nil {
+			return nil, p.err
+		}
+		statedb.AddBalance(address, balance)
+		statedb.SetCode(address, code)
+		for _, key := range sortedKeys(storage) {
+			slot := p.hash(field+".storage."+key, key, false)
+			value := p.hash(field+".storage."+key, p.str(field+".storage."+key, storage[key]), false)
+			if p.err != nil {
+				return nil, p.err
+			}
+			statedb.SetState(address, slot, value)
 		}
 	}
+	header := &types.Header{
+		Nonce:      types.EncodeNonce(p.uint64("nonce", genesis["nonce"])),
+		Time:       p.number("timestamp", genesis["timestamp"], false),
+		ParentHash: p.hash("parentHash", p.str("parentHash", genesis["parentHash"]), true),
+		Extra:      p.bytes("extraData", genesis["extraData"]),
+		GasLimit:   p.number("gasLimit", genesis["gasLimit"], true),
+		Difficulty: p.number("difficulty", genesis["difficulty"], true),
+		MixDigest:  p.hash("mixhash", p.str("mixhash", genesis["mixhash"]), true),
+		Coinbase:   p.address("coinbase", p.str("coinbase", genesis["coinbase"])),
+	}
+	config := p.config("config", genesis["config"])
+	if p.err == nil && header.GasLimit.Sign() == 0 {
+		p.fail("gasLimit", "must be positive")
+	}
+	if p.err == nil && header.Difficulty.Sign() == 0 {
+		p.fail("difficulty", "must be positive")
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
 	root, stateBatch := statedb.CommitBatch()
+	header.Root = root
+	block := types.NewBlock(header, nil, nil, nil)
 
-	difficulty := common.String2Big(genesis.Difficulty)
-	block := types.NewBlock(&types.Header{
-		Nonce:      types.EncodeNonce(common.String2Big(genesis.Nonce).Uint64()),
-		Time:       common.String2Big(genesis.Timestamp),
-		ParentHash: common.HexToHash(genesis.ParentHash),
-		Extra:      common.FromHex(genesis.ExtraData),
-		GasLimit:   common.String2Big(genesis.GasLimit),
-		Difficulty: difficulty,
-		MixDigest:  common.HexToHash(genesis.Mixhash),
-		Coinbase:   common.HexToAddress(genesis.Coinbase),
-		Root:       root,
-	}, nil, nil, nil)
-
+	if config != nil {
+		if err := WriteChainConfig(chainDb, block.Hash(), config); err != nil {
+			return nil, err
+		}
+	}
 	if block := GetBlock(chainDb, block.Hash()); block != nil {
 		glog.V(logger.Info).Infoln("Genesis block already in chain. Writing canonical number")
 		err := WriteCanonicalHash(chainDb, block.Hash(), block.NumberU64())
@@ -97,7 +115,7 @@ func WriteGenesisBlock(chainDb pbfdb.Database, reader io.Reader) (*types.Block,
 	if err := stateBatch.Write(); err != nil {
 		return nil, fmt.Errorf("cannot write state: %v", err)
 	}
-	if err := WriteTd(chainDb, block.Hash(), difficulty); err != nil {
+	if err := WriteTd(chainDb, block.Hash(), header.Difficulty); err != nil {
 		return nil, err
 	}
 	if err := WriteBlock(chainDb, block); err != nil {
@@ -115,6 +133,207 @@ func WriteGenesisBlock(chainDb pbfdb.Database, reader io.Reader) (*types.Block,
 	return block, nil
 }
 
+// InitGenesisBlock writes the genesis block like WriteGenesisBlock, but refuses to
+// replace a different genesis block already present in the database.
+func InitGenesisBlock(chainDb pbfdb.Database, reader io.Reader) (*types.Block, error) {
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	// Assemble the block in a throwaway database to learn its hash first
+	memdb, _ := pbfdb.NewMemDatabase()
+	block, err := WriteGenesisBlock(memdb, bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+	if stored := GetCanonicalHash(chainDb, 0); stored != (common.Hash{}) && stored != block.Hash() {
+		return nil, fmt.Errorf("database already contains an incompatible genesis block (have %x, new %x)", stored[:4], block.Hash().Bytes()[:4])
+	}
+	return WriteGenesisBlock(chainDb, bytes.NewReader(contents))
+}
+
+var (
+	// genesisFields are the keys allowed in a genesis specification.
+	genesisFields = []string{"nonce", "timestamp", "parentHash", "extraData", "gasLimit", "difficulty", "mixhash", "coinbase", "alloc", "config"}
+
+	// genesisAccountFields are the keys allowed in an account of the genesis alloc.
+	genesisAccountFields = []string{"balance", "code", "storage"}
+)
+
+// genesisParser decodes the values of a genesis specification strictly. The first
+// malformed value is recorded as a GenesisErr, after which all methods are no-ops.
+type genesisParser struct {
+	err error
+}
+
+func (p *genesisParser) fail(field, format string, v ...interface{}) {
+	if p.err == nil {
+		p.err = &GenesisErr{Field: field, Reason: fmt.Sprintf(format, v...)}
+	}
+}
+
+// object decodes a JSON object. If known keys are given, any other key is rejected
+// and the returned map is keyed by the known spelling, matched case insensitively
+// like encoding/json does for struct fields.
+func (p *genesisParser) object(field string, raw json.RawMessage, known ...string) map[string]json.RawMessage {
+	var obj map[string]json.RawMessage
+	if p.err != nil || raw == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		p.fail(field, "expected an object")
+		return nil
+	}
+	if len(known) == 0 {
+		return obj
+	}
+	fields := make(map[string]json.RawMessage)
+	for _, key := range sortedKeys(obj) {
+		name := ""
+		for _, k := range known {
+			if strings.EqualFold(k, key) {
+				name = k
+				break
+			}
+		}
+		if name == "" {
+			p.fail(strings.TrimPrefix(field+"."+key, "."), "unknown field")
+			return nil
+		}
+		fields[name] = obj[key]
+	}
+	return fields
+}
+
+// str decodes a JSON string, a missing value or null decodes to the empty string.
+func (p *genesisParser) str(field string, raw json.RawMessage) string {
+	var s string
+	if p.err != nil || raw == nil {
+		return ""
+	}
+	if err := json.Unmarshal(raw, &s); err != nil {
+		p.fail(field, "expected a string")
+	}
+	return s
+}
+
+// number decodes a non-negative decimal or 0x prefixed hexadecimal integer.
+// Missing optional numbers are zero.
+func (p *genesisParser) number(field string, raw json.RawMessage, required bool) *big.Int {
+	s := p.str(field, raw)
+	if p.err != nil {
+		return nil
+	}
+	if s == "" {
+		if required {
+			p.fail(field, "missing value")
+			return nil
+		}
+		return new(big.Int)
+	}
+	var (
+		n  *big.Int
+		ok bool
+	)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		n, ok = new(big.Int).SetString(s[2:], 16)
+	} else {
+		n, ok = new(big.Int).SetString(s, 10)
+	}
+	if !ok || n.Sign() < 0 {
+		p.fail(field, "invalid number %q", s)
+		return nil
+	}
+	return n
+}
+
+func (p *genesisParser) uint64(field string, raw json.RawMessage) uint64 {
+	n := p.number(field, raw, false)
+	if p.err != nil {
+		return 0
+	}
+	if n.BitLen() > 64 {
+		p.fail(field, "value exceeds 64 bits")
+		return 0
+	}
+	return n.Uint64()
+}
+
+// hex decodes a hex string with an optional 0x prefix.
+func (p *genesisParser) hex(field, s string) []byte {
+	if p.err != nil {
+		return nil
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		p.fail(field, "invalid hex string")
+		return nil
+	}
+	return b
+}
+
+func (p *genesisParser) bytes(field string, raw json.RawMessage) []byte {
+	return p.hex(field, p.str(field, raw))
+}
+
+// address decodes an account address, which must be exactly 20 bytes long unless
+// missing entirely.
+func (p *genesisParser) address(field, s string) common.Address {
+	b := p.hex(field, s)
+	if p.err != nil || s == "" {
+		return common.Address{}
+	}
+	if len(b) != len(common.Address{}) {
+		p.fail(field, "invalid address length %d, want %d bytes", len(b), len(common.Address{}))
+		return common.Address{}
+	}
+	return common.BytesToAddress(b)
+}
+
+// hash decodes a 32 byte value. Exact hashes must be given in full, others (storage
+// slots and values) may be shorter and are left padded with zeroes.
+func (p *genesisParser) hash(field, s string, exact bool) common.Hash {
+	b := p.hex(field, s)
+	if p.err != nil || s == "" {
+		return common.Hash{}
+	}
+	if len(b) > len(common.Hash{}) || (exact && len(b) != len(common.Hash{})) {
+		p.fail(field, "invalid hash length %d, want %d bytes", len(b), len(common.Hash{}))
+		return common.Hash{}
+	}
+	return common.BytesToHash(b)
+}
+
+// config decodes and validates the chain configuration, nil if missing.
+func (p *genesisParser) config(field string, raw json.RawMessage) *params.ChainConfig {
+	var config *params.ChainConfig
+	if p.err != nil || raw == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		p.fail(field, "%v", err)
+		return nil
+	}
+	if err := config.Validate(); err != nil {
+		p.fail(field, "%v", err)
+		return nil
+	}
+	return config
+}
+
+// sortedKeys returns the keys of a decoded JSON object in a deterministic order.
+func sortedKeys(obj map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // GenesisBlockForTesting creates a block in which addr has the given wei balance.
 // The state trie of the block is written to db. the passed db needs to contain a state root
 func GenesisBlockForTesting(db pbfdb.Database, addr common.Address, balance *big.Int) *types.Block {
@@ -201,3 +420,21 @@ func WriteOlympicGenesisBlock(chainDb pbfdb.Database, nonce uint64) (*types.Bloc
 }`, types.EncodeNonce(nonce), params.GenesisGasLimit.Bytes(), params.GenesisDifficulty.Bytes())
 	return WriteGenesisBlock(chainDb, strings.NewReader(testGenesis))
 }
+
+// WriteDevGenesisBlock writes the genesis block of the developer mode chain, in
+// which the developer account holds a large balance to pay for transactions.
+func WriteDevGenesisBlock(chainDb pbfdb.Database, developer common.Address) (*types.Block, error) {
+	devGenesis := fmt.Sprintf(`{
+	"nonce":"0x%x",
+	"gasLimit":"0x%x",
+	"difficulty":"0x1",
+	"alloc": {
+		"0000000000000000000000000000000000000001": {"balance": "1"},
+		"0000000000000000000000000000000000000002": {"balance": "1"},
+		"0000000000000000000000000000000000000003": {"balance": "1"},
+		"0000000000000000000000000000000000000004": {"balance": "1"},
+		"%x": {"balance": "1606938044258990275541962092341162602522202993782792835301376"}
+	}
+}`, types.EncodeNonce(0), params.GenesisGasLimit.Bytes(), developer)
+	return WriteGenesisBlock(chainDb, strings.NewReader(devGenesis))
+}
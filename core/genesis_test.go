@@ -0,0 +1,108 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/state"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+)
+
+// Tests that malformed genesis specifications are rejected with the path of the
+// offending field instead of being silently zeroed.
+func TestGenesisValidation(t *testing.T) {
+	tests := []struct {
+		genesis string
+		field   string
+	}{
+		{`{"difficulty": "0x20000"}`, "gasLimit"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x0"}`, "difficulty"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x2000g"}`, "difficulty"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": 131072}`, "difficulty"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "dificulty": "0x1"}`, "dificulty"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "nonce": "0x10000000000000000"}`, "nonce"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "timestamp": "-1"}`, "timestamp"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "coinbase": "0x1234"}`, "coinbase"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "mixhash": "0x01"}`, "mixhash"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "extraData": "0x123"}`, "extraData"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "alloc": {"0x1234": {"balance": "1"}}}`, "alloc.0x1234"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "alloc": {"0000000000000000000000000000000000000001": {"balance": "1O"}}}`, "alloc.0000000000000000000000000000000000000001.balance"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "alloc": {"0000000000000000000000000000000000000001": {"balanse": "1"}}}`, "alloc.0000000000000000000000000000000000000001.balanse"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "alloc": {"0000000000000000000000000000000000000001": {"storage": {"0x01": "0xzz"}}}}`, "alloc.0000000000000000000000000000000000000001.storage.0x01"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "config": {"forks": [{"block": 10}, {"block": 5}]}}`, "config"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "config": {"checkpoint": {"number": 10, "hash": "0x01", "td": 10}}}`, "config"},
+		{`{"gasLimit": "0x2fefd8", "difficulty": "0x20000", "config": {"checkpoint": {"number": 10, "hash": "0x0102030405060708091011121314151617181920212223242526272829303132"}}}`, "config"},
+	}
+	for i, tt := range tests {
+		db, _ := pbfdb.NewMemDatabase()
+		_, err := WriteGenesisBlock(db, strings.NewReader(tt.genesis))
+		if !IsGenesisErr(err) {
+			t.Errorf("test %d: error mismatch: have %v, want genesis error", i, err)
+			continue
+		}
+		if field := err.(*GenesisErr).Field; field != tt.field {
+			t.Errorf("test %d: field mismatch: have %q, want %q (%v)", i, field, tt.field, err)
+		}
+	}
+}
+
+// Tests that a genesis block is only initialized into a database which is empty or
+// already holds the same genesis block.
+func TestInitGenesisBlock(t *testing.T) {
+	db, _ := pbfdb.NewMemDatabase()
+
+	genesis := `{
+	"difficulty": "0x20000",
+	"gasLimit": "0x2fefd8",
+	"alloc": {
+		"0x0000000000000000000000000000000000000001": {"balance": "1000", "storage": {"0x01": "0x02"}}
+	}
+}`
+	block, err := InitGenesisBlock(db, strings.NewReader(genesis))
+	if err != nil {
+		t.Fatalf("failed to initialize genesis block: %v", err)
+	}
+	if hash := GetCanonicalHash(db, 0); hash != block.Hash() {
+		t.Fatalf("canonical genesis mismatch: have %x, want %x", hash, block.Hash())
+	}
+	statedb, err := state.New(block.Root(), db)
+	if err != nil {
+		t.Fatalf("failed to open genesis state: %v", err)
+	}
+	addr := common.BytesToAddress([]byte{1})
+	if balance := statedb.GetBalance(addr); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 1000", balance)
+	}
+	if value := statedb.GetState(addr, common.BytesToHash([]byte{1})); value != common.BytesToHash([]byte{2}) {
+		t.Errorf("storage mismatch: have %x, want 0x02", value)
+	}
+	// Initializing the same genesis again is allowed, a different one is not
+	if again, err := InitGenesisBlock(db, strings.NewReader(genesis)); err != nil || again.Hash() != block.Hash() {
+		t.Errorf("failed to reinitialize the same genesis: %v", err)
+	}
+	other := strings.Replace(genesis, "0x20000", "0x40000", 1)
+	if _, err := InitGenesisBlock(db, strings.NewReader(other)); err == nil {
+		t.Errorf("different genesis block overwrote the existing one")
+	}
+	if hash := GetCanonicalHash(db, 0); hash != block.Hash() {
+		t.Errorf("canonical genesis changed: have %x, want %x", hash, block.Hash())
+	}
+}
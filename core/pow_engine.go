@@ -0,0 +1,123 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus"
+	"github.com/pbfcoin/go-pbfcoin/core/state"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/pow"
+)
+
+// PowEngine is the proof-of-work consensus engine. It seals blocks by searching
+// for a valid nonce using the wrapped pow algorithm and rewards the coinbase and
+// uncles of every block.
+//
+// PowEngine implements consensus.PoW.
+type PowEngine struct {
+	pow pow.PoW
+}
+
+// NewPowEngine creates a proof-of-work consensus engine around the given pow
+// algorithm.
+func NewPowEngine(pow pow.PoW) *PowEngine {
+	return &PowEngine{pow: pow}
+}
+
+// Pow returns the underlying proof-of-work algorithm.
+func (e *PowEngine) Pow() pow.PoW { return e.pow }
+
+// Author returns the coinbase of the header, the account that mined the block.
+func (e *PowEngine) Author(header *types.Header) (common.Address, error) {
+	return header.Coinbase, nil
+}
+
+// VerifyHeader checks the extra-data size, the timestamp against the parent and
+// whpbfer the difficulty honours the difficulty adjustment algorithm.
+func (e *PowEngine) VerifyHeader(chain consensus.ChainReader, header, parent *types.Header, uncle bool) error {
+	if big.NewInt(int64(len(header.Extra))).Cmp(chain.Config().Rules(header.Number).MaximumExtraDataSize) == 1 {
+		return fmt.Errorf("Header extra data too long (%d)", len(header.Extra))
+	}
+	if header.Time.Cmp(parent.Time) != 1 {
+		return BlockEqualTSErr
+	}
+	expd := e.CalcDifficulty(chain, header.Time.Uint64(), parent)
+	if expd.Cmp(header.Difficulty) != 0 {
+		return fmt.Errorf("Difficulty check failed for header %v, %v", header.Difficulty, expd)
+	}
+	return nil
+}
+
+// VerifySeal checks the nonce and mix digest of the header against the
+// difficulty it claims.
+func (e *PowEngine) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
+	if !e.pow.Verify(types.NewBlockWithHeader(header)) {
+		return &BlockNonceErr{header.Number, header.Hash(), header.Nonce.Uint64()}
+	}
+	return nil
+}
+
+// CalcDifficulty returns the difficulty a block created at the given time on
+// top of parent should have.
+func (e *PowEngine) CalcDifficulty(chain consensus.ChainReader, time uint64, parent *types.Header) *big.Int {
+	return CalcDifficulty(chain.Config(), time, parent.Time.Uint64(), parent.Number, parent.Difficulty)
+}
+
+// Prepare sets the difficulty of the header based on its parent.
+func (e *PowEngine) Prepare(chain consensus.ChainReader, header *types.Header) error {
+	parent := chain.Gpbfeader(header.ParentHash)
+	if parent == nil {
+		return ParentError(header.ParentHash)
+	}
+	header.Difficulty = e.CalcDifficulty(chain, header.Time.Uint64(), parent)
+	return nil
+}
+
+// Finalize credits the block and uncle rewards.
+func (e *PowEngine) Finalize(chain consensus.ChainReader, statedb *state.StateDB, header *types.Header, uncles []*types.Header) error {
+	AccumulateRewards(chain.Config(), statedb, header, uncles)
+	return nil
+}
+
+// Seal searches for a nonce satisfying the block's difficulty. It returns a nil
+// block if the search was aborted.
+func (e *PowEngine) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
+	return e.SealThread(chain, block, stop, 0)
+}
+
+// SealThread searches for a nonce satisfying the block's difficulty as the given
+// mining thread. It returns a nil block if the search was aborted.
+func (e *PowEngine) SealThread(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}, thread int) (*types.Block, error) {
+	nonce, mixDigest := e.pow.Search(block, stop, thread)
+
+	// The search only gives up if stopped, zero is a valid nonce otherwise
+	select {
+	case <-stop:
+		return nil, nil
+	default:
+	}
+	return block.WithMiningResult(nonce, common.BytesToHash(mixDigest)), nil
+}
+
+// Hashrate returns the current hashrate of the pow algorithm.
+func (e *PowEngine) Hashrate() int64 {
+	return e.pow.Gpbfashrate()
+}
@@ -0,0 +1,255 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+	"github.com/pbfcoin/go-pbfcoin/rlp"
+	"github.com/pbfcoin/go-pbfcoin/trie"
+)
+
+// recentRootsLimit is the number of recently committed state roots which are
+// kept by pruning, regardless of whpbfer their blocks are canonical yet.
+const recentRootsLimit = 64
+
+// errPruneAborted is returned by Prune if it was aborted before all dropped
+// states were swept.
+var errPruneAborted = errors.New("pruning aborted")
+
+// pruneTracker holds the pruning bookkeeping of a single database.
+type pruneTracker struct {
+	// commitLock serialises state commits with the deletion of nodes. Commits
+	// only hold the read lock, so they run concurrently with each other but
+	// never while Prune deletes nodes which a commit could be writing again.
+	commitLock sync.RWMutex
+
+	// recent is a ring of the last committed state roots. Blocks are only
+	// written to the chain after their state was committed, these states are
+	// retained as they may not be reachable from any canonical block yet.
+	recent [recentRootsLimit]common.Hash
+	next   int
+
+	// commits collects the roots committed while Prune is running, these are
+	// marked live before each sweep.
+	commits  []common.Hash
+	tracking bool
+
+	lock sync.Mutex // protects recent, next, commits and tracking
+}
+
+var (
+	trackers     = make(map[pbfdb.Database]*pruneTracker)
+	trackersLock sync.Mutex
+)
+
+// trackerOf returns the pruning bookkeeping of the given database.
+func trackerOf(db pbfdb.Database) *pruneTracker {
+	trackersLock.Lock()
+	defer trackersLock.Unlock()
+
+	t := trackers[db]
+	if t == nil {
+		t = new(pruneTracker)
+		trackers[db] = t
+	}
+	return t
+}
+
+// add records a committed state root.
+func (t *pruneTracker) add(root common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.recent[t.next] = root
+	t.next = (t.next + 1) % recentRootsLimit
+	if t.tracking {
+		t.commits = append(t.commits, root)
+	}
+}
+
+// track starts or stops collecting committed roots for a running Prune. It
+// returns the recently committed roots.
+func (t *pruneTracker) track(on bool) [recentRootsLimit]common.Hash {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.tracking, t.commits = on, nil
+	return t.recent
+}
+
+// takeCommits returns and clears the roots committed since the last call.
+func (t *pruneTracker) takeCommits() []common.Hash {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	commits := t.commits
+	t.commits = nil
+	return commits
+}
+
+// Prune deletes the trie nodes and contract codes of the dropped state roots
+// which aren't part of any of the retained states, and returns the number of
+// deleted database entries. The recently committed states are always retained.
+//
+// The retained states are marked first, a missing node in any of them aborts
+// pruning without deleting anything. The dropped states are then swept one
+// after the other, skipping the nodes which were already deleted. State commits
+// are only blocked while the nodes of a single dropped state are deleted, the
+// states committed in the meantime are marked live right before. Closing abort
+// stops pruning between two sweeps.
+func Prune(db pbfdb.Database, retain, drop []common.Hash, abort <-chan struct{}) (int, error) {
+	tracker := trackerOf(db)
+	recent := tracker.track(true)
+	defer tracker.track(false)
+
+	live := make(map[common.Hash]struct{})
+	for _, root := range retain {
+		err := walkState(db, root, func(hash common.Hash) bool {
+			if _, ok := live[hash]; ok {
+				return false
+			}
+			live[hash] = struct{}{}
+			return true
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	for _, root := range recent {
+		if err := markPresent(db, root, live, nil); err != nil {
+			return 0, err
+		}
+	}
+	deleted := 0
+	for _, root := range drop {
+		select {
+		case <-abort:
+			return deleted, errPruneAborted
+		default:
+		}
+		garbage := make(map[common.Hash]struct{})
+		err := walkState(db, root, func(hash common.Hash) bool {
+			if _, ok := live[hash]; ok {
+				return false
+			}
+			if _, ok := garbage[hash]; ok {
+				return false
+			}
+			// Nodes deleted with an earlier root were swept entirely
+			if !hasEntry(db, hash) {
+				return false
+			}
+			garbage[hash] = struct{}{}
+			return true
+		})
+		if err != nil {
+			return deleted, err
+		}
+		n, err := sweep(db, tracker, live, garbage)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// sweep deletes the garbage nodes while holding off state commits. The nodes of
+// the states committed since the last sweep are rescued from the garbage first,
+// as commits may have written some of them again.
+func sweep(db pbfdb.Database, tracker *pruneTracker, live, garbage map[common.Hash]struct{}) (int, error) {
+	tracker.commitLock.Lock()
+	defer tracker.commitLock.Unlock()
+
+	for _, root := range tracker.takeCommits() {
+		if err := markPresent(db, root, live, garbage); err != nil {
+			return 0, err
+		}
+	}
+	batch := db.NewBatch()
+	for hash := range garbage {
+		if err := batch.Delete(hash[:]); err != nil {
+			return 0, err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	return len(garbage), nil
+}
+
+// markPresent marks the nodes of the given state which are present in the
+// database as live, removing them from garbage.
+func markPresent(db pbfdb.Database, root common.Hash, live, garbage map[common.Hash]struct{}) error {
+	return walkState(db, root, func(hash common.Hash) bool {
+		if _, ok := live[hash]; ok {
+			return false
+		}
+		if _, ok := garbage[hash]; !ok && !hasEntry(db, hash) {
+			return false
+		}
+		live[hash] = struct{}{}
+		delete(garbage, hash)
+		return true
+	})
+}
+
+// walkState calls visit for the hash of every node of the account trie with
+// the given root, the nodes of all storage tries and the contract codes.
+// Subtrees for which visit returns false are skipped.
+func walkState(db pbfdb.Database, root common.Hash, visit func(common.Hash) bool) error {
+	tr, err := trie.New(root, db)
+	if err != nil {
+		if !visit(root) {
+			return nil // root skipped, its absence doesn't matter
+		}
+		return &trie.MissingNodeError{Hash: root}
+	}
+	return tr.Walk(visit, func(leaf []byte) error {
+		var obj struct {
+			Nonce    uint64
+			Balance  *big.Int
+			Root     common.Hash
+			CodeHash []byte
+		}
+		if err := rlp.Decode(bytes.NewReader(leaf), &obj); err != nil {
+			return err
+		}
+		storage, err := trie.New(obj.Root, db)
+		if err != nil {
+			if visit(obj.Root) {
+				return &trie.MissingNodeError{Hash: obj.Root}
+			}
+		} else if err := storage.Walk(visit, func([]byte) error { return nil }); err != nil {
+			return err
+		}
+		visit(common.BytesToHash(obj.CodeHash))
+		return nil
+	})
+}
+
+// hasEntry reports whpbfer the database contains the given key.
+func hasEntry(db pbfdb.Database, hash common.Hash) bool {
+	data, err := db.Get(hash[:])
+	return err == nil && data != nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+	"github.com/pbfcoin/go-pbfcoin/trie"
+)
+
+// Tests that pruning an old state deletes its unique nodes, but keeps those
+// shared with the retained state intact.
+func TestPrune(t *testing.T) {
+	db, oldRoot, accounts := makeTestState()
+
+	// Modify a few accounts, sharing most of the trie with the old state
+	state, _ := New(oldRoot, db)
+	for i := 0; i < len(accounts); i += 10 {
+		obj := state.GetOrNewStateObject(accounts[i].address)
+		obj.AddBalance(big.NewInt(1))
+		accounts[i].balance = new(big.Int).Add(accounts[i].balance, big.NewInt(1))
+		state.UpdateStateObject(obj)
+	}
+	newRoot, err := state.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit modified state: %v", err)
+	}
+	// Don't let the recently committed roots retain the old state
+	tracker := trackerOf(db)
+	tracker.lock.Lock()
+	tracker.recent = [recentRootsLimit]common.Hash{}
+	tracker.lock.Unlock()
+
+	deleted, err := Prune(db, []common.Hash{newRoot}, []common.Hash{oldRoot}, nil)
+	if err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	if deleted == 0 {
+		t.Fatalf("no state entries deleted")
+	}
+	checkStateAccounts(t, db, newRoot, accounts)
+	if err := checkStateComplete(db, newRoot); err != nil {
+		t.Fatalf("retained state inconsistent: %v", err)
+	}
+	if _, err := New(oldRoot, db); err == nil {
+		t.Fatalf("pruned state root still present")
+	}
+	// Pruning the same state again must not delete anything else
+	if deleted, err := Prune(db, []common.Hash{newRoot}, []common.Hash{oldRoot}, nil); err != nil || deleted != 0 {
+		t.Fatalf("repeated pruning: deleted %d entries, error %v", deleted, err)
+	}
+}
+
+// Tests that pruning aborts without deleting anything if a retained state is
+// incomplete.
+func TestPruneMissingRetained(t *testing.T) {
+	db, root, _ := makeTestState()
+
+	missing := common.HexToHash("0x01")
+	_, err := Prune(db, []common.Hash{missing}, []common.Hash{root}, nil)
+	if _, ok := err.(*trie.MissingNodeError); !ok {
+		t.Fatalf("error mismatch: have %v, want missing node error", err)
+	}
+	if err := checkStateComplete(db, root); err != nil {
+		t.Fatalf("dropped state modified: %v", err)
+	}
+}
+
+// checkStateComplete verifies that all trie nodes of a state are present.
+func checkStateComplete(db pbfdb.Database, root common.Hash) error {
+	return walkState(db, root, func(common.Hash) bool { return true })
+}
@@ -347,7 +347,14 @@ func (s *StateDB) IntermediateRoot() common.Hash {
 
 // Commit commits all state changes to the database.
 func (s *StateDB) Commit() (root common.Hash, err error) {
-	return s.commit(s.db)
+	tracker := trackerOf(s.db)
+	tracker.commitLock.RLock()
+	defer tracker.commitLock.RUnlock()
+
+	if root, err = s.commit(s.db); err == nil {
+		tracker.add(root)
+	}
+	return root, err
 }
 
 // CommitBatch commits all state changes to a write batch but does not
@@ -9,6 +9,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/crypto"
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/params"
 )
 
 var (
@@ -25,8 +26,9 @@ func NewStateProcessor(bc *BlockChain) *StateProcessor {
 }
 
 // Process processes the state changes according to the pbfcoin rules by running
-// the transaction messages using the statedb and applying any rewards to both
-// the processor (coinbase) and any included uncles.
+// the transaction messages using the statedb and finalizing the block with the
+// chain's consensus engine (e.g. applying the rewards to both the processor
+// (coinbase) and any included uncles).
 //
 // Process returns the receipts and logs accumulated during the process and
 // returns the amount of gas that was used in the process. If any of the
@@ -51,8 +53,9 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB) (ty
 		receipts = append(receipts, receipt)
 		allLogs = append(allLogs, logs...)
 	}
-	AccumulateRewards(statedb, header, block.Uncles())
-
+	if err := p.bc.Engine().Finalize(p.bc, statedb, header, block.Uncles()); err != nil {
+		return nil, nil, totalUsedGas, err
+	}
 	return receipts, allLogs, totalUsedGas, err
 }
 
@@ -88,19 +91,21 @@ func ApplyTransaction(bc *BlockChain, gp *GasPool, statedb *state.StateDB, heade
 
 // AccumulateRewards credits the coinbase of the given block with the
 // mining reward. The total reward consists of the static block reward
-// and rewards for included uncles. The coinbase of each uncle block is
-// also rewarded.
-func AccumulateRewards(statedb *state.StateDB, header *types.Header, uncles []*types.Header) {
-	reward := new(big.Int).Set(BlockReward)
+// of the chain config and rewards for included uncles. The coinbase of
+// each uncle block is also rewarded.
+func AccumulateRewards(config *params.ChainConfig, statedb *state.StateDB, header *types.Header, uncles []*types.Header) {
+	blockReward := config.Rules(header.Number).BlockReward
+
+	reward := new(big.Int).Set(blockReward)
 	r := new(big.Int)
 	for _, uncle := range uncles {
 		r.Add(uncle.Number, big8)
 		r.Sub(r, header.Number)
-		r.Mul(r, BlockReward)
+		r.Mul(r, blockReward)
 		r.Div(r, big8)
 		statedb.AddBalance(uncle.Coinbase, r)
 
-		r.Div(BlockReward, big32)
+		r.Div(blockReward, big32)
 		reward.Add(reward, r)
 	}
 	statedb.AddBalance(header.Coinbase, reward)
@@ -0,0 +1,118 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/state"
+	"github.com/pbfcoin/go-pbfcoin/logger"
+	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+)
+
+// MinPruneRetain is the minimum number of recent states kept by pruning. The
+// miner and the block processor work on states derived from the recent heads,
+// which must not disappear beneath them.
+const MinPruneRetain = 16
+
+// PruneConfig are the options of the state pruning.
+type PruneConfig struct {
+	Retain     uint64 // number of recent canonical blocks whose state is kept
+	Checkpoint uint64 // the state of every block divisible by this is kept forever, 0 keeps only genesis
+}
+
+// isCheckpoint reports whpbfer the state of the given block is never pruned.
+func (cfg *PruneConfig) isCheckpoint(number uint64) bool {
+	return number == 0 || (cfg.Checkpoint > 0 && number%cfg.Checkpoint == 0)
+}
+
+// PruneState deletes the states of the canonical blocks which fell out of the
+// retention window since the last run, except for the checkpoint states. The
+// states of side chain blocks are left untouched. It returns the number of
+// deleted database entries. Closing abort stops pruning early, the remaining
+// states are pruned by the next run.
+//
+// Blocks whose header or state isn't available are skipped, as checkpoint synced
+// nodes lack the blocks below the checkpoint and fast synced nodes the states
+// below the pivot block.
+func PruneState(db pbfdb.Database, head uint64, cfg *PruneConfig, abort <-chan struct{}) (int, error) {
+	if cfg.Retain < MinPruneRetain {
+		return 0, fmt.Errorf("at least %d states must be retained", MinPruneRetain)
+	}
+	if head <= cfg.Retain {
+		return 0, nil
+	}
+	var (
+		first = GetLastPrunedNumber(db) + 1
+		last  = head - cfg.Retain
+	)
+	if first > last {
+		return 0, nil
+	}
+	stateRoot := func(number uint64) (common.Hash, bool) {
+		header := Gpbfeader(db, GetCanonicalHash(db, number))
+		if header == nil {
+			return common.Hash{}, false
+		}
+		if _, err := state.New(header.Root, db); err != nil {
+			return common.Hash{}, false
+		}
+		return header.Root, true
+	}
+	// Start at the oldest state which actually exists, there's nothing to
+	// prune if none of the blocks up to last has a state
+	for ; first <= last; first++ {
+		if _, ok := stateRoot(first); ok {
+			break
+		}
+	}
+	if first > last {
+		return 0, WriteLastPrunedNumber(db, last)
+	}
+	// Collect the roots to keep and the ones to drop
+	var retain, drop []common.Hash
+	for number := last + 1; number <= head; number++ {
+		if root, ok := stateRoot(number); ok {
+			retain = append(retain, root)
+		}
+	}
+	for number := uint64(0); number <= last; number += cfg.Checkpoint {
+		if root, ok := stateRoot(number); ok {
+			retain = append(retain, root)
+		}
+		if cfg.Checkpoint == 0 {
+			break
+		}
+	}
+	for number := first; number <= last; number++ {
+		if cfg.isCheckpoint(number) {
+			continue
+		}
+		if root, ok := stateRoot(number); ok {
+			drop = append(drop, root)
+		}
+	}
+	glog.V(logger.Info).Infof("pruning state of blocks #%d-#%d, keeping %d states", first, last, len(retain))
+
+	deleted, err := state.Prune(db, retain, drop, abort)
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, WriteLastPrunedNumber(db, last)
+}
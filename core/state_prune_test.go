@@ -0,0 +1,103 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/state"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/event"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+)
+
+// Tests that pruning works on nodes which don't have the states of the blocks
+// below the fast sync pivot, nor the blocks below a sync checkpoint.
+func TestPruneStateFastSync(t *testing.T)       { testPruneStateSynced(t, 0) }
+func TestPruneStateCheckpointSync(t *testing.T) { testPruneStateSynced(t, 12) }
+
+func testPruneStateSynced(t *testing.T, checkpoint int) {
+	const (
+		blocks = 64
+		pivot  = 20
+	)
+	var (
+		db, _   = pbfdb.NewMemDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = WriteGenesisBlockForTesting(db, GenesisAccount{address, big.NewInt(1000000000)})
+	)
+	// Generate the chain into the database, which keeps the state of every block
+	chain, _ := GenerateChain(genesis, db, blocks, func(i int, block *BlockGen) {
+		block.SetCoinbase(common.Address{0x01})
+	})
+	headers := make([]*types.Header, len(chain))
+	for i, block := range chain {
+		headers[i] = block.Header()
+	}
+	bc, err := NewBlockChain(db, NewPowEngine(FakePow{}), new(event.TypeMux))
+	if err != nil {
+		t.Fatalf("failed to create block chain: %v", err)
+	}
+	if checkpoint == 0 {
+		if n, err := bc.InsertHeaderChain(headers, 1); err != nil {
+			t.Fatalf("failed to insert header %d: %v", n, err)
+		}
+	} else {
+		td := new(big.Int).Set(genesis.Difficulty())
+		for _, header := range headers[:checkpoint] {
+			td.Add(td, header.Difficulty)
+		}
+		if err := bc.InsertCheckpoint(headers[checkpoint-1], td); err != nil {
+			t.Fatalf("failed to insert checkpoint: %v", err)
+		}
+		if n, err := bc.InsertHeaderChain(headers[checkpoint:], 1); err != nil {
+			t.Fatalf("failed to insert header %d: %v", checkpoint+n, err)
+		}
+	}
+	// Drop the states the node never downloaded
+	for _, header := range headers[:pivot-1] {
+		db.Delete(header.Root[:])
+	}
+	cfg := &PruneConfig{Retain: MinPruneRetain, Checkpoint: 8}
+	if _, err := PruneState(db, blocks, cfg, nil); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	last := uint64(blocks - MinPruneRetain)
+	if number := GetLastPrunedNumber(db); number != last {
+		t.Fatalf("last pruned number mismatch: have %d, want %d", number, last)
+	}
+	for _, header := range headers[pivot-1:] {
+		number := header.Number.Uint64()
+		_, err := state.New(header.Root, db)
+		switch {
+		case number > last || cfg.isCheckpoint(number):
+			if err != nil {
+				t.Errorf("retained state of block #%d missing: %v", number, err)
+			}
+		case err == nil:
+			t.Errorf("state of block #%d not pruned", number)
+		}
+	}
+	// Pruning again must neither fail nor delete anything
+	if deleted, err := PruneState(db, blocks, cfg, nil); err != nil || deleted != 0 {
+		t.Fatalf("repeated pruning: deleted %d entries, error %v", deleted, err)
+	}
+}
@@ -74,9 +74,9 @@ func MessageCreatesContract(msg Message) bool {
 }
 
 // IntrinsicGas computes the 'intrisic gas' for a message
-// with the given data.
-func IntrinsicGas(data []byte) *big.Int {
-	igas := new(big.Int).Set(params.TxGas)
+// with the given data under the given rules.
+func IntrinsicGas(rules *params.Rules, data []byte) *big.Int {
+	igas := new(big.Int).Set(rules.TxGas)
 	if len(data) > 0 {
 		var nz int64
 		for _, byt := range data {
@@ -85,10 +85,10 @@ func IntrinsicGas(data []byte) *big.Int {
 			}
 		}
 		m := big.NewInt(nz)
-		m.Mul(m, params.TxDataNonZeroGas)
+		m.Mul(m, rules.TxDataNonZeroGas)
 		igas.Add(igas, m)
 		m.SetInt64(int64(len(data)) - nz)
-		m.Mul(m, params.TxDataZeroGas)
+		m.Mul(m, rules.TxDataZeroGas)
 		igas.Add(igas, m)
 	}
 	return igas
@@ -200,7 +200,7 @@ func (self *StateTransition) transitionDb() (ret []byte, usedGas *big.Int, err e
 	sender, _ := self.from() // err checked in preCheck
 
 	// Pay intrinsic gas
-	if err = self.useGas(IntrinsicGas(self.data)); err != nil {
+	if err = self.useGas(IntrinsicGas(self.env.Rules(), self.data)); err != nil {
 		return nil, nil, InvalidTxError(err)
 	}
 
@@ -210,7 +210,7 @@ func (self *StateTransition) transitionDb() (ret []byte, usedGas *big.Int, err e
 		ret, addr, err = vmenv.Create(sender, self.data, self.gas, self.gasPrice, self.value)
 		if err == nil {
 			dataGas := big.NewInt(int64(len(ret)))
-			dataGas.Mul(dataGas, params.CreateDataGas)
+			dataGas.Mul(dataGas, vmenv.Rules().CreateDataGas)
 			if err := self.useGas(dataGas); err == nil {
 				self.state.SetCode(addr, ret)
 			} else {
@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
 	"github.com/pbfcoin/go-pbfcoin/core/state"
@@ -29,6 +30,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/event"
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/params"
 )
 
 var (
@@ -42,12 +44,35 @@ var (
 	ErrIntrinsicGas       = errors.New("Intrinsic gas too low")
 	ErrGasLimit           = errors.New("Exceeds block gas limit")
 	ErrNegativeValue      = errors.New("Negative value")
+	ErrReplaceUnderpriced = errors.New("Replacement transaction underpriced")
 )
 
 const (
 	maxQueued = 64 // max limit of queued txs per address
 )
 
+// TxPoolConfig are the configuration parameters of the transaction pool.
+type TxPoolConfig struct {
+	PriceBump    uint64 // Minimum price bump percentage to replace a transaction with the same nonce
+	GlobalSlots  uint64 // Maximum number of processable transactions of all accounts
+	GlobalQueue  uint64 // Maximum number of future transactions of all accounts
+	AccountSlots uint64 // Number of processable transactions per account never evicted
+
+	Journal   string        // Journal of local transactions to survive node restarts, empty disables
+	Rejournal time.Duration // Time interval to regenerate the local transaction journal
+}
+
+// DefaultTxPoolConfig contains the default transaction pool configuration.
+var DefaultTxPoolConfig = TxPoolConfig{
+	PriceBump:    10,
+	GlobalSlots:  4096,
+	GlobalQueue:  1024,
+	AccountSlots: 16,
+
+	Journal:   "transactions.rlp",
+	Rejournal: time.Hour,
+}
+
 type stateFn func() (*state.StateDB, error)
 
 // TxPool contains all currently known transactions. Transactions
@@ -58,10 +83,12 @@ type stateFn func() (*state.StateDB, error)
 // current state) and future transactions. Transactions move between those
 // two states over time as they are received and processed.
 type TxPool struct {
+	config       TxPoolConfig
 	quit         chan bool // Quiting channel
 	currentState stateFn   // The state function which will allow us to do some pre checkes
 	pendingState *state.ManagedState
-	gasLimit     func() *big.Int // The current gas limit function callback
+	gasLimit     func() *big.Int      // The current gas limit function callback
+	rules        func() *params.Rules // The rules of the pending block function callback
 	minGasPrice  *big.Int
 	eventMux     *event.TypeMux
 	events       event.Subscription
@@ -69,20 +96,47 @@ type TxPool struct {
 	mu      sync.RWMutex
 	pending map[common.Hash]*types.Transaction // processable transactions
 	queue   map[common.Address]map[common.Hash]*types.Transaction
+	locals  map[common.Address]struct{} // accounts of locally submitted transactions
+	journal *txJournal                  // journal of local transactions to back up to disk
+
+	wg sync.WaitGroup // for shutdown sync
 }
 
-func NewTxPool(eventMux *event.TypeMux, currentStateFn stateFn, gasLimitFn func() *big.Int) *TxPool {
+func NewTxPool(config TxPoolConfig, eventMux *event.TypeMux, currentStateFn stateFn, gasLimitFn func() *big.Int, rulesFn func() *params.Rules) *TxPool {
+	if config.GlobalSlots == 0 || config.GlobalQueue == 0 {
+		glog.V(logger.Warn).Infof("invalid txpool slot limits %d/%d, using defaults", config.GlobalSlots, config.GlobalQueue)
+		config.GlobalSlots, config.GlobalQueue = DefaultTxPoolConfig.GlobalSlots, DefaultTxPoolConfig.GlobalQueue
+	}
 	pool := &TxPool{
+		config:       config,
 		pending:      make(map[common.Hash]*types.Transaction),
 		queue:        make(map[common.Address]map[common.Hash]*types.Transaction),
+		locals:       make(map[common.Address]struct{}),
 		quit:         make(chan bool),
 		eventMux:     eventMux,
 		currentState: currentStateFn,
 		gasLimit:     gasLimitFn,
+		rules:        rulesFn,
 		minGasPrice:  new(big.Int),
 		pendingState: nil,
 		events:       eventMux.Subscribe(ChainHeadEvent{}, GasPriceChanged{}, RemovedTransactionEvent{}),
 	}
+	// Restore the local transactions from the journal and start rotating it
+	if config.Journal != "" {
+		if pool.config.Rejournal <= 0 {
+			pool.config.Rejournal = DefaultTxPoolConfig.Rejournal
+		}
+		pool.journal = newTxJournal(config.Journal)
+		if err := pool.journal.load(pool.addLocal); err != nil {
+			glog.V(logger.Warn).Infof("failed to load transaction journal: %v", err)
+		}
+		pool.checkQueue()
+		if err := pool.journal.rotate(pool.localTransactions()); err != nil {
+			glog.V(logger.Warn).Infof("failed to rotate transaction journal: %v", err)
+		}
+		pool.wg.Add(1)
+		go pool.journalLoop()
+	}
 	go pool.eventLoop()
 
 	return pool
@@ -108,6 +162,28 @@ func (pool *TxPool) eventLoop() {
 	}
 }
 
+// journalLoop periodically regenerates the local transaction journal, dropping
+// the transactions which were included or became invalid in the meantime.
+func (pool *TxPool) journalLoop() {
+	defer pool.wg.Done()
+
+	ticker := time.NewTicker(pool.config.Rejournal)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pool.mu.Lock()
+			if err := pool.journal.rotate(pool.localTransactions()); err != nil {
+				glog.V(logger.Warn).Infof("failed to rotate transaction journal: %v", err)
+			}
+			pool.mu.Unlock()
+		case <-pool.quit:
+			return
+		}
+	}
+}
+
 func (pool *TxPool) resetState() {
 	currentState, err := pool.currentState()
 	if err != nil {
@@ -146,6 +222,13 @@ func (pool *TxPool) resetState() {
 func (pool *TxPool) Stop() {
 	close(pool.quit)
 	pool.events.Unsubscribe()
+	pool.wg.Wait()
+
+	if pool.journal != nil {
+		pool.mu.Lock()
+		pool.journal.close()
+		pool.mu.Unlock()
+	}
 	glog.V(logger.Info).Infoln("Transaction pool stopped")
 }
 
@@ -222,7 +305,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction) error {
 	}
 
 	// Should supply enough intrinsic gas
-	if tx.Gas().Cmp(IntrinsicGas(tx.Data())) < 0 {
+	if tx.Gas().Cmp(IntrinsicGas(pool.rules(), tx.Data())) < 0 {
 		return ErrIntrinsicGas
 	}
 
@@ -240,6 +323,25 @@ func (self *TxPool) add(tx *types.Transaction) error {
 	if err != nil {
 		return err
 	}
+	// Replace a known transaction with the same nonce if the price is bumped enough
+	from, _ := tx.From() // already validated
+	if oldHash, old := self.findNonce(from, tx.Nonce()); old != nil {
+		threshold := new(big.Int).Mul(old.GasPrice(), big.NewInt(int64(100+self.config.PriceBump)))
+		threshold.Div(threshold, big.NewInt(100))
+		if tx.GasPrice().Cmp(old.GasPrice()) <= 0 || tx.GasPrice().Cmp(threshold) < 0 {
+			return ErrReplaceUnderpriced
+		}
+		if glog.V(logger.Debug) {
+			glog.Infof("replacing tx %x with %x (nonce %d)\n", oldHash[:4], hash[:4], tx.Nonce())
+		}
+		if _, ok := self.pending[oldHash]; ok {
+			delete(self.pending, oldHash)
+			self.pending[hash] = tx
+			go self.eventMux.Post(TxPreEvent{tx})
+			return nil
+		}
+		delete(self.queue[from], oldHash)
+	}
 	self.queueTx(hash, tx)
 
 	if glog.V(logger.Debug) {
@@ -259,6 +361,25 @@ func (self *TxPool) add(tx *types.Transaction) error {
 	return nil
 }
 
+// findNonce returns the pending or queued transaction of an account with the
+// given nonce, if any.
+func (self *TxPool) findNonce(from common.Address, nonce uint64) (common.Hash, *types.Transaction) {
+	for hash, tx := range self.queue[from] {
+		if tx.Nonce() == nonce {
+			return hash, tx
+		}
+	}
+	for hash, tx := range self.pending {
+		if tx.Nonce() != nonce {
+			continue
+		}
+		if sender, _ := tx.From(); sender == from {
+			return hash, tx
+		}
+	}
+	return common.Hash{}, nil
+}
+
 // queueTx will queue an unknown transaction
 func (self *TxPool) queueTx(hash common.Hash, tx *types.Transaction) {
 	from, _ := tx.From() // already validated
@@ -300,6 +421,64 @@ func (self *TxPool) Add(tx *types.Transaction) error {
 	return nil
 }
 
+// AddLocal queues a single locally created transaction in the pool if it is
+// valid. Its sender is marked local, exempting the account from price based
+// eviction, and the transaction is journaled to survive node restarts.
+func (self *TxPool) AddLocal(tx *types.Transaction) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if err := self.addLocal(tx); err != nil {
+		return err
+	}
+	self.checkQueue()
+	return nil
+}
+
+// addLocal validates and queues a local transaction and writes it to the
+// journal if one is active.
+func (self *TxPool) addLocal(tx *types.Transaction) error {
+	if err := self.add(tx); err != nil {
+		return err
+	}
+	from, _ := tx.From() // already validated
+	self.locals[from] = struct{}{}
+
+	if self.journal != nil {
+		if err := self.journal.insert(tx); err != nil && err != errNoActiveJournal {
+			glog.V(logger.Warn).Infof("failed to journal local transaction: %v", err)
+		}
+	}
+	return nil
+}
+
+// localTransactions returns all pending and queued transactions of the local
+// accounts, ordered by nonce.
+func (self *TxPool) localTransactions() types.Transactions {
+	var txs types.Transactions
+	for _, tx := range self.pending {
+		if from, _ := tx.From(); self.isLocal(from) {
+			txs = append(txs, tx)
+		}
+	}
+	for addr, queued := range self.queue {
+		if !self.isLocal(addr) {
+			continue
+		}
+		for _, tx := range queued {
+			txs = append(txs, tx)
+		}
+	}
+	sort.Sort(types.TxByNonce{txs})
+	return txs
+}
+
+// isLocal reports whpbfer the account submitted transactions locally.
+func (self *TxPool) isLocal(addr common.Address) bool {
+	_, ok := self.locals[addr]
+	return ok
+}
+
 // AddTransactions attempts to queue all valid transactions in txs.
 func (self *TxPool) AddTransactions(txs []*types.Transaction) {
 	self.mu.Lock()
@@ -458,6 +637,87 @@ func (pool *TxPool) checkQueue() {
 			delete(pool.queue, address)
 		}
 	}
+	pool.enforceLimits()
+}
+
+// enforceLimits evicts transactions until the pool fits into the global slot
+// limits. Only the transaction with the highest nonce of an account is ever
+// evicted, so no nonce gaps are introduced, and among these the cheapest one
+// goes first. Processable transactions are only evicted from accounts which
+// exceed their guaranteed number of slots. Local accounts are never evicted.
+func (pool *TxPool) enforceLimits() {
+	if uint64(len(pool.pending)) > pool.config.GlobalSlots {
+		spammers := make(map[common.Address]txQueue)
+		for hash, tx := range pool.pending {
+			if addr, _ := tx.From(); !pool.isLocal(addr) {
+				spammers[addr] = append(spammers[addr], txQueueEntry{hash, addr, tx})
+			}
+		}
+		for _, txs := range spammers {
+			sort.Sort(txs)
+		}
+		for uint64(len(pool.pending)) > pool.config.GlobalSlots {
+			victim := cheapestTail(spammers, pool.config.AccountSlots)
+			if victim == nil {
+				break
+			}
+			if glog.V(logger.Debug) {
+				glog.Infof("Pending tx limit exceeded. Tx %x of %x evicted\n", victim.hash[:4], victim.addr[:4])
+			}
+			delete(pool.pending, victim.hash)
+			pool.pendingState.SetNonce(victim.addr, victim.Nonce())
+		}
+	}
+	queued := 0
+	for _, txs := range pool.queue {
+		queued += len(txs)
+	}
+	if uint64(queued) > pool.config.GlobalQueue {
+		accounts := make(map[common.Address]txQueue)
+		for addr, txs := range pool.queue {
+			if pool.isLocal(addr) {
+				continue
+			}
+			for hash, tx := range txs {
+				accounts[addr] = append(accounts[addr], txQueueEntry{hash, addr, tx})
+			}
+			sort.Sort(accounts[addr])
+		}
+		for ; uint64(queued) > pool.config.GlobalQueue; queued-- {
+			victim := cheapestTail(accounts, 0)
+			if victim == nil {
+				break
+			}
+			if glog.V(logger.Debug) {
+				glog.Infof("Queued tx limit exceeded. Tx %x of %x evicted\n", victim.hash[:4], victim.addr[:4])
+			}
+			delete(pool.queue[victim.addr], victim.hash)
+			if len(pool.queue[victim.addr]) == 0 {
+				delete(pool.queue, victim.addr)
+			}
+		}
+	}
+}
+
+// cheapestTail removes and returns the cheapest of the highest nonce transactions
+// of the accounts holding more than keep transactions, or nil if there's none.
+// The transactions of every account must be sorted by nonce.
+func cheapestTail(accounts map[common.Address]txQueue, keep uint64) *txQueueEntry {
+	var victim *txQueueEntry
+	for addr, txs := range accounts {
+		if uint64(len(txs)) <= keep {
+			delete(accounts, addr)
+			continue
+		}
+		if tail := &txs[len(txs)-1]; victim == nil || tail.GasPrice().Cmp(victim.GasPrice()) < 0 {
+			victim = tail
+		}
+	}
+	if victim != nil {
+		txs := accounts[victim.addr]
+		accounts[victim.addr] = txs[:len(txs)-1]
+	}
+	return victim
 }
 
 // validatePool removes invalid and processed transactions from the main pool.
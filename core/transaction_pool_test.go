@@ -18,7 +18,10 @@ package core
 
 import (
 	"crypto/ecdsa"
+	"io/ioutil"
 	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
@@ -26,11 +29,16 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/crypto"
 	"github.com/pbfcoin/go-pbfcoin/event"
+	"github.com/pbfcoin/go-pbfcoin/params"
 	"github.com/pbfcoin/go-pbfcoin/pbfdb"
 )
 
 func transaction(nonce uint64, gaslimit *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
-	tx, _ := types.NewTransaction(nonce, common.Address{}, big.NewInt(100), gaslimit, big.NewInt(1), nil).SignECDSA(key)
+	return pricedTransaction(nonce, gaslimit, big.NewInt(1), key)
+}
+
+func pricedTransaction(nonce uint64, gaslimit, gasprice *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
+	tx, _ := types.NewTransaction(nonce, common.Address{}, big.NewInt(100), gaslimit, gasprice, nil).SignECDSA(key)
 	return tx
 }
 
@@ -40,7 +48,7 @@ func setupTxPool() (*TxPool, *ecdsa.PrivateKey) {
 
 	var m event.TypeMux
 	key, _ := crypto.GenerateKey()
-	newPool := NewTxPool(&m, func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) })
+	newPool := NewTxPool(DefaultTxPoolConfig, &m, func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) }, params.DefaultRules)
 	newPool.resetState()
 	return newPool, key
 }
@@ -204,13 +212,45 @@ func TestTransactionDoubleNonce(t *testing.T) {
 	if err := pool.add(tx); err != nil {
 		t.Error("didn't expect error", err)
 	}
-	if err := pool.add(tx2); err != nil {
-		t.Error("didn't expect error", err)
+	if err := pool.add(tx2); err != ErrReplaceUnderpriced {
+		t.Error("expected replacement underpriced error, got", err)
 	}
 
 	pool.checkQueue()
-	if len(pool.pending) != 2 {
-		t.Error("expected 2 pending txs. Got", len(pool.pending))
+	if len(pool.pending) != 1 {
+		t.Error("expected 1 pending txs. Got", len(pool.pending))
+	}
+	if pool.pending[tx.Hash()] == nil {
+		t.Error("original transaction replaced by equally priced one")
+	}
+}
+
+// Tests that a pending or queued transaction is replaced by one with the same
+// nonce only if the gas price is bumped by at least the configured percentage.
+func TestTransactionReplacement(t *testing.T) {
+	pool, key := setupTxPool()
+	account, _ := transaction(0, big.NewInt(0), key).From()
+
+	state, _ := pool.currentState()
+	state.AddBalance(account, big.NewInt(1000000000))
+
+	for _, nonce := range []uint64{0, 2} { // pending and queued
+		if err := pool.Add(pricedTransaction(nonce, big.NewInt(100000), big.NewInt(100), key)); err != nil {
+			t.Fatalf("nonce %d: failed to add original transaction: %v", nonce, err)
+		}
+		if err := pool.Add(pricedTransaction(nonce, big.NewInt(100000), big.NewInt(109), key)); err != ErrReplaceUnderpriced {
+			t.Fatalf("nonce %d: insufficient bump error mismatch: have %v, want %v", nonce, err, ErrReplaceUnderpriced)
+		}
+		replacement := pricedTransaction(nonce, big.NewInt(100000), big.NewInt(110), key)
+		if err := pool.Add(replacement); err != nil {
+			t.Fatalf("nonce %d: failed to replace transaction: %v", nonce, err)
+		}
+		if tx := pool.GetTransaction(replacement.Hash()); tx == nil {
+			t.Fatalf("nonce %d: replacement transaction not found", nonce)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("pool size mismatch: have %d/%d, want %d/%d", pending, queued, 1, 1)
 	}
 }
 
@@ -524,3 +564,154 @@ func benchmarkCheckQueue(b *testing.B, size int) {
 		pool.checkQueue()
 	}
 }
+
+// Tests that the global pending limit evicts the cheapest transactions of the
+// accounts exceeding their guaranteed slots, keeping the nonces gapless.
+func TestTransactionPendingGlobalLimiting(t *testing.T) {
+	pool, _ := setupTxPool()
+	pool.config.GlobalSlots = 8
+	pool.config.AccountSlots = 2
+
+	state, _ := pool.currentState()
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		state.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	// The first account pays the most, the last one the least
+	txs := types.Transactions{}
+	for i, key := range keys {
+		for nonce := uint64(0); nonce < 4; nonce++ {
+			txs = append(txs, pricedTransaction(nonce, big.NewInt(100000), big.NewInt(int64(10*(3-i))), key))
+		}
+	}
+	pool.AddTransactions(txs)
+
+	if len(pool.pending) != 8 {
+		t.Fatalf("pending pool size mismatch: have %d, want %d", len(pool.pending), 8)
+	}
+	want := []uint64{4, 2, 2}
+	for i, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		count := 0
+		for _, tx := range pool.pending {
+			if from, _ := tx.From(); from == addr {
+				count++
+			}
+		}
+		if count != int(want[i]) {
+			t.Errorf("account %d: pending count mismatch: have %d, want %d", i, count, want[i])
+		}
+		if nonce := pool.pendingState.GetNonce(addr); nonce != want[i] {
+			t.Errorf("account %d: pending nonce mismatch: have %d, want %d", i, nonce, want[i])
+		}
+	}
+}
+
+// Tests that the global queue limit evicts the cheapest future transactions.
+func TestTransactionQueueGlobalLimiting(t *testing.T) {
+	pool, _ := setupTxPool()
+	pool.config.GlobalQueue = 4
+
+	state, _ := pool.currentState()
+	cheap, _ := crypto.GenerateKey()
+	costly, _ := crypto.GenerateKey()
+	state.AddBalance(crypto.PubkeyToAddress(cheap.PublicKey), big.NewInt(1000000000))
+	state.AddBalance(crypto.PubkeyToAddress(costly.PublicKey), big.NewInt(1000000000))
+
+	for nonce := uint64(1); nonce <= 4; nonce++ {
+		if err := pool.Add(pricedTransaction(nonce, big.NewInt(100000), big.NewInt(1), cheap)); err != nil {
+			t.Fatalf("failed to add cheap transaction: %v", err)
+		}
+		if err := pool.Add(pricedTransaction(nonce, big.NewInt(100000), big.NewInt(2), costly)); err != nil {
+			t.Fatalf("failed to add costly transaction: %v", err)
+		}
+	}
+	if _, queued := pool.Stats(); queued != 4 {
+		t.Fatalf("queued pool size mismatch: have %d, want %d", queued, 4)
+	}
+	if n := len(pool.queue[crypto.PubkeyToAddress(costly.PublicKey)]); n != 4 {
+		t.Errorf("costly account queue size mismatch: have %d, want %d", n, 4)
+	}
+}
+
+// Tests that local transactions are journaled to disk and restored into a new
+// pool, dropping the ones which became invalid in the meantime.
+func TestTransactionJournaling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txjournal")
+	if err != nil {
+		t.Fatalf("failed to create temporary dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, _ := pbfdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, db)
+	stateFn := func() (*state.StateDB, error) { return statedb, nil }
+	gasLimitFn := func() *big.Int { return big.NewInt(1000000) }
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+	statedb.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	statedb.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	config := DefaultTxPoolConfig
+	config.Journal = filepath.Join(dir, "transactions.rlp")
+
+	pool := NewTxPool(config, new(event.TypeMux), stateFn, gasLimitFn, params.DefaultRules)
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		if err := pool.AddLocal(transaction(nonce, big.NewInt(100000), local)); err != nil {
+			t.Fatalf("failed to add local transaction: %v", err)
+		}
+	}
+	if err := pool.Add(transaction(0, big.NewInt(100000), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	pool.Stop()
+
+	// Restart the pool, only the local transactions must be restored
+	pool = NewTxPool(config, new(event.TypeMux), stateFn, gasLimitFn, params.DefaultRules)
+	if pending, queued := pool.Stats(); pending != 3 || queued != 0 {
+		t.Fatalf("restored pool size mismatch: have %d/%d, want %d/%d", pending, queued, 3, 0)
+	}
+	pool.Stop()
+
+	// Include the first local transaction, it must be dropped on restart
+	statedb.SetNonce(crypto.PubkeyToAddress(local.PublicKey), 1)
+	pool = NewTxPool(config, new(event.TypeMux), stateFn, gasLimitFn, params.DefaultRules)
+	if pending, queued := pool.Stats(); pending != 2 || queued != 0 {
+		t.Fatalf("restored pool size mismatch: have %d/%d, want %d/%d", pending, queued, 2, 0)
+	}
+	if txs := pool.localTransactions(); len(txs) != 2 {
+		t.Fatalf("rotated journal size mismatch: have %d, want %d", len(txs), 2)
+	}
+	pool.Stop()
+}
+
+// Tests that transactions of local accounts are exempt from eviction.
+func TestTransactionLocalEvictionExemption(t *testing.T) {
+	pool, local := setupTxPool()
+	pool.config.GlobalSlots = 2
+	pool.config.AccountSlots = 0
+
+	remote, _ := crypto.GenerateKey()
+	state, _ := pool.currentState()
+	state.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	state.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		if err := pool.AddLocal(pricedTransaction(nonce, big.NewInt(100000), big.NewInt(1), local)); err != nil {
+			t.Fatalf("failed to add local transaction: %v", err)
+		}
+	}
+	if err := pool.Add(pricedTransaction(0, big.NewInt(100000), big.NewInt(100), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	if len(pool.pending) != 3 {
+		t.Fatalf("pending pool size mismatch: have %d, want %d", len(pool.pending), 3)
+	}
+	for _, tx := range pool.pending {
+		if from, _ := tx.From(); from != crypto.PubkeyToAddress(local.PublicKey) {
+			t.Errorf("remote transaction %x not evicted", tx.Hash())
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+)
+
+// CallFrame is a single message call of an execution, the calls made by the
+// executed code are nested in Calls.
+type CallFrame struct {
+	Type    OpCode // CALL, CALLCODE, CREATE or SUICIDE
+	From    common.Address
+	To      common.Address // callee, code address of a CALLCODE or created contract
+	Value   *big.Int
+	Gas     *big.Int
+	GasUsed *big.Int
+	Input   []byte // call data or init code of a CREATE
+	Output  []byte // return data or deployed code of a CREATE
+	Err     error
+	Calls   []*CallFrame
+}
+
+// CallLogger is a CallTracer which reconstructs the tree of message calls of
+// an execution, including the internal transactions made by contracts.
+type CallLogger struct {
+	root  *CallFrame
+	stack []*CallFrame // frames entered but not yet returned
+}
+
+// NewCallLogger returns a new, empty call logger.
+func NewCallLogger() *CallLogger {
+	return new(CallLogger)
+}
+
+// CaptureState implements Tracer, the individual steps are ignored.
+func (l *CallLogger) CaptureState(env Environment, pc uint64, op OpCode, gas, cost *big.Int, memory *Memory, stack []*big.Int, contract *Contract, depth int, err error) {
+}
+
+// CaptureEnter implements CallTracer, opening a new frame below the running one.
+func (l *CallLogger) CaptureEnter(typ OpCode, from, to common.Address, input []byte, gas, value *big.Int) {
+	frame := &CallFrame{
+		Type:  typ,
+		From:  from,
+		To:    to,
+		Value: value,
+		Gas:   gas,
+		Input: common.CopyBytes(input),
+	}
+	if len(l.stack) == 0 {
+		l.root = frame
+	} else {
+		parent := l.stack[len(l.stack)-1]
+		parent.Calls = append(parent.Calls, frame)
+	}
+	l.stack = append(l.stack, frame)
+}
+
+// CaptureExit implements CallTracer, closing the running frame.
+func (l *CallLogger) CaptureExit(output []byte, gasLeft *big.Int, err error) {
+	if len(l.stack) == 0 {
+		return
+	}
+	frame := l.stack[len(l.stack)-1]
+	l.stack = l.stack[:len(l.stack)-1]
+
+	frame.Output = common.CopyBytes(output)
+	frame.GasUsed = new(big.Int).Sub(frame.Gas, gasLeft)
+	frame.Err = err
+}
+
+// Call returns the outermost call of the execution, nil if nothing was
+// executed.
+func (l *CallLogger) Call() *CallFrame {
+	return l.root
+}
@@ -72,7 +72,7 @@ func calcMemSize(off, l *big.Int) *big.Int {
 }
 
 // calculates the quadratic gas
-func quadMemGas(mem *Memory, newMemSize, gas *big.Int) {
+func quadMemGas(mem *Memory, newMemSize, gas *big.Int, rules *params.Rules) {
 	if newMemSize.Cmp(common.Big0) > 0 {
 		newMemSizeWords := toWordSize(newMemSize)
 		newMemSize.Mul(newMemSizeWords, u256(32))
@@ -82,13 +82,13 @@ func quadMemGas(mem *Memory, newMemSize, gas *big.Int) {
 			// The order has been optimised to reduce allocation
 			oldSize := toWordSize(big.NewInt(int64(mem.Len())))
 			pow := new(big.Int).Exp(oldSize, common.Big2, Zero)
-			linCoef := oldSize.Mul(oldSize, params.MemoryGas)
-			quadCoef := new(big.Int).Div(pow, params.QuadCoeffDiv)
+			linCoef := oldSize.Mul(oldSize, rules.MemoryGas)
+			quadCoef := new(big.Int).Div(pow, rules.QuadCoeffDiv)
 			oldTotalFee := new(big.Int).Add(linCoef, quadCoef)
 
 			pow.Exp(newMemSizeWords, common.Big2, Zero)
-			linCoef = linCoef.Mul(newMemSizeWords, params.MemoryGas)
-			quadCoef = quadCoef.Div(pow, params.QuadCoeffDiv)
+			linCoef = linCoef.Mul(newMemSizeWords, rules.MemoryGas)
+			quadCoef = quadCoef.Div(pow, rules.QuadCoeffDiv)
 			newTotalFee := linCoef.Add(linCoef, quadCoef)
 
 			fee := newTotalFee.Sub(newTotalFee, oldTotalFee)
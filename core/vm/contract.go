@@ -69,6 +69,16 @@ func NewContract(caller ContractRef, object ContractRef, value, gas, price *big.
 	return c
 }
 
+// Caller returns the address of the account which called the contract.
+func (c *Contract) Caller() common.Address {
+	return c.caller.Address()
+}
+
+// Value returns the value sent along with the call.
+func (c *Contract) Value() *big.Int {
+	return c.value
+}
+
 // GetOp returns the n'th element in the contract's byte array
 func (c *Contract) GetOp(n uint64) OpCode {
 	return OpCode(c.GetByte(n))
@@ -20,6 +20,7 @@ import (
 	"math/big"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/params"
 )
 
 // Environment is is required by the virtual machine to get information from
@@ -38,6 +39,8 @@ type Environment interface {
 	Origin() common.Address
 	// The block number this VM is invoken on
 	BlockNumber() *big.Int
+	// The protocol rules (gas table) in force at the block number
+	Rules() *params.Rules
 	// The n'th hash ago from this block number
 	Gpbfash(uint64) common.Hash
 	// The handler's address
@@ -58,6 +61,8 @@ type Environment interface {
 	AddStructLog(StructLog)
 	// Returns all coalesced structured logs
 	StructLogs() []StructLog
+	// Tracer capturing the execution steps, nil if the execution isn't traced
+	Tracer() Tracer
 
 	// Type of the VM
 	VmType() Type
@@ -109,6 +114,7 @@ type StructLog struct {
 	Memory  []byte
 	Stack   []*big.Int
 	Storage map[common.Hash][]byte
+	Depth   int
 	Err     error
 }
 
@@ -38,7 +38,7 @@ var (
 )
 
 // baseCheck checks for any stack error underflows
-func baseCheck(op OpCode, stack *stack, gas *big.Int) error {
+func baseCheck(op OpCode, stack *stack, gas *big.Int, rules *params.Rules) error {
 	// PUSH and DUP are a bit special. They all cost the same but we do want to have checking on stack push limit
 	// PUSH is also allowed to calculate the same price for all PUSHes
 	// DUP requirements are handled elsewhere (except for the stack limit check)
@@ -59,7 +59,29 @@ func baseCheck(op OpCode, stack *stack, gas *big.Int) error {
 			return fmt.Errorf("stack limit reached %d (%d)", stack.len(), params.StackLimit.Int64())
 		}
 
-		gas.Add(gas, r.gas)
+		if r.gas != nil {
+			gas.Add(gas, r.gas)
+		} else {
+			gas.Add(gas, ruleGas(op, rules))
+		}
+	}
+	return nil
+}
+
+// ruleGas returns the base gas of the opcodes priced by the chain rules, nil for
+// opcodes with a fixed base gas.
+func ruleGas(op OpCode, rules *params.Rules) *big.Int {
+	switch op {
+	case SLOAD:
+		return rules.SloadGas
+	case SHA3:
+		return rules.Sha3Gas
+	case CREATE:
+		return rules.CreateGas
+	case CALL, CALLCODE:
+		return rules.CallGas
+	case JUMPDEST:
+		return rules.JumpdestGas
 	}
 	return nil
 }
@@ -79,7 +101,7 @@ type req struct {
 }
 
 var _baseCheck = map[OpCode]req{
-	// opcode  |  stack pop | gas price | stack push
+	// opcode  |  stack pop | gas price (nil if set by the rules) | stack push
 	ADD:          {2, GasFastestStep, 1},
 	LT:           {2, GasFastestStep, 1},
 	GT:           {2, GasFastestStep, 1},
@@ -130,13 +152,13 @@ var _baseCheck = map[OpCode]req{
 	BALANCE:      {1, GasExtStep, 1},
 	EXTCODESIZE:  {1, GasExtStep, 1},
 	EXTCODECOPY:  {4, GasExtStep, 0},
-	SLOAD:        {1, params.SloadGas, 1},
+	SLOAD:        {1, nil, 1},
 	SSTORE:       {2, Zero, 0},
-	SHA3:         {2, params.Sha3Gas, 1},
-	CREATE:       {3, params.CreateGas, 1},
-	CALL:         {7, params.CallGas, 1},
-	CALLCODE:     {7, params.CallGas, 1},
-	JUMPDEST:     {0, params.JumpdestGas, 0},
+	SHA3:         {2, nil, 1},
+	CREATE:       {3, nil, 1},
+	CALL:         {7, nil, 1},
+	CALLCODE:     {7, nil, 1},
+	JUMPDEST:     {0, nil, 0},
 	SUICIDE:      {1, Zero, 0},
 	RETURN:       {2, Zero, 0},
 	PUSH1:        {0, GasFastestStep, 1},
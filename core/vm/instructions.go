@@ -22,7 +22,6 @@ import (
 
 	"github.com/pbfcoin/go-pbfcoin/common"
 	"github.com/pbfcoin/go-pbfcoin/crypto"
-	"github.com/pbfcoin/go-pbfcoin/params"
 )
 
 type programInstruction interface {
@@ -526,7 +525,7 @@ func opCreate(instr instruction, pc *uint64, env Environment, contract *Contract
 	} else {
 		// gas < len(ret) * Createinstr.dataGas == NO_CODE
 		dataGas := big.NewInt(int64(len(ret)))
-		dataGas.Mul(dataGas, params.CreateDataGas)
+		dataGas.Mul(dataGas, env.Rules().CreateDataGas)
 		if contract.UseGas(dataGas) {
 			env.Db().SetCode(addr, ret)
 		}
@@ -552,7 +551,7 @@ func opCall(instr instruction, pc *uint64, env Environment, contract *Contract,
 	args := memory.Get(inOffset.Int64(), inSize.Int64())
 
 	if len(value.Bytes()) > 0 {
-		gas.Add(gas, params.CallStipend)
+		gas.Add(gas, env.Rules().CallStipend)
 	}
 
 	ret, err := env.Call(contract, address, args, gas, contract.Price, value)
@@ -583,7 +582,7 @@ func opCallCode(instr instruction, pc *uint64, env Environment, contract *Contra
 	args := memory.Get(inOffset.Int64(), inSize.Int64())
 
 	if len(value.Bytes()) > 0 {
-		gas.Add(gas, params.CallStipend)
+		gas.Add(gas, env.Rules().CallStipend)
 	}
 
 	ret, err := env.CallCode(contract, address, args, gas, contract.Price, value)
@@ -605,7 +604,12 @@ func opStop(instr instruction, pc *uint64, env Environment, contract *Contract,
 
 func opSuicide(instr instruction, pc *uint64, env Environment, contract *Contract, memory *Memory, stack *stack) {
 	balance := env.Db().GetBalance(contract.Address())
-	env.Db().AddBalance(common.BigToAddress(stack.pop()), balance)
+	receiver := common.BigToAddress(stack.pop())
+	if tracer, ok := env.Tracer().(CallTracer); ok {
+		tracer.CaptureEnter(SUICIDE, contract.Address(), receiver, nil, new(big.Int), new(big.Int).Set(balance))
+		tracer.CaptureExit(nil, new(big.Int), nil)
+	}
+	env.Db().AddBalance(receiver, balance)
 
 	env.Db().Delete(contract.Address())
 }
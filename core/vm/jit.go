@@ -355,8 +355,9 @@ func jitCalculateGasAndSize(env Environment, contract *Contract, instr instructi
 	var (
 		gas                 = new(big.Int)
 		newMemSize *big.Int = new(big.Int)
+		rules               = env.Rules()
 	)
-	err := jitBaseCheck(instr, stack, gas)
+	err := jitBaseCheck(instr, stack, gas, rules)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -387,13 +388,13 @@ func jitCalculateGasAndSize(env Environment, contract *Contract, instr instructi
 		mSize, mStart := stack.data[stack.len()-2], stack.data[stack.len()-1]
 
 		add := new(big.Int)
-		gas.Add(gas, params.LogGas)
-		gas.Add(gas, add.Mul(big.NewInt(int64(n)), params.LogTopicGas))
-		gas.Add(gas, add.Mul(mSize, params.LogDataGas))
+		gas.Add(gas, rules.LogGas)
+		gas.Add(gas, add.Mul(big.NewInt(int64(n)), rules.LogTopicGas))
+		gas.Add(gas, add.Mul(mSize, rules.LogDataGas))
 
 		newMemSize = calcMemSize(mStart, mSize)
 	case EXP:
-		gas.Add(gas, new(big.Int).Mul(big.NewInt(int64(len(stack.data[stack.len()-2].Bytes()))), params.ExpByteGas))
+		gas.Add(gas, new(big.Int).Mul(big.NewInt(int64(len(stack.data[stack.len()-2].Bytes()))), rules.ExpByteGas))
 	case SSTORE:
 		err := stack.require(2)
 		if err != nil {
@@ -409,18 +410,18 @@ func jitCalculateGasAndSize(env Environment, contract *Contract, instr instructi
 		// 2. From a non-zero value address to a zero-value address (DELETE)
 		// 3. From a nen-zero to a non-zero                         (CHANGE)
 		if common.EmptyHash(val) && !common.EmptyHash(common.BigToHash(y)) {
-			g = params.SstoreSetGas
+			g = rules.SstoreSetGas
 		} else if !common.EmptyHash(val) && common.EmptyHash(common.BigToHash(y)) {
-			statedb.AddRefund(params.SstoreRefundGas)
+			statedb.AddRefund(rules.SstoreRefundGas)
 
-			g = params.SstoreClearGas
+			g = rules.SstoreClearGas
 		} else {
-			g = params.SstoreClearGas
+			g = rules.SstoreClearGas
 		}
 		gas.Set(g)
 	case SUICIDE:
 		if !statedb.IsDeleted(contract.Address()) {
-			statedb.AddRefund(params.SuicideRefundGas)
+			statedb.AddRefund(rules.SuicideRefundGas)
 		}
 	case MLOAD:
 		newMemSize = calcMemSize(stack.peek(), u256(32))
@@ -434,22 +435,22 @@ func jitCalculateGasAndSize(env Environment, contract *Contract, instr instructi
 		newMemSize = calcMemSize(stack.peek(), stack.data[stack.len()-2])
 
 		words := toWordSize(stack.data[stack.len()-2])
-		gas.Add(gas, words.Mul(words, params.Sha3WordGas))
+		gas.Add(gas, words.Mul(words, rules.Sha3WordGas))
 	case CALLDATACOPY:
 		newMemSize = calcMemSize(stack.peek(), stack.data[stack.len()-3])
 
 		words := toWordSize(stack.data[stack.len()-3])
-		gas.Add(gas, words.Mul(words, params.CopyGas))
+		gas.Add(gas, words.Mul(words, rules.CopyGas))
 	case CODECOPY:
 		newMemSize = calcMemSize(stack.peek(), stack.data[stack.len()-3])
 
 		words := toWordSize(stack.data[stack.len()-3])
-		gas.Add(gas, words.Mul(words, params.CopyGas))
+		gas.Add(gas, words.Mul(words, rules.CopyGas))
 	case EXTCODECOPY:
 		newMemSize = calcMemSize(stack.data[stack.len()-2], stack.data[stack.len()-4])
 
 		words := toWordSize(stack.data[stack.len()-4])
-		gas.Add(gas, words.Mul(words, params.CopyGas))
+		gas.Add(gas, words.Mul(words, rules.CopyGas))
 
 	case CREATE:
 		newMemSize = calcMemSize(stack.data[stack.len()-2], stack.data[stack.len()-3])
@@ -459,12 +460,12 @@ func jitCalculateGasAndSize(env Environment, contract *Contract, instr instructi
 		if op == CALL {
 			//if env.Db().GetStateObject(common.BigToAddress(stack.data[stack.len()-2])) == nil {
 			if !env.Db().Exist(common.BigToAddress(stack.data[stack.len()-2])) {
-				gas.Add(gas, params.CallNewAccountGas)
+				gas.Add(gas, rules.CallNewAccountGas)
 			}
 		}
 
 		if len(stack.data[stack.len()-3].Bytes()) > 0 {
-			gas.Add(gas, params.CallValueTransferGas)
+			gas.Add(gas, rules.CallValueTransferGas)
 		}
 
 		x := calcMemSize(stack.data[stack.len()-6], stack.data[stack.len()-7])
@@ -472,14 +473,15 @@ func jitCalculateGasAndSize(env Environment, contract *Contract, instr instructi
 
 		newMemSize = common.BigMax(x, y)
 	}
-	quadMemGas(mem, newMemSize, gas)
+	quadMemGas(mem, newMemSize, gas, rules)
 
 	return newMemSize, gas, nil
 }
 
 // jitBaseCheck is the same as baseCheck except it doesn't do the look up in the
-// gas table. This is done during compilation instead.
-func jitBaseCheck(instr instruction, stack *stack, gas *big.Int) error {
+// gas table. This is done during compilation instead, only the gas set by the
+// rules is looked up at run time.
+func jitBaseCheck(instr instruction, stack *stack, gas *big.Int, rules *params.Rules) error {
 	err := stack.require(instr.spop)
 	if err != nil {
 		return err
@@ -489,8 +491,11 @@ func jitBaseCheck(instr instruction, stack *stack, gas *big.Int) error {
 		return fmt.Errorf("stack limit reached %d (%d)", stack.len(), params.StackLimit.Int64())
 	}
 
-	// nil on gas means no base calculation
+	// nil on gas means the base gas is set by the rules, if at all
 	if instr.gas == nil {
+		if g := ruleGas(instr.op, rules); g != nil {
+			gas.Add(gas, g)
+		}
 		return nil
 	}
 
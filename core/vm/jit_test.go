@@ -22,6 +22,7 @@ import (
 
 	"github.com/pbfcoin/go-pbfcoin/common"
 	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/params"
 )
 
 const maxRun = 1000
@@ -170,11 +171,15 @@ func NewEnv() *Env {
 
 func (self *Env) Origin() common.Address { return common.Address{} }
 func (self *Env) BlockNumber() *big.Int  { return big.NewInt(0) }
+func (self *Env) Rules() *params.Rules   { return params.DefaultRules() }
 func (self *Env) AddStructLog(log StructLog) {
 }
 func (self *Env) StructLogs() []StructLog {
 	return nil
 }
+func (self *Env) Tracer() Tracer {
+	return nil
+}
 
 //func (self *Env) PrevHash() []byte      { return self.parent }
 func (self *Env) Coinbase() common.Address { return common.Address{} }
@@ -18,12 +18,104 @@ package vm
 
 import (
 	"fmt"
+	"math/big"
 	"os"
 	"unicode"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
 )
 
+// Tracer is used to collect execution traces of an EVM execution. CaptureState
+// is called for each step of the VM with the current VM state, before the
+// operation is executed. Memory, stack and contract are the actual VM data
+// structures, they have to be copied if they are retained beyond the call.
+type Tracer interface {
+	CaptureState(env Environment, pc uint64, op OpCode, gas, cost *big.Int, memory *Memory, stack []*big.Int, contract *Contract, depth int, err error)
+}
+
+// CallTracer is a Tracer which is additionally notified of the message calls
+// made during an execution. CaptureEnter is called when a CALL, CALLCODE or
+// CREATE starts executing and for every SUICIDE, CaptureExit when the call
+// returns with the remaining gas. Calls rejected before execution (depth limit,
+// insufficient balance) aren't captured.
+type CallTracer interface {
+	Tracer
+	CaptureEnter(typ OpCode, from, to common.Address, input []byte, gas, value *big.Int)
+	CaptureExit(output []byte, gasLeft *big.Int, err error)
+}
+
+// LogConfig are the configuration options of the structured logger.
+type LogConfig struct {
+	DisableMemory  bool // disable memory capture
+	DisableStack   bool // disable stack capture
+	DisableStorage bool // disable storage capture
+}
+
+// StructLogger is a Tracer collecting the execution steps as StructLogs. The
+// storage of each step lists the slots of the executing contract which were
+// written to so far.
+type StructLogger struct {
+	cfg LogConfig
+
+	logs          []StructLog
+	changedValues map[common.Address]map[common.Hash][]byte
+}
+
+// NewStructLogger returns a new logger, a nil config captures everything.
+func NewStructLogger(cfg *LogConfig) *StructLogger {
+	logger := &StructLogger{
+		changedValues: make(map[common.Address]map[common.Hash][]byte),
+	}
+	if cfg != nil {
+		logger.cfg = *cfg
+	}
+	return logger
+}
+
+// CaptureState implements Tracer, adding a copy of the VM state to the logs.
+func (l *StructLogger) CaptureState(env Environment, pc uint64, op OpCode, gas, cost *big.Int, memory *Memory, stack []*big.Int, contract *Contract, depth int, err error) {
+	// Track the storage writes of the contract, the value is written by this step
+	changed := l.changedValues[contract.Address()]
+	if changed == nil {
+		changed = make(map[common.Hash][]byte)
+		l.changedValues[contract.Address()] = changed
+	}
+	if op == SSTORE && len(stack) >= 2 {
+		key, value := common.BigToHash(stack[len(stack)-1]), common.BigToHash(stack[len(stack)-2])
+		changed[key] = value.Bytes()
+	}
+
+	var mem []byte
+	if !l.cfg.DisableMemory {
+		mem = make([]byte, len(memory.Data()))
+		copy(mem, memory.Data())
+	}
+	var stck []*big.Int
+	if !l.cfg.DisableStack {
+		stck = make([]*big.Int, len(stack))
+		for i, item := range stack {
+			stck[i] = new(big.Int).Set(item)
+		}
+	}
+	var storage map[common.Hash][]byte
+	if !l.cfg.DisableStorage {
+		storage = make(map[common.Hash][]byte, len(changed))
+		for key, value := range changed {
+			storage[key] = value
+		}
+	}
+	var gasCost *big.Int
+	if cost != nil {
+		gasCost = new(big.Int).Set(cost)
+	}
+	l.logs = append(l.logs, StructLog{pc, op, new(big.Int).Set(gas), gasCost, mem, stck, storage, depth, err})
+}
+
+// StructLogs returns the captured execution steps.
+func (l *StructLogger) StructLogs() []StructLog {
+	return l.logs
+}
+
 // StdErrFormat formats a slice of StructLogs to human readable format
 func StdErrFormat(logs []StructLog) {
 	fmt.Fprintf(os.Stderr, "VM STAT %d OPs\n", len(logs))
@@ -23,6 +23,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/core"
 	"github.com/pbfcoin/go-pbfcoin/core/state"
 	"github.com/pbfcoin/go-pbfcoin/core/vm"
+	"github.com/pbfcoin/go-pbfcoin/params"
 )
 
 // Env is a basic runtime environment required for running the EVM.
@@ -37,8 +38,10 @@ type Env struct {
 	time       *big.Int
 	difficulty *big.Int
 	gasLimit   *big.Int
+	rules      *params.Rules
 
-	logs []vm.StructLog
+	logs   []vm.StructLog
+	tracer vm.Tracer
 
 	gpbfashFn func(uint64) common.Hash
 }
@@ -53,6 +56,8 @@ func NewEnv(cfg *Config, state *state.StateDB) vm.Environment {
 		time:       cfg.Time,
 		difficulty: cfg.Difficulty,
 		gasLimit:   cfg.GasLimit,
+		rules:      cfg.ChainConfig.Rules(cfg.BlockNumber),
+		tracer:     cfg.Tracer,
 	}
 }
 
@@ -64,8 +69,13 @@ func (self *Env) AddStructLog(log vm.StructLog) {
 	self.logs = append(self.logs, log)
 }
 
+func (self *Env) Tracer() vm.Tracer {
+	return self.tracer
+}
+
 func (self *Env) Origin() common.Address   { return self.origin }
 func (self *Env) BlockNumber() *big.Int    { return self.number }
+func (self *Env) Rules() *params.Rules     { return self.rules }
 func (self *Env) Coinbase() common.Address { return self.coinbase }
 func (self *Env) Time() *big.Int           { return self.time }
 func (self *Env) Difficulty() *big.Int     { return self.difficulty }
@@ -24,6 +24,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/core/state"
 	"github.com/pbfcoin/go-pbfcoin/core/vm"
 	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/params"
 	"github.com/pbfcoin/go-pbfcoin/pbfdb"
 )
 
@@ -40,6 +41,8 @@ type Config struct {
 	Value       *big.Int
 	DisableJit  bool // "disable" so it's enabled by default
 	Debug       bool
+	Tracer      vm.Tracer           // captures the execution steps, the JIT is skipped if set
+	ChainConfig *params.ChainConfig // rules to run with, nil for the default rules
 
 	GpbfashFn func(n uint64) common.Hash
 }
@@ -17,12 +17,14 @@
 package runtime
 
 import (
+	"math/big"
 	"strings"
 	"testing"
 
 	"github.com/pbfcoin/go-pbfcoin/accounts/abi"
 	"github.com/pbfcoin/go-pbfcoin/common"
 	"github.com/pbfcoin/go-pbfcoin/core/vm"
+	"github.com/pbfcoin/go-pbfcoin/params"
 )
 
 func TestDefaults(t *testing.T) {
@@ -118,3 +120,101 @@ func BenchmarkCall(b *testing.B) {
 		}
 	}
 }
+
+func TestStructLogger(t *testing.T) {
+	// PUSH1 0x2a PUSH1 0x01 SSTORE PUSH1 0x20 PUSH1 0x00 MSTORE STOP
+	code := common.Hex2Bytes("602a6001556020600052" + "00")
+
+	logger := vm.NewStructLogger(nil)
+	if _, _, err := Execute(code, nil, &Config{Tracer: logger}); err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	logs := logger.StructLogs()
+	if len(logs) != 7 {
+		t.Fatalf("step count mismatch: have %d, want 7", len(logs))
+	}
+	if logs[2].Op != vm.SSTORE || len(logs[2].Stack) != 2 || logs[2].Depth != 1 {
+		t.Errorf("SSTORE step mismatch: %+v", logs[2])
+	}
+	if value := logs[3].Storage[common.BigToHash(common.Big1)]; common.BytesToHash(value) != common.BigToHash(big.NewInt(42)) {
+		t.Errorf("storage mismatch: have %x, want 2a", value)
+	}
+	if len(logs[6].Memory) != 32 {
+		t.Errorf("memory size mismatch: have %d, want 32", len(logs[6].Memory))
+	}
+	if vm.Debug {
+		t.Errorf("tracing enabled the global debug flag")
+	}
+
+	// Disabled captures are left empty
+	logger = vm.NewStructLogger(&vm.LogConfig{DisableMemory: true, DisableStack: true, DisableStorage: true})
+	if _, _, err := Execute(code, nil, &Config{Tracer: logger}); err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	for i, log := range logger.StructLogs() {
+		if log.Memory != nil || log.Stack != nil || log.Storage != nil {
+			t.Errorf("step %d: disabled capture recorded: %+v", i, log)
+		}
+	}
+}
+
+// Tests that the gas table follows the fork schedule of the chain config.
+func TestChainConfigGasTable(t *testing.T) {
+	// PUSH1 0x2a PUSH1 0x01 SSTORE STOP
+	code := common.Hex2Bytes("602a600155" + "00")
+
+	config := &params.ChainConfig{Forks: []*params.Fork{
+		{Block: big.NewInt(10), Rules: params.Rules{SstoreSetGas: big.NewInt(12345)}},
+	}}
+	tests := []struct {
+		number int64
+		cost   *big.Int
+	}{
+		{9, params.SstoreSetGas},
+		{10, big.NewInt(12345)},
+		{11, big.NewInt(12345)},
+	}
+	for i, tt := range tests {
+		logger := vm.NewStructLogger(nil)
+		cfg := &Config{Tracer: logger, ChainConfig: config, BlockNumber: big.NewInt(tt.number)}
+		if _, _, err := Execute(code, nil, cfg); err != nil {
+			t.Fatalf("test %d: didn't expect error: %v", i, err)
+		}
+		logs := logger.StructLogs()
+		if len(logs) < 3 || logs[2].Op != vm.SSTORE {
+			t.Fatalf("test %d: SSTORE step missing: %+v", i, logs)
+		}
+		if logs[2].GasCost.Cmp(tt.cost) != 0 {
+			t.Errorf("test %d: SSTORE cost mismatch at block %d: have %v, want %v", i, tt.number, logs[2].GasCost, tt.cost)
+		}
+	}
+}
+
+func TestCallLogger(t *testing.T) {
+	// PUSH3 0x6001ff PUSH1 0x00 MSTORE (init code: PUSH1 0x01 SUICIDE)
+	// PUSH1 0x03 PUSH1 0x1d PUSH1 0x00 CREATE POP
+	// PUSH1 0x20 PUSH1 0x40 PUSH1 0x20 PUSH1 0x00 PUSH1 0x00 PUSH1 0x04 PUSH2 0xffff CALL STOP
+	code := common.Hex2Bytes("626001ff600052" + "6003601d6000f050" + "60206040602060006000600461fffff1" + "00")
+
+	logger := vm.NewCallLogger()
+	if _, _, err := Execute(code, nil, &Config{Tracer: logger}); err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	root := logger.Call()
+	if root == nil || root.Type != vm.CALL || root.To != common.StringToAddress("contract") {
+		t.Fatalf("root call mismatch: %+v", root)
+	}
+	if root.GasUsed.Sign() <= 0 || len(root.Calls) != 2 {
+		t.Fatalf("root call mismatch: %+v", root)
+	}
+	create, call := root.Calls[0], root.Calls[1]
+	if create.Type != vm.CREATE || create.From != root.To || len(create.Calls) != 1 {
+		t.Fatalf("create mismatch: %+v", create)
+	}
+	if suicide := create.Calls[0]; suicide.Type != vm.SUICIDE || suicide.From != create.To || suicide.To != common.BytesToAddress([]byte{1}) {
+		t.Errorf("suicide mismatch: %+v", suicide)
+	}
+	if call.Type != vm.CALL || call.To != common.BytesToAddress([]byte{4}) || len(call.Output) != 32 || call.Err != nil {
+		t.Errorf("call mismatch: %+v", call)
+	}
+}
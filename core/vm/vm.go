@@ -25,7 +25,6 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/crypto"
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
-	"github.com/pbfcoin/go-pbfcoin/params"
 )
 
 // Vm is an EVM and implements VirtualMachine
@@ -68,7 +67,8 @@ func (self *Vm) Run(contract *Contract, input []byte) (ret []byte, err error) {
 		codehash = crypto.Sha3Hash(contract.Code) // codehash is used when doing jump dest caching
 		program  *Program
 	)
-	if EnableJit {
+	// JIT programs don't report their steps, traced executions are interpreted
+	if EnableJit && self.env.Tracer() == nil {
 		// If the JIT is enabled check the status of the JIT program,
 		// if it doesn't exist compile a new program in a seperate
 		// goroutine or wait for compilation to finish if the JIT is
@@ -229,8 +229,9 @@ func calculateGasAndSize(env Environment, contract *Contract, caller ContractRef
 	var (
 		gas                 = new(big.Int)
 		newMemSize *big.Int = new(big.Int)
+		rules               = env.Rules()
 	)
-	err := baseCheck(op, stack, gas)
+	err := baseCheck(op, stack, gas, rules)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -260,13 +261,13 @@ func calculateGasAndSize(env Environment, contract *Contract, caller ContractRef
 
 		mSize, mStart := stack.data[stack.len()-2], stack.data[stack.len()-1]
 
-		gas.Add(gas, params.LogGas)
-		gas.Add(gas, new(big.Int).Mul(big.NewInt(int64(n)), params.LogTopicGas))
-		gas.Add(gas, new(big.Int).Mul(mSize, params.LogDataGas))
+		gas.Add(gas, rules.LogGas)
+		gas.Add(gas, new(big.Int).Mul(big.NewInt(int64(n)), rules.LogTopicGas))
+		gas.Add(gas, new(big.Int).Mul(mSize, rules.LogDataGas))
 
 		newMemSize = calcMemSize(mStart, mSize)
 	case EXP:
-		gas.Add(gas, new(big.Int).Mul(big.NewInt(int64(len(stack.data[stack.len()-2].Bytes()))), params.ExpByteGas))
+		gas.Add(gas, new(big.Int).Mul(big.NewInt(int64(len(stack.data[stack.len()-2].Bytes()))), rules.ExpByteGas))
 	case SSTORE:
 		err := stack.require(2)
 		if err != nil {
@@ -283,19 +284,19 @@ func calculateGasAndSize(env Environment, contract *Contract, caller ContractRef
 		// 3. From a nen-zero to a non-zero                         (CHANGE)
 		if common.EmptyHash(val) && !common.EmptyHash(common.BigToHash(y)) {
 			// 0 => non 0
-			g = params.SstoreSetGas
+			g = rules.SstoreSetGas
 		} else if !common.EmptyHash(val) && common.EmptyHash(common.BigToHash(y)) {
-			statedb.AddRefund(params.SstoreRefundGas)
+			statedb.AddRefund(rules.SstoreRefundGas)
 
-			g = params.SstoreClearGas
+			g = rules.SstoreClearGas
 		} else {
 			// non 0 => non 0 (or 0 => 0)
-			g = params.SstoreClearGas
+			g = rules.SstoreClearGas
 		}
 		gas.Set(g)
 	case SUICIDE:
 		if !statedb.IsDeleted(contract.Address()) {
-			statedb.AddRefund(params.SuicideRefundGas)
+			statedb.AddRefund(rules.SuicideRefundGas)
 		}
 	case MLOAD:
 		newMemSize = calcMemSize(stack.peek(), u256(32))
@@ -309,22 +310,22 @@ func calculateGasAndSize(env Environment, contract *Contract, caller ContractRef
 		newMemSize = calcMemSize(stack.peek(), stack.data[stack.len()-2])
 
 		words := toWordSize(stack.data[stack.len()-2])
-		gas.Add(gas, words.Mul(words, params.Sha3WordGas))
+		gas.Add(gas, words.Mul(words, rules.Sha3WordGas))
 	case CALLDATACOPY:
 		newMemSize = calcMemSize(stack.peek(), stack.data[stack.len()-3])
 
 		words := toWordSize(stack.data[stack.len()-3])
-		gas.Add(gas, words.Mul(words, params.CopyGas))
+		gas.Add(gas, words.Mul(words, rules.CopyGas))
 	case CODECOPY:
 		newMemSize = calcMemSize(stack.peek(), stack.data[stack.len()-3])
 
 		words := toWordSize(stack.data[stack.len()-3])
-		gas.Add(gas, words.Mul(words, params.CopyGas))
+		gas.Add(gas, words.Mul(words, rules.CopyGas))
 	case EXTCODECOPY:
 		newMemSize = calcMemSize(stack.data[stack.len()-2], stack.data[stack.len()-4])
 
 		words := toWordSize(stack.data[stack.len()-4])
-		gas.Add(gas, words.Mul(words, params.CopyGas))
+		gas.Add(gas, words.Mul(words, rules.CopyGas))
 
 	case CREATE:
 		newMemSize = calcMemSize(stack.data[stack.len()-2], stack.data[stack.len()-3])
@@ -334,12 +335,12 @@ func calculateGasAndSize(env Environment, contract *Contract, caller ContractRef
 		if op == CALL {
 			//if env.Db().GetStateObject(common.BigToAddress(stack.data[stack.len()-2])) == nil {
 			if !env.Db().Exist(common.BigToAddress(stack.data[stack.len()-2])) {
-				gas.Add(gas, params.CallNewAccountGas)
+				gas.Add(gas, rules.CallNewAccountGas)
 			}
 		}
 
 		if len(stack.data[stack.len()-3].Bytes()) > 0 {
-			gas.Add(gas, params.CallValueTransferGas)
+			gas.Add(gas, rules.CallValueTransferGas)
 		}
 
 		x := calcMemSize(stack.data[stack.len()-6], stack.data[stack.len()-7])
@@ -347,7 +348,7 @@ func calculateGasAndSize(env Environment, contract *Contract, caller ContractRef
 
 		newMemSize = common.BigMax(x, y)
 	}
-	quadMemGas(mem, newMemSize, gas)
+	quadMemGas(mem, newMemSize, gas, rules)
 
 	return newMemSize, gas, nil
 }
@@ -367,6 +368,9 @@ func (self *Vm) RunPrecompiled(p *PrecompiledAccount, input []byte, contract *Co
 // log emits a log event to the environment for each opcode encountered. This is not to be confused with the
 // LOG* opcode.
 func (self *Vm) log(pc uint64, op OpCode, gas, cost *big.Int, memory *Memory, stack *stack, contract *Contract, err error) {
+	if tracer := self.env.Tracer(); tracer != nil {
+		tracer.CaptureState(self.env, pc, op, gas, cost, memory, stack.Data(), contract, self.env.Depth(), err)
+	}
 	if Debug {
 		mem := make([]byte, len(memory.Data()))
 		copy(mem, memory.Data())
@@ -382,7 +386,7 @@ func (self *Vm) log(pc uint64, op OpCode, gas, cost *big.Int, memory *Memory, st
 				storage[common.BytesToHash(k)] = v
 			})
 		*/
-		self.env.AddStructLog(StructLog{pc, op, new(big.Int).Set(gas), cost, mem, stck, storage, err})
+		self.env.AddStructLog(StructLog{pc, op, new(big.Int).Set(gas), cost, mem, stck, storage, self.env.Depth(), err})
 	}
 }
 
@@ -23,6 +23,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/core/state"
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/core/vm"
+	"github.com/pbfcoin/go-pbfcoin/params"
 )
 
 type VMEnv struct {
@@ -31,23 +32,31 @@ type VMEnv struct {
 	msg    Message
 	depth  int
 	chain  *BlockChain
+	rules  *params.Rules
 	typ    vm.Type
 	// structured logging
-	logs []vm.StructLog
+	logs   []vm.StructLog
+	tracer vm.Tracer
 }
 
 func NewEnv(state *state.StateDB, chain *BlockChain, msg Message, header *types.Header) *VMEnv {
+	var config *params.ChainConfig
+	if chain != nil {
+		config = chain.Config()
+	}
 	return &VMEnv{
 		chain:  chain,
 		state:  state,
 		header: header,
 		msg:    msg,
+		rules:  config.Rules(header.Number),
 		typ:    vm.StdVmTy,
 	}
 }
 
 func (self *VMEnv) Origin() common.Address   { f, _ := self.msg.From(); return f }
 func (self *VMEnv) BlockNumber() *big.Int    { return self.header.Number }
+func (self *VMEnv) Rules() *params.Rules     { return self.rules }
 func (self *VMEnv) Coinbase() common.Address { return self.header.Coinbase }
 func (self *VMEnv) Time() *big.Int           { return self.header.Time }
 func (self *VMEnv) Difficulty() *big.Int     { return self.header.Difficulty }
@@ -105,3 +114,13 @@ func (self *VMEnv) StructLogs() []vm.StructLog {
 func (self *VMEnv) AddStructLog(log vm.StructLog) {
 	self.logs = append(self.logs, log)
 }
+
+func (self *VMEnv) Tracer() vm.Tracer {
+	return self.tracer
+}
+
+// SetTracer sets a tracer which captures the execution steps of this
+// environment only, independently of the global vm.Debug flag.
+func (self *VMEnv) SetTracer(tracer vm.Tracer) {
+	self.tracer = tracer
+}
@@ -0,0 +1,153 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package light implements on-demand retrieval of state and chain data for
+// clients which only synchronise block headers.
+package light
+
+import (
+	"errors"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+	"github.com/pbfcoin/go-pbfcoin/rlp"
+	"github.com/pbfcoin/go-pbfcoin/trie"
+)
+
+var (
+	errHashMismatch    = errors.New("hash mismatch")
+	errTxHashMismatch  = errors.New("transaction hash mismatch")
+	errUncleMismatch   = errors.New("uncle hash mismatch")
+	errReceiptMismatch = errors.New("receipt hash mismatch")
+)
+
+// OdrBackend is the interface of a light client to the network, retrieving the
+// data it lacks from full peers on demand (ODR).
+type OdrBackend interface {
+	// Database returns the local database retrieved data is stored into.
+	Database() pbfdb.Database
+
+	// Retrieve fetches the data of the request from the network. If a valid
+	// response arrives, the result is stored into the local database.
+	Retrieve(req OdrRequest) error
+}
+
+// OdrRequest is a request for data which is checked against a trusted hash,
+// ultimately a field of a synced header, before being accepted.
+type OdrRequest interface {
+	// StoreResult writes a validated response into the local database.
+	StoreResult(db pbfdb.Database)
+}
+
+// TrieRequest requests the merkle proof of a key in a state or storage trie.
+type TrieRequest struct {
+	Root common.Hash // Root hash of the trie, taken from a trusted header or account
+	Key  []byte      // Key to prove, already hashed (secure trie)
+
+	Proof []rlp.RawValue // Verified proof of the key
+	Value []byte         // Proven value, nil if the trie doesn't contain the key
+}
+
+// Validate checks a proof against the root of the requested trie. Proofs of
+// absence are accepted.
+func (req *TrieRequest) Validate(proof []rlp.RawValue) error {
+	value, err := trie.VerifyProof(req.Root, req.Key, proof)
+	if err != nil {
+		return err
+	}
+	req.Proof, req.Value = proof, value
+	return nil
+}
+
+// StoreResult writes the nodes of the proof into the database, making the
+// proven path accessible to regular tries.
+func (req *TrieRequest) StoreResult(db pbfdb.Database) {
+	for _, node := range req.Proof {
+		db.Put(crypto.Sha3(node), node)
+	}
+}
+
+// NodeDataRequest requests a single trie node or contract code by hash.
+type NodeDataRequest struct {
+	Hash common.Hash // Hash of the requested data
+	Data []byte      // Verified data
+}
+
+// Validate checks that the data hashes to the requested hash.
+func (req *NodeDataRequest) Validate(data []byte) error {
+	if common.BytesToHash(crypto.Sha3(data)) != req.Hash {
+		return errHashMismatch
+	}
+	req.Data = data
+	return nil
+}
+
+// StoreResult writes the data into the database under its hash.
+func (req *NodeDataRequest) StoreResult(db pbfdb.Database) {
+	db.Put(req.Hash[:], req.Data)
+}
+
+// BodyRequest requests the transactions and uncles of a block.
+type BodyRequest struct {
+	Header *types.Header // Header of the block
+	Body   *types.Body   // Verified block body
+}
+
+// Validate checks the body against the transaction and uncle hashes of the header.
+func (req *BodyRequest) Validate(body *types.Body) error {
+	if types.DeriveSha(types.Transactions(body.Transactions)) != req.Header.TxHash {
+		return errTxHashMismatch
+	}
+	if types.CalcUncleHash(body.Uncles) != req.Header.UncleHash {
+		return errUncleMismatch
+	}
+	req.Body = body
+	return nil
+}
+
+// StoreResult writes the body into the database along with the lookup entries
+// of its transactions.
+func (req *BodyRequest) StoreResult(db pbfdb.Database) {
+	block := types.NewBlockWithHeader(req.Header).WithBody(req.Body.Transactions, req.Body.Uncles)
+	core.WriteBody(db, block.Hash(), req.Body)
+	core.WriteTransactions(db, block)
+}
+
+// ReceiptsRequest requests the transaction receipts of a block.
+type ReceiptsRequest struct {
+	Block    *types.Block   // Block the receipts belong to, including its body
+	Receipts types.Receipts // Verified receipts
+}
+
+// Validate checks the receipts against the receipt hash of the block header.
+func (req *ReceiptsRequest) Validate(receipts types.Receipts) error {
+	if types.DeriveSha(receipts) != req.Block.ReceiptHash() {
+		return errReceiptMismatch
+	}
+	req.Receipts = receipts
+	return nil
+}
+
+// StoreResult fills in the derived fields of the receipts and writes them into
+// the database, both per block and per transaction.
+func (req *ReceiptsRequest) StoreResult(db pbfdb.Database) {
+	core.SetReceiptsData(req.Block, req.Receipts)
+	core.WriteBlockReceipts(db, req.Block.Hash(), req.Receipts)
+	core.WriteReceipts(db, req.Receipts)
+}
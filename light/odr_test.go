@@ -0,0 +1,241 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core"
+	"github.com/pbfcoin/go-pbfcoin/core/state"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/params"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+	"github.com/pbfcoin/go-pbfcoin/rlp"
+	"github.com/pbfcoin/go-pbfcoin/trie"
+)
+
+var (
+	testKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr     = crypto.PubkeyToAddress(testKey.PublicKey)
+	testContract = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	testCode     = []byte{0x60, 0x01, 0x60, 0x00, 0x55}
+)
+
+// testOdr serves the requests of a light client from the database of a full node,
+// optionally corrupting the responses.
+type testOdr struct {
+	sdb, ldb pbfdb.Database
+	receipts map[common.Hash]types.Receipts
+	corrupt  bool
+	served   int
+}
+
+func (odr *testOdr) Database() pbfdb.Database { return odr.ldb }
+
+func (odr *testOdr) Retrieve(req OdrRequest) error {
+	odr.served++
+
+	var err error
+	switch req := req.(type) {
+	case *TrieRequest:
+		t, _ := trie.New(req.Root, odr.sdb)
+		proof := t.Prove(req.Key)
+		if odr.corrupt && len(proof) > 0 {
+			proof[len(proof)-1] = append(rlp.RawValue{}, proof[len(proof)-1]...)
+			proof[len(proof)-1][len(proof[len(proof)-1])-1]++
+		}
+		err = req.Validate(proof)
+	case *NodeDataRequest:
+		data, _ := odr.sdb.Get(req.Hash[:])
+		if odr.corrupt {
+			data = append(data, 0x00)
+		}
+		err = req.Validate(data)
+	case *BodyRequest:
+		body := core.GetBody(odr.sdb, req.Header.Hash())
+		if odr.corrupt {
+			body.Transactions = body.Transactions[1:]
+		}
+		err = req.Validate(body)
+	case *ReceiptsRequest:
+		receipts := odr.receipts[req.Block.Hash()]
+		if odr.corrupt {
+			receipts = receipts[1:]
+		}
+		err = req.Validate(receipts)
+	default:
+		err = errors.New("unknown request")
+	}
+	if err != nil {
+		return err
+	}
+	req.StoreResult(odr.ldb)
+	return nil
+}
+
+// newTestState creates a full state with a funded account and a contract with
+// code and storage, returning its root.
+func newTestState(db pbfdb.Database) common.Hash {
+	statedb, _ := state.New(common.Hash{}, db)
+	statedb.AddBalance(testAddr, big.NewInt(1000000))
+	statedb.SetNonce(testAddr, 5)
+	statedb.SetCode(testContract, testCode)
+	statedb.SetState(testContract, common.Hash{1}, common.Hash{2})
+	statedb.SetState(testContract, common.Hash{3}, common.Hash{4})
+	root, _ := statedb.Commit()
+	return root
+}
+
+// Tests that accounts, code and storage are retrieved on demand and proven against
+// the state root, and that retrieved data is served locally afterwards.
+func TestStateRetrieval(t *testing.T) {
+	sdb, _ := pbfdb.NewMemDatabase()
+	ldb, _ := pbfdb.NewMemDatabase()
+	odr := &testOdr{sdb: sdb, ldb: ldb}
+	root := newTestState(sdb)
+
+	light := NewState(root, odr)
+	if balance, err := light.GetBalance(testAddr); err != nil || balance.Cmp(big.NewInt(1000000)) != 0 {
+		t.Errorf("balance mismatch: have %v, %v; want 1000000", balance, err)
+	}
+	if nonce, err := light.GetNonce(testAddr); err != nil || nonce != 5 {
+		t.Errorf("nonce mismatch: have %d, %v; want 5", nonce, err)
+	}
+	if code, err := light.GetCode(testContract); err != nil || !bytes.Equal(code, testCode) {
+		t.Errorf("code mismatch: have %x, %v; want %x", code, err, testCode)
+	}
+	if value, err := light.GetState(testContract, common.Hash{1}); err != nil || value != (common.Hash{2}) {
+		t.Errorf("storage mismatch: have %x, %v; want %x", value, err, common.Hash{2})
+	}
+	if value, err := light.GetState(testContract, common.Hash{2}); err != nil || value != (common.Hash{}) {
+		t.Errorf("unset storage mismatch: have %x, %v; want zero", value, err)
+	}
+	if balance, err := light.GetBalance(common.Address{0xff}); err != nil || balance.Sign() != 0 {
+		t.Errorf("missing account balance mismatch: have %v, %v; want 0", balance, err)
+	}
+	// Repeated reads must be served from the local database
+	served := odr.served
+	light.GetBalance(testAddr)
+	light.GetCode(testContract)
+	light.GetState(testContract, common.Hash{1})
+	if odr.served != served {
+		t.Errorf("retrieved data requested again: %d new requests", odr.served-served)
+	}
+}
+
+// Tests that corrupted responses are rejected and not stored.
+func TestStateRetrievalCorrupted(t *testing.T) {
+	sdb, _ := pbfdb.NewMemDatabase()
+	ldb, _ := pbfdb.NewMemDatabase()
+	odr := &testOdr{sdb: sdb, ldb: ldb, corrupt: true}
+	root := newTestState(sdb)
+
+	light := NewState(root, odr)
+	if _, err := light.GetBalance(testAddr); err == nil {
+		t.Errorf("corrupted account proof accepted")
+	}
+	if _, err := ldb.Get(root[:]); err == nil {
+		t.Errorf("corrupted proof stored")
+	}
+	odr.corrupt = false
+	if _, err := light.GetBalance(testAddr); err != nil {
+		t.Fatalf("failed to retrieve account: %v", err)
+	}
+	odr.corrupt = true
+	if _, err := light.GetCode(testContract); err == nil {
+		t.Errorf("corrupted code accepted")
+	}
+}
+
+// Tests that a state database backed by on demand retrieval reads the same state
+// as the full node.
+func TestOdrStateDB(t *testing.T) {
+	sdb, _ := pbfdb.NewMemDatabase()
+	ldb, _ := pbfdb.NewMemDatabase()
+	root := newTestState(sdb)
+
+	statedb, err := NewStateDB(root, &testOdr{sdb: sdb, ldb: ldb})
+	if err != nil {
+		t.Fatalf("failed to create state database: %v", err)
+	}
+	if balance := statedb.GetBalance(testAddr); balance.Cmp(big.NewInt(1000000)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 1000000", balance)
+	}
+	if code := statedb.GetCode(testContract); !bytes.Equal(code, testCode) {
+		t.Errorf("code mismatch: have %x, want %x", code, testCode)
+	}
+	if value := statedb.GetState(testContract, common.Hash{3}); value != (common.Hash{4}) {
+		t.Errorf("storage mismatch: have %x, want %x", value, common.Hash{4})
+	}
+}
+
+// Tests that block bodies and receipts are retrieved and checked against the
+// header they belong to.
+func TestBlockRetrieval(t *testing.T) {
+	sdb, _ := pbfdb.NewMemDatabase()
+	genesis := core.WriteGenesisBlockForTesting(sdb, core.GenesisAccount{testAddr, big.NewInt(1000000000)})
+	blocks, receipts := core.GenerateChain(genesis, sdb, 3, func(i int, gen *core.BlockGen) {
+		for j := 0; j < i+2; j++ {
+			tx, _ := types.NewTransaction(gen.TxNonce(testAddr), common.Address{byte(j)}, big.NewInt(1000), params.TxGas, nil, nil).SignECDSA(testKey)
+			gen.AddTx(tx)
+		}
+	})
+	odr := &testOdr{sdb: sdb, receipts: make(map[common.Hash]types.Receipts)}
+	for i, block := range blocks {
+		core.WriteBlock(sdb, block)
+		odr.receipts[block.Hash()] = receipts[i]
+	}
+	for i, block := range blocks {
+		odr.ldb, _ = pbfdb.NewMemDatabase()
+
+		odr.corrupt = true
+		if _, err := GetBlock(odr, block.Header()); err == nil {
+			t.Errorf("block %d: corrupted body accepted", i)
+		}
+		odr.corrupt = false
+		have, err := GetBlock(odr, block.Header())
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve block: %v", i, err)
+		}
+		if have.Hash() != block.Hash() || len(have.Transactions()) != len(block.Transactions()) {
+			t.Errorf("block %d: retrieved block mismatch", i)
+		}
+		if tx, hash, _, _ := core.GetTransaction(odr.ldb, block.Transactions()[0].Hash()); tx == nil || hash != block.Hash() {
+			t.Errorf("block %d: transaction lookup missing", i)
+		}
+		odr.corrupt = true
+		if _, err := GetBlockReceipts(odr, block.Header()); err == nil {
+			t.Errorf("block %d: corrupted receipts accepted", i)
+		}
+		odr.corrupt = false
+		rs, err := GetBlockReceipts(odr, block.Header())
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve receipts: %v", i, err)
+		}
+		if len(rs) != len(receipts[i]) || rs[0].TxHash != block.Transactions()[0].Hash() {
+			t.Errorf("block %d: retrieved receipts mismatch", i)
+		}
+		if r := core.GetReceipt(odr.ldb, block.Transactions()[0].Hash()); r == nil {
+			t.Errorf("block %d: transaction receipt missing", i)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core"
+	"github.com/pbfcoin/go-pbfcoin/core/state"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+)
+
+// GetBody retrieves the body of the block with the given header, from the local
+// database if available or from the network otherwise.
+func GetBody(odr OdrBackend, header *types.Header) (*types.Body, error) {
+	if header.TxHash == types.EmptyRootHash && header.UncleHash == types.EmptyUncleHash {
+		return &types.Body{}, nil
+	}
+	if body := core.GetBody(odr.Database(), header.Hash()); body != nil {
+		return body, nil
+	}
+	req := &BodyRequest{Header: header}
+	if err := odr.Retrieve(req); err != nil {
+		return nil, err
+	}
+	return req.Body, nil
+}
+
+// GetBlock assembles the block with the given header, retrieving its body if
+// needed.
+func GetBlock(odr OdrBackend, header *types.Header) (*types.Block, error) {
+	body, err := GetBody(odr, header)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles), nil
+}
+
+// GetBlockReceipts retrieves the transaction receipts of the block with the
+// given header, from the local database if available or from the network
+// otherwise.
+func GetBlockReceipts(odr OdrBackend, header *types.Header) (types.Receipts, error) {
+	if header.ReceiptHash == types.EmptyRootHash {
+		return types.Receipts{}, nil
+	}
+	if receipts := core.GetBlockReceipts(odr.Database(), header.Hash()); receipts != nil {
+		return receipts, nil
+	}
+	block, err := GetBlock(odr, header)
+	if err != nil {
+		return nil, err
+	}
+	req := &ReceiptsRequest{Block: block}
+	if err := odr.Retrieve(req); err != nil {
+		return nil, err
+	}
+	return req.Receipts, nil
+}
+
+// NewStateDB creates a state database for the given state root whose trie nodes
+// and contract code are retrieved from the network on demand, checking each of
+// them against the hash it is referenced by. It allows arbitrary state access,
+// like running calls in the VM, when the accessed keys aren't known beforehand.
+func NewStateDB(root common.Hash, odr OdrBackend) (*state.StateDB, error) {
+	return state.New(root, &odrDatabase{Database: odr.Database(), odr: odr})
+}
+
+// odrDatabase is a database which retrieves missing hash addressed entries, trie
+// nodes and contract code, from the network.
+type odrDatabase struct {
+	pbfdb.Database
+	odr OdrBackend
+}
+
+func (db *odrDatabase) Get(key []byte) ([]byte, error) {
+	value, err := db.Database.Get(key)
+	if len(value) > 0 || len(key) != len(common.Hash{}) || common.BytesToHash(key) == common.BytesToHash(emptyCodeHash) {
+		return value, err
+	}
+	req := &NodeDataRequest{Hash: common.BytesToHash(key)}
+	if err := db.odr.Retrieve(req); err != nil {
+		return nil, err
+	}
+	return req.Data, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"math/big"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/rlp"
+	"github.com/pbfcoin/go-pbfcoin/trie"
+)
+
+var emptyCodeHash = crypto.Sha3(nil)
+
+// account is the consensus representation of an account in the state trie.
+type account struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash // Root of the storage trie
+	CodeHash []byte
+}
+
+// State gives access to the accounts of the state trie with a given root,
+// retrieving them from the network if missing locally. Every value read is
+// proven against the state root, which is taken from a synced header.
+type State struct {
+	root common.Hash
+	odr  OdrBackend
+}
+
+// NewState creates a light state for the given state root.
+func NewState(root common.Hash, odr OdrBackend) *State {
+	return &State{root: root, odr: odr}
+}
+
+// GetBalance retrieves the balance of an account, zero if it doesn't exist.
+func (self *State) GetBalance(addr common.Address) (*big.Int, error) {
+	acc, err := self.getAccount(addr)
+	if err != nil || acc == nil {
+		return new(big.Int), err
+	}
+	return acc.Balance, nil
+}
+
+// GetNonce retrieves the nonce of an account, zero if it doesn't exist.
+func (self *State) GetNonce(addr common.Address) (uint64, error) {
+	acc, err := self.getAccount(addr)
+	if err != nil || acc == nil {
+		return 0, err
+	}
+	return acc.Nonce, nil
+}
+
+// GetCode retrieves the contract code of an account, nil for accounts without code.
+func (self *State) GetCode(addr common.Address) ([]byte, error) {
+	acc, err := self.getAccount(addr)
+	if err != nil || acc == nil || common.BytesToHash(acc.CodeHash) == common.BytesToHash(emptyCodeHash) {
+		return nil, err
+	}
+	hash := common.BytesToHash(acc.CodeHash)
+	if code, _ := self.odr.Database().Get(hash[:]); len(code) > 0 {
+		return code, nil
+	}
+	req := &NodeDataRequest{Hash: hash}
+	if err := self.odr.Retrieve(req); err != nil {
+		return nil, err
+	}
+	return req.Data, nil
+}
+
+// GetState retrieves a storage slot of an account, zero if it's not set.
+func (self *State) GetState(addr common.Address, key common.Hash) (common.Hash, error) {
+	acc, err := self.getAccount(addr)
+	if err != nil || acc == nil {
+		return common.Hash{}, err
+	}
+	enc, err := self.retrieve(acc.Root, crypto.Sha3(key[:]))
+	if err != nil || enc == nil {
+		return common.Hash{}, err
+	}
+	var value []byte
+	if err := rlp.DecodeBytes(enc, &value); err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(value), nil
+}
+
+// getAccount retrieves and decodes an account, nil if it doesn't exist.
+func (self *State) getAccount(addr common.Address) (*account, error) {
+	enc, err := self.retrieve(self.root, crypto.Sha3(addr[:]))
+	if err != nil || enc == nil {
+		return nil, err
+	}
+	acc := new(account)
+	if err := rlp.DecodeBytes(enc, acc); err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+// retrieve proves the value of a key in the trie with the given root, using the
+// nodes of the local database if available and requesting a proof otherwise.
+func (self *State) retrieve(root common.Hash, key []byte) ([]byte, error) {
+	if proof, ok := localProof(self.odr, root, key); ok {
+		return trie.VerifyProof(root, key, proof)
+	}
+	req := &TrieRequest{Root: root, Key: key}
+	if err := self.odr.Retrieve(req); err != nil {
+		return nil, err
+	}
+	return req.Value, nil
+}
+
+// localProof assembles the proof of a key from the trie nodes of the local
+// database, returning false if any of them is missing.
+func localProof(odr OdrBackend, root common.Hash, key []byte) ([]rlp.RawValue, bool) {
+	var proof []rlp.RawValue
+	for {
+		next, err := trie.NextProofNode(root, key, proof)
+		if err != nil {
+			return nil, false
+		}
+		if next == (common.Hash{}) {
+			return proof, true
+		}
+		node, _ := odr.Database().Get(next[:])
+		if len(node) == 0 {
+			return nil, false
+		}
+		proof = append(proof, node)
+	}
+}
@@ -21,10 +21,10 @@ import (
 
 	"sync/atomic"
 
-	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
-	"github.com/pbfcoin/go-pbfcoin/pow"
 )
 
 type CpuAgent struct {
@@ -35,23 +35,24 @@ type CpuAgent struct {
 	quitCurrentOp chan struct{}
 	returnCh      chan<- *Result
 
-	index int
-	pow   pow.PoW
+	index  int
+	chain  consensus.ChainReader
+	engine consensus.Engine
 
 	isMining int32 // isMining indicates whpbfer the agent is currently mining
 }
 
-func NewCpuAgent(index int, pow pow.PoW) *CpuAgent {
+func NewCpuAgent(index int, chain consensus.ChainReader, engine consensus.Engine) *CpuAgent {
 	miner := &CpuAgent{
-		pow:   pow,
-		index: index,
+		chain:  chain,
+		engine: engine,
+		index:  index,
 	}
 
 	return miner
 }
 
 func (self *CpuAgent) Work() chan<- *Work            { return self.workCh }
-func (self *CpuAgent) Pow() pow.PoW                  { return self.pow }
 func (self *CpuAgent) SetReturnCh(ch chan<- *Result) { self.returnCh = ch }
 
 func (self *CpuAgent) Stop() {
@@ -118,9 +119,19 @@ func (self *CpuAgent) mine(work *Work, stop <-chan struct{}) {
 	glog.V(logger.Debug).Infof("(re)started agent[%d]. mining...\n", self.index)
 
 	// Mine
-	nonce, mixDigest := self.pow.Search(work.Block, stop, self.index)
-	if nonce != 0 {
-		block := work.Block.WithMiningResult(nonce, common.BytesToHash(mixDigest))
+	var (
+		block *types.Block
+		err   error
+	)
+	if pow, ok := self.engine.(consensus.PoW); ok {
+		block, err = pow.SealThread(self.chain, work.Block, stop, self.index)
+	} else {
+		block, err = self.engine.Seal(self.chain, work.Block, stop)
+	}
+	if err != nil {
+		glog.V(logger.Error).Infof("agent[%d] failed to seal block: %v\n", self.index, err)
+	}
+	if block != nil {
 		self.returnCh <- &Result{work, block}
 	} else {
 		self.returnCh <- nil
@@ -128,5 +139,8 @@ func (self *CpuAgent) mine(work *Work, stop <-chan struct{}) {
 }
 
 func (self *CpuAgent) GpbfashRate() int64 {
-	return self.pow.Gpbfashrate()
+	if pow, ok := self.engine.(consensus.PoW); ok {
+		return pow.Hashrate()
+	}
+	return 0
 }
@@ -23,15 +23,14 @@ import (
 	"sync/atomic"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus"
 	"github.com/pbfcoin/go-pbfcoin/core"
 	"github.com/pbfcoin/go-pbfcoin/core/state"
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/event"
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
-	"github.com/pbfcoin/go-pbfcoin/params"
 	"github.com/pbfcoin/go-pbfcoin/pbf/downloader"
-	"github.com/pbfcoin/go-pbfcoin/pow"
 )
 
 type Miner struct {
@@ -45,14 +44,14 @@ type Miner struct {
 	coinbase common.Address
 	mining   int32
 	pbf      core.Backend
-	pow      pow.PoW
+	engine   consensus.Engine
 
 	canStart    int32 // can start indicates whpbfer we can start the mining operation
 	shouldStart int32 // should start indicates whpbfer we should start after sync
 }
 
-func New(pbf core.Backend, mux *event.TypeMux, pow pow.PoW) *Miner {
-	miner := &Miner{pbf: pbf, mux: mux, pow: pow, worker: newWorker(common.Address{}, pbf), canStart: 1}
+func New(pbf core.Backend, mux *event.TypeMux, engine consensus.Engine) *Miner {
+	miner := &Miner{pbf: pbf, mux: mux, engine: engine, worker: newWorker(common.Address{}, pbf), canStart: 1}
 	go miner.update()
 
 	return miner
@@ -112,8 +111,12 @@ func (self *Miner) Start(coinbase common.Address, threads int) {
 
 	atomic.StoreInt32(&self.mining, 1)
 
+	// Only proof-of-work benefits from multiple sealing threads
+	if _, ok := self.engine.(consensus.PoW); !ok && threads > 1 {
+		threads = 1
+	}
 	for i := 0; i < threads; i++ {
-		self.worker.register(NewCpuAgent(i, self.pow))
+		self.worker.register(NewCpuAgent(i, self.pbf.BlockChain(), self.engine))
 	}
 
 	glog.V(logger.Info).Infof("Starting mining operation (CPU=%d TOT=%d)\n", threads, len(self.worker.agents))
@@ -145,7 +148,9 @@ func (self *Miner) Mining() bool {
 }
 
 func (self *Miner) HashRate() (tot int64) {
-	tot += self.pow.Gpbfashrate()
+	if pow, ok := self.engine.(consensus.PoW); ok {
+		tot += pow.Hashrate()
+	}
 	// do we care this might race? is it worth we're rewriting some
 	// aspects of the worker/locking up agents so we can get an accurate
 	// hashrate?
@@ -155,9 +160,13 @@ func (self *Miner) HashRate() (tot int64) {
 	return
 }
 
+// SetExtra sets the extra-data of mined blocks, which must fit the limit of the
+// chain rules in effect for the next block.
 func (self *Miner) SetExtra(extra []byte) error {
-	if uint64(len(extra)) > params.MaximumExtraDataSize.Uint64() {
-		return fmt.Errorf("Extra exceeds max length. %d > %v", len(extra), params.MaximumExtraDataSize)
+	chain := self.pbf.BlockChain()
+	number := new(big.Int).Add(chain.CurrentBlock().Number(), common.Big1)
+	if limit := chain.Config().Rules(number).MaximumExtraDataSize; big.NewInt(int64(len(extra))).Cmp(limit) > 0 {
+		return fmt.Errorf("Extra exceeds max length. %d > %v", len(extra), limit)
 	}
 
 	self.worker.extra = extra
@@ -26,6 +26,7 @@ import (
 
 	"github.com/pbfcoin/go-pbfcoin/accounts"
 	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus"
 	"github.com/pbfcoin/go-pbfcoin/core"
 	"github.com/pbfcoin/go-pbfcoin/core/state"
 	"github.com/pbfcoin/go-pbfcoin/core/types"
@@ -34,7 +35,6 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
 	"github.com/pbfcoin/go-pbfcoin/pbfdb"
-	"github.com/pbfcoin/go-pbfcoin/pow"
 	"gopkg.in/fatih/set.v0"
 )
 
@@ -96,7 +96,7 @@ type worker struct {
 	recv   chan *Result
 	mux    *event.TypeMux
 	quit   chan struct{}
-	pow    pow.PoW
+	engine consensus.Engine
 
 	pbf     core.Backend
 	chain   *core.BlockChain
@@ -131,6 +131,7 @@ func newWorker(coinbase common.Address, pbf core.Backend) *worker {
 		recv:           make(chan *Result, resultQueueSize),
 		gasPrice:       new(big.Int),
 		chain:          pbf.BlockChain(),
+		engine:         pbf.BlockChain().Engine(),
 		proc:           pbf.BlockChain().Validator(),
 		possibleUncles: make(map[common.Hash]*types.Block),
 		coinbase:       coinbase,
@@ -246,6 +247,15 @@ func (self *worker) update() {
 					self.currentMu.Lock()
 					self.current.commitTransactions(types.Transactions{ev.Tx}, self.gasPrice, self.chain)
 					self.currentMu.Unlock()
+				} else {
+					// If we're mining an empty block, recommit so the transaction
+					// gets included (engines like instant never seal empty blocks)
+					self.currentMu.Lock()
+					empty := self.current != nil && self.current.tcount == 0
+					self.currentMu.Unlock()
+					if empty {
+						self.commitNewWork()
+					}
 				}
 			}
 		case <-self.quit:
@@ -291,8 +301,7 @@ func (self *worker) wait() {
 					continue
 				}
 
-				auxValidator := self.pbf.BlockChain().AuxValidator()
-				if err := core.ValidateHeader(auxValidator, block.Header(), parent.Header(), true, false); err != nil && err != core.BlockFutureErr {
+				if err := core.ValidateHeader(self.engine, self.chain, block.Header(), parent.Header(), true, false); err != nil && err != core.BlockFutureErr {
 					glog.V(logger.Error).Infoln("Invalid header on mined block:", err)
 					continue
 				}
@@ -476,13 +485,22 @@ func (self *worker) commitNewWork() {
 	header := &types.Header{
 		ParentHash: parent.Hash(),
 		Number:     num.Add(num, common.Big1),
-		Difficulty: core.CalcDifficulty(uint64(tstamp), parent.Time().Uint64(), parent.Number(), parent.Difficulty()),
-		GasLimit:   core.CalcGasLimit(parent),
+		GasLimit:   core.CalcGasLimit(self.chain.Config(), parent),
 		GasUsed:    new(big.Int),
 		Coinbase:   self.coinbase,
 		Extra:      self.extra,
 		Time:       big.NewInt(tstamp),
 	}
+	// A fork may have lowered the extra-data limit since it was set
+	if limit := self.chain.Config().Rules(header.Number).MaximumExtraDataSize; big.NewInt(int64(len(header.Extra))).Cmp(limit) > 0 {
+		glog.V(logger.Warn).Infof("Extra-data exceeds the limit of %v at block #%v, mining without", limit, header.Number)
+		header.Extra = nil
+	}
+	// Let the consensus engine fill in the difficulty and its own fields
+	if err := self.engine.Prepare(self.chain, header); err != nil {
+		glog.V(logger.Error).Infoln("Failed to prepare header for mining:", err)
+		return
+	}
 
 	previous := self.current
 	// Could potentially happen if starting to mine in an odd state.
@@ -538,6 +556,10 @@ func (self *worker) commitNewWork() {
 		badUncles []common.Hash
 	)
 	for hash, uncle := range self.possibleUncles {
+		// Only proof-of-work engines accept uncles
+		if _, ok := self.engine.(consensus.PoW); !ok {
+			break
+		}
 		if len(uncles) == 2 {
 			break
 		}
@@ -558,7 +580,10 @@ func (self *worker) commitNewWork() {
 
 	if atomic.LoadInt32(&self.mining) == 1 {
 		// commit state root after all state transitions.
-		core.AccumulateRewards(work.state, header, uncles)
+		if err := self.engine.Finalize(self.chain, work.state, header, uncles); err != nil {
+			glog.V(logger.Error).Infoln("Failed to finalize block for sealing:", err)
+			return
+		}
 		header.Root = work.state.IntermediateRoot()
 	}
 
@@ -36,6 +36,9 @@ const (
 	// Discovery lookups are throttled and can only run
 	// once every few seconds.
 	lookupInterval = 4 * time.Second
+
+	// Maximum number of nodes returned by a topic search.
+	topicSearchResults = 16
 )
 
 // dialstate schedules dials and discovery lookups.
@@ -63,6 +66,12 @@ type discoverTable interface {
 	ReadRandomNodes([]*discover.Node) int
 }
 
+// topicSearcher is implemented by discovery tables able to search
+// for nodes advertised under a topic.
+type topicSearcher interface {
+	SearchTopic(topic discover.Topic, max int) []*discover.Node
+}
+
 // the dial history remembers recent dials.
 type dialHistory []pastDial
 
@@ -86,8 +95,8 @@ type dialTask struct {
 // Only one discoverTask is active at any time.
 //
 // If bootstrap is true, the task runs Table.Bootstrap,
-// otherwise it performs a random lookup and leaves the
-// results in the task.
+// otherwise it searches for the server's search topic, if any,
+// performs a random lookup and leaves the results in the task.
 type discoverTask struct {
 	bootstrap bool
 	results   []*discover.Node
@@ -118,6 +127,10 @@ func (s *dialstate) addStatic(n *discover.Node) {
 	s.static[n.ID] = n
 }
 
+func (s *dialstate) removeStatic(n *discover.Node) {
+	delete(s.static, n.ID)
+}
+
 func (s *dialstate) newTasks(nRunning int, peers map[discover.NodeID]*Peer, now time.Time) []task {
 	var newtasks []task
 	addDial := func(flag connFlag, n *discover.Node) bool {
@@ -205,6 +218,18 @@ func (s *dialstate) taskDone(t task, now time.Time) {
 }
 
 func (t *dialTask) Do(srv *Server) {
+	if srv.isBanned(t.dest.ID) {
+		glog.V(logger.Detail).Infof("not dialing banned node %x", t.dest.ID[:8])
+		return
+	}
+	if !srv.permitsIP(t.dest.IP) {
+		glog.V(logger.Detail).Infof("not dialing node %x: %v", t.dest.ID[:8], errNetRestrict)
+		return
+	}
+	if t.flags&staticDialedConn == 0 && srv.dialSubnetFull(t.dest.IP) {
+		glog.V(logger.Detail).Infof("not dialing node %x: subnet full", t.dest.ID[:8])
+		return
+	}
 	addr := &net.TCPAddr{IP: t.dest.IP, Port: int(t.dest.TCP)}
 	glog.V(logger.Debug).Infof("dialing %v\n", t.dest)
 	fd, err := srv.Dialer.Dial("tcp", addr.String())
@@ -233,9 +258,24 @@ func (t *discoverTask) Do(srv *Server) {
 		time.Sleep(next.Sub(now))
 	}
 	srv.lastLookup = time.Now()
+	// Nodes advertising the search topic come first, the random
+	// lookup keeps the table fresh and finds other peers.
+	t.results = nil
+	seen := make(map[discover.NodeID]bool)
+	if tab, ok := srv.ntab.(topicSearcher); ok && srv.SearchTopic != "" {
+		for _, n := range tab.SearchTopic(srv.SearchTopic, topicSearchResults) {
+			seen[n.ID] = true
+			t.results = append(t.results, n)
+		}
+	}
 	var target discover.NodeID
 	rand.Read(target[:])
-	t.results = srv.ntab.Lookup(target)
+	for _, n := range srv.ntab.Lookup(target) {
+		if !seen[n.ID] {
+			seen[n.ID] = true
+			t.results = append(t.results, n)
+		}
+	}
 }
 
 func (t *discoverTask) String() (s string) {
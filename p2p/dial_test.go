@@ -474,6 +474,54 @@ func TestDialStateCache(t *testing.T) {
 	})
 }
 
+// This test checks that removed static nodes are no longer dialed.
+func TestDialStateRemoveStatic(t *testing.T) {
+	state := newDialState([]*discover.Node{{ID: uintID(1)}, {ID: uintID(2)}}, fakeTable{}, 0)
+	state.removeStatic(&discover.Node{ID: uintID(2)})
+
+	want := []task{&dialTask{staticDialedConn, &discover.Node{ID: uintID(1)}}}
+	if new := state.newTasks(0, nil, time.Time{}); !sametasks(new, want) {
+		t.Errorf("new tasks mismatch:\ngot %v\nwant %v", spew.Sdump(new), spew.Sdump(want))
+	}
+}
+
+// fakeTopicTable is a discovery table supporting topic search.
+type fakeTopicTable struct {
+	fakeTable
+	lookup []*discover.Node
+	topics map[discover.Topic][]*discover.Node
+}
+
+func (t fakeTopicTable) Lookup(target discover.NodeID) []*discover.Node {
+	return t.lookup
+}
+func (t fakeTopicTable) SearchTopic(topic discover.Topic, max int) []*discover.Node {
+	return t.topics[topic]
+}
+
+func TestDiscoverTaskTopicSearch(t *testing.T) {
+	tab := fakeTopicTable{
+		lookup: []*discover.Node{{ID: uintID(1)}, {ID: uintID(2)}},
+		topics: map[discover.Topic][]*discover.Node{"pbf@1": {{ID: uintID(3)}, {ID: uintID(2)}}},
+	}
+	// Nodes advertising the search topic come first, followed by
+	// the lookup results not found by the search.
+	srv := &Server{ntab: tab, SearchTopic: "pbf@1"}
+	task := new(discoverTask)
+	task.Do(srv)
+	want := []*discover.Node{{ID: uintID(3)}, {ID: uintID(2)}, {ID: uintID(1)}}
+	if !reflect.DeepEqual(task.results, want) {
+		t.Errorf("topic search results mismatch:\ngot %v\nwant %v", task.results, want)
+	}
+	// Only the lookup results are used if no node advertises the topic.
+	srv = &Server{ntab: tab, SearchTopic: "pbf@2"}
+	task = new(discoverTask)
+	task.Do(srv)
+	if !reflect.DeepEqual(task.results, tab.lookup) {
+		t.Errorf("lookup results mismatch:\ngot %v\nwant %v", task.results, tab.lookup)
+	}
+}
+
 // compares task lists but doesn't care about the order.
 func sametasks(a, b []task) bool {
 	if len(a) != len(b) {
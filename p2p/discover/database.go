@@ -57,6 +57,7 @@ type nodeDB struct {
 var (
 	nodeDBVersionKey = []byte("version") // Version of the database to flush if changes
 	nodeDBItemPrefix = []byte("n:")      // Identifier to prefix node entries with
+	nodeDBBanPrefix  = []byte("b:")      // Identifier to prefix node ban entries with
 
 	nodeDBDiscoverRoot      = ":discover"
 	nodeDBDiscoverPing      = nodeDBDiscoverRoot + ":lastping"
@@ -300,6 +301,61 @@ func (db *nodeDB) updateFindFails(id NodeID, fails int) error {
 	return db.storeInt64(makeKey(id, nodeDBDiscoverFindFails), int64(fails))
 }
 
+// banKey generates the leveldb key-blob of a node's ban entry. Bans are kept
+// outside of the node entries so that they are not dropped by the expirer.
+func banKey(id NodeID) []byte {
+	return append(append([]byte{}, nodeDBBanPrefix...), id[:]...)
+}
+
+// banExpiry retrieves the time until which a node is banned. The zero time is
+// returned if the node has never been banned.
+func (db *nodeDB) banExpiry(id NodeID) time.Time {
+	expiry := db.fetchInt64(banKey(id))
+	if expiry == 0 {
+		return time.Time{}
+	}
+	return time.Unix(expiry, 0)
+}
+
+// banned reports whether the node is currently banned.
+func (db *nodeDB) banned(id NodeID) bool {
+	return db.banExpiry(id).After(time.Now())
+}
+
+// updateBan bans a node until the given time.
+func (db *nodeDB) updateBan(id NodeID, until time.Time) error {
+	return db.storeInt64(banKey(id), until.Unix())
+}
+
+// deleteBan lifts the ban of a node.
+func (db *nodeDB) deleteBan(id NodeID) error {
+	return db.lvl.Delete(banKey(id), nil)
+}
+
+// bans retrieves all nodes currently banned along with their ban expiration,
+// deleting any bans that have already expired.
+func (db *nodeDB) bans() map[NodeID]time.Time {
+	var (
+		now  = time.Now()
+		bans = make(map[NodeID]time.Time)
+		it   = db.lvl.NewIterator(util.BytesPrefix(nodeDBBanPrefix), nil)
+	)
+	defer it.Release()
+
+	for it.Next() {
+		var id NodeID
+		copy(id[:], it.Key()[len(nodeDBBanPrefix):])
+
+		expiry, read := binary.Varint(it.Value())
+		if read <= 0 || time.Unix(expiry, 0).Before(now) {
+			db.lvl.Delete(it.Key(), nil)
+			continue
+		}
+		bans[id] = time.Unix(expiry, 0)
+	}
+	return bans
+}
+
 // querySeeds retrieves random nodes to be used as potential seed nodes
 // for bootstrapping.
 func (db *nodeDB) querySeeds(n int, maxAge time.Duration) []*Node {
@@ -378,3 +378,47 @@ func TestNodeDBSelfExpiration(t *testing.T) {
 		t.Errorf("self not evacuated")
 	}
 }
+
+func TestNodeDBBans(t *testing.T) {
+	db, _ := newNodeDB("", Version, NodeID{})
+	defer db.close()
+
+	var (
+		active  = MustHexID("0x1dd9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439")
+		expired = nodeDBExpirationNodes[0].node.ID
+		until   = time.Now().Add(time.Hour)
+	)
+	if db.banned(active) {
+		t.Fatalf("node banned before ban")
+	}
+	if err := db.updateBan(active, until); err != nil {
+		t.Fatalf("failed to ban node: %v", err)
+	}
+	if err := db.updateBan(expired, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("failed to ban node: %v", err)
+	}
+	if !db.banned(active) {
+		t.Errorf("active ban not reported")
+	}
+	if db.banned(expired) {
+		t.Errorf("expired ban reported")
+	}
+	// Bans must survive node expiration and expired bans must be dropped
+	if err := db.expireNodes(); err != nil {
+		t.Fatalf("failed to expire nodes: %v", err)
+	}
+	bans := db.bans()
+	if len(bans) != 1 || bans[active].Unix() != until.Unix() {
+		t.Errorf("ban list mismatch: have %v, want only %x until %v", bans, active[:8], until)
+	}
+	if stored := db.banExpiry(expired); !stored.IsZero() {
+		t.Errorf("expired ban not deleted: %v", stored)
+	}
+	// Lift the remaining ban and make sure it's gone
+	if err := db.deleteBan(active); err != nil {
+		t.Fatalf("failed to lift ban: %v", err)
+	}
+	if db.banned(active) {
+		t.Errorf("lifted ban still reported")
+	}
+}
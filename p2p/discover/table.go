@@ -25,6 +25,7 @@ package discover
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"net"
 	"sort"
 	"sync"
@@ -34,6 +35,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/crypto"
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/p2p/netutil"
 )
 
 const (
@@ -50,6 +52,8 @@ const (
 	seedMaxAge          = 5 * 24 * time.Hour
 )
 
+var errBannedNode = errors.New("node is banned")
+
 type Table struct {
 	mutex   sync.Mutex        // protects buckets, their content, and nursery
 	buckets [nBuckets]*bucket // index of known nodes by distance
@@ -66,6 +70,14 @@ type Table struct {
 
 	nodeAddedHook func(*Node) // for testing
 
+	netrestrict *netutil.Netlist // if non-nil, only nodes within these networks are accepted
+	netdeny     *netutil.Netlist // nodes within these networks are never accepted
+
+	topics    *topicTable    // registrations held for advertising nodes
+	advertMu  sync.Mutex     // protects adverts
+	adverts   map[Topic]bool // topics the local node is advertised under
+	advertReq chan struct{}
+
 	net  transport
 	self *Node // metadata of the local node
 }
@@ -83,6 +95,8 @@ type transport interface {
 	ping(NodeID, *net.UDPAddr) error
 	waitping(NodeID) error
 	findnode(toid NodeID, addr *net.UDPAddr, target NodeID) ([]*Node, error)
+	topicRegister(toid NodeID, addr *net.UDPAddr, topic Topic) error
+	topicQuery(toid NodeID, addr *net.UDPAddr, topic Topic) ([]*Node, error)
 	close()
 }
 
@@ -103,6 +117,9 @@ func newTable(t transport, ourID NodeID, ourAddr *net.UDPAddr, nodeDBPath string
 		self:       newNode(ourID, ourAddr.IP, uint16(ourAddr.Port), uint16(ourAddr.Port)),
 		bonding:    make(map[NodeID]*bondproc),
 		bondslots:  make(chan struct{}, maxBondingPingPongs),
+		topics:     newTopicTable(),
+		adverts:    make(map[Topic]bool),
+		advertReq:  make(chan struct{}),
 		refreshReq: make(chan struct{}),
 		closeReq:   make(chan struct{}),
 		closed:     make(chan struct{}),
@@ -180,6 +197,28 @@ func (tab *Table) Close() {
 	}
 }
 
+// BanNode bans the given node until the given time. The ban is stored in the
+// node database and banned nodes are evicted from the table and will not be
+// bonded with until the ban expires.
+func (tab *Table) BanNode(id NodeID, until time.Time) error {
+	if err := tab.db.updateBan(id, until); err != nil {
+		return err
+	}
+	tab.delete(&Node{ID: id, sha: crypto.Sha3Hash(id[:])})
+	return nil
+}
+
+// UnbanNode lifts the ban of the given node.
+func (tab *Table) UnbanNode(id NodeID) error {
+	return tab.db.deleteBan(id)
+}
+
+// BannedNodes returns all nodes currently banned, along with the time their
+// bans expire.
+func (tab *Table) BannedNodes() map[NodeID]time.Time {
+	return tab.db.bans()
+}
+
 // Bootstrap sets the bootstrap nodes. These nodes are used to connect
 // to the network if the table is empty. Bootstrap will also attempt to
 // fill the table by performing random lookup operations on the
@@ -283,7 +322,25 @@ func (tab *Table) refreshLoop() {
 	}()
 
 	timer := time.NewTicker(autoRefreshInterval)
-	var done chan struct{}
+	advert := time.NewTicker(topicRefreshInterval)
+	defer timer.Stop()
+	defer advert.Stop()
+
+	var (
+		done          chan struct{}
+		advertDone    chan bool // reports whether all topics reached registrars
+		advertPending bool      // topics changed while advertising
+		advertRetry   bool      // last advertisement missed registrars, retry after refresh
+	)
+	advertise := func() {
+		advertDone = make(chan bool, 1)
+		go tab.doAdvertise(advertDone)
+	}
+	filled := func() bool {
+		tab.mutex.Lock()
+		defer tab.mutex.Unlock()
+		return tab.len() > 0
+	}
 	for {
 		select {
 		case <-timer.C:
@@ -298,10 +355,41 @@ func (tab *Table) refreshLoop() {
 			}
 		case <-done:
 			done = nil
+			// Topics advertised while the table was still empty reached no
+			// registrars, don't wait for the next advertisement round.
+			if advertRetry && advertDone == nil && filled() {
+				advertRetry = false
+				advertise()
+			}
+		case <-advert.C:
+			if advertDone == nil {
+				advertise()
+			}
+		case <-tab.advertReq:
+			if advertDone == nil {
+				advertise()
+			} else {
+				advertPending = true
+			}
+		case complete := <-advertDone:
+			advertDone = nil
+			switch {
+			case advertPending:
+				advertPending = false
+				advertise()
+			case !complete && done == nil && filled():
+				// The table was filled while advertising
+				advertise()
+			default:
+				advertRetry = !complete
+			}
 		case <-tab.closeReq:
 			if done != nil {
 				<-done
 			}
+			if advertDone != nil {
+				<-advertDone
+			}
 			return
 		}
 	}
@@ -405,6 +493,13 @@ func (tab *Table) bondall(nodes []*Node) (result []*Node) {
 // If pinged is true, the remote node has just pinged us and one half
 // of the process can be skipped.
 func (tab *Table) bond(pinged bool, id NodeID, addr *net.UDPAddr, tcpPort uint16) (*Node, error) {
+	// Refuse to bond with nodes banned by the server or off the permitted networks
+	if tab.db.banned(id) {
+		return nil, errBannedNode
+	}
+	if !tab.permits(addr.IP) {
+		return nil, errNetRestrict
+	}
 	// Retrieve a previously known node and any recent findnode failures
 	node, fails := tab.db.node(id), 0
 	if node != nil {
@@ -450,6 +545,12 @@ func (tab *Table) bond(pinged bool, id NodeID, addr *net.UDPAddr, tcpPort uint16
 	return node, result
 }
 
+// permits reports whether the network restrictions allow bonding with a node
+// at the given IP.
+func (tab *Table) permits(ip net.IP) bool {
+	return netutil.Permits(tab.netrestrict, tab.netdeny, ip)
+}
+
 func (tab *Table) pingpong(w *bondproc, pinged bool, id NodeID, addr *net.UDPAddr, tcpPort uint16) {
 	// Request a bonding slot to limit network usage
 	<-tab.bondslots
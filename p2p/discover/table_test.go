@@ -29,6 +29,7 @@ import (
 
 	"github.com/pbfcoin/go-pbfcoin/common"
 	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/p2p/netutil"
 )
 
 func TestTable_pingReplace(t *testing.T) {
@@ -88,6 +89,28 @@ func TestTable_pingReplace(t *testing.T) {
 	doit(false, false)
 }
 
+func TestTable_bondRestrictions(t *testing.T) {
+	transport := newPingRecorder()
+	tab := newTable(transport, NodeID{}, &net.UDPAddr{}, "")
+	defer tab.Close()
+
+	tab.netrestrict, _ = netutil.ParseNetlist("10.0.0.0/8")
+	var (
+		offlist = MustHexID("a502af0f59b2aab7746995408c79e9ca312d2793cc997e44fc55eda62f0150bbb8c59a6f9269ba3a081518b62699ee807c7c19c20125ddfccca872608af9e370")
+		banned  = MustHexID("1dd9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439")
+	)
+	if _, err := tab.bond(true, offlist, &net.UDPAddr{IP: net.IP{192, 168, 0, 1}}, 0); err != errNetRestrict {
+		t.Errorf("wrong error for off-list node: %v", err)
+	}
+	tab.BanNode(banned, time.Now().Add(time.Hour))
+	if _, err := tab.bond(true, banned, &net.UDPAddr{IP: net.IP{10, 0, 0, 1}}, 0); err != errBannedNode {
+		t.Errorf("wrong error for banned node: %v", err)
+	}
+	if transport.pinged[offlist] || transport.pinged[banned] {
+		t.Error("table pinged refused node")
+	}
+}
+
 func TestBucket_bumpNoDuplicates(t *testing.T) {
 	t.Parallel()
 	cfg := &quick.Config{
@@ -159,6 +182,12 @@ func newPingRecorder() *pingRecorder {
 func (t *pingRecorder) findnode(toid NodeID, toaddr *net.UDPAddr, target NodeID) ([]*Node, error) {
 	panic("findnode called on pingRecorder")
 }
+func (t *pingRecorder) topicRegister(toid NodeID, toaddr *net.UDPAddr, topic Topic) error {
+	panic("topicRegister called on pingRecorder")
+}
+func (t *pingRecorder) topicQuery(toid NodeID, toaddr *net.UDPAddr, topic Topic) ([]*Node, error) {
+	panic("topicQuery called on pingRecorder")
+}
 func (t *pingRecorder) close() {}
 func (t *pingRecorder) waitping(from NodeID) error {
 	return nil // remote always pings
@@ -522,6 +551,12 @@ func (tn *preminedTestnet) findnode(toid NodeID, toaddr *net.UDPAddr, target Nod
 	return result, nil
 }
 
+func (*preminedTestnet) topicRegister(toid NodeID, toaddr *net.UDPAddr, topic Topic) error {
+	return nil
+}
+func (*preminedTestnet) topicQuery(toid NodeID, toaddr *net.UDPAddr, topic Topic) ([]*Node, error) {
+	return nil, nil
+}
 func (*preminedTestnet) close()                                      {}
 func (*preminedTestnet) waitping(from NodeID) error                  { return nil }
 func (*preminedTestnet) ping(toid NodeID, toaddr *net.UDPAddr) error { return nil }
@@ -0,0 +1,323 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the topic advertisement and search extension of the discovery
+// protocol.
+//
+// Nodes advertise a topic by registering themselves with the nodes closest to
+// the hash of the topic, the topic's registrars. Searching for a topic looks up
+// the registrars the same way and queries them for the nodes registered.
+
+package discover
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/logger"
+	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+)
+
+const (
+	maxTopicLength   = 64  // Maximum length of a topic in bytes
+	maxTopics        = 256 // Maximum number of topics a registrar keeps registrations for
+	maxTopicEntries  = 64  // Maximum number of nodes registered under a single topic
+	maxTopicAdverts  = 16  // Maximum number of topics advertised by the local node
+	maxTopicSearches = 64  // Maximum number of results returned by a topic search
+	maxNodeTopics    = 16  // Maximum number of topics a single node may be registered under
+
+	topicTTL             = 20 * time.Minute // Time a registration is kept by registrars
+	topicRefreshInterval = 10 * time.Minute // Time between re-registrations of advertised topics
+)
+
+var (
+	errBadTopic      = errors.New("invalid topic")
+	errTooManyTopics = errors.New("too many advertised topics")
+	errNodeTopics    = errors.New("too many registrations by node")
+)
+
+// Topic is an identifier nodes advertise themselves under, so that others can
+// search for nodes providing a particular service. A topic is usually the name
+// of a protocol combined with a network identifier, e.g. "pbf@1".
+type Topic string
+
+// target returns the lookup target locating the registrars of the topic.
+func (t Topic) target() (id NodeID) {
+	copy(id[:], crypto.Sha3([]byte(t)))
+	return id
+}
+
+// valid reports whether the topic can be advertised and searched for.
+func (t Topic) valid() bool {
+	return len(t) > 0 && len(t) <= maxTopicLength
+}
+
+// topicEntry is a node registration held by a registrar.
+type topicEntry struct {
+	node    *Node
+	expires time.Time
+}
+
+// topicTable holds the registrations received from advertising nodes.
+type topicTable struct {
+	lock    sync.Mutex
+	entries map[Topic]map[NodeID]*topicEntry
+}
+
+func newTopicTable() *topicTable {
+	return &topicTable{entries: make(map[Topic]map[NodeID]*topicEntry)}
+}
+
+// add registers the node under the topic, or refreshes its registration. If the
+// topic is full, the oldest registration under it is replaced. If the table is
+// full, the topic least recently registered under is dropped to make room. New
+// registrations are refused only if the node is registered under too many topics.
+func (t *topicTable) add(topic Topic, n *Node, now time.Time) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.expire(now)
+	if entry := t.entries[topic][n.ID]; entry != nil {
+		entry.node, entry.expires = n, now.Add(topicTTL)
+		return nil
+	}
+	if t.count(n.ID) >= maxNodeTopics {
+		return errNodeTopics
+	}
+	nodes := t.entries[topic]
+	if nodes == nil {
+		if len(t.entries) >= maxTopics {
+			t.evictTopic()
+		}
+		nodes = make(map[NodeID]*topicEntry)
+		t.entries[topic] = nodes
+	}
+	if len(nodes) >= maxTopicEntries {
+		delete(nodes, oldestEntry(nodes))
+	}
+	nodes[n.ID] = &topicEntry{node: n, expires: now.Add(topicTTL)}
+	return nil
+}
+
+// evictTopic drops the topic whose most recent registration is the oldest.
+// The caller must hold t.lock.
+func (t *topicTable) evictTopic() {
+	var (
+		oldest  Topic
+		expires time.Time
+	)
+	for topic, nodes := range t.entries {
+		latest := nodes[newestEntry(nodes)].expires
+		if expires.IsZero() || latest.Before(expires) {
+			oldest, expires = topic, latest
+		}
+	}
+	delete(t.entries, oldest)
+}
+
+// oldestEntry returns the node whose registration expires first.
+func oldestEntry(nodes map[NodeID]*topicEntry) (oldest NodeID) {
+	var expires time.Time
+	for id, entry := range nodes {
+		if expires.IsZero() || entry.expires.Before(expires) {
+			oldest, expires = id, entry.expires
+		}
+	}
+	return oldest
+}
+
+// newestEntry returns the node whose registration expires last.
+func newestEntry(nodes map[NodeID]*topicEntry) (newest NodeID) {
+	var expires time.Time
+	for id, entry := range nodes {
+		if entry.expires.After(expires) {
+			newest, expires = id, entry.expires
+		}
+	}
+	return newest
+}
+
+// count returns the number of topics the node is registered under.
+// The caller must hold t.lock.
+func (t *topicTable) count(id NodeID) int {
+	count := 0
+	for _, nodes := range t.entries {
+		if nodes[id] != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// get returns up to max nodes registered under the topic, most recently
+// registered first.
+func (t *topicTable) get(topic Topic, max int, now time.Time) []*Node {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.expire(now)
+	entries := make([]*topicEntry, 0, len(t.entries[topic]))
+	for _, entry := range t.entries[topic] {
+		entries = append(entries, entry)
+	}
+	sort.Sort(topicEntriesByExpiry(entries))
+
+	nodes := make([]*Node, 0, max)
+	for i := 0; i < len(entries) && i < max; i++ {
+		cpy := *entries[i].node
+		nodes = append(nodes, &cpy)
+	}
+	return nodes
+}
+
+// expire drops all registrations that haven't been refreshed in time.
+// The caller must hold t.lock.
+func (t *topicTable) expire(now time.Time) {
+	for topic, nodes := range t.entries {
+		for id, entry := range nodes {
+			if !entry.expires.After(now) {
+				delete(nodes, id)
+			}
+		}
+		if len(nodes) == 0 {
+			delete(t.entries, topic)
+		}
+	}
+}
+
+type topicEntriesByExpiry []*topicEntry
+
+func (s topicEntriesByExpiry) Len() int           { return len(s) }
+func (s topicEntriesByExpiry) Less(i, j int) bool { return s[i].expires.After(s[j].expires) }
+func (s topicEntriesByExpiry) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// RegisterTopic starts advertising the local node under the given topic. The
+// node registers with the topic's registrars right away and keeps refreshing
+// its registrations until the topic is unregistered.
+func (tab *Table) RegisterTopic(topic Topic) error {
+	if !topic.valid() {
+		return errBadTopic
+	}
+	tab.advertMu.Lock()
+	if !tab.adverts[topic] && len(tab.adverts) >= maxTopicAdverts {
+		tab.advertMu.Unlock()
+		return errTooManyTopics
+	}
+	tab.adverts[topic] = true
+	tab.advertMu.Unlock()
+
+	select {
+	case tab.advertReq <- struct{}{}:
+	case <-tab.closed:
+	}
+	return nil
+}
+
+// UnregisterTopic stops advertising the local node under the given topic.
+// Registrations already made expire on their own.
+func (tab *Table) UnregisterTopic(topic Topic) {
+	tab.advertMu.Lock()
+	defer tab.advertMu.Unlock()
+
+	delete(tab.adverts, topic)
+}
+
+// Topics returns the topics the local node is advertised under.
+func (tab *Table) Topics() []Topic {
+	tab.advertMu.Lock()
+	defer tab.advertMu.Unlock()
+
+	topics := make([]Topic, 0, len(tab.adverts))
+	for topic := range tab.adverts {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// advertises reports whether the local node is advertised under the topic.
+func (tab *Table) advertises(topic Topic) bool {
+	tab.advertMu.Lock()
+	defer tab.advertMu.Unlock()
+
+	return tab.adverts[topic]
+}
+
+// SearchTopic performs a network search for nodes advertised under the given
+// topic. It locates the registrars of the topic and queries them for up to max
+// registered nodes.
+func (tab *Table) SearchTopic(topic Topic, max int) []*Node {
+	if !topic.valid() {
+		return nil
+	}
+	if max > maxTopicSearches {
+		max = maxTopicSearches
+	}
+	var (
+		seen   = map[NodeID]bool{tab.self.ID: true}
+		result []*Node
+	)
+	// Registrations made with the local node are free to check.
+	for _, n := range tab.topics.get(topic, max, time.Now()) {
+		if tab.permits(n.IP) && !tab.db.banned(n.ID) {
+			seen[n.ID] = true
+			result = append(result, n)
+		}
+	}
+	registrars := tab.Lookup(topic.target())
+	reply := make(chan []*Node, len(registrars))
+	for _, n := range registrars {
+		go func(n *Node) {
+			r, err := tab.net.topicQuery(n.ID, n.addr(), topic)
+			if err != nil {
+				glog.V(logger.Detail).Infof("topic query %q to %x failed: %v", topic, n.ID[:8], err)
+			}
+			reply <- r
+		}(n)
+	}
+	for _ = range registrars {
+		for _, n := range <-reply {
+			if len(result) < max && !seen[n.ID] && !tab.db.banned(n.ID) {
+				seen[n.ID] = true
+				result = append(result, n)
+			}
+		}
+	}
+	return result
+}
+
+// doAdvertise registers the local node with the registrars of all topics it is
+// advertised under. It reports on done whether registrars were found for every
+// topic, lookups come back empty while the table is still being filled.
+func (tab *Table) doAdvertise(done chan<- bool) {
+	complete := true
+	defer func() { done <- complete }()
+
+	for _, topic := range tab.Topics() {
+		registrars := tab.Lookup(topic.target())
+		if len(registrars) == 0 {
+			complete = false
+		}
+		for _, n := range registrars {
+			if err := tab.net.topicRegister(n.ID, n.addr(), topic); err != nil {
+				glog.V(logger.Detail).Infof("topic registration %q with %x failed: %v", topic, n.ID[:8], err)
+			}
+		}
+		glog.V(logger.Debug).Infof("advertised topic %q to %d registrars", topic, len(registrars))
+	}
+}
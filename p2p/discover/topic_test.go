@@ -0,0 +1,197 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTopicTable(t *testing.T) {
+	var (
+		tab  = newTopicTable()
+		now  = time.Now()
+		node = func(i byte) *Node { return newNode(NodeID{i}, net.IP{10, 0, 0, i}, 30303, 30303) }
+	)
+	// Registrations are returned most recent first.
+	for i := byte(1); i <= 3; i++ {
+		if err := tab.add("pbf@1", node(i), now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("registration %d refused: %v", i, err)
+		}
+	}
+	nodes := tab.get("pbf@1", 2, now.Add(3*time.Second))
+	if len(nodes) != 2 || nodes[0].ID != node(3).ID || nodes[1].ID != node(2).ID {
+		t.Fatalf("wrong registered nodes: %v", nodes)
+	}
+	if nodes := tab.get("shh@1", 10, now); len(nodes) != 0 {
+		t.Errorf("unregistered topic returned nodes: %v", nodes)
+	}
+
+	// Refreshed registrations outlive stale ones.
+	tab.add("pbf@1", node(1), now.Add(10*time.Second))
+	nodes = tab.get("pbf@1", 10, now.Add(topicTTL+5*time.Second))
+	if len(nodes) != 1 || nodes[0].ID != node(1).ID {
+		t.Fatalf("wrong nodes after expiry: %v", nodes)
+	}
+	if nodes := tab.get("pbf@1", 10, now.Add(topicTTL+10*time.Second)); len(nodes) != 0 {
+		t.Fatalf("expired registrations returned: %v", nodes)
+	}
+	if len(tab.entries) != 0 {
+		t.Errorf("expired topic still tracked")
+	}
+
+	// Full topics replace the oldest registration with new nodes.
+	for i := 0; i < maxTopicEntries; i++ {
+		tab.add("pbf@1", node(byte(i)), now.Add(time.Duration(i)*time.Second))
+	}
+	if err := tab.add("pbf@1", node(0), now.Add(time.Minute)); err != nil {
+		t.Errorf("refresh refused by full topic: %v", err)
+	}
+	if err := tab.add("pbf@1", node(maxTopicEntries), now.Add(time.Minute)); err != nil {
+		t.Errorf("registration refused by full topic: %v", err)
+	}
+	nodes = tab.get("pbf@1", maxTopicEntries+1, now.Add(time.Minute))
+	if len(nodes) != maxTopicEntries {
+		t.Fatalf("registration count mismatch: have %d, want %d", len(nodes), maxTopicEntries)
+	}
+	for _, n := range nodes {
+		if n.ID == node(1).ID {
+			t.Errorf("oldest registration not replaced")
+		}
+	}
+}
+
+func TestTopicTableFull(t *testing.T) {
+	var (
+		tab = newTopicTable()
+		now = time.Now()
+	)
+	for i := 0; i < maxTopics; i++ {
+		n := newNode(NodeID{byte(i), byte(i >> 8)}, net.IP{10, 0, byte(i >> 8), byte(i)}, 30303, 30303)
+		if err := tab.add(Topic(fmt.Sprintf("topic%d", i)), n, now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("registration %d refused: %v", i, err)
+		}
+	}
+	// A full table makes room by dropping the topic registered under the longest ago.
+	n := newNode(NodeID{0xff, 0xff}, net.IP{10, 0, 0xff, 0xff}, 30303, 30303)
+	if err := tab.add("pbf@1", n, now.Add(5*time.Minute)); err != nil {
+		t.Fatalf("registration refused by full table: %v", err)
+	}
+	if len(tab.entries) != maxTopics {
+		t.Errorf("topic count mismatch: have %d, want %d", len(tab.entries), maxTopics)
+	}
+	if nodes := tab.get("topic0", 10, now.Add(5*time.Minute)); len(nodes) != 0 {
+		t.Errorf("oldest topic not dropped: %v", nodes)
+	}
+	if nodes := tab.get("pbf@1", 10, now.Add(5*time.Minute)); len(nodes) != 1 || nodes[0].ID != n.ID {
+		t.Errorf("wrong nodes registered under new topic: %v", nodes)
+	}
+}
+
+func TestTopicTableNodeLimit(t *testing.T) {
+	var (
+		tab   = newTopicTable()
+		now   = time.Now()
+		n     = newNode(NodeID{1}, net.IP{10, 0, 0, 1}, 30303, 30303)
+		other = newNode(NodeID{2}, net.IP{10, 0, 0, 2}, 30303, 30303)
+	)
+	for i := 0; i < maxNodeTopics; i++ {
+		if err := tab.add(Topic(fmt.Sprintf("topic%d", i)), n, now); err != nil {
+			t.Fatalf("registration %d refused: %v", i, err)
+		}
+	}
+	if err := tab.add("pbf@1", n, now); err != errNodeTopics {
+		t.Errorf("registration beyond node limit: got error %v, want %v", err, errNodeTopics)
+	}
+	if err := tab.add("topic0", n, now); err != nil {
+		t.Errorf("refresh refused at node limit: %v", err)
+	}
+	if err := tab.add("pbf@1", other, now); err != nil {
+		t.Errorf("registration by other node refused: %v", err)
+	}
+	// Expired registrations no longer count towards the limit.
+	if err := tab.add("pbf@1", n, now.Add(topicTTL)); err != nil {
+		t.Errorf("registration after expiry refused: %v", err)
+	}
+}
+
+// This test checks that topics advertised while the table is still empty get
+// advertised again once the table has been filled.
+func TestTopicAdvertiseRetry(t *testing.T) {
+	transport := &advertRecorder{newPingRecorder(), make(chan Topic, 1)}
+	tab := newTable(transport, NodeID{}, &net.UDPAddr{}, "")
+	defer tab.Close()
+
+	if err := tab.RegisterTopic("pbf@1"); err != nil {
+		t.Fatalf("failed to register topic: %v", err)
+	}
+	select {
+	case topic := <-transport.registered:
+		t.Fatalf("topic %q registered without known nodes", topic)
+	case <-time.After(100 * time.Millisecond):
+	}
+	tab.mutex.Lock()
+	fillBucket(tab, 200)
+	tab.mutex.Unlock()
+	tab.requestRefresh()
+
+	select {
+	case topic := <-transport.registered:
+		if topic != "pbf@1" {
+			t.Errorf("wrong topic registered: have %q, want %q", topic, "pbf@1")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("topic not advertised after the table was filled")
+	}
+}
+
+// advertRecorder is a transport without remote nodes, which records the topic
+// registrations sent.
+type advertRecorder struct {
+	*pingRecorder
+	registered chan Topic
+}
+
+func (t *advertRecorder) findnode(toid NodeID, toaddr *net.UDPAddr, target NodeID) ([]*Node, error) {
+	return nil, nil
+}
+func (t *advertRecorder) topicRegister(toid NodeID, toaddr *net.UDPAddr, topic Topic) error {
+	select {
+	case t.registered <- topic:
+	default:
+	}
+	return nil
+}
+
+func TestTopicValid(t *testing.T) {
+	tests := []struct {
+		topic Topic
+		valid bool
+	}{
+		{"", false},
+		{"pbf@1", true},
+		{Topic(make([]byte, maxTopicLength)), true},
+		{Topic(make([]byte, maxTopicLength+1)), false},
+	}
+	for _, tt := range tests {
+		if valid := tt.topic.valid(); valid != tt.valid {
+			t.Errorf("topic %q: valid %t, want %t", tt.topic, valid, tt.valid)
+		}
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
 	"github.com/pbfcoin/go-pbfcoin/p2p/nat"
+	"github.com/pbfcoin/go-pbfcoin/p2p/netutil"
 	"github.com/pbfcoin/go-pbfcoin/rlp"
 )
 
@@ -44,6 +45,7 @@ var (
 	errTimeout          = errors.New("RPC timeout")
 	errClockWarp        = errors.New("reply deadline too far in the future")
 	errClosed           = errors.New("socket closed")
+	errNetRestrict      = errors.New("not permitted by network restrictions")
 )
 
 // Timeouts
@@ -142,6 +144,7 @@ type conn interface {
 type udp struct {
 	conn        conn
 	priv        *ecdsa.PrivateKey
+	netkey      []byte // pre-shared network key, mixed into packet hashes and signatures
 	ourEndpoint rpcEndpoint
 
 	addpending chan *pending
@@ -191,7 +194,9 @@ type reply struct {
 }
 
 // ListenUDP returns a new table that listens for UDP packets on laddr.
-func ListenUDP(priv *ecdsa.PrivateKey, laddr string, natm nat.Interface, nodeDBPath string) (*Table, error) {
+// Nodes outside of netrestrict (if non-nil) or inside of netdeny are ignored.
+// If netkey is set, only packets from nodes sharing the same key are accepted.
+func ListenUDP(priv *ecdsa.PrivateKey, laddr string, natm nat.Interface, nodeDBPath string, netrestrict, netdeny *netutil.Netlist, netkey []byte) (*Table, error) {
 	addr, err := net.ResolveUDPAddr("udp", laddr)
 	if err != nil {
 		return nil, err
@@ -200,15 +205,16 @@ func ListenUDP(priv *ecdsa.PrivateKey, laddr string, natm nat.Interface, nodeDBP
 	if err != nil {
 		return nil, err
 	}
-	tab, _ := newUDP(priv, conn, natm, nodeDBPath)
+	tab, _ := newUDP(priv, conn, natm, nodeDBPath, netrestrict, netdeny, netkey)
 	glog.V(logger.Info).Infoln("Listening,", tab.self)
 	return tab, nil
 }
 
-func newUDP(priv *ecdsa.PrivateKey, c conn, natm nat.Interface, nodeDBPath string) (*Table, *udp) {
+func newUDP(priv *ecdsa.PrivateKey, c conn, natm nat.Interface, nodeDBPath string, netrestrict, netdeny *netutil.Netlist, netkey []byte) (*Table, *udp) {
 	udp := &udp{
 		conn:       c,
 		priv:       priv,
+		netkey:     netkey,
 		closing:    make(chan struct{}),
 		gotreply:   make(chan reply),
 		addpending: make(chan *pending),
@@ -226,6 +232,7 @@ func newUDP(priv *ecdsa.PrivateKey, c conn, natm nat.Interface, nodeDBPath strin
 	// TODO: separate TCP port
 	udp.ourEndpoint = makeEndpoint(realaddr, uint16(realaddr.Port))
 	udp.Table = newTable(udp, PubkeyID(&priv.PublicKey), realaddr, nodeDBPath)
+	udp.Table.netrestrict, udp.Table.netdeny = netrestrict, netdeny
 	go udp.loop()
 	go udp.readLoop()
 	return udp.Table, udp
@@ -263,7 +270,7 @@ func (t *udp) findnode(toid NodeID, toaddr *net.UDPAddr, target NodeID) ([]*Node
 		reply := r.(*neighbors)
 		for _, rn := range reply.Nodes {
 			nreceived++
-			if n, valid := nodeFromRPC(rn); valid {
+			if n, valid := nodeFromRPC(rn); valid && t.permits(n.IP) {
 				nodes = append(nodes, n)
 			}
 		}
@@ -414,7 +421,7 @@ func init() {
 }
 
 func (t *udp) send(toaddr *net.UDPAddr, ptype byte, req interface{}) error {
-	packet, err := encodePacket(t.priv, ptype, req)
+	packet, err := encodePacket(t.priv, t.netkey, ptype, req)
 	if err != nil {
 		return err
 	}
@@ -425,7 +432,10 @@ func (t *udp) send(toaddr *net.UDPAddr, ptype byte, req interface{}) error {
 	return err
 }
 
-func encodePacket(priv *ecdsa.PrivateKey, ptype byte, req interface{}) ([]byte, error) {
+// encodePacket assembles and signs a discovery packet. If a network key is
+// given, it is mixed into both the signed data and the packet hash so that
+// nodes not sharing the key reject the packet.
+func encodePacket(priv *ecdsa.PrivateKey, netkey []byte, ptype byte, req interface{}) ([]byte, error) {
 	b := new(bytes.Buffer)
 	b.Write(headSpace)
 	b.WriteByte(ptype)
@@ -434,7 +444,7 @@ func encodePacket(priv *ecdsa.PrivateKey, ptype byte, req interface{}) ([]byte,
 		return nil, err
 	}
 	packet := b.Bytes()
-	sig, err := crypto.Sign(crypto.Sha3(packet[headSize:]), priv)
+	sig, err := crypto.Sign(crypto.Sha3(netkey, packet[headSize:]), priv)
 	if err != nil {
 		glog.V(logger.Error).Infoln("could not sign packet:", err)
 		return nil, err
@@ -443,7 +453,7 @@ func encodePacket(priv *ecdsa.PrivateKey, ptype byte, req interface{}) ([]byte,
 	// add the hash to the front. Note: this doesn't protect the
 	// packet in any way. Our public key will be part of this hash in
 	// The future.
-	copy(packet, crypto.Sha3(packet[macSize:]))
+	copy(packet, crypto.Sha3(netkey, packet[macSize:]))
 	return packet, nil
 }
 
@@ -474,7 +484,15 @@ func (t *udp) readLoop() {
 }
 
 func (t *udp) handlePacket(from *net.UDPAddr, buf []byte) error {
-	packet, fromID, hash, err := decodePacket(buf)
+	if !t.permits(from.IP) {
+		glog.V(logger.Detail).Infof("Ignoring packet from %v: %v\n", from, errNetRestrict)
+		return errNetRestrict
+	}
+	decode := decodePacket
+	if bytes.HasPrefix(buf, v5Prefix) {
+		decode = decodeV5Packet
+	}
+	packet, fromID, hash, err := decode(buf, t.netkey)
 	if err != nil {
 		glog.V(logger.Debug).Infof("Bad packet from %v: %v\n", from, err)
 		return err
@@ -487,18 +505,10 @@ func (t *udp) handlePacket(from *net.UDPAddr, buf []byte) error {
 	return err
 }
 
-func decodePacket(buf []byte) (packet, NodeID, []byte, error) {
-	if len(buf) < headSize+1 {
-		return nil, NodeID{}, nil, errPacketTooSmall
-	}
-	hash, sig, sigdata := buf[:macSize], buf[macSize:headSize], buf[headSize:]
-	shouldhash := crypto.Sha3(buf[macSize:])
-	if !bytes.Equal(hash, shouldhash) {
-		return nil, NodeID{}, nil, errBadHash
-	}
-	fromID, err := recoverNodeID(crypto.Sha3(buf[headSize:]), sig)
+func decodePacket(buf []byte, netkey []byte) (packet, NodeID, []byte, error) {
+	sigdata, fromID, hash, err := verifyPacket(buf, netkey)
 	if err != nil {
-		return nil, NodeID{}, hash, err
+		return nil, fromID, hash, err
 	}
 	var req packet
 	switch ptype := sigdata[0]; ptype {
@@ -517,6 +527,24 @@ func decodePacket(buf []byte) (packet, NodeID, []byte, error) {
 	return req, fromID, hash, err
 }
 
+// verifyPacket checks the hash and signature of a packet, returning the signed
+// data starting with the packet type and the ID of the sender.
+func verifyPacket(buf []byte, netkey []byte) ([]byte, NodeID, []byte, error) {
+	if len(buf) < headSize+1 {
+		return nil, NodeID{}, nil, errPacketTooSmall
+	}
+	hash, sig, sigdata := buf[:macSize], buf[macSize:headSize], buf[headSize:]
+	shouldhash := crypto.Sha3(netkey, buf[macSize:])
+	if !bytes.Equal(hash, shouldhash) {
+		return nil, NodeID{}, nil, errBadHash
+	}
+	fromID, err := recoverNodeID(crypto.Sha3(netkey, buf[headSize:]), sig)
+	if err != nil {
+		return nil, NodeID{}, hash, err
+	}
+	return sigdata, fromID, hash, nil
+}
+
 func (req *ping) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) error {
 	if expired(req.Expiration) {
 		return errExpired
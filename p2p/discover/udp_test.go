@@ -69,13 +69,13 @@ func newUDPTest(t *testing.T) *udpTest {
 		remotekey:  newkey(),
 		remoteaddr: &net.UDPAddr{IP: net.IP{1, 2, 3, 4}, Port: 30303},
 	}
-	test.table, test.udp = newUDP(test.localkey, test.pipe, nil, "")
+	test.table, test.udp = newUDP(test.localkey, test.pipe, nil, "", nil, nil, nil)
 	return test
 }
 
 // handles a packet as if it had been sent to the transport.
 func (test *udpTest) packetIn(wantError error, ptype byte, data packet) error {
-	enc, err := encodePacket(test.remotekey, ptype, data)
+	enc, err := encodePacket(test.remotekey, test.udp.netkey, ptype, data)
 	if err != nil {
 		return test.errorf("packet (%d) encode error: %v", err)
 	}
@@ -86,11 +86,28 @@ func (test *udpTest) packetIn(wantError error, ptype byte, data packet) error {
 	return nil
 }
 
+// handles a v5 packet as if it had been sent to the transport.
+func (test *udpTest) packetInV5(wantError error, ptype byte, data packet) error {
+	enc, err := encodeV5Packet(test.remotekey, test.udp.netkey, ptype, data)
+	if err != nil {
+		return test.errorf("v5 packet (%d) encode error: %v", ptype, err)
+	}
+	test.sent = append(test.sent, enc)
+	if err = test.udp.handlePacket(test.remoteaddr, enc); err != wantError {
+		return test.errorf("error mismatch: got %q, want %q", err, wantError)
+	}
+	return nil
+}
+
 // waits for a packet to be sent by the transport.
 // validate should have type func(*udpTest, X) error, where X is a packet type.
 func (test *udpTest) waitPacketOut(validate interface{}) error {
 	dgram := test.pipe.waitPacketOut()
-	p, _, _, err := decodePacket(dgram)
+	decode := decodePacket
+	if bytes.HasPrefix(dgram, v5Prefix) {
+		decode = decodeV5Packet
+	}
+	p, _, _, err := decode(dgram, test.udp.netkey)
 	if err != nil {
 		return test.errorf("sent packet decode error: %v", err)
 	}
@@ -125,6 +142,35 @@ func TestUDP_packetErrors(t *testing.T) {
 	test.packetIn(errUnsolicitedReply, pongPacket, &pong{ReplyTok: []byte{}, Expiration: futureExp})
 	test.packetIn(errUnknownNode, findnodePacket, &findnode{Expiration: futureExp})
 	test.packetIn(errUnsolicitedReply, neighborsPacket, &neighbors{Expiration: futureExp})
+	test.packetInV5(errUnknownNode, v5TopicRegisterPacket, &topicRegister{Topic: "pbf@1", Expiration: futureExp})
+	test.packetInV5(errUnknownNode, v5TopicQueryPacket, &topicQuery{Topic: "pbf@1", Expiration: futureExp})
+	test.packetInV5(errBadTopic, v5TopicQueryPacket, &topicQuery{Expiration: futureExp})
+	test.packetInV5(errUnsolicitedReply, v5TopicNodesPacket, &topicNodes{Topic: "pbf@1", Expiration: futureExp})
+}
+
+func TestUDP_networkKey(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+	test.udp.netkey = []byte("private network")
+
+	// Packets signed without the key or with a different one are rejected.
+	req := &ping{From: testRemote, To: testLocalAnnounced, Version: Version, Expiration: futureExp}
+	for _, key := range [][]byte{nil, []byte("other network")} {
+		enc, err := encodePacket(test.remotekey, key, pingPacket, req)
+		if err != nil {
+			t.Fatalf("packet encode error: %v", err)
+		}
+		if err := test.udp.handlePacket(test.remoteaddr, enc); err != errBadHash {
+			t.Errorf("key %q: error mismatch: got %q, want %q", key, err, errBadHash)
+		}
+	}
+	// Packets signed with the shared key are accepted and replied to.
+	go test.packetIn(nil, pingPacket, req)
+	test.waitPacketOut(func(p *pong) {
+		if !bytes.Equal(p.ReplyTok, test.sent[0][:macSize]) {
+			t.Errorf("got pong.ReplyTok %x, want %x", p.ReplyTok, test.sent[0][:macSize])
+		}
+	})
 }
 
 func TestUDP_pingTimeout(t *testing.T) {
@@ -448,3 +494,85 @@ func (c *dgramPipe) waitPacketOut() []byte {
 	c.queue = c.queue[:len(c.queue)-1]
 	return p
 }
+
+func TestUDP_topicRegistration(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	// ensure there's a bond with the test node,
+	// registrations and queries won't be accepted otherwise.
+	remote := newNode(PubkeyID(&test.remotekey.PublicKey), test.remoteaddr.IP, uint16(test.remoteaddr.Port), 99)
+	test.table.db.updateNode(remote)
+
+	// the remote node registers and finds itself, along
+	// with the local node advertising the same topic.
+	test.table.adverts["pbf@1"] = true
+	test.packetInV5(nil, v5TopicRegisterPacket, &topicRegister{Topic: "pbf@1", Expiration: futureExp})
+	test.packetInV5(nil, v5TopicQueryPacket, &topicQuery{Topic: "pbf@1", Expiration: futureExp})
+	test.waitPacketOut(func(p *topicNodes) {
+		if p.Topic != "pbf@1" || p.Total != 2 || len(p.Nodes) != 2 {
+			t.Fatalf("wrong reply: topic %q, total %d, %d nodes", p.Topic, p.Total, len(p.Nodes))
+		}
+		if p.Nodes[0].ID != remote.ID || p.Nodes[0].TCP != 99 {
+			t.Errorf("wrong registered node: got %v, want %v", p.Nodes[0], nodeToRPC(remote))
+		}
+		if p.Nodes[1].ID != test.table.self.ID {
+			t.Errorf("local node missing from reply: got %v", p.Nodes[1])
+		}
+	})
+	// topics without registrations get an empty reply.
+	test.packetInV5(nil, v5TopicQueryPacket, &topicQuery{Topic: "shh@1", Expiration: futureExp})
+	test.waitPacketOut(func(p *topicNodes) {
+		if p.Topic != "shh@1" || p.Total != 0 || len(p.Nodes) != 0 {
+			t.Errorf("wrong reply: topic %q, total %d, %d nodes", p.Topic, p.Total, len(p.Nodes))
+		}
+	})
+}
+
+func TestUDP_topicQueryMultiReply(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	// queue a pending topic query
+	resultc, errc := make(chan []*Node), make(chan error)
+	go func() {
+		rid := PubkeyID(&test.remotekey.PublicKey)
+		ns, err := test.udp.topicQuery(rid, test.remoteaddr, "pbf@1")
+		if err != nil {
+			errc <- err
+		} else {
+			resultc <- ns
+		}
+	}()
+	test.waitPacketOut(func(p *topicQuery) {
+		if p.Topic != "pbf@1" {
+			t.Errorf("wrong topic: got %q, want %q", p.Topic, "pbf@1")
+		}
+	})
+
+	// send the reply as two packets, with a reply for
+	// another topic in between.
+	list := []*Node{
+		MustParseNode("enode://ba85011c70bcc5c04d8607d3a0ed29aa6179c092cbdda10d5d32684fb33ed01bd94f588ca8f91ac48318087dcb02eaf36773a7a453f0eedd6742af668097b29c@10.0.1.16:30303?discport=30304"),
+		MustParseNode("enode://81fa361d25f157cd421c60dcc28d8dac5ef6a89476633339c5df30287474520caca09627da18543d9079b5b288698b542d56167aa5c09111e55acdbbdf2ef799@10.0.1.16:30303"),
+		MustParseNode("enode://9bffefd833d53fac8e652415f4973bee289e8b1a5c6c4cbe70abf817ce8a64cee11b823b66a987f51aaa9fba0d6a91b3e6bf0d5a5d1042de8e9eeea057b217f8@10.0.1.36:30301?discport=17"),
+	}
+	rpclist := make([]rpcNode, len(list))
+	for i := range list {
+		rpclist[i] = nodeToRPC(list[i])
+	}
+	test.packetInV5(nil, v5TopicNodesPacket, &topicNodes{Topic: "pbf@1", Total: 3, Expiration: futureExp, Nodes: rpclist[:2]})
+	test.packetInV5(nil, v5TopicNodesPacket, &topicNodes{Topic: "shh@1", Total: 1, Expiration: futureExp, Nodes: rpclist[:1]})
+	test.packetInV5(nil, v5TopicNodesPacket, &topicNodes{Topic: "pbf@1", Total: 3, Expiration: futureExp, Nodes: rpclist[2:]})
+
+	select {
+	case result := <-resultc:
+		if !reflect.DeepEqual(result, list) {
+			t.Errorf("topic nodes mismatch:\n  got:  %v\n  want: %v", result, list)
+		}
+	case err := <-errc:
+		t.Errorf("topic query error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Error("topic query did not return within 5 seconds")
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the next version of the discovery wire protocol, which carries the
+// topic advertisement and search packets.
+//
+// The v5 packets run next to the v4 ones on the same UDP socket. They start with
+// a version prefix and have a packet type space of their own, nodes speaking v4
+// only drop them as malformed. Registrars are still located with v4 lookups.
+
+package discover
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pbfcoin/go-pbfcoin/logger"
+	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/rlp"
+)
+
+// v5Prefix starts every v5 packet, separating it from v4 packets.
+var v5Prefix = []byte("pbfcoin discovery v5")
+
+// v5 RPC packet types
+const (
+	v5TopicRegisterPacket = iota + 1 // zero is 'reserved'
+	v5TopicQueryPacket
+	v5TopicNodesPacket
+)
+
+// v5 RPC request structures
+type (
+	// topicRegister asks the recipient to list the sender under
+	// a topic it is one of the registrars of.
+	topicRegister struct {
+		Topic      Topic
+		Expiration uint64
+	}
+
+	// topicQuery is a query for nodes registered under a topic.
+	topicQuery struct {
+		Topic      Topic
+		Expiration uint64
+	}
+
+	// reply to topicQuery
+	topicNodes struct {
+		Topic      Topic
+		Total      uint // number of nodes sent across all reply packets
+		Nodes      []rpcNode
+		Expiration uint64
+	}
+)
+
+// Topic query replies are sent across multiple packets to stay
+// below the 1280 byte limit, like neighbors replies.
+var maxTopicNodes int
+
+func init() {
+	p := topicNodes{Topic: Topic(make([]byte, maxTopicLength)), Total: ^uint(0), Expiration: ^uint64(0)}
+	maxSizeNode := rpcNode{IP: make(net.IP, 16), UDP: ^uint16(0), TCP: ^uint16(0)}
+	for n := 0; ; n++ {
+		p.Nodes = append(p.Nodes, maxSizeNode)
+		size, _, err := rlp.EncodeToReader(p)
+		if err != nil {
+			// If this ever happens, it will be caught by the unit tests.
+			panic("cannot encode: " + err.Error())
+		}
+		if len(v5Prefix)+headSize+size+1 >= 1280 {
+			maxTopicNodes = n
+			break
+		}
+	}
+}
+
+// v5Reply returns the type a v5 reply is matched by in the pending reply queue,
+// which is shared with v4 replies.
+func v5Reply(ptype byte) byte {
+	return 0x80 | ptype
+}
+
+// topicRegister asks the given node to list us under the topic.
+// Registrations are not acknowledged.
+func (t *udp) topicRegister(toid NodeID, toaddr *net.UDPAddr, topic Topic) error {
+	return t.sendV5(toaddr, v5TopicRegisterPacket, topicRegister{
+		Topic:      topic,
+		Expiration: uint64(time.Now().Add(expiration).Unix()),
+	})
+}
+
+// topicQuery sends a topic query to the given node and waits until
+// the node has sent all nodes registered under the topic.
+func (t *udp) topicQuery(toid NodeID, toaddr *net.UDPAddr, topic Topic) ([]*Node, error) {
+	var nodes []*Node
+	nreceived := 0
+	errc := t.pending(toid, v5Reply(v5TopicNodesPacket), func(r interface{}) bool {
+		reply := r.(*topicNodes)
+		if reply.Topic != topic {
+			return false // reply to another query
+		}
+		for _, rn := range reply.Nodes {
+			nreceived++
+			if n, valid := nodeFromRPC(rn); valid && t.permits(n.IP) {
+				nodes = append(nodes, n)
+			}
+		}
+		return nreceived >= int(reply.Total)
+	})
+	t.sendV5(toaddr, v5TopicQueryPacket, topicQuery{
+		Topic:      topic,
+		Expiration: uint64(time.Now().Add(expiration).Unix()),
+	})
+	err := <-errc
+	return nodes, err
+}
+
+func (t *udp) sendV5(toaddr *net.UDPAddr, ptype byte, req interface{}) error {
+	packet, err := encodeV5Packet(t.priv, t.netkey, ptype, req)
+	if err != nil {
+		return err
+	}
+	glog.V(logger.Detail).Infof(">>> %v %T (v5)\n", toaddr, req)
+	if _, err = t.conn.WriteToUDP(packet, toaddr); err != nil {
+		glog.V(logger.Detail).Infoln("UDP send failed:", err)
+	}
+	return err
+}
+
+// encodeV5Packet assembles and signs a v5 discovery packet. Apart from the
+// version prefix, the framing is the same as for v4 packets.
+func encodeV5Packet(priv *ecdsa.PrivateKey, netkey []byte, ptype byte, req interface{}) ([]byte, error) {
+	packet, err := encodePacket(priv, netkey, ptype, req)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, v5Prefix...), packet...), nil
+}
+
+func decodeV5Packet(buf []byte, netkey []byte) (packet, NodeID, []byte, error) {
+	if !bytes.HasPrefix(buf, v5Prefix) {
+		return nil, NodeID{}, nil, errPacketTooSmall
+	}
+	sigdata, fromID, hash, err := verifyPacket(buf[len(v5Prefix):], netkey)
+	if err != nil {
+		return nil, fromID, hash, err
+	}
+	var req packet
+	switch ptype := sigdata[0]; ptype {
+	case v5TopicRegisterPacket:
+		req = new(topicRegister)
+	case v5TopicQueryPacket:
+		req = new(topicQuery)
+	case v5TopicNodesPacket:
+		req = new(topicNodes)
+	default:
+		return nil, fromID, hash, fmt.Errorf("unknown v5 type: %d", ptype)
+	}
+	err = rlp.DecodeBytes(sigdata[1:], req)
+	return req, fromID, hash, err
+}
+
+func (req *topicRegister) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) error {
+	if expired(req.Expiration) {
+		return errExpired
+	}
+	if !req.Topic.valid() {
+		return errBadTopic
+	}
+	// Only bonded nodes may register, the endpoint listed is the
+	// one verified during bonding.
+	n := t.db.node(fromID)
+	if n == nil {
+		return errUnknownNode
+	}
+	return t.topics.add(req.Topic, n, time.Now())
+}
+
+func (req *topicQuery) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) error {
+	if expired(req.Expiration) {
+		return errExpired
+	}
+	if !req.Topic.valid() {
+		return errBadTopic
+	}
+	if t.db.node(fromID) == nil {
+		// No bond exists, see findnode for why we don't answer.
+		return errUnknownNode
+	}
+	nodes := t.topics.get(req.Topic, bucketSize, time.Now())
+	if t.advertises(req.Topic) {
+		nodes = append(nodes, t.self)
+	}
+	p := topicNodes{
+		Topic:      req.Topic,
+		Total:      uint(len(nodes)),
+		Expiration: uint64(time.Now().Add(expiration).Unix()),
+	}
+	// Reply even if no node is registered, so the querier doesn't
+	// have to wait for a timeout. Larger replies are sent in chunks
+	// to stay below the 1280 byte limit.
+	if len(nodes) == 0 {
+		t.sendV5(from, v5TopicNodesPacket, p)
+	}
+	for i, n := range nodes {
+		p.Nodes = append(p.Nodes, nodeToRPC(n))
+		if len(p.Nodes) == maxTopicNodes || i == len(nodes)-1 {
+			t.sendV5(from, v5TopicNodesPacket, p)
+			p.Nodes = p.Nodes[:0]
+		}
+	}
+	return nil
+}
+
+func (req *topicNodes) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) error {
+	if expired(req.Expiration) {
+		return errExpired
+	}
+	if !t.handleReply(fromID, v5Reply(v5TopicNodesPacket), req) {
+		return errUnsolicitedReply
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package netutil contains extensions to the net package.
+package netutil
+
+import (
+	"net"
+	"strings"
+)
+
+const (
+	subnetBitsV4 = 24 // Prefix length grouping IPv4 addresses by operator
+	subnetBitsV6 = 64 // Prefix length grouping IPv6 addresses by operator
+)
+
+// Netlist is a list of IP networks.
+type Netlist []net.IPNet
+
+// ParseNetlist parses a comma-separated list of CIDR masks.
+// Whitespace and extra commas are ignored.
+func ParseNetlist(s string) (*Netlist, error) {
+	ws := strings.NewReplacer(" ", "", "\n", "", "\t", "")
+	masks := strings.Split(ws.Replace(s), ",")
+	l := make(Netlist, 0)
+	for _, mask := range masks {
+		if mask == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(mask)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, *n)
+	}
+	return &l, nil
+}
+
+// Add parses a CIDR mask and appends it to the list. It panics for invalid masks
+// and is intended to be used for setting up static lists.
+func (l *Netlist) Add(cidr string) {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	*l = append(*l, *n)
+}
+
+// Contains reports whether the given IP is contained in the list.
+// A nil list contains no addresses.
+func (l *Netlist) Contains(ip net.IP) bool {
+	if l == nil {
+		return false
+	}
+	for _, n := range *l {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// String implements fmt.Stringer.
+func (l Netlist) String() string {
+	masks := make([]string, len(l))
+	for i, n := range l {
+		masks[i] = n.String()
+	}
+	return strings.Join(masks, ", ")
+}
+
+// Permits reports whether connections to the given IP are allowed by an allow
+// and a deny list. A nil allow list permits every address not denied.
+func Permits(allow, deny *Netlist, ip net.IP) bool {
+	return (allow == nil || allow.Contains(ip)) && !deny.Contains(ip)
+}
+
+// Subnet returns the network that groups the given IP with the other addresses
+// most likely run by the same operator: its /24 for IPv4 and its /64 for IPv6.
+func Subnet(ip net.IP) net.IPNet {
+	if ip4 := ip.To4(); ip4 != nil {
+		mask := net.CIDRMask(subnetBitsV4, 32)
+		return net.IPNet{IP: ip4.Mask(mask), Mask: mask}
+	}
+	mask := net.CIDRMask(subnetBitsV6, 128)
+	return net.IPNet{IP: ip.Mask(mask), Mask: mask}
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package netutil
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseNetlist(t *testing.T) {
+	var tests = []struct {
+		input    string
+		wantErr  bool
+		wantList *Netlist
+	}{
+		{
+			input:    "",
+			wantList: &Netlist{},
+		},
+		{
+			input:    "127.0.0.0/8",
+			wantList: &Netlist{{IP: net.IP{127, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}},
+		},
+		{
+			input:   "127.0.0.0/44",
+			wantErr: true,
+		},
+		{
+			input: "127.0.0.0/16, 23.23.23.23/24,",
+			wantList: &Netlist{
+				{IP: net.IP{127, 0, 0, 0}, Mask: net.CIDRMask(16, 32)},
+				{IP: net.IP{23, 23, 23, 0}, Mask: net.CIDRMask(24, 32)},
+			},
+		},
+	}
+	for i, test := range tests {
+		l, err := ParseNetlist(test.input)
+		if (err != nil) != test.wantErr {
+			t.Errorf("test %d: error mismatch: have %v, want error %t", i, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && !reflect.DeepEqual(l, test.wantList) {
+			t.Errorf("test %d: list mismatch: have %v, want %v", i, l, test.wantList)
+		}
+	}
+}
+
+func TestPermits(t *testing.T) {
+	allow, _ := ParseNetlist("10.0.0.0/8, 192.168.0.0/16")
+	deny, _ := ParseNetlist("10.1.0.0/16")
+
+	var tests = []struct {
+		allow, deny *Netlist
+		ip          string
+		want        bool
+	}{
+		{nil, nil, "1.2.3.4", true},
+		{allow, nil, "1.2.3.4", false},
+		{allow, nil, "10.1.2.3", true},
+		{allow, deny, "10.1.2.3", false},
+		{allow, deny, "10.2.2.3", true},
+		{nil, deny, "10.1.2.3", false},
+		{nil, deny, "192.168.1.1", true},
+	}
+	for i, test := range tests {
+		if have := Permits(test.allow, test.deny, net.ParseIP(test.ip)); have != test.want {
+			t.Errorf("test %d: %s permitted %t, want %t", i, test.ip, have, test.want)
+		}
+	}
+}
+
+func TestSubnet(t *testing.T) {
+	var tests = []struct {
+		ip, other string
+		same      bool
+	}{
+		{"10.1.2.3", "10.1.2.200", true},
+		{"10.1.2.3", "10.1.3.3", false},
+		{"2001:db8::1", "2001:db8::ffff:1", true},
+		{"2001:db8::1", "2001:db8:0:1::1", false},
+	}
+	for i, test := range tests {
+		subnet := Subnet(net.ParseIP(test.ip))
+		if have := subnet.Contains(net.ParseIP(test.other)); have != test.same {
+			t.Errorf("test %d: %s in subnet %v: have %t, want %t", i, test.other, subnet, have, test.same)
+		}
+	}
+}
@@ -67,6 +67,8 @@ type Peer struct {
 	protoErr chan error
 	closed   chan struct{}
 	disc     chan DiscReason
+
+	reputation func(delta int) // reports reputation changes to the server
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -113,6 +115,15 @@ func (p *Peer) Disconnect(reason DiscReason) {
 	}
 }
 
+// AdjustReputation changes the reputation score of the remote node by delta.
+// Protocols use it to report misbehaviour (negative delta), penalties wear off
+// over time. Nodes whose score drops too low get disconnected and banned.
+func (p *Peer) AdjustReputation(delta int) {
+	if p.reputation != nil {
+		p.reputation(delta)
+	}
+}
+
 // String implements fmt.Stringer.
 func (p *Peer) String() string {
 	return fmt.Sprintf("Peer %x %v", p.rw.id[:8], p.RemoteAddr())
@@ -0,0 +1,266 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the peer reputation tracking and the node ban list of the server.
+
+package p2p
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pbfcoin/go-pbfcoin/logger"
+	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/p2p/discover"
+)
+
+const (
+	defaultBanThreshold = -100           // Reputation score at which a peer gets banned
+	defaultBanDuration  = 12 * time.Hour // Time a node is refused after being banned
+
+	scoreRecoveryInterval = time.Minute // Time needed to recover one point of reputation
+	maxScoredNodes        = 1024        // Maximum number of nodes to track the reputation of
+)
+
+// Reputation adjustments for the common kinds of misbehaviour, to be reported
+// by protocol handlers through Peer.AdjustReputation. A single offence never
+// reaches the default ban threshold, as honest peers may trigger any of them
+// occasionally, e.g. by relaying a block that lost a reorg race.
+const (
+	ScoreTimeout       = -5  // Peer was dropped for timing out or stalling a request
+	ScoreUselessPeer   = -20 // Peer was dropped for delivering useless data
+	ScoreProtocolError = -50 // Peer sent a message violating the protocol
+	ScoreBadBlock      = -50 // Peer propagated a block failing verification
+)
+
+// reputation is the penalty score of a node, recovering towards zero over time.
+type reputation struct {
+	score   int       // Score as of the last update, always negative
+	updated time.Time // Time the score was last updated
+}
+
+// current returns the score of the node at the given time, after recovering
+// one point for every scoreRecoveryInterval passed since the last update.
+func (r reputation) current(now time.Time) int {
+	score := r.score + int(now.Sub(r.updated)/scoreRecoveryInterval)
+	if score > 0 {
+		score = 0
+	}
+	return score
+}
+
+// banStore is implemented by node tables able to persist bans across restarts.
+type banStore interface {
+	BanNode(id discover.NodeID, until time.Time) error
+	UnbanNode(id discover.NodeID) error
+	BannedNodes() map[discover.NodeID]time.Time
+}
+
+// BannedNode represents a node currently refused by the server.
+type BannedNode struct {
+	ID    string    `json:"id"`    // Unique node identifier
+	Until time.Time `json:"until"` // Time when the ban expires
+}
+
+// AdjustReputation changes the reputation score of a node by delta. Scores start
+// at zero and never exceed it, penalties wear off by one point every minute. A
+// node whose score reaches the ban threshold is disconnected and banned, unless
+// trusted.
+func (srv *Server) AdjustReputation(id discover.NodeID, delta int) {
+	threshold := srv.BanThreshold
+	if threshold == 0 {
+		threshold = defaultBanThreshold
+	}
+	now := time.Now()
+
+	srv.repLock.Lock()
+	if srv.scores == nil {
+		srv.scores = make(map[discover.NodeID]reputation)
+	}
+	rep, ok := srv.scores[id]
+	score := delta
+	if ok {
+		score += rep.current(now)
+	}
+	if score >= 0 {
+		delete(srv.scores, id)
+	} else {
+		if !ok && len(srv.scores) >= maxScoredNodes {
+			srv.evictScore(now)
+		}
+		srv.scores[id] = reputation{score: score, updated: now}
+	}
+	srv.repLock.Unlock()
+
+	glog.V(logger.Detail).Infof("node %x reputation adjusted by %d to %d", id[:8], delta, score)
+	if score <= threshold && !srv.isTrusted(id) {
+		srv.BanNode(id, 0)
+	}
+}
+
+// evictScore makes room for a new node in the reputation set by dropping the
+// nodes which recovered fully, or failing that, the least penalised node.
+// The caller must hold repLock.
+func (srv *Server) evictScore(now time.Time) {
+	var (
+		best      discover.NodeID
+		bestScore int
+		found     bool
+	)
+	for id, rep := range srv.scores {
+		score := rep.current(now)
+		if score == 0 {
+			delete(srv.scores, id)
+			continue
+		}
+		if !found || score > bestScore {
+			best, bestScore, found = id, score, true
+		}
+	}
+	if len(srv.scores) >= maxScoredNodes {
+		delete(srv.scores, best)
+	}
+}
+
+// Reputation returns the current reputation score of a node.
+func (srv *Server) Reputation(id discover.NodeID) int {
+	srv.repLock.Lock()
+	defer srv.repLock.Unlock()
+
+	rep, ok := srv.scores[id]
+	if !ok {
+		return 0
+	}
+	score := rep.current(time.Now())
+	if score == 0 {
+		delete(srv.scores, id)
+	}
+	return score
+}
+
+// BanNode disconnects the given node and refuses any connections to and from
+// it for the given duration, or for BanDuration if zero. If discovery is running,
+// the ban is stored in the node database and survives restarts.
+func (srv *Server) BanNode(id discover.NodeID, duration time.Duration) error {
+	if duration == 0 {
+		duration = srv.BanDuration
+	}
+	if duration == 0 {
+		duration = defaultBanDuration
+	}
+	until := time.Now().Add(duration)
+
+	srv.repLock.Lock()
+	if srv.bans == nil {
+		srv.bans = make(map[discover.NodeID]time.Time)
+	}
+	srv.bans[id] = until
+	delete(srv.scores, id)
+	srv.repLock.Unlock()
+
+	glog.V(logger.Info).Infof("banning node %x until %v", id[:8], until)
+	if store, ok := srv.ntab.(banStore); ok {
+		if err := store.BanNode(id, until); err != nil {
+			return err
+		}
+	}
+	srv.dropPeer(id, DiscUselessPeer)
+	return nil
+}
+
+// UnbanNode lifts the ban of the given node and resets its reputation.
+func (srv *Server) UnbanNode(id discover.NodeID) error {
+	srv.repLock.Lock()
+	delete(srv.bans, id)
+	delete(srv.scores, id)
+	srv.repLock.Unlock()
+
+	glog.V(logger.Info).Infof("unbanning node %x", id[:8])
+	if store, ok := srv.ntab.(banStore); ok {
+		return store.UnbanNode(id)
+	}
+	return nil
+}
+
+// BannedNodes returns the nodes currently banned, sorted by node identifier.
+func (srv *Server) BannedNodes() []*BannedNode {
+	srv.repLock.Lock()
+	defer srv.repLock.Unlock()
+
+	now := time.Now()
+	banned := make([]*BannedNode, 0, len(srv.bans))
+	for id, until := range srv.bans {
+		if until.Before(now) {
+			delete(srv.bans, id)
+			continue
+		}
+		banned = append(banned, &BannedNode{ID: id.String(), Until: until})
+	}
+	sort.Sort(bannedNodesByID(banned))
+	return banned
+}
+
+// isBanned reports whether the node is currently banned, dropping the ban if it
+// has expired.
+func (srv *Server) isBanned(id discover.NodeID) bool {
+	srv.repLock.Lock()
+	defer srv.repLock.Unlock()
+
+	until, ok := srv.bans[id]
+	if ok && until.Before(time.Now()) {
+		delete(srv.bans, id)
+		return false
+	}
+	return ok
+}
+
+// loadBans fills the ban list from the node table's database, if it has one.
+func (srv *Server) loadBans() {
+	store, ok := srv.ntab.(banStore)
+	if !ok {
+		return
+	}
+	srv.repLock.Lock()
+	defer srv.repLock.Unlock()
+
+	for id, until := range store.BannedNodes() {
+		srv.bans[id] = until
+	}
+}
+
+// dropPeer disconnects the peer with the given id, if connected.
+func (srv *Server) dropPeer(id discover.NodeID, reason DiscReason) {
+	// Don't take srv.lock here, Stop holds it while waiting for the
+	// protocol handlers calling into us to terminate.
+	if srv.quit == nil {
+		return // server not started
+	}
+	var peer *Peer
+	select {
+	case srv.peerOp <- func(peers map[discover.NodeID]*Peer) { peer = peers[id] }:
+		<-srv.peerOpDone
+	case <-srv.quit:
+	}
+	if peer != nil {
+		peer.Disconnect(reason)
+	}
+}
+
+type bannedNodesByID []*BannedNode
+
+func (s bannedNodesByID) Len() int           { return len(s) }
+func (s bannedNodesByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+func (s bannedNodesByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
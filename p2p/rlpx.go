@@ -68,7 +68,8 @@ const (
 // rlpx is the transport protocol used by actual (non-test) connections.
 // It wraps the frame encoder with locks and read/write deadlines.
 type rlpx struct {
-	fd net.Conn
+	fd     net.Conn
+	netkey []byte // pre-shared network key, mixed into the session secrets
 
 	rmu, wmu sync.Mutex
 	rw       *rlpxFrameRW
@@ -167,9 +168,9 @@ func (t *rlpx) doEncHandshake(prv *ecdsa.PrivateKey, dial *discover.Node) (disco
 		err error
 	)
 	if dial == nil {
-		sec, err = receiverEncHandshake(t.fd, prv, nil)
+		sec, err = receiverEncHandshake(t.fd, prv, nil, t.netkey)
 	} else {
-		sec, err = initiatorEncHandshake(t.fd, prv, dial.ID, nil)
+		sec, err = initiatorEncHandshake(t.fd, prv, dial.ID, nil, t.netkey)
 	}
 	if err != nil {
 		return discover.NodeID{}, err
@@ -189,6 +190,7 @@ type encHandshake struct {
 	initNonce, respNonce []byte            // nonce
 	randomPrivKey        *ecies.PrivateKey // ecdhe-random
 	remoteRandomPub      *ecies.PublicKey  // ecdhe-random-pubk
+	netKey               []byte            // pre-shared network key
 }
 
 // secrets represents the connection secrets
@@ -207,6 +209,12 @@ func (h *encHandshake) secrets(auth, authResp []byte) (secrets, error) {
 	if err != nil {
 		return secrets{}, err
 	}
+	// on private networks, mix the pre-shared key into the agreed secret.
+	// sides holding different keys derive different MACs and fail to
+	// exchange the first frame.
+	if h.netKey != nil {
+		ecdheSecret = crypto.Sha3(ecdheSecret, h.netKey)
+	}
 
 	// derive base secrets from ephemeral key agreement
 	sharedSecret := crypto.Sha3(ecdheSecret, crypto.Sha3(h.respNonce, h.initNonce))
@@ -243,11 +251,13 @@ func (h *encHandshake) ecdhShared(prv *ecdsa.PrivateKey) ([]byte, error) {
 //
 // prv is the local client's private key.
 // token is the token from a previous session with this node.
-func initiatorEncHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey, remoteID discover.NodeID, token []byte) (s secrets, err error) {
+// netkey is the pre-shared network key, if any.
+func initiatorEncHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey, remoteID discover.NodeID, token, netkey []byte) (s secrets, err error) {
 	h, err := newInitiatorHandshake(remoteID)
 	if err != nil {
 		return s, err
 	}
+	h.netKey = netkey
 	auth, err := h.authMsg(prv, token)
 	if err != nil {
 		return s, err
@@ -349,7 +359,8 @@ func (h *encHandshake) decodeAuthResp(auth []byte, prv *ecdsa.PrivateKey) error
 //
 // prv is the local client's private key.
 // token is the token from a previous session with this node.
-func receiverEncHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey, token []byte) (s secrets, err error) {
+// netkey is the pre-shared network key, if any.
+func receiverEncHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey, token, netkey []byte) (s secrets, err error) {
 	// read remote auth sent by initiator.
 	auth := make([]byte, encAuthMsgLen)
 	if _, err := io.ReadFull(conn, auth); err != nil {
@@ -359,6 +370,7 @@ func receiverEncHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey, token []byt
 	if err != nil {
 		return s, err
 	}
+	h.netKey = netkey
 
 	// send auth response
 	resp, err := h.authResp(prv, token)
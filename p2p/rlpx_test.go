@@ -143,6 +143,54 @@ func testEncHandshake(token []byte) error {
 	return nil
 }
 
+func TestEncHandshakeNetworkKey(t *testing.T) {
+	tests := []struct {
+		key0, key1 []byte
+		ok         bool
+	}{
+		{key0: nil, key1: nil, ok: true},
+		{key0: []byte("private"), key1: []byte("private"), ok: true},
+		{key0: []byte("private"), key1: nil, ok: false},
+		{key0: []byte("private"), key1: []byte("other"), ok: false},
+	}
+	for i, tt := range tests {
+		var (
+			prv0, _  = crypto.GenerateKey()
+			prv1, _  = crypto.GenerateKey()
+			fd0, fd1 = net.Pipe()
+			c0, c1   = newRLPX(fd0).(*rlpx), newRLPX(fd1).(*rlpx)
+			errc     = make(chan error, 1)
+		)
+		c0.netkey, c1.netkey = tt.key0, tt.key1
+
+		// The encryption handshake itself succeeds, but sides holding
+		// different keys can't exchange frames afterwards.
+		go func() {
+			dest := &discover.Node{ID: discover.PubkeyID(&prv1.PublicKey)}
+			if _, err := c0.doEncHandshake(prv0, dest); err != nil {
+				errc <- err
+				return
+			}
+			errc <- c0.WriteMsg(Msg{Code: 1, Size: 3, Payload: bytes.NewReader([]byte{1, 2, 3})})
+		}()
+		if _, err := c1.doEncHandshake(prv1, nil); err != nil {
+			t.Fatalf("test %d: receiver handshake failed: %v", i, err)
+		}
+		_, err := c1.ReadMsg()
+		fd1.Close()
+		if werr := <-errc; tt.ok && werr != nil {
+			t.Errorf("test %d: write failed: %v", i, werr)
+		}
+		if tt.ok && err != nil {
+			t.Errorf("test %d: read failed: %v", i, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("test %d: read succeeded with mismatching network keys", i)
+		}
+		fd0.Close()
+	}
+}
+
 func TestProtocolHandshake(t *testing.T) {
 	var (
 		prv0, _ = crypto.GenerateKey()
@@ -29,6 +29,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
 	"github.com/pbfcoin/go-pbfcoin/p2p/discover"
 	"github.com/pbfcoin/go-pbfcoin/p2p/nat"
+	"github.com/pbfcoin/go-pbfcoin/p2p/netutil"
 )
 
 const (
@@ -50,7 +51,10 @@ const (
 	frameWriteTimeout = 20 * time.Second
 )
 
-var errServerStopped = errors.New("server stopped")
+var (
+	errServerStopped = errors.New("server stopped")
+	errNetRestrict   = errors.New("not permitted by network restrictions")
+)
 
 var srvjslog = logger.NewJsonLogger()
 
@@ -89,7 +93,8 @@ type Server struct {
 	StaticNodes []*discover.Node
 
 	// Trusted nodes are used as pre-configured connections which are always
-	// allowed to connect, even above the peer limit.
+	// allowed to connect, even above the peer limit. Use AddTrustedPeer and
+	// RemoveTrustedPeer to modify the set while the server is running.
 	TrustedNodes []*discover.Node
 
 	// NodeDatabase is the path to the database containing the previously seen
@@ -121,6 +126,40 @@ type Server struct {
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool
 
+	// NetRestrict, if set, restricts connections and discovery to nodes
+	// within the given networks, both inbound and outbound.
+	NetRestrict *netutil.Netlist
+
+	// NetDeny, if set, refuses connections and discovery to and from nodes
+	// within the given networks. It takes precedence over NetRestrict.
+	NetDeny *netutil.Netlist
+
+	// MaxPeersPerSubnet is the maximum number of peers from a single /24
+	// (IPv4) or /64 (IPv6) subnet. Static and trusted peers are exempt.
+	// Zero means no limit.
+	MaxPeersPerSubnet int
+
+	// NetworkKey, if set, is a pre-shared secret of a private network. It is
+	// mixed into the encryption handshake and the discovery packets, so that
+	// nodes not holding the same key can neither connect nor be discovered.
+	NetworkKey []byte
+
+	// DiscoveryTopics are advertised through the discovery protocol, so
+	// that nodes searching for peers of a particular kind find this one.
+	DiscoveryTopics []discover.Topic
+
+	// SearchTopic, if set, makes the dialer search for nodes advertised
+	// under the topic in addition to the random lookups it always does.
+	SearchTopic discover.Topic
+
+	// BanThreshold is the reputation score at which a peer is disconnected
+	// and its node banned. It must be negative, zero defaults to a preset value.
+	BanThreshold int
+
+	// BanDuration is the time a node is refused after its reputation reached
+	// BanThreshold. Zero defaults to a preset value.
+	BanDuration time.Duration
+
 	// Hooks for testing. These are useful because we can inhibit
 	// the whole protocol stack.
 	newTransport func(net.Conn) transport
@@ -134,12 +173,23 @@ type Server struct {
 	ourHandshake *protoHandshake
 	lastLookup   time.Time
 
-	// These are for Peers, PeerCount (and nothing else).
+	trustLock sync.RWMutex             // protects trusted
+	trusted   map[discover.NodeID]bool // trusted node set, mutable at runtime
+
+	repLock sync.Mutex                     // protects scores and bans
+	scores  map[discover.NodeID]reputation // reputation of misbehaving nodes
+	bans    map[discover.NodeID]time.Time  // banned nodes and their ban expiry
+
+	pendingLock sync.Mutex       // protects pending
+	pending     map[*conn]net.IP // conns between the handshakes, counted towards subnet limits
+
+	// These are for Peers, PeerCount and dialSubnetFull (and nothing else).
 	peerOp     chan peerOpFunc
 	peerOpDone chan struct{}
 
 	quit          chan struct{}
 	addstatic     chan *discover.Node
+	removestatic  chan *discover.Node
 	posthandshake chan *conn
 	addpeer       chan *conn
 	delpeer       chan *Peer
@@ -255,6 +305,36 @@ func (srv *Server) AddPeer(node *discover.Node) {
 	}
 }
 
+// RemovePeer disconnects from the given node and stops maintaining a connection
+// to it if it was added as a static node before.
+func (srv *Server) RemovePeer(node *discover.Node) {
+	select {
+	case srv.removestatic <- node:
+	case <-srv.quit:
+	}
+}
+
+// AddTrustedPeer adds the given node to the trusted set. Trusted nodes are
+// always allowed to connect, even above the peer limit.
+func (srv *Server) AddTrustedPeer(node *discover.Node) {
+	srv.trustLock.Lock()
+	defer srv.trustLock.Unlock()
+
+	if srv.trusted == nil {
+		srv.trusted = make(map[discover.NodeID]bool)
+	}
+	srv.trusted[node.ID] = true
+}
+
+// RemoveTrustedPeer removes the given node from the trusted set. Existing
+// connections to the node are left intact.
+func (srv *Server) RemoveTrustedPeer(node *discover.Node) {
+	srv.trustLock.Lock()
+	defer srv.trustLock.Unlock()
+
+	delete(srv.trusted, node.ID)
+}
+
 // Self returns the local node's endpoint information.
 func (srv *Server) Self() *discover.Node {
 	srv.lock.Lock()
@@ -316,6 +396,14 @@ func (srv *Server) Start() (err error) {
 	}
 	if srv.newTransport == nil {
 		srv.newTransport = newRLPX
+		if srv.NetworkKey != nil {
+			netkey := srv.NetworkKey
+			srv.newTransport = func(fd net.Conn) transport {
+				t := newRLPX(fd).(*rlpx)
+				t.netkey = netkey
+				return t
+			}
+		}
 	}
 	if srv.Dialer == nil {
 		srv.Dialer = &net.Dialer{Timeout: defaultDialTimeout}
@@ -325,16 +413,42 @@ func (srv *Server) Start() (err error) {
 	srv.delpeer = make(chan *Peer)
 	srv.posthandshake = make(chan *conn)
 	srv.addstatic = make(chan *discover.Node)
+	srv.removestatic = make(chan *discover.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 
+	// Put trusted nodes into a map to speed up checks.
+	srv.trustLock.Lock()
+	if srv.trusted == nil {
+		srv.trusted = make(map[discover.NodeID]bool, len(srv.TrustedNodes))
+	}
+	for _, n := range srv.TrustedNodes {
+		srv.trusted[n.ID] = true
+	}
+	srv.trustLock.Unlock()
+
+	srv.repLock.Lock()
+	if srv.scores == nil {
+		srv.scores = make(map[discover.NodeID]reputation)
+	}
+	if srv.bans == nil {
+		srv.bans = make(map[discover.NodeID]time.Time)
+	}
+	srv.repLock.Unlock()
+
 	// node table
 	if srv.Discovery {
-		ntab, err := discover.ListenUDP(srv.PrivateKey, srv.ListenAddr, srv.NAT, srv.NodeDatabase)
+		ntab, err := discover.ListenUDP(srv.PrivateKey, srv.ListenAddr, srv.NAT, srv.NodeDatabase, srv.NetRestrict, srv.NetDeny, srv.NetworkKey)
 		if err != nil {
 			return err
 		}
 		srv.ntab = ntab
+		srv.loadBans()
+		for _, topic := range srv.DiscoveryTopics {
+			if err := ntab.RegisterTopic(topic); err != nil {
+				return fmt.Errorf("discovery topic %q: %v", topic, err)
+			}
+		}
 	}
 
 	dynPeers := srv.MaxPeers / 2
@@ -390,24 +504,17 @@ type dialer interface {
 	newTasks(running int, peers map[discover.NodeID]*Peer, now time.Time) []task
 	taskDone(task, time.Time)
 	addStatic(*discover.Node)
+	removeStatic(*discover.Node)
 }
 
 func (srv *Server) run(dialstate dialer) {
 	defer srv.loopWG.Done()
 	var (
-		peers   = make(map[discover.NodeID]*Peer)
-		trusted = make(map[discover.NodeID]bool, len(srv.TrustedNodes))
-
+		peers        = make(map[discover.NodeID]*Peer)
 		tasks        []task
 		pendingTasks []task
 		taskdone     = make(chan task, maxActiveDialTasks)
 	)
-	// Put trusted nodes into a map to speed up checks.
-	// Trusted peers are loaded on startup and cannot be
-	// modified while the server is running.
-	for _, n := range srv.TrustedNodes {
-		trusted[n.ID] = true
-	}
 
 	// Some task list helpers.
 	delTask := func(t task) {
@@ -454,6 +561,14 @@ running:
 			// it will keep the node connected.
 			glog.V(logger.Detail).Infoln("<-addstatic:", n)
 			dialstate.addStatic(n)
+		case n := <-srv.removestatic:
+			// This channel is used by RemovePeer to remove from the
+			// static peer list and drop the connection if it exists.
+			glog.V(logger.Detail).Infoln("<-removestatic:", n)
+			dialstate.removeStatic(n)
+			if p, ok := peers[n.ID]; ok {
+				p.Disconnect(DiscRequested)
+			}
 		case op := <-srv.peerOp:
 			// This channel is used by Peers and PeerCount.
 			op(peers)
@@ -468,23 +583,30 @@ running:
 		case c := <-srv.posthandshake:
 			// A connection has passed the encryption handshake so
 			// the remote identity is known (but hasn't been verified yet).
-			if trusted[c.id] {
+			if srv.isTrusted(c.id) {
 				// Ensure that the trusted flag is set before checking against MaxPeers.
 				c.flags |= trustedConn
 			}
 			glog.V(logger.Detail).Infoln("<-posthandshake:", c)
 			// TODO: track in-progress inbound node IDs (pre-Peer) to avoid dialing them.
-			c.cont <- srv.encHandshakeChecks(peers, c)
+			err := srv.encHandshakeChecks(peers, c)
+			if err == nil {
+				srv.addPending(c)
+			}
+			c.cont <- err
 		case c := <-srv.addpeer:
 			// At this point the connection is past the protocol handshake.
 			// Its capabilities are known and the remote identity is verified.
 			glog.V(logger.Detail).Infoln("<-addpeer:", c)
+			srv.removePending(c)
 			err := srv.protoHandshakeChecks(peers, c)
 			if err != nil {
 				glog.V(logger.Detail).Infof("Not adding %v as peer: %v", c, err)
 			} else {
 				// The handshakes are done and it passed all checks.
 				p := newPeer(c, srv.Protocols)
+				id := c.id
+				p.reputation = func(delta int) { srv.AdjustReputation(id, delta) }
 				peers[c.id] = p
 				go srv.runPeer(p)
 			}
@@ -518,6 +640,14 @@ running:
 	}
 }
 
+// isTrusted reports whether the node is in the trusted set.
+func (srv *Server) isTrusted(id discover.NodeID) bool {
+	srv.trustLock.RLock()
+	defer srv.trustLock.RUnlock()
+
+	return srv.trusted[id]
+}
+
 func (srv *Server) protoHandshakeChecks(peers map[discover.NodeID]*Peer, c *conn) error {
 	// Drop connections with no matching protocols.
 	if len(srv.Protocols) > 0 && countMatchingProtocols(srv.Protocols, c.caps) == 0 {
@@ -530,8 +660,12 @@ func (srv *Server) protoHandshakeChecks(peers map[discover.NodeID]*Peer, c *conn
 
 func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, c *conn) error {
 	switch {
+	case srv.isBanned(c.id):
+		return DiscUselessPeer
 	case !c.is(trustedConn|staticDialedConn) && len(peers) >= srv.MaxPeers:
 		return DiscTooManyPeers
+	case !c.is(trustedConn|staticDialedConn) && srv.subnetFull(peers, remoteIP(c.fd)):
+		return DiscTooManyPeers
 	case peers[c.id] != nil:
 		return DiscAlreadyConnected
 	case c.id == srv.Self().ID:
@@ -541,6 +675,79 @@ func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, c *conn)
 	}
 }
 
+// permitsIP reports whether the network restrictions allow connections to and
+// from the given IP.
+func (srv *Server) permitsIP(ip net.IP) bool {
+	return netutil.Permits(srv.NetRestrict, srv.NetDeny, ip)
+}
+
+// subnetFull reports whether the subnet of the given IP already provides the
+// maximum number of peers allowed per subnet. Connections between the
+// handshakes count as peers, as they may all be added at once.
+func (srv *Server) subnetFull(peers map[discover.NodeID]*Peer, ip net.IP) bool {
+	if srv.MaxPeersPerSubnet == 0 || ip == nil {
+		return false
+	}
+	subnet, count := netutil.Subnet(ip), 0
+	for _, p := range peers {
+		if pip := remoteIP(p.rw.fd); pip != nil && subnet.Contains(pip) {
+			count++
+		}
+	}
+	srv.pendingLock.Lock()
+	for _, pip := range srv.pending {
+		if pip != nil && subnet.Contains(pip) {
+			count++
+		}
+	}
+	srv.pendingLock.Unlock()
+
+	return count >= srv.MaxPeersPerSubnet
+}
+
+// dialSubnetFull reports whether the subnet of the given IP already provides
+// the maximum number of peers allowed per subnet, so dialing it is pointless.
+func (srv *Server) dialSubnetFull(ip net.IP) bool {
+	if srv.MaxPeersPerSubnet == 0 {
+		return false
+	}
+	var full bool
+	select {
+	case srv.peerOp <- func(ps map[discover.NodeID]*Peer) { full = srv.subnetFull(ps, ip) }:
+		<-srv.peerOpDone
+	case <-srv.quit:
+	}
+	return full
+}
+
+// addPending counts a connection which passed the encryption handshake checks
+// towards the limits until it's added as a peer or dropped.
+func (srv *Server) addPending(c *conn) {
+	srv.pendingLock.Lock()
+	defer srv.pendingLock.Unlock()
+
+	if srv.pending == nil {
+		srv.pending = make(map[*conn]net.IP)
+	}
+	srv.pending[c] = remoteIP(c.fd)
+}
+
+// removePending stops counting a connection added by addPending.
+func (srv *Server) removePending(c *conn) {
+	srv.pendingLock.Lock()
+	defer srv.pendingLock.Unlock()
+
+	delete(srv.pending, c)
+}
+
+// remoteIP returns the IP address of the remote end of a TCP connection, or nil.
+func remoteIP(fd net.Conn) net.IP {
+	if addr, ok := fd.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP
+	}
+	return nil
+}
+
 type tempError interface {
 	Temporary() bool
 }
@@ -607,6 +814,12 @@ func (srv *Server) setupConn(fd net.Conn, flags connFlag, dialDest *discover.Nod
 		c.close(errServerStopped)
 		return
 	}
+	// Refuse connections to and from outside of the permitted networks.
+	if addr, ok := fd.RemoteAddr().(*net.TCPAddr); ok && !srv.permitsIP(addr.IP) {
+		glog.V(logger.Debug).Infof("%v refused: %v", c, errNetRestrict)
+		c.close(errNetRestrict)
+		return
+	}
 	// Run the encryption handshake.
 	var err error
 	if c.id, err = c.doEncHandshake(srv.PrivateKey, dialDest); err != nil {
@@ -625,6 +838,8 @@ func (srv *Server) setupConn(fd net.Conn, flags connFlag, dialDest *discover.Nod
 		c.close(err)
 		return
 	}
+	defer srv.removePending(c)
+
 	// Run the protocol handshake
 	phs, err := c.doProtoHandshake(srv.ourHandshake)
 	if err != nil {
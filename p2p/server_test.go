@@ -28,6 +28,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/crypto"
 	"github.com/pbfcoin/go-pbfcoin/crypto/sha3"
 	"github.com/pbfcoin/go-pbfcoin/p2p/discover"
+	"github.com/pbfcoin/go-pbfcoin/p2p/netutil"
 )
 
 func init() {
@@ -248,6 +249,8 @@ func (tg taskgen) taskDone(t task, now time.Time) {
 }
 func (tg taskgen) addStatic(*discover.Node) {
 }
+func (tg taskgen) removeStatic(*discover.Node) {
+}
 
 type testTask struct {
 	index  int
@@ -300,7 +303,192 @@ func TestServerAtCap(t *testing.T) {
 	if !c.is(trustedConn) {
 		t.Error("Server did not set trusted flag")
 	}
+	// Trust a node at runtime, then revoke it again.
+	id := randomID()
+	srv.AddTrustedPeer(&discover.Node{ID: id})
+	c = newconn(id)
+	if err := srv.checkpoint(c, srv.posthandshake); err != nil {
+		t.Error("unexpected error for runtime trusted conn @posthandshake:", err)
+	}
+	if !c.is(trustedConn) {
+		t.Error("Server did not set trusted flag for runtime trusted conn")
+	}
+	srv.RemoveTrustedPeer(&discover.Node{ID: id})
+	c = newconn(id)
+	if err := srv.checkpoint(c, srv.posthandshake); err != DiscTooManyPeers {
+		t.Error("wrong error for untrusted conn:", err)
+	}
+}
+
+// addrConn is a net.Conn reporting a fixed remote address.
+type addrConn struct {
+	net.Conn
+	raddr *net.TCPAddr
+}
+
+func (c addrConn) RemoteAddr() net.Addr { return c.raddr }
+
+// This test checks that connections from outside of the permitted networks are
+// refused before the handshakes and that the per-subnet peer limit holds.
+func TestServerNetRestrict(t *testing.T) {
+	restrict, _ := netutil.ParseNetlist("10.0.0.0/8")
+	deny, _ := netutil.ParseNetlist("10.9.0.0/16")
+	srv := &Server{
+		PrivateKey:        newkey(),
+		MaxPeers:          10,
+		MaxPeersPerSubnet: 1,
+		NoDial:            true,
+		NetRestrict:       restrict,
+		NetDeny:           deny,
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newfd := func(ip string) net.Conn {
+		fd, _ := net.Pipe()
+		return addrConn{fd, &net.TCPAddr{IP: net.ParseIP(ip), Port: 30303}}
+	}
+	// Connections from off-list networks must not reach the handshake.
+	for _, ip := range []string{"192.168.0.1", "10.9.0.1"} {
+		tt := &setupTransport{id: randomID()}
+		srv.newTransport = func(fd net.Conn) transport { return tt }
+		srv.setupConn(newfd(ip), inboundConn, nil)
+		if tt.calls != "close," || tt.closeErr != errNetRestrict {
+			t.Errorf("%s: calls %q, close error %v; want refusal", ip, tt.calls, tt.closeErr)
+		}
+	}
+	// Fill the subnet of 10.0.0.0/24 and check that it's capped.
+	newconn := func(ip string, flags connFlag) *conn {
+		id, fd := randomID(), newfd(ip)
+		return &conn{fd: fd, transport: newTestTransport(id, fd), flags: flags, id: id, cont: make(chan error)}
+	}
+	if err := srv.checkpoint(newconn("10.0.0.1", inboundConn), srv.addpeer); err != nil {
+		t.Fatalf("could not add conn: %v", err)
+	}
+	if err := srv.checkpoint(newconn("10.0.0.2", inboundConn), srv.posthandshake); err != DiscTooManyPeers {
+		t.Error("wrong error for conn from full subnet:", err)
+	}
+	if err := srv.checkpoint(newconn("10.0.0.2", staticDialedConn), srv.posthandshake); err != nil {
+		t.Error("unexpected error for static conn from full subnet:", err)
+	}
+	if err := srv.checkpoint(newconn("10.0.1.1", inboundConn), srv.posthandshake); err != nil {
+		t.Error("unexpected error for conn from other subnet:", err)
+	}
+	// Connections still in the protocol handshake count towards the limit.
+	if err := srv.checkpoint(newconn("10.0.2.1", inboundConn), srv.posthandshake); err != nil {
+		t.Error("unexpected error for first conn from subnet:", err)
+	}
+	if err := srv.checkpoint(newconn("10.0.2.2", inboundConn), srv.posthandshake); err != DiscTooManyPeers {
+		t.Error("wrong error for simultaneous conn from subnet:", err)
+	}
+	// Nodes in full subnets aren't worth dialing.
+	if !srv.dialSubnetFull(net.ParseIP("10.0.0.3")) {
+		t.Error("subnet 10.0.0.0/24 not reported full for dialing")
+	}
+	if srv.dialSubnetFull(net.ParseIP("10.0.3.1")) {
+		t.Error("subnet 10.0.3.0/24 reported full for dialing")
+	}
+}
 
+// This test checks that nodes are banned once their reputation drops to the
+// threshold, that bans are enforced after the encryption handshake and that
+// trusted nodes are exempt from automatic bans.
+func TestServerReputationBan(t *testing.T) {
+	trustedID := randomID()
+	srv := &Server{
+		PrivateKey:   newkey(),
+		MaxPeers:     10,
+		NoDial:       true,
+		TrustedNodes: []*discover.Node{{ID: trustedID}},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id discover.NodeID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(id, fd)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, id: id, cont: make(chan error)}
+	}
+	// Penalise a node until it gets banned
+	id := randomID()
+	srv.AdjustReputation(id, ScoreProtocolError)
+	if score := srv.Reputation(id); score != ScoreProtocolError {
+		t.Errorf("reputation mismatch: have %d, want %d", score, ScoreProtocolError)
+	}
+	if err := srv.checkpoint(newconn(id), srv.posthandshake); err != nil {
+		t.Errorf("unexpected error for unbanned conn: %v", err)
+	}
+	srv.AdjustReputation(id, ScoreProtocolError)
+	if banned := srv.BannedNodes(); len(banned) != 1 || banned[0].ID != id.String() {
+		t.Errorf("ban list mismatch: have %v, want %x", banned, id[:8])
+	}
+	if err := srv.checkpoint(newconn(id), srv.posthandshake); err != DiscUselessPeer {
+		t.Errorf("wrong error for banned conn: %v", err)
+	}
+	// Lift the ban and check that the node may connect again
+	if err := srv.UnbanNode(id); err != nil {
+		t.Fatalf("failed to unban node: %v", err)
+	}
+	if err := srv.checkpoint(newconn(id), srv.posthandshake); err != nil {
+		t.Errorf("unexpected error for unbanned conn: %v", err)
+	}
+	// A single bad block must not get a node banned
+	srv.AdjustReputation(randomID(), ScoreBadBlock)
+	if banned := srv.BannedNodes(); len(banned) != 0 {
+		t.Errorf("node banned for a single bad block: %v", banned)
+	}
+	// Trusted nodes must not be banned automatically
+	srv.AdjustReputation(trustedID, defaultBanThreshold)
+	if banned := srv.BannedNodes(); len(banned) != 0 {
+		t.Errorf("trusted node banned: %v", banned)
+	}
+}
+
+// This test checks that penalties wear off over time and that the number of
+// nodes whose reputation is tracked stays bounded.
+func TestServerReputationDecay(t *testing.T) {
+	srv := &Server{PrivateKey: newkey()}
+
+	// Age a penalty and check that it partially recovered
+	id := randomID()
+	srv.AdjustReputation(id, ScoreUselessPeer)
+	rep := srv.scores[id]
+	rep.updated = rep.updated.Add(-5 * scoreRecoveryInterval)
+	srv.scores[id] = rep
+	if score := srv.Reputation(id); score != ScoreUselessPeer+5 {
+		t.Errorf("reputation mismatch: have %d, want %d", score, ScoreUselessPeer+5)
+	}
+	// Further penalties add up on the recovered score
+	srv.AdjustReputation(id, ScoreTimeout)
+	if score := srv.Reputation(id); score != ScoreUselessPeer+5+ScoreTimeout {
+		t.Errorf("reputation mismatch: have %d, want %d", score, ScoreUselessPeer+5+ScoreTimeout)
+	}
+	// Once recovered fully, the node must be forgotten
+	rep = srv.scores[id]
+	rep.updated = rep.updated.Add(-time.Duration(-rep.score) * scoreRecoveryInterval)
+	srv.scores[id] = rep
+	if score := srv.Reputation(id); score != 0 {
+		t.Errorf("reputation not recovered: have %d, want 0", score)
+	}
+	if _, ok := srv.scores[id]; ok {
+		t.Errorf("recovered node still tracked")
+	}
+	// Penalise more nodes than tracked, the least penalised ones must be dropped
+	worst := randomID()
+	srv.AdjustReputation(worst, ScoreProtocolError)
+	for i := 0; i < maxScoredNodes; i++ {
+		srv.AdjustReputation(randomID(), ScoreTimeout)
+	}
+	if len(srv.scores) > maxScoredNodes {
+		t.Errorf("tracked nodes mismatch: have %d, want at most %d", len(srv.scores), maxScoredNodes)
+	}
+	if score := srv.Reputation(worst); score != ScoreProtocolError {
+		t.Errorf("worst node reputation mismatch: have %d, want %d", score, ScoreProtocolError)
+	}
 }
 
 func TestServerSetupConn(t *testing.T) {
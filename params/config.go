@@ -0,0 +1,226 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+)
+
+var (
+	BlockReward   = big.NewInt(5e+18)  // Reward in wei credited to the coinbase of each mined block.
+	ExpDiffPeriod = big.NewInt(100000) // Number of blocks after which the difficulty bomb doubles.
+)
+
+// Rules are the protocol parameters a block and its transactions are processed
+// with. Every field is optional in a fork, a nil field keeps its previous value.
+type Rules struct {
+	// Consensus rules
+	BlockReward            *big.Int `json:"blockReward,omitempty"`
+	MaximumExtraDataSize   *big.Int `json:"maximumExtraDataSize,omitempty"`
+	MinGasLimit            *big.Int `json:"minGasLimit,omitempty"`
+	GasLimitBoundDivisor   *big.Int `json:"gasLimitBoundDivisor,omitempty"`
+	MinimumDifficulty      *big.Int `json:"minimumDifficulty,omitempty"`
+	DifficultyBoundDivisor *big.Int `json:"difficultyBoundDivisor,omitempty"`
+	DurationLimit          *big.Int `json:"durationLimit,omitempty"`
+	ExpDiffPeriod          *big.Int `json:"expDiffPeriod,omitempty"`
+
+	// Transaction gas costs
+	TxGas            *big.Int `json:"txGas,omitempty"`
+	TxDataZeroGas    *big.Int `json:"txDataZeroGas,omitempty"`
+	TxDataNonZeroGas *big.Int `json:"txDataNonZeroGas,omitempty"`
+
+	// VM gas table
+	CreateGas            *big.Int `json:"createGas,omitempty"`
+	CreateDataGas        *big.Int `json:"createDataGas,omitempty"`
+	CallGas              *big.Int `json:"callGas,omitempty"`
+	CallStipend          *big.Int `json:"callStipend,omitempty"`
+	CallValueTransferGas *big.Int `json:"callValueTransferGas,omitempty"`
+	CallNewAccountGas    *big.Int `json:"callNewAccountGas,omitempty"`
+	SloadGas             *big.Int `json:"sloadGas,omitempty"`
+	SstoreSetGas         *big.Int `json:"sstoreSetGas,omitempty"`
+	SstoreClearGas       *big.Int `json:"sstoreClearGas,omitempty"`
+	SstoreRefundGas      *big.Int `json:"sstoreRefundGas,omitempty"`
+	SuicideRefundGas     *big.Int `json:"suicideRefundGas,omitempty"`
+	JumpdestGas          *big.Int `json:"jumpdestGas,omitempty"`
+	ExpByteGas           *big.Int `json:"expByteGas,omitempty"`
+	Sha3Gas              *big.Int `json:"sha3Gas,omitempty"`
+	Sha3WordGas          *big.Int `json:"sha3WordGas,omitempty"`
+	CopyGas              *big.Int `json:"copyGas,omitempty"`
+	LogGas               *big.Int `json:"logGas,omitempty"`
+	LogDataGas           *big.Int `json:"logDataGas,omitempty"`
+	LogTopicGas          *big.Int `json:"logTopicGas,omitempty"`
+	MemoryGas            *big.Int `json:"memoryGas,omitempty"`
+	QuadCoeffDiv         *big.Int `json:"quadCoeffDiv,omitempty"`
+}
+
+// DefaultRules returns the rules of chains without a chain config, taken from the
+// protocol parameters of this package.
+func DefaultRules() *Rules {
+	return &Rules{
+		BlockReward:            BlockReward,
+		MaximumExtraDataSize:   MaximumExtraDataSize,
+		MinGasLimit:            MinGasLimit,
+		GasLimitBoundDivisor:   GasLimitBoundDivisor,
+		MinimumDifficulty:      MinimumDifficulty,
+		DifficultyBoundDivisor: DifficultyBoundDivisor,
+		DurationLimit:          DurationLimit,
+		ExpDiffPeriod:          ExpDiffPeriod,
+
+		TxGas:            TxGas,
+		TxDataZeroGas:    TxDataZeroGas,
+		TxDataNonZeroGas: TxDataNonZeroGas,
+
+		CreateGas:            CreateGas,
+		CreateDataGas:        CreateDataGas,
+		CallGas:              CallGas,
+		CallStipend:          CallStipend,
+		CallValueTransferGas: CallValueTransferGas,
+		CallNewAccountGas:    CallNewAccountGas,
+		SloadGas:             SloadGas,
+		SstoreSetGas:         SstoreSetGas,
+		SstoreClearGas:       SstoreClearGas,
+		SstoreRefundGas:      SstoreRefundGas,
+		SuicideRefundGas:     SuicideRefundGas,
+		JumpdestGas:          JumpdestGas,
+		ExpByteGas:           ExpByteGas,
+		Sha3Gas:              Sha3Gas,
+		Sha3WordGas:          Sha3WordGas,
+		CopyGas:              CopyGas,
+		LogGas:               LogGas,
+		LogDataGas:           LogDataGas,
+		LogTopicGas:          LogTopicGas,
+		MemoryGas:            MemoryGas,
+		QuadCoeffDiv:         QuadCoeffDiv,
+	}
+}
+
+// override replaces the rules with every rule set in changes.
+func (r *Rules) override(changes *Rules) {
+	dst, src := reflect.ValueOf(r).Elem(), reflect.ValueOf(changes).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if !src.Field(i).IsNil() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
+
+// Fork is a change of the rules activated at a given block number.
+type Fork struct {
+	Block *big.Int `json:"block"` // first block processed with the changed rules
+	Rules          // rules changed by the fork, unset ones are inherited
+}
+
+// Checkpoint is a trusted block of the canonical chain. Fast and light syncs of
+// a chain behind it start from the checkpoint instead of the genesis block, and
+// peers whose chain doesn't contain it are not synchronised with.
+type Checkpoint struct {
+	Number uint64      // number of the checkpoint block
+	Hash   common.Hash // hash of the checkpoint block
+	TD     *big.Int    // total difficulty of the chain up to and including the block
+}
+
+// checkpointJSON is the genesis file encoding of a checkpoint.
+type checkpointJSON struct {
+	Number uint64   `json:"number"`
+	Hash   string   `json:"hash"`
+	TD     *big.Int `json:"td"`
+}
+
+// MarshalJSON encodes the checkpoint with a hex hash.
+func (c *Checkpoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&checkpointJSON{Number: c.Number, Hash: c.Hash.Hex(), TD: c.TD})
+}
+
+// UnmarshalJSON decodes a checkpoint with a hex hash.
+func (c *Checkpoint) UnmarshalJSON(input []byte) error {
+	var dec checkpointJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	hash := common.FromHex(dec.Hash)
+	if len(hash) != len(common.Hash{}) {
+		return fmt.Errorf("invalid checkpoint hash length %d, want %d bytes", len(hash), len(common.Hash{}))
+	}
+	c.Number, c.Hash, c.TD = dec.Number, common.BytesToHash(hash), dec.TD
+	return nil
+}
+
+// Validate checks that the checkpoint is above the genesis block and has a hash
+// and a total difficulty.
+func (c *Checkpoint) Validate() error {
+	switch {
+	case c.Number == 0:
+		return errors.New("checkpoint: must be above the genesis block")
+	case c.Hash == (common.Hash{}):
+		return errors.New("checkpoint: missing hash")
+	case c.TD == nil || c.TD.Sign() <= 0:
+		return errors.New("checkpoint: missing or non-positive total difficulty")
+	}
+	return nil
+}
+
+// ChainConfig is the hard-fork schedule of a chain, read from the "config"
+// section of its genesis file. A fork at block 0 changes the rules of the whole
+// chain; a nil config runs every block with the default rules.
+type ChainConfig struct {
+	Forks      []*Fork     `json:"forks"`                // rule changes, in order of activation
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"` // trusted block to sync from, nil syncs from genesis
+}
+
+// Validate checks that every fork has an activation block and that the forks
+// are ordered by it, as well as the checkpoint if any.
+func (c *ChainConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Checkpoint != nil {
+		if err := c.Checkpoint.Validate(); err != nil {
+			return err
+		}
+	}
+	for i, fork := range c.Forks {
+		if fork.Block == nil || fork.Block.Sign() < 0 {
+			return fmt.Errorf("fork %d: missing or negative activation block", i)
+		}
+		if i > 0 && fork.Block.Cmp(c.Forks[i-1].Block) <= 0 {
+			return fmt.Errorf("fork %d: activation block %v not after previous fork's %v", i, fork.Block, c.Forks[i-1].Block)
+		}
+	}
+	return nil
+}
+
+// Rules returns the rules in force at the given block number. The returned rules
+// are shared, their values must not be modified.
+func (c *ChainConfig) Rules(number *big.Int) *Rules {
+	rules := DefaultRules()
+	if c == nil {
+		return rules
+	}
+	for _, fork := range c.Forks {
+		if fork.Block.Cmp(number) > 0 {
+			break
+		}
+		rules.override(&fork.Rules)
+	}
+	return rules
+}
@@ -28,6 +28,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -35,18 +36,24 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/common"
 	"github.com/pbfcoin/go-pbfcoin/common/compiler"
 	"github.com/pbfcoin/go-pbfcoin/common/httpclient"
+	"github.com/pbfcoin/go-pbfcoin/consensus"
+	"github.com/pbfcoin/go-pbfcoin/consensus/instant"
+	"github.com/pbfcoin/go-pbfcoin/consensus/poa"
 	"github.com/pbfcoin/go-pbfcoin/core"
 	"github.com/pbfcoin/go-pbfcoin/core/state"
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/core/vm"
 	"github.com/pbfcoin/go-pbfcoin/crypto"
 	"github.com/pbfcoin/go-pbfcoin/event"
+	"github.com/pbfcoin/go-pbfcoin/light"
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
 	"github.com/pbfcoin/go-pbfcoin/miner"
 	"github.com/pbfcoin/go-pbfcoin/p2p"
 	"github.com/pbfcoin/go-pbfcoin/p2p/discover"
 	"github.com/pbfcoin/go-pbfcoin/p2p/nat"
+	"github.com/pbfcoin/go-pbfcoin/p2p/netutil"
+	"github.com/pbfcoin/go-pbfcoin/params"
 	"github.com/pbfcoin/go-pbfcoin/pbf/downloader"
 	"github.com/pbfcoin/go-pbfcoin/pbfdb"
 	"github.com/pbfcoin/go-pbfcoin/rlp"
@@ -87,14 +94,21 @@ var (
 )
 
 type Config struct {
-	DevMode bool
-	TestNet bool
+	DevMode    bool
+	DevPeriod  uint64         // dev mode sealing period in seconds, 0 seals on every transaction
+	DevAccount common.Address // pre-funded developer account of the dev mode chain
+	TestNet    bool
 
 	Name         string
 	NetworkId    int
 	GenesisFile  string
 	GenesisBlock *types.Block // used by block tests
 	FastSync     bool
+	LightMode    bool               // header-only sync, retrieving state and blocks on demand
+	LightServ    int                // maximum number of light clients to serve, 0 disables serving
+	StatePrune   *core.PruneConfig  // state pruning options, nil keeps all states
+	Authority    *poa.Config        // proof-of-authority consensus, nil uses proof-of-work
+	Checkpoint   *params.Checkpoint // trusted block to sync from, overrides the chain config's
 	Olympic      bool
 
 	BlockChainVersion  int
@@ -111,10 +125,18 @@ type Config struct {
 	PowTest   bool
 	ExtraData []byte
 
-	MaxPeers        int
-	MaxPendingPeers int
-	Discovery       bool
-	Port            string
+	MaxPeers          int
+	MaxPendingPeers   int
+	MaxPeersPerSubnet int
+	Discovery         bool
+	Port              string
+
+	// IP networks to restrict connections to and to refuse connections from
+	NetRestrict *netutil.Netlist
+	NetDeny     *netutil.Netlist
+
+	// Pre-shared key of a private network, nil on public networks
+	NetworkKey []byte
 
 	// Space-separated list of discovery node URLs
 	BootNodes string
@@ -140,6 +162,8 @@ type Config struct {
 	GpobaseStepUp           int
 	GpobaseCorrectionFactor int
 
+	TxPool core.TxPoolConfig
+
 	// NewDB is used to create databases.
 	// If nil, the default is to create leveldb databases on disk.
 	NewDB func(path string) (pbfdb.Database, error)
@@ -223,6 +247,23 @@ func (cfg *Config) nodeKey() (*ecdsa.PrivateKey, error) {
 	return key, nil
 }
 
+// discoveryTopics returns the discovery topics the node is advertised under,
+// along with the one searched for when looking for peers. Full nodes look for
+// other full nodes on the same network, light clients for light servers.
+func (cfg *Config) discoveryTopics() (advertise []discover.Topic, search discover.Topic) {
+	full := discover.Topic(fmt.Sprintf("%s@%d", ProtocolName, cfg.NetworkId))
+	light := discover.Topic(fmt.Sprintf("%s@%d", LightProtocolName, cfg.NetworkId))
+
+	if cfg.LightMode {
+		return nil, light
+	}
+	advertise = []discover.Topic{full}
+	if cfg.LightServ > 0 {
+		advertise = append(advertise, light)
+	}
+	return advertise, full
+}
+
 type pbfcoin struct {
 	// Channel for shutting down the pbfcoin
 	shutdownChan chan bool
@@ -237,6 +278,7 @@ type pbfcoin struct {
 	accountManager  *accounts.Manager
 	whisper         *whisper.Whisper
 	pow             *pbfash.pbfash
+	engine          consensus.Engine
 	protocolManager *ProtocolManager
 	SolcPath        string
 	solc            *compiler.Solidity
@@ -254,6 +296,8 @@ type pbfcoin struct {
 	eventMux *event.TypeMux
 	miner    *miner.Miner
 
+	nodesLock sync.Mutex // Serialises updates of the static and trusted node files
+
 	// logger logger.LogSystem
 
 	Mining        bool
@@ -330,12 +374,15 @@ func New(config *Config) (*pbfcoin, error) {
 	switch {
 	case config.Olympic:
 		glog.V(logger.Error).Infoln("Starting Olympic network")
-		fallthrough
-	case config.DevMode:
 		_, err := core.WriteOlympicGenesisBlock(chainDb, 42)
 		if err != nil {
 			return nil, err
 		}
+	case config.DevMode:
+		_, err := core.WriteDevGenesisBlock(chainDb, config.DevAccount)
+		if err != nil {
+			return nil, err
+		}
 	case config.TestNet:
 		state.StartingNonce = 1048576 // (2**20)
 		_, err := core.WriteTestNetGenesisBlock(chainDb, 0x6d6f7264656e)
@@ -394,21 +441,73 @@ func New(config *Config) (*pbfcoin, error) {
 	} else {
 		pbf.pow = pbfash.New()
 	}
+	switch {
+	case config.DevMode:
+		glog.V(logger.Info).Infof("Using instant sealing consensus (period %ds)", config.DevPeriod)
+		pbf.engine = instant.New(config.DevPeriod)
+	case config.Authority != nil:
+		glog.V(logger.Info).Infof("Using proof-of-authority consensus (period %ds, epoch %d)", config.Authority.Period, config.Authority.Epoch)
+		pbf.engine = poa.New(config.Authority)
+	default:
+		pbf.engine = core.NewPowEngine(pbf.pow)
+	}
 	//genesis := core.GenesisBlock(uint64(config.GenesisNonce), stateDb)
-	pbf.blockchain, err = core.NewBlockChain(chainDb, pbf.pow, pbf.EventMux())
+	pbf.blockchain, err = core.NewBlockChain(chainDb, pbf.engine, pbf.EventMux())
 	if err != nil {
 		if err == core.ErrNoGenesis {
 			return nil, fmt.Errorf(`Genesis block not found. Please supply a genesis block with the "--genesis /path/to/file" argument`)
 		}
 		return nil, err
 	}
-	newPool := core.NewTxPool(pbf.EventMux(), pbf.blockchain.State, pbf.blockchain.GasLimit)
+	if config.StatePrune != nil {
+		pbf.blockchain.SetPruning(config.StatePrune)
+	}
+	poolConfig := config.TxPool
+	if poolConfig.Journal != "" && !filepath.IsAbs(poolConfig.Journal) {
+		poolConfig.Journal = filepath.Join(config.DataDir, poolConfig.Journal)
+	}
+	stateFn, gasLimitFn := pbf.blockchain.State, pbf.blockchain.GasLimit
+	if config.LightMode {
+		// Light clients only have the headers, validate against the state of the head header
+		stateFn = func() (*state.StateDB, error) {
+			return light.NewStateDB(pbf.blockchain.CurrentHeader().Root, pbf.protocolManager.odr)
+		}
+		gasLimitFn = func() *big.Int {
+			return pbf.blockchain.CurrentHeader().GasLimit
+		}
+	}
+	newPool := core.NewTxPool(poolConfig, pbf.EventMux(), stateFn, gasLimitFn, pbf.blockchain.PendingRules)
 	pbf.txPool = newPool
 
-	if pbf.protocolManager, err = NewProtocolManager(config.FastSync, config.NetworkId, pbf.eventMux, pbf.txPool, pbf.pow, pbf.blockchain, chainDb); err != nil {
+	mode := downloader.FullSync
+	if config.LightMode {
+		mode = downloader.LightSync
+	} else if config.FastSync {
+		mode = downloader.FastSync
+	}
+	if pbf.protocolManager, err = NewProtocolManager(mode, config.LightServ, config.NetworkId, pbf.eventMux, pbf.txPool, pbf.engine, pbf.blockchain, chainDb); err != nil {
 		return nil, err
 	}
-	pbf.miner = miner.New(pbf, pbf.EventMux(), pbf.pow)
+	checkpoint := config.Checkpoint
+	if checkpoint == nil && pbf.blockchain.Config() != nil {
+		checkpoint = pbf.blockchain.Config().Checkpoint
+	}
+	if checkpoint != nil {
+		// Signer snapshots are rebuilt from the nearest epoch header, which a sync
+		// from the checkpoint only has if the checkpoint is one
+		if config.Authority != nil {
+			epoch := config.Authority.Epoch
+			if epoch == 0 {
+				epoch = poa.DefaultEpoch
+			}
+			if checkpoint.Number%epoch != 0 {
+				return nil, fmt.Errorf("checkpoint #%d is not an epoch block (epoch length %d)", checkpoint.Number, epoch)
+			}
+		}
+		glog.V(logger.Info).Infof("Syncing from checkpoint #%d [%x…]", checkpoint.Number, checkpoint.Hash[:4])
+		pbf.protocolManager.downloader.SetCheckpoint(checkpoint)
+	}
+	pbf.miner = miner.New(pbf, pbf.EventMux(), pbf.engine)
 	pbf.miner.SetGasPrice(config.GasPrice)
 	pbf.miner.SetExtra(config.ExtraData)
 
@@ -425,19 +524,26 @@ func New(config *Config) (*pbfcoin, error) {
 	if config.Shh {
 		protocols = append(protocols, pbf.whisper.Protocol())
 	}
+	topics, searchTopic := config.discoveryTopics()
 	pbf.net = &p2p.Server{
-		PrivateKey:      netprv,
-		Name:            config.Name,
-		MaxPeers:        config.MaxPeers,
-		MaxPendingPeers: config.MaxPendingPeers,
-		Discovery:       config.Discovery,
-		Protocols:       protocols,
-		NAT:             config.NAT,
-		NoDial:          !config.Dial,
-		BootstrapNodes:  config.parseBootNodes(),
-		StaticNodes:     config.parseNodes(staticNodes),
-		TrustedNodes:    config.parseNodes(trustedNodes),
-		NodeDatabase:    nodeDb,
+		PrivateKey:        netprv,
+		Name:              config.Name,
+		MaxPeers:          config.MaxPeers,
+		MaxPendingPeers:   config.MaxPendingPeers,
+		MaxPeersPerSubnet: config.MaxPeersPerSubnet,
+		NetRestrict:       config.NetRestrict,
+		NetDeny:           config.NetDeny,
+		NetworkKey:        config.NetworkKey,
+		Discovery:         config.Discovery,
+		DiscoveryTopics:   topics,
+		SearchTopic:       searchTopic,
+		Protocols:         protocols,
+		NAT:               config.NAT,
+		NoDial:            !config.Dial,
+		BootstrapNodes:    config.parseBootNodes(),
+		StaticNodes:       config.parseNodes(staticNodes),
+		TrustedNodes:      config.parseNodes(trustedNodes),
+		NodeDatabase:      nodeDb,
 	}
 	if len(config.Port) > 0 {
 		pbf.net.ListenAddr = ":" + config.Port
@@ -476,6 +582,16 @@ func (self *pbfcoin) Setpbferbase(pbferbase common.Address) {
 	self.miner.Setpbferbase(pbferbase)
 }
 
+// authorizeSigner sets the account sealing blocks if the consensus engine is
+// signature based. The account must be unlocked for sealing to succeed.
+func (s *pbfcoin) authorizeSigner(signer common.Address) {
+	if engine, ok := s.engine.(*poa.PoA); ok {
+		engine.Authorize(signer, func(signer common.Address, hash []byte) ([]byte, error) {
+			return s.accountManager.Sign(accounts.Account{Address: signer}, hash)
+		})
+	}
+}
+
 func (s *pbfcoin) StopMining()         { s.miner.Stop() }
 func (s *pbfcoin) IsMining() bool      { return s.miner.Mining() }
 func (s *pbfcoin) Miner() *miner.Miner { return s.miner }
@@ -499,6 +615,15 @@ func (s *pbfcoin) NetVersion() int                    { return s.netVersionId }
 func (s *pbfcoin) ShhVersion() int                    { return s.shhVersionId }
 func (s *pbfcoin) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
 
+// Odr retrieves the on-demand data retriever of a light client, or nil if the
+// node runs in full mode.
+func (s *pbfcoin) Odr() light.OdrBackend {
+	if s.protocolManager.odr == nil {
+		return nil
+	}
+	return s.protocolManager.odr
+}
+
 // Start the pbfcoin
 func (s *pbfcoin) Start() error {
 	jsonlogger.LogJson(&logger.LogStarting{
@@ -536,16 +661,98 @@ func (s *pbfcoin) StartForTest() {
 
 // AddPeer connects to the given node and maintains the connection until the
 // server is shut down. If the connection fails for any reason, the server will
-// attempt to reconnect the peer.
-func (self *pbfcoin) AddPeer(nodeURL string) error {
+// attempt to reconnect the peer. If persist is set, the node is also added to
+// the static node list in the data directory.
+func (self *pbfcoin) AddPeer(nodeURL string, persist bool) error {
 	n, err := discover.ParseNode(nodeURL)
 	if err != nil {
 		return fmt.Errorf("invalid node URL: %v", err)
 	}
 	self.net.AddPeer(n)
+	if persist {
+		return self.updateNodes(staticNodes, n, true)
+	}
+	return nil
+}
+
+// RemovePeer disconnects from the given node and stops maintaining the
+// connection. If persist is set, the node is also removed from the static node
+// list in the data directory.
+func (self *pbfcoin) RemovePeer(nodeURL string, persist bool) error {
+	n, err := discover.ParseNode(nodeURL)
+	if err != nil {
+		return fmt.Errorf("invalid node URL: %v", err)
+	}
+	self.net.RemovePeer(n)
+	if persist {
+		return self.updateNodes(staticNodes, n, false)
+	}
+	return nil
+}
+
+// AddTrustedPeer allows the given node to always connect, even above the peer
+// limit. If persist is set, the node is also added to the trusted node list in
+// the data directory.
+func (self *pbfcoin) AddTrustedPeer(nodeURL string, persist bool) error {
+	n, err := discover.ParseNode(nodeURL)
+	if err != nil {
+		return fmt.Errorf("invalid node URL: %v", err)
+	}
+	self.net.AddTrustedPeer(n)
+	if persist {
+		return self.updateNodes(trustedNodes, n, true)
+	}
 	return nil
 }
 
+// RemoveTrustedPeer revokes the trusted status of the given node. If persist is
+// set, the node is also removed from the trusted node list in the data directory.
+func (self *pbfcoin) RemoveTrustedPeer(nodeURL string, persist bool) error {
+	n, err := discover.ParseNode(nodeURL)
+	if err != nil {
+		return fmt.Errorf("invalid node URL: %v", err)
+	}
+	self.net.RemoveTrustedPeer(n)
+	if persist {
+		return self.updateNodes(trustedNodes, n, false)
+	}
+	return nil
+}
+
+// updateNodes adds or removes a node in one of the node list .json files of the
+// data directory, creating the file if it doesn't exist yet. Entries that fail
+// to parse are left untouched.
+func (self *pbfcoin) updateNodes(file string, node *discover.Node, add bool) error {
+	self.nodesLock.Lock()
+	defer self.nodesLock.Unlock()
+
+	path := filepath.Join(self.DataDir, file)
+	nodelist := []string{}
+	if blob, err := ioutil.ReadFile(path); err == nil {
+		if err := json.Unmarshal(blob, &nodelist); err != nil {
+			return fmt.Errorf("failed to load %s: %v", file, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	// Drop any existing entry of the node, re-adding it if requested
+	updated := make([]string, 0, len(nodelist)+1)
+	for _, url := range nodelist {
+		if n, err := discover.ParseNode(url); err == nil && n.ID == node.ID {
+			continue
+		}
+		updated = append(updated, url)
+	}
+	if add {
+		updated = append(updated, node.String())
+	}
+	blob, err := json.MarshalIndent(updated, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, blob, 0644)
+}
+
 func (s *pbfcoin) Stop() {
 	s.net.Stop()
 	s.blockchain.Stop()
@@ -684,46 +891,43 @@ func upgradeChainDatabase(db pbfdb.Database) error {
 	// At least some of the database is still the old format, upgrade (skip the head block!)
 	glog.V(logger.Info).Info("Old database detected, upgrading...")
 
-	if db, ok := db.(*pbfdb.LDBDatabase); ok {
-		blockPrefix := []byte("block-hash-")
-		for it := db.NewIterator(); it.Next(); {
-			// Skip anything other than a combined block
-			if !bytes.HasPrefix(it.Key(), blockPrefix) {
-				continue
-			}
-			// Skip the head block (merge last to signal upgrade completion)
-			if bytes.HasSuffix(it.Key(), head.Bytes()) {
-				continue
-			}
-			// Load the block, split and serialize (order!)
-			block := core.GetBlockByHashOld(db, common.BytesToHash(bytes.TrimPrefix(it.Key(), blockPrefix)))
+	blockPrefix := []byte("block-hash-")
+	it := db.NewPrefixIterator(blockPrefix)
+	defer it.Release()
 
-			if err := core.WriteTd(db, block.Hash(), block.DeprecatedTd()); err != nil {
-				return err
-			}
-			if err := core.WriteBody(db, block.Hash(), &types.Body{block.Transactions(), block.Uncles()}); err != nil {
-				return err
-			}
-			if err := core.WriteHeader(db, block.Header()); err != nil {
-				return err
-			}
-			if err := db.Delete(it.Key()); err != nil {
-				return err
-			}
+	for it.Next() {
+		// Skip the head block (merge last to signal upgrade completion)
+		if bytes.HasSuffix(it.Key(), head.Bytes()) {
+			continue
 		}
-		// Lastly, upgrade the head block, disabling the upgrade mechanism
-		current := core.GetBlockByHashOld(db, head)
+		// Load the block, split and serialize (order!)
+		block := core.GetBlockByHashOld(db, common.BytesToHash(bytes.TrimPrefix(it.Key(), blockPrefix)))
 
-		if err := core.WriteTd(db, current.Hash(), current.DeprecatedTd()); err != nil {
+		if err := core.WriteTd(db, block.Hash(), block.DeprecatedTd()); err != nil {
+			return err
+		}
+		if err := core.WriteBody(db, block.Hash(), &types.Body{block.Transactions(), block.Uncles()}); err != nil {
 			return err
 		}
-		if err := core.WriteBody(db, current.Hash(), &types.Body{current.Transactions(), current.Uncles()}); err != nil {
+		if err := core.WriteHeader(db, block.Header()); err != nil {
 			return err
 		}
-		if err := core.WriteHeader(db, current.Header()); err != nil {
+		if err := db.Delete(it.Key()); err != nil {
 			return err
 		}
 	}
+	// Lastly, upgrade the head block, disabling the upgrade mechanism
+	current := core.GetBlockByHashOld(db, head)
+
+	if err := core.WriteTd(db, current.Hash(), current.DeprecatedTd()); err != nil {
+		return err
+	}
+	if err := core.WriteBody(db, current.Hash(), &types.Body{current.Transactions(), current.Uncles()}); err != nil {
+		return err
+	}
+	if err := core.WriteHeader(db, current.Header()); err != nil {
+		return err
+	}
 	return nil
 }
 
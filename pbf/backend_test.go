@@ -1,13 +1,16 @@
 package pbf
 
 import (
+	"io/ioutil"
 	"math/big"
+	"os"
 	"testing"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
 	"github.com/pbfcoin/go-pbfcoin/core"
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/core/vm"
+	"github.com/pbfcoin/go-pbfcoin/p2p/discover"
 	"github.com/pbfcoin/go-pbfcoin/pbfdb"
 )
 
@@ -65,3 +68,32 @@ func TestMipmapUpgrade(t *testing.T) {
 		t.Error("setting-mipmap-version not written to database")
 	}
 }
+
+// Tests that node list updates are written back to the data directory and are
+// picked up again on the next startup.
+func TestUpdateNodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pbf-nodes-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		pbf  = &pbfcoin{DataDir: dir}
+		keep = discover.MustParseNode("enode://a979fb575495b8d6db44f750317d0f4622bf4c2aa3365d6af7c284339968eef29b69ad0dce72a4d8db5ebb4968de0e3bec910127f134779fbcb0cb6d3331163c@52.16.188.185:30303")
+		drop = discover.MustParseNode("enode://de471bccee3d042261d52e9bff31458daecc406142b401d4cd848f677479f73104b9fdeb090af9583d3391b7f10cb2ba9e26865dd5fca4fcdc0fb1e3b723c786@54.94.239.50:30303")
+	)
+	if err := pbf.updateNodes(trustedNodes, keep, true); err != nil {
+		t.Fatalf("failed to add node: %v", err)
+	}
+	if err := pbf.updateNodes(trustedNodes, drop, true); err != nil {
+		t.Fatalf("failed to add node: %v", err)
+	}
+	if err := pbf.updateNodes(trustedNodes, drop, false); err != nil {
+		t.Fatalf("failed to remove node: %v", err)
+	}
+	nodes := (&Config{DataDir: dir}).parseNodes(trustedNodes)
+	if len(nodes) != 1 || nodes[0].ID != keep.ID {
+		t.Errorf("node list mismatch: have %v, want [%v]", nodes, keep)
+	}
+}
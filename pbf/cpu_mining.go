@@ -29,6 +29,11 @@ import (
 const disabledInfo = "Set GO_OPENCL and re-build to enable."
 
 func (s *pbfcoin) StartMining(threads int, gpus string) error {
+	if s.Odr() != nil {
+		err := errors.New("Cannot start mining on a light client")
+		glog.V(logger.Error).Infoln(err)
+		return err
+	}
 	eb, err := s.pbferbase()
 	if err != nil {
 		err = fmt.Errorf("Cannot start mining without pbferbase address: %v", err)
@@ -41,6 +46,7 @@ func (s *pbfcoin) StartMining(threads int, gpus string) error {
 	}
 
 	// CPU mining
+	s.authorizeSigner(eb)
 	go s.miner.Start(eb, threads)
 	return nil
 }
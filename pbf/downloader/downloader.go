@@ -33,6 +33,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/event"
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/params"
 	"github.com/pbfcoin/go-pbfcoin/pbfdb"
 	"github.com/rcrowley/go-metrics"
 )
@@ -92,6 +93,7 @@ var (
 	errCancelStateFetch   = errors.New("state data download canceled (requested)")
 	errCancelProcessing   = errors.New("processing canceled (requested)")
 	errNoSyncActive       = errors.New("no sync active")
+	errCheckpointMismatch = errors.New("checkpoint not in remote chain")
 )
 
 type Downloader struct {
@@ -99,6 +101,8 @@ type Downloader struct {
 	noFast bool           // Flag to disable fast syncing in case of a security error
 	mux    *event.TypeMux // Event multiplexer to announce sync operation events
 
+	checkpoint *params.Checkpoint // Trusted block remote chains must contain, nil if none
+
 	queue *queue   // Scheduler for selecting the hashes to download
 	peers *peerSet // Set of active peers from which download can proceed
 
@@ -122,10 +126,11 @@ type Downloader struct {
 	commitHeadBlock  headBlockCommitterFn     // Commits a manually assembled block as the chain head
 	getTd            tdRetrievalFn            // Retrieves the TD of a block from the chain
 	insertHeaders    headerChainInsertFn      // Injects a batch of headers into the chain
+	insertCheckpoint checkpointInsertFn       // Injects a trusted header into the chain
 	insertBlocks     blockChainInsertFn       // Injects a batch of blocks into the chain
 	insertReceipts   receiptChainInsertFn     // Injects a batch of blocks and their receipts into the chain
 	rollback         chainRollbackFn          // Removes a batch of recently added chain links
-	dropPeer         peerDropFn               // Drops a peer for misbehaving
+	dropPeer         peerDropFn               // Drops a peer for misbehaving or stalling
 
 	// Status
 	synchroniseMock func(id string, hash common.Hash) error // Replacement for synchronise during testing
@@ -159,7 +164,7 @@ type Downloader struct {
 func New(stateDb pbfdb.Database, mux *event.TypeMux, hasHeader headerCheckFn, hasBlockAndState blockAndStateCheckFn,
 	gpbfeader headerRetrievalFn, getBlock blockRetrievalFn, headHeader headHeaderRetrievalFn, headBlock headBlockRetrievalFn,
 	headFastBlock headFastBlockRetrievalFn, commitHeadBlock headBlockCommitterFn, getTd tdRetrievalFn, insertHeaders headerChainInsertFn,
-	insertBlocks blockChainInsertFn, insertReceipts receiptChainInsertFn, rollback chainRollbackFn, dropPeer peerDropFn) *Downloader {
+	insertCheckpoint checkpointInsertFn, insertBlocks blockChainInsertFn, insertReceipts receiptChainInsertFn, rollback chainRollbackFn, dropPeer peerDropFn) *Downloader {
 
 	return &Downloader{
 		mode:             FullSync,
@@ -176,6 +181,7 @@ func New(stateDb pbfdb.Database, mux *event.TypeMux, hasHeader headerCheckFn, ha
 		commitHeadBlock:  commitHeadBlock,
 		getTd:            getTd,
 		insertHeaders:    insertHeaders,
+		insertCheckpoint: insertCheckpoint,
 		insertBlocks:     insertBlocks,
 		insertReceipts:   insertReceipts,
 		rollback:         rollback,
@@ -213,6 +219,13 @@ func (d *Downloader) Progress() (uint64, uint64, uint64) {
 	return d.syncStatsChainOrigin, current, d.syncStatsChainHeight
 }
 
+// SetCheckpoint sets the trusted block remote chains must contain to be synced
+// with. Fast and light syncs of a local chain behind it skip the blocks up to
+// the checkpoint. It must be called before synchronising.
+func (d *Downloader) SetCheckpoint(checkpoint *params.Checkpoint) {
+	d.checkpoint = checkpoint
+}
+
 // Synchronising returns whpbfer the downloader is currently retrieving blocks.
 func (d *Downloader) Synchronising() bool {
 	return atomic.LoadInt32(&d.synchronising) > 0
@@ -259,9 +272,13 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 	case errBusy:
 		glog.V(logger.Detail).Infof("Synchronisation already in progress")
 
-	case errTimeout, errBadPeer, errStallingPeer, errEmptyHashSet, errEmptyHeaderSet, errPeersUnavailable, errInvalidChain:
+	case errTimeout, errStallingPeer:
 		glog.V(logger.Debug).Infof("Removing peer %v: %v", id, err)
-		d.dropPeer(id)
+		d.dropPeer(id, true)
+
+	case errBadPeer, errEmptyHashSet, errEmptyHeaderSet, errPeersUnavailable, errInvalidChain, errCheckpointMismatch:
+		glog.V(logger.Debug).Infof("Removing peer %v: %v", id, err)
+		d.dropPeer(id, false)
 
 	default:
 		glog.V(logger.Warn).Infof("Synchronisation failed: %v", err)
@@ -341,6 +358,11 @@ func (d *Downloader) syncWithPeer(p *peer, hash common.Hash, td *big.Int) (err e
 
 	switch {
 	case p.version == 61:
+		// Headers can't be retrieved by number to verify a checkpoint, refuse the peer
+		if d.checkpoint != nil {
+			glog.V(logger.Debug).Infof("%v: cannot verify checkpoint on pbf/61", p)
+			return errCheckpointMismatch
+		}
 		// Look up the sync boundaries: the common ancestor and the target block
 		latest, err := d.fetchHeight61(p)
 		if err != nil {
@@ -373,10 +395,25 @@ func (d *Downloader) syncWithPeer(p *peer, hash common.Hash, td *big.Int) (err e
 		if err != nil {
 			return err
 		}
+		var checkpoint *types.Header
+		if d.checkpoint != nil {
+			if checkpoint, err = d.fetchCheckpoint(p); err != nil {
+				return err
+			}
+		}
 		origin, err := d.findAncestor(p)
 		if err != nil {
 			return err
 		}
+		// Skip the chain below the checkpoint if it's not needed to sync the head
+		if checkpoint != nil && d.mode != FullSync && origin < d.checkpoint.Number {
+			if !d.hasHeader(d.checkpoint.Hash) {
+				if err := d.insertCheckpoint(checkpoint, d.checkpoint.TD); err != nil {
+					return err
+				}
+			}
+			origin = d.checkpoint.Number
+		}
 		d.syncStatsLock.Lock()
 		if d.syncStatsChainHeight <= origin || d.syncStatsChainOrigin > origin {
 			d.syncStatsChainOrigin = origin
@@ -398,6 +435,14 @@ func (d *Downloader) syncWithPeer(p *peer, hash common.Hash, td *big.Int) (err e
 			if latest > uint64(fsMinFullBlocks)+pivotOffset.Uint64() {
 				pivot = latest - uint64(fsMinFullBlocks) - pivotOffset.Uint64()
 			}
+			// Never pivot below the checkpoint, the chain before it may be missing,
+			// nor beyond the remote head
+			if checkpoint != nil && pivot <= d.checkpoint.Number {
+				pivot = d.checkpoint.Number + 1
+			}
+			if pivot > latest {
+				pivot = latest
+			}
 			// If the point is below the origin, move origin back to ensure state download
 			if pivot < origin {
 				if pivot > 0 {
@@ -840,7 +885,7 @@ func (d *Downloader) fetchBlocks61(from uint64) error {
 						peer.SetBlocksIdle(0)
 					} else {
 						glog.V(logger.Debug).Infof("%s: stalling block delivery, dropping", peer)
-						d.dropPeer(pid)
+						d.dropPeer(pid, true)
 					}
 				}
 			}
@@ -944,6 +989,51 @@ func (d *Downloader) fetchHeight(p *peer) (uint64, error) {
 	}
 }
 
+// fetchCheckpoint retrieves the header of the remote peer at the checkpoint
+// number and verifies that it's the trusted checkpoint block.
+func (d *Downloader) fetchCheckpoint(p *peer) (*types.Header, error) {
+	glog.V(logger.Debug).Infof("%v: verifying checkpoint #%d", p, d.checkpoint.Number)
+
+	// Request the header at the checkpoint number and wait for the response
+	go p.getAbsHeaders(d.checkpoint.Number, 1, 0, false)
+
+	timeout := time.After(headerTTL)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelHeaderFetch
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				glog.V(logger.Debug).Infof("Received headers from incorrect peer(%s)", packet.PeerId())
+				break
+			}
+			// Make sure the peer's chain contains the checkpoint
+			headers := packet.(*headerPack).headers
+			if len(headers) != 1 || headers[0].Number.Uint64() != d.checkpoint.Number || headers[0].Hash() != d.checkpoint.Hash {
+				glog.V(logger.Debug).Infof("%v: checkpoint #%d [%x…] not in remote chain", p, d.checkpoint.Number, d.checkpoint.Hash[:4])
+				return nil, errCheckpointMismatch
+			}
+			return headers[0], nil
+
+		case <-timeout:
+			glog.V(logger.Debug).Infof("%v: checkpoint header timeout", p)
+			return nil, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.stateCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+
+		case <-d.hashCh:
+		case <-d.blockCh:
+			// Ignore pbf/61 packets because this is pbf/62+.
+			// These can arrive as a late delivery from a previous sync.
+		}
+	}
+}
+
 // findAncestor tries to locate the common ancestor link of the local chain and
 // a remote peers blockchain. In the general case when our node was in sync and
 // on the correct chain, checking the top N links should already get us a match.
@@ -1241,7 +1331,7 @@ func (d *Downloader) fetchHeaders(p *peer, td *big.Int, from uint64) error {
 			// Header retrieval timed out, consider the peer bad and drop
 			glog.V(logger.Debug).Infof("%v: header request timed out", p)
 			headerTimeoutMeter.Mark(1)
-			d.dropPeer(p.id)
+			d.dropPeer(p.id, true)
 
 			// Finish the sync gracefully instead of dumping the gathered data though
 			for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh, d.stateWakeCh} {
@@ -1438,7 +1528,7 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 						setIdle(peer, 0)
 					} else {
 						glog.V(logger.Debug).Infof("%s: stalling %s delivery, dropping", peer, strings.ToLower(kind))
-						d.dropPeer(pid)
+						d.dropPeer(pid, true)
 					}
 				}
 			}
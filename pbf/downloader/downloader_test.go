@@ -154,7 +154,7 @@ func newTester() *downloadTester {
 
 	tester.downloader = New(tester.stateDb, new(event.TypeMux), tester.hasHeader, tester.hasBlock, tester.gpbfeader,
 		tester.getBlock, tester.headHeader, tester.headBlock, tester.headFastBlock, tester.commitHeadBlock, tester.getTd,
-		tester.insertHeaders, tester.insertBlocks, tester.insertReceipts, tester.rollback, tester.dropPeer)
+		tester.insertHeaders, tester.insertCheckpoint, tester.insertBlocks, tester.insertReceipts, tester.rollback, tester.dropPeer)
 
 	return tester
 }
@@ -293,6 +293,21 @@ func (dl *downloadTester) insertHeaders(headers []*types.Header, checkFreq int)
 	return len(headers), nil
 }
 
+// insertCheckpoint injects a trusted header into the simulated chain, without
+// any of its ancestors.
+func (dl *downloadTester) insertCheckpoint(header *types.Header, td *big.Int) error {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	dl.ownHashes = append(dl.ownHashes, header.Hash())
+	dl.ownHeaders[header.Hash()] = header
+	dl.ownChainTd[header.Hash()] = td
+
+	// The simulated receipt insertion needs parent blocks, mark the header as one
+	dl.ownBlocks[header.Hash()] = types.NewBlockWithHeader(header)
+	return nil
+}
+
 // insertBlocks injects a new batch of blocks into the simulated chain.
 func (dl *downloadTester) insertBlocks(blocks types.Blocks) (int, error) {
 	dl.lock.Lock()
@@ -416,7 +431,7 @@ func (dl *downloadTester) newSlowPeer(id string, version int, hashes []common.Ha
 }
 
 // dropPeer simulates a hard peer removal from the connection pool.
-func (dl *downloadTester) dropPeer(id string) {
+func (dl *downloadTester) dropPeer(id string, timeout bool) {
 	dl.lock.Lock()
 	defer dl.lock.Unlock()
 
@@ -1251,6 +1266,7 @@ func testBlockHeaderAttackerDropping(t *testing.T, protocol int) {
 		{errCancelBodyFetch, false},    // Synchronisation was canceled, origin may be innocent, don't drop
 		{errCancelReceiptFetch, false}, // Synchronisation was canceled, origin may be innocent, don't drop
 		{errCancelProcessing, false},   // Synchronisation was canceled, origin may be innocent, don't drop
+		{errCheckpointMismatch, true},  // Remote chain doesn't contain the trusted checkpoint, drop
 	}
 	// Run the tests and check disconnection status
 	tester := newTester()
@@ -1619,3 +1635,89 @@ func testDeliverHeadersHang(t *testing.T, protocol int, mode SyncMode) {
 		}
 	}
 }
+
+// Tests that fast and light syncs from an empty chain start at the checkpoint,
+// skipping the blocks below it, while full syncs still retrieve the whole chain.
+func TestCheckpointSync62(t *testing.T)      { testCheckpointSync(t, 62, FullSync) }
+func TestCheckpointSync63Full(t *testing.T)  { testCheckpointSync(t, 63, FullSync) }
+func TestCheckpointSync63Fast(t *testing.T)  { testCheckpointSync(t, 63, FastSync) }
+func TestCheckpointSync64Full(t *testing.T)  { testCheckpointSync(t, 64, FullSync) }
+func TestCheckpointSync64Fast(t *testing.T)  { testCheckpointSync(t, 64, FastSync) }
+func TestCheckpointSync64Light(t *testing.T) { testCheckpointSync(t, 64, LightSync) }
+
+func testCheckpointSync(t *testing.T, protocol int, mode SyncMode) {
+	t.Parallel()
+
+	// Create a chain with a checkpoint more than a header batch into it
+	targetBlocks := 3 * MaxHeaderFetch
+	hashes, headers, blocks, receipts := makeChain(targetBlocks, 0, genesis, nil)
+	number := 2 * MaxHeaderFetch
+
+	tester := newTester()
+	tester.newPeer("peer", protocol, hashes, headers, blocks, receipts)
+
+	checkpoint := hashes[targetBlocks-number]
+	tester.downloader.SetCheckpoint(&params.Checkpoint{
+		Number: uint64(number),
+		Hash:   checkpoint,
+		TD:     tester.peerChainTds["peer"][checkpoint],
+	})
+	// Synchronise with the peer and make sure the pre-checkpoint chain was only retrieved in full sync
+	if err := tester.sync("peer", nil, mode); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	if mode == FullSync {
+		assertOwnChain(t, tester, targetBlocks+1)
+		return
+	}
+	if hs := len(tester.ownHeaders); hs != targetBlocks-number+2 {
+		t.Fatalf("synchronised headers mismatch: have %v, want %v", hs, targetBlocks-number+2)
+	}
+	if tester.gpbfeader(hashes[targetBlocks-number+1]) != nil {
+		t.Fatalf("header below the checkpoint retrieved")
+	}
+	if head := tester.headHeader().Hash(); head != hashes[0] {
+		t.Fatalf("head header mismatch: have %x, want %x", head[:4], hashes[0][:4])
+	}
+	if td := tester.getTd(hashes[0]); td.Cmp(tester.peerChainTds["peer"][hashes[0]]) != 0 {
+		t.Fatalf("head total difficulty mismatch: have %v, want %v", td, tester.peerChainTds["peer"][hashes[0]])
+	}
+	if mode == FastSync {
+		if head := tester.headBlock().Hash(); head != hashes[0] {
+			t.Fatalf("head block mismatch: have %x, want %x", head[:4], hashes[0][:4])
+		}
+	}
+}
+
+// Tests that peers whose chain doesn't contain the checkpoint are refused before
+// anything is retrieved from them.
+func TestCheckpointMismatch61(t *testing.T)      { testCheckpointMismatch(t, 61, FullSync) }
+func TestCheckpointMismatch62(t *testing.T)      { testCheckpointMismatch(t, 62, FullSync) }
+func TestCheckpointMismatch63Full(t *testing.T)  { testCheckpointMismatch(t, 63, FullSync) }
+func TestCheckpointMismatch63Fast(t *testing.T)  { testCheckpointMismatch(t, 63, FastSync) }
+func TestCheckpointMismatch64Full(t *testing.T)  { testCheckpointMismatch(t, 64, FullSync) }
+func TestCheckpointMismatch64Fast(t *testing.T)  { testCheckpointMismatch(t, 64, FastSync) }
+func TestCheckpointMismatch64Light(t *testing.T) { testCheckpointMismatch(t, 64, LightSync) }
+
+func testCheckpointMismatch(t *testing.T, protocol int, mode SyncMode) {
+	t.Parallel()
+
+	// Create a chain and a checkpoint of some other chain
+	targetBlocks := 2 * MaxHeaderFetch
+	hashes, headers, blocks, receipts := makeChain(targetBlocks, 0, genesis, nil)
+
+	tester := newTester()
+	tester.newPeer("peer", protocol, hashes, headers, blocks, receipts)
+	tester.downloader.SetCheckpoint(&params.Checkpoint{
+		Number: uint64(MaxHeaderFetch),
+		Hash:   common.Hash{0x01},
+		TD:     big.NewInt(1),
+	})
+	// Synchronise with the peer and make sure it's refused
+	if err := tester.sync("peer", nil, mode); err != errCheckpointMismatch {
+		t.Fatalf("synchronisation error mismatch: have %v, want %v", err, errCheckpointMismatch)
+	}
+	if hs := len(tester.ownHeaders); hs != 1 {
+		t.Fatalf("synchronised headers mismatch: have %v, want %v", hs, 1)
+	}
+}
@@ -54,6 +54,9 @@ type tdRetrievalFn func(common.Hash) *big.Int
 // headerChainInsertFn is a callback type to insert a batch of headers into the local chain.
 type headerChainInsertFn func([]*types.Header, int) (int, error)
 
+// checkpointInsertFn is a callback type to insert a trusted header with its total difficulty into the local chain.
+type checkpointInsertFn func(*types.Header, *big.Int) error
+
 // blockChainInsertFn is a callback type to insert a batch of blocks into the local chain.
 type blockChainInsertFn func(types.Blocks) (int, error)
 
@@ -63,8 +66,9 @@ type receiptChainInsertFn func(types.Blocks, []types.Receipts) (int, error)
 // chainRollbackFn is a callback type to remove a few recently added elements from the local chain.
 type chainRollbackFn func([]common.Hash)
 
-// peerDropFn is a callback type for dropping a peer detected as malicious.
-type peerDropFn func(id string)
+// peerDropFn is a callback type for dropping a peer detected as malicious, or
+// merely too slow to be of use if timeout is set.
+type peerDropFn func(id string, timeout bool)
 
 // dataPack is a data message returned by a peer for some query.
 type dataPack interface {
@@ -0,0 +1,150 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package pbf
+
+import (
+	"sync"
+	"time"
+)
+
+// flowParams are the flow control parameters a light server grants to each of
+// its clients: a request buffer of BufLimit cost units, recharging by MinRecharge
+// units every millisecond. Requests costing more than the buffer are rejected.
+type flowParams struct {
+	BufLimit    uint64
+	MinRecharge uint64
+}
+
+// defaultFlowParams lets a client burst a few hundred proofs and sustain a few
+// dozen every second.
+var defaultFlowParams = flowParams{BufLimit: 300000000, MinRecharge: 50000}
+
+// requestCost is the cost of a type of light request, made up of a base cost
+// and a cost for every requested item.
+type requestCost struct {
+	MsgCode  uint64
+	BaseCost uint64
+	ReqCost  uint64
+}
+
+// requestCosts is the cost table a light server announces to its clients.
+type requestCosts []requestCost
+
+// defaultRequestCosts roughly follows the database and CPU load of serving the
+// different request types, while letting the largest request of every type fit
+// into a full buffer.
+var defaultRequestCosts = requestCosts{
+	{LightGetBlockHeadersMsg, 150000, 7500},
+	{LightGetBlockBodiesMsg, 0, 700000},
+	{LightGetReceiptsMsg, 0, 1000000},
+	{LightGetProofsMsg, 0, 700000},
+	{LightGetCodeMsg, 0, 450000},
+}
+
+// cost calculates the cost of a request for the given number of items, returning
+// false if the request type isn't served.
+func (costs requestCosts) cost(code uint64, amount int) (uint64, bool) {
+	for _, c := range costs {
+		if c.MsgCode == code {
+			return c.BaseCost + c.ReqCost*uint64(amount), true
+		}
+	}
+	return 0, false
+}
+
+// flowBuffer is the request buffer of a light client. Servers track it to police
+// their clients, while clients estimate it to avoid exceeding it.
+type flowBuffer struct {
+	params  flowParams
+	value   uint64
+	updated time.Time
+	lock    sync.Mutex
+}
+
+// newFlowBuffer creates a full request buffer.
+func newFlowBuffer(params flowParams) *flowBuffer {
+	return &flowBuffer{
+		params:  params,
+		value:   params.BufLimit,
+		updated: time.Now(),
+	}
+}
+
+// recharge adds the recharge accumulated since the last update to the buffer.
+func (b *flowBuffer) recharge(now time.Time) {
+	ms := uint64(now.Sub(b.updated) / time.Millisecond)
+	if missing := b.params.BufLimit - b.value; b.params.MinRecharge == 0 || ms < missing/b.params.MinRecharge+1 {
+		b.value += ms * b.params.MinRecharge
+		b.updated = b.updated.Add(time.Duration(ms) * time.Millisecond)
+	} else {
+		b.value, b.updated = b.params.BufLimit, now
+	}
+}
+
+// accept deducts the cost of a received request from the buffer, returning the
+// remaining value, or false if the client exceeded its buffer.
+func (b *flowBuffer) accept(cost uint64) (uint64, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.recharge(time.Now())
+	if cost > b.value {
+		return b.value, false
+	}
+	b.value -= cost
+	return b.value, true
+}
+
+// reserve deducts the cost of a request about to be sent from the buffer, or
+// returns how long to wait for the buffer to recharge enough.
+func (b *flowBuffer) reserve(cost uint64) time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.recharge(time.Now())
+	if cost > b.value {
+		if b.params.MinRecharge == 0 {
+			return time.Duration(1<<63 - 1)
+		}
+		missing := cost - b.value
+		return time.Duration((missing+b.params.MinRecharge-1)/b.params.MinRecharge) * time.Millisecond
+	}
+	b.value -= cost
+	return 0
+}
+
+// refund gives back the cost of a reserved request which wasn't sent.
+func (b *flowBuffer) refund(cost uint64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.recharge(time.Now())
+	if b.value += cost; b.value > b.params.BufLimit {
+		b.value = b.params.BufLimit
+	}
+}
+
+// update sets the buffer value reported by the server.
+func (b *flowBuffer) update(value uint64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if value > b.params.BufLimit {
+		value = b.params.BufLimit
+	}
+	b.value, b.updated = value, time.Now()
+}
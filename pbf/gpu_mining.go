@@ -19,6 +19,7 @@
 package pbf
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 	"strconv"
@@ -26,6 +27,7 @@ import (
 	"time"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core"
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
@@ -34,6 +36,11 @@ import (
 )
 
 func (s *pbfcoin) StartMining(threads int, gpus string) error {
+	if s.Odr() != nil {
+		err := errors.New("Cannot start mining on a light client")
+		glog.V(logger.Error).Infoln(err)
+		return err
+	}
 	eb, err := s.pbferbase()
 	if err != nil {
 		err = fmt.Errorf("Cannot start mining without pbferbase address: %v", err)
@@ -57,12 +64,13 @@ func (s *pbfcoin) StartMining(threads int, gpus string) error {
 
 		// TODO: re-creating miner is a bit ugly
 		cl := pbfash.NewCL(ids)
-		s.miner = miner.New(s, s.EventMux(), cl)
+		s.miner = miner.New(s, s.EventMux(), core.NewPowEngine(cl))
 		go s.miner.Start(eb, len(ids))
 		return nil
 	}
 
 	// CPU mining
+	s.authorizeSigner(eb)
 	go s.miner.Start(eb, threads)
 	return nil
 }
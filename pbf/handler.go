@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus"
 	"github.com/pbfcoin/go-pbfcoin/core"
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/event"
@@ -35,7 +36,6 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/pbf/downloader"
 	"github.com/pbfcoin/go-pbfcoin/pbf/fetcher"
 	"github.com/pbfcoin/go-pbfcoin/pbfdb"
-	"github.com/pbfcoin/go-pbfcoin/pow"
 	"github.com/pbfcoin/go-pbfcoin/rlp"
 )
 
@@ -48,8 +48,19 @@ const (
 // not compatible (low protocol version restrictions and high requirements).
 var errIncompatibleConfig = errors.New("incompatible configuration")
 
+// protocolError is a protocol violation of a remote peer, as opposed to a
+// failure of the underlying network connection.
+type protocolError struct {
+	code errCode
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("%v - %v", e.code, e.msg)
+}
+
 func errResp(code errCode, format string, v ...interface{}) error {
-	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+	return &protocolError{code: code, msg: fmt.Sprintf(format, v...)}
 }
 
 type hashFetcherFn func(common.Hash) error
@@ -59,13 +70,17 @@ type ProtocolManager struct {
 	networkId int
 
 	fastSync   bool
+	lightSync  bool
+	lightServ  int // Maximum number of light clients to serve, 0 if not serving
 	txpool     txPool
 	blockchain *core.BlockChain
 	chaindb    pbfdb.Database
 
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
+	odr        *odrRetriever // On-demand data retriever, only used by light clients
 	peers      *peerSet
+	lightPeers *lightPeerSet
 
 	SubProtocols []p2p.Protocol
 
@@ -86,21 +101,29 @@ type ProtocolManager struct {
 
 // NewProtocolManager returns a new pbfcoin sub protocol manager. The pbfcoin sub protocol manages peers capable
 // with the pbfcoin network.
-func NewProtocolManager(fastSync bool, networkId int, mux *event.TypeMux, txpool txPool, pow pow.PoW, blockchain *core.BlockChain, chaindb pbfdb.Database) (*ProtocolManager, error) {
+func NewProtocolManager(mode downloader.SyncMode, lightServ int, networkId int, mux *event.TypeMux, txpool txPool, engine consensus.Engine, blockchain *core.BlockChain, chaindb pbfdb.Database) (*ProtocolManager, error) {
 	// Figure out whpbfer to allow fast sync or not
+	fastSync, lightSync := mode == downloader.FastSync, mode == downloader.LightSync
 	if fastSync && blockchain.CurrentBlock().NumberU64() > 0 {
 		glog.V(logger.Info).Infof("blockchain not empty, fast sync disabled")
 		fastSync = false
 	}
+	// Light clients don't have the data to serve other light clients
+	if lightSync {
+		lightServ = 0
+	}
 	// Create the protocol manager with the base fields
 	manager := &ProtocolManager{
 		networkId:  networkId,
 		fastSync:   fastSync,
+		lightSync:  lightSync,
+		lightServ:  lightServ,
 		eventMux:   mux,
 		txpool:     txpool,
 		blockchain: blockchain,
 		chaindb:    chaindb,
 		peers:      newPeerSet(),
+		lightPeers: newLightPeerSet(),
 		newPeerCh:  make(chan *peer, 1),
 		txsyncCh:   make(chan *txsync),
 		quitSync:   make(chan struct{}),
@@ -109,7 +132,7 @@ func NewProtocolManager(fastSync bool, networkId int, mux *event.TypeMux, txpool
 	manager.SubProtocols = make([]p2p.Protocol, 0, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
 		// Skip protocol version if incompatible with the mode of operation
-		if fastSync && version < pbf63 {
+		if (fastSync || lightSync) && version < pbf63 {
 			continue
 		}
 		// Compatible; initialise the sub-protocol
@@ -137,19 +160,38 @@ func NewProtocolManager(fastSync bool, networkId int, mux *event.TypeMux, txpool
 	if len(manager.SubProtocols) == 0 {
 		return nil, errIncompatibleConfig
 	}
+	// Light clients retrieve their data over the light protocol, which servers serve
+	if lightSync || lightServ > 0 {
+		for i, version := range LightProtocolVersions {
+			version := version // Closure for the run
+			manager.SubProtocols = append(manager.SubProtocols, p2p.Protocol{
+				Name:    LightProtocolName,
+				Version: version,
+				Length:  LightProtocolLengths[i],
+				Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+					return manager.handleLight(newLightPeer(int(version), p, rw))
+				},
+			})
+		}
+	}
 	// Construct the different synchronisation mechanisms
 	manager.downloader = downloader.New(chaindb, manager.eventMux, blockchain.HasHeader, blockchain.HasBlockAndState, blockchain.Gpbfeader,
 		blockchain.GetBlock, blockchain.CurrentHeader, blockchain.CurrentBlock, blockchain.CurrentFastBlock, blockchain.FastSyncCommitHead,
-		blockchain.GetTd, blockchain.InsertHeaderChain, blockchain.InsertChain, blockchain.InsertReceiptChain, blockchain.Rollback,
-		manager.removePeer)
+		blockchain.GetTd, blockchain.InsertHeaderChain, blockchain.InsertCheckpoint, blockchain.InsertChain, blockchain.InsertReceiptChain,
+		blockchain.Rollback, manager.dropSyncPeer)
 
 	validator := func(block *types.Block, parent *types.Block) error {
-		return core.ValidateHeader(pow, block.Header(), parent.Header(), true, false)
+		return core.ValidateHeader(engine, blockchain, block.Header(), parent.Header(), true, false)
 	}
 	heighter := func() uint64 {
 		return blockchain.CurrentBlock().NumberU64()
 	}
-	manager.fetcher = fetcher.New(blockchain.GetBlock, validator, manager.BroadcastBlock, heighter, blockchain.InsertChain, manager.removePeer)
+	manager.fetcher = fetcher.New(blockchain.GetBlock, validator, manager.BroadcastBlock, heighter, blockchain.InsertChain, manager.dropBadBlockPeer)
+
+	// Light clients retrieve any state and block data they need from their peers
+	if lightSync {
+		manager.odr = newOdrRetriever(chaindb, manager.peers, manager.lightPeers, manager.dropPeer, manager.quitSync)
+	}
 
 	return manager, nil
 }
@@ -173,6 +215,37 @@ func (pm *ProtocolManager) removePeer(id string) {
 	}
 }
 
+// dropPeer removes a peer that delivered useless data, also lowering its
+// reputation so that repeat offenders get banned by the server.
+func (pm *ProtocolManager) dropPeer(id string) {
+	if peer := pm.peers.Peer(id); peer != nil {
+		peer.AdjustReputation(p2p.ScoreUselessPeer)
+	}
+	pm.removePeer(id)
+}
+
+// dropSyncPeer removes a peer the downloader deemed useless. Peers that merely
+// timed out or stalled are charged less than those delivering invalid data, as
+// honest but slow or overloaded peers do so too.
+func (pm *ProtocolManager) dropSyncPeer(id string, timeout bool) {
+	if !timeout {
+		pm.dropPeer(id)
+		return
+	}
+	if peer := pm.peers.Peer(id); peer != nil {
+		peer.AdjustReputation(p2p.ScoreTimeout)
+	}
+	pm.removePeer(id)
+}
+
+// dropBadBlockPeer removes a peer that propagated a block failing verification.
+func (pm *ProtocolManager) dropBadBlockPeer(id string) {
+	if peer := pm.peers.Peer(id); peer != nil {
+		peer.AdjustReputation(p2p.ScoreBadBlock)
+	}
+	pm.removePeer(id)
+}
+
 func (pm *ProtocolManager) Start() {
 	// broadcast transactions
 	pm.txSub = pm.eventMux.Subscribe(core.TxPreEvent{})
@@ -242,6 +315,9 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	for {
 		if err := pm.handleMsg(p); err != nil {
 			glog.V(logger.Debug).Infof("%v: message handling failed: %v", p, err)
+			if _, ok := err.(*protocolError); ok {
+				p.AdjustReputation(p2p.ScoreProtocolError)
+			}
 			return err
 		}
 	}
@@ -373,63 +449,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&query); err != nil {
 			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
-		// Gather headers until the fetch or network limits is reached
-		var (
-			bytes   common.StorageSize
-			headers []*types.Header
-			unknown bool
-		)
-		for !unknown && len(headers) < int(query.Amount) && bytes < softResponseLimit && len(headers) < downloader.MaxHeaderFetch {
-			// Retrieve the next header satisfying the query
-			var origin *types.Header
-			if query.Origin.Hash != (common.Hash{}) {
-				origin = pm.blockchain.Gpbfeader(query.Origin.Hash)
-			} else {
-				origin = pm.blockchain.GpbfeaderByNumber(query.Origin.Number)
-			}
-			if origin == nil {
-				break
-			}
-			headers = append(headers, origin)
-			bytes += estHeaderRlpSize
-
-			// Advance to the next header of the query
-			switch {
-			case query.Origin.Hash != (common.Hash{}) && query.Reverse:
-				// Hash based traversal towards the genesis block
-				for i := 0; i < int(query.Skip)+1; i++ {
-					if header := pm.blockchain.Gpbfeader(query.Origin.Hash); header != nil {
-						query.Origin.Hash = header.ParentHash
-					} else {
-						unknown = true
-						break
-					}
-				}
-			case query.Origin.Hash != (common.Hash{}) && !query.Reverse:
-				// Hash based traversal towards the leaf block
-				if header := pm.blockchain.GpbfeaderByNumber(origin.Number.Uint64() + query.Skip + 1); header != nil {
-					if pm.blockchain.GetBlockHashesFromHash(header.Hash(), query.Skip+1)[query.Skip] == query.Origin.Hash {
-						query.Origin.Hash = header.Hash()
-					} else {
-						unknown = true
-					}
-				} else {
-					unknown = true
-				}
-			case query.Reverse:
-				// Number based traversal towards the genesis block
-				if query.Origin.Number >= query.Skip+1 {
-					query.Origin.Number -= (query.Skip + 1)
-				} else {
-					unknown = true
-				}
-
-			case !query.Reverse:
-				// Number based traversal towards the leaf block
-				query.Origin.Number += (query.Skip + 1)
-			}
-		}
-		return p.SendBlockHeaders(headers)
+		return p.SendBlockHeaders(pm.collectHeaders(query))
 
 	case p.version >= pbf62 && msg.Code == BlockHeadersMsg:
 		// A batch of headers arrived to one of our previous requests
@@ -482,6 +502,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&request); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		// Hand the bodies to the on-demand retriever if it's waiting for them
+		if pm.odr != nil && pm.odr.deliver(p.id, msg.Code, request) {
+			break
+		}
 		// Deliver them all to the downloader for queuing
 		trasactions := make([][]*types.Transaction, len(request))
 		uncles := make([][]*types.Header, len(request))
@@ -531,6 +555,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&data); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		// Hand the data to the on-demand retriever if it's waiting for it
+		if pm.odr != nil && pm.odr.deliver(p.id, msg.Code, data) {
+			break
+		}
 		// Deliver all to the downloader
 		if err := pm.downloader.DeliverNodeData(p.id, data); err != nil {
 			glog.V(logger.Debug).Infof("failed to deliver node state data: %v", err)
@@ -578,6 +606,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&receipts); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		// Hand the receipts to the on-demand retriever if it's waiting for them
+		if pm.odr != nil && pm.odr.deliver(p.id, msg.Code, receipts) {
+			break
+		}
 		// Deliver all to the downloader
 		if err := pm.downloader.DeliverReceipts(p.id, receipts); err != nil {
 			glog.V(logger.Debug).Infof("failed to deliver receipts: %v", err)
@@ -615,6 +647,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			p.MarkBlock(block.Hash)
 			p.Spbfead(block.Hash)
 		}
+		// Light clients don't fetch announced blocks, they only follow the headers
+		if pm.lightSync {
+			break
+		}
 		// Schedule all the unknown hashes for retrieval
 		unknown := make([]announce, 0, len(announces))
 		for _, block := range announces {
@@ -645,18 +681,23 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		p.MarkBlock(request.Block.Hash())
 		p.Spbfead(request.Block.Hash())
 
-		pm.fetcher.Enqueue(p.id, request.Block)
-
+		if !pm.lightSync {
+			pm.fetcher.Enqueue(p.id, request.Block)
+		}
 		// Update the peers total difficulty if needed, schedule a download if gapped
 		if request.TD.Cmp(p.Td()) > 0 {
 			p.SetTd(request.TD)
-			td := pm.blockchain.GetTd(pm.blockchain.CurrentBlock().Hash())
+			td := pm.localTd()
 			if request.TD.Cmp(new(big.Int).Add(td, request.Block.Difficulty())) > 0 {
 				go pm.synchronise(p)
 			}
 		}
 
 	case msg.Code == TxMsg:
+		// Light clients can't validate remote transactions, ignore them
+		if pm.lightSync {
+			break
+		}
 		// Transactions arrived, parse all of them and deliver to the pool
 		var txs []*types.Transaction
 		if err := msg.Decode(&txs); err != nil {
@@ -677,6 +718,76 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	return nil
 }
 
+// collectHeaders gathers the headers satisfying a header query, until the fetch
+// or network limits are reached.
+func (pm *ProtocolManager) collectHeaders(query getBlockHeadersData) []*types.Header {
+	var (
+		bytes   common.StorageSize
+		headers []*types.Header
+		unknown bool
+	)
+	for !unknown && len(headers) < int(query.Amount) && bytes < softResponseLimit && len(headers) < downloader.MaxHeaderFetch {
+		// Retrieve the next header satisfying the query
+		var origin *types.Header
+		if query.Origin.Hash != (common.Hash{}) {
+			origin = pm.blockchain.Gpbfeader(query.Origin.Hash)
+		} else {
+			origin = pm.blockchain.GpbfeaderByNumber(query.Origin.Number)
+		}
+		if origin == nil {
+			break
+		}
+		headers = append(headers, origin)
+		bytes += estHeaderRlpSize
+
+		// Advance to the next header of the query
+		switch {
+		case query.Origin.Hash != (common.Hash{}) && query.Reverse:
+			// Hash based traversal towards the genesis block
+			for i := 0; i < int(query.Skip)+1; i++ {
+				if header := pm.blockchain.Gpbfeader(query.Origin.Hash); header != nil {
+					query.Origin.Hash = header.ParentHash
+				} else {
+					unknown = true
+					break
+				}
+			}
+		case query.Origin.Hash != (common.Hash{}) && !query.Reverse:
+			// Hash based traversal towards the leaf block
+			if header := pm.blockchain.GpbfeaderByNumber(origin.Number.Uint64() + query.Skip + 1); header != nil {
+				if pm.blockchain.GetBlockHashesFromHash(header.Hash(), query.Skip+1)[query.Skip] == query.Origin.Hash {
+					query.Origin.Hash = header.Hash()
+				} else {
+					unknown = true
+				}
+			} else {
+				unknown = true
+			}
+		case query.Reverse:
+			// Number based traversal towards the genesis block
+			if query.Origin.Number >= query.Skip+1 {
+				query.Origin.Number -= (query.Skip + 1)
+			} else {
+				unknown = true
+			}
+
+		case !query.Reverse:
+			// Number based traversal towards the leaf block
+			query.Origin.Number += (query.Skip + 1)
+		}
+	}
+	return headers
+}
+
+// localTd retrieves the total difficulty of the local chain head, which for light
+// clients is the head header.
+func (pm *ProtocolManager) localTd() *big.Int {
+	if pm.lightSync {
+		return pm.blockchain.GetTd(pm.blockchain.CurrentHeader().Hash())
+	}
+	return pm.blockchain.GetTd(pm.blockchain.CurrentBlock().Hash())
+}
+
 // BroadcastBlock will either propagate a block to a subset of it's peers, or
 // will only announce it's availability (depending what's requested).
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
@@ -16,6 +16,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/event"
 	"github.com/pbfcoin/go-pbfcoin/p2p"
 	"github.com/pbfcoin/go-pbfcoin/p2p/discover"
+	"github.com/pbfcoin/go-pbfcoin/pbf/downloader"
 	"github.com/pbfcoin/go-pbfcoin/pbfdb"
 )
 
@@ -31,16 +32,20 @@ var (
 func newTestProtocolManager(fastSync bool, blocks int, generator func(int, *core.BlockGen), newtx chan<- []*types.Transaction) (*ProtocolManager, error) {
 	var (
 		evmux         = new(event.TypeMux)
-		pow           = new(core.FakePow)
+		engine        = core.NewPowEngine(new(core.FakePow))
 		db, _         = pbfdb.NewMemDatabase()
 		genesis       = core.WriteGenesisBlockForTesting(db, core.GenesisAccount{testBankAddress, testBankFunds})
-		blockchain, _ = core.NewBlockChain(db, pow, evmux)
+		blockchain, _ = core.NewBlockChain(db, engine, evmux)
 	)
 	chain, _ := core.GenerateChain(genesis, db, blocks, generator)
 	if _, err := blockchain.InsertChain(chain); err != nil {
 		panic(err)
 	}
-	pm, err := NewProtocolManager(fastSync, NetworkId, evmux, &testTxPool{added: newtx}, pow, blockchain, db)
+	mode := downloader.FullSync
+	if fastSync {
+		mode = downloader.FastSync
+	}
+	pm, err := NewProtocolManager(mode, 0, NetworkId, evmux, &testTxPool{added: newtx}, engine, blockchain, db)
 	if err != nil {
 		return nil, err
 	}
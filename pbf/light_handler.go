@@ -0,0 +1,269 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package pbf
+
+import (
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/logger"
+	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/p2p"
+	"github.com/pbfcoin/go-pbfcoin/pbf/downloader"
+	"github.com/pbfcoin/go-pbfcoin/rlp"
+	"github.com/pbfcoin/go-pbfcoin/trie"
+)
+
+// maxLightHeaderSkip is the maximum number of headers a light client may skip
+// between the headers it requests.
+const maxLightHeaderSkip = downloader.MaxHeaderFetch
+
+// maxLightItems is the maximum number of items a light client may request in a
+// single message. The request costs are chosen so that the largest requests fit
+// into a full buffer.
+var maxLightItems = map[uint64]int{
+	LightGetBlockHeadersMsg: downloader.MaxHeaderFetch + (downloader.MaxHeaderFetch-1)*maxLightHeaderSkip,
+	LightGetBlockBodiesMsg:  downloader.MaxBlockFetch,
+	LightGetReceiptsMsg:     downloader.MaxReceiptFetch,
+	LightGetProofsMsg:       downloader.MaxStateFetch,
+	LightGetCodeMsg:         downloader.MaxStateFetch,
+}
+
+// handleLight is the callback invoked to manage the life cycle of a light
+// protocol peer. When this function terminates, the peer is disconnected.
+func (pm *ProtocolManager) handleLight(p *lightPeer) error {
+	glog.V(logger.Debug).Infof("%v: light peer connected [%s]", p, p.Name())
+
+	// Execute the light protocol handshake, light clients announcing their header chain
+	td, head, genesis := pm.blockchain.Status()
+	if pm.lightSync {
+		head = pm.blockchain.CurrentHeader().Hash()
+		td = pm.blockchain.GetTd(head)
+	}
+	if err := p.Handshake(pm.networkId, td, head, genesis, pm.lightServ > 0); err != nil {
+		glog.V(logger.Debug).Infof("%v: light handshake failed: %v", p, err)
+		return err
+	}
+	// Light clients have no use for each other
+	if pm.lightSync && !p.serving {
+		return p2p.DiscUselessPeer
+	}
+	if err := pm.lightPeers.Register(p, pm.lightServ); err != nil {
+		glog.V(logger.Debug).Infof("%v: light peer addition failed: %v", p, err)
+		return err
+	}
+	defer func() {
+		pm.lightPeers.Unregister(p.id)
+		close(p.closed)
+	}()
+	for {
+		if err := pm.handleLightMsg(p); err != nil {
+			glog.V(logger.Debug).Infof("%v: light message handling failed: %v", p, err)
+			if _, ok := err.(*protocolError); ok {
+				p.AdjustReputation(p2p.ScoreProtocolError)
+			}
+			return err
+		}
+	}
+}
+
+// handleLightMsg is invoked whenever an inbound message is received from a remote
+// light peer. The remote connection is torn down upon returning any error.
+func (pm *ProtocolManager) handleLightMsg(p *lightPeer) error {
+	// Read the next message from the remote peer, and ensure it's fully consumed
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	// Handle the message depending on its contents
+	switch msg.Code {
+	case LightStatusMsg:
+		// Status messages should never arrive after the handshake
+		return errResp(ErrExtraStatusMsg, "uncontrolled status message")
+
+	case LightGetBlockHeadersMsg:
+		var req lightHeadersQuery
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		// Check the limits before converting, huge amounts would turn negative
+		query := req.Query
+		if query.Amount > uint64(downloader.MaxHeaderFetch) || query.Skip > maxLightHeaderSkip {
+			return errResp(ErrRequestRejected, "%d headers requested skipping %d (limit %d, %d)", query.Amount, query.Skip, downloader.MaxHeaderFetch, maxLightHeaderSkip)
+		}
+		// Hash based queries walk the skipped headers one by one, charge for them too
+		amount := int(query.Amount)
+		if query.Origin.Hash != (common.Hash{}) && amount > 1 {
+			amount += (amount - 1) * int(query.Skip)
+		}
+		bv, err := pm.acceptLight(p, msg.Code, amount)
+		if err != nil {
+			return err
+		}
+		return p.reply(LightBlockHeadersMsg, req.ReqID, bv, pm.collectHeaders(req.Query))
+
+	case LightGetBlockBodiesMsg:
+		var req lightHashesQuery
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		bv, err := pm.acceptLight(p, msg.Code, len(req.Hashes))
+		if err != nil {
+			return err
+		}
+		var (
+			bytes  int
+			bodies []rlp.RawValue
+		)
+		for _, hash := range req.Hashes {
+			if bytes >= softResponseLimit {
+				break
+			}
+			if data := pm.blockchain.GetBodyRLP(hash); len(data) != 0 {
+				bodies = append(bodies, data)
+				bytes += len(data)
+			}
+		}
+		return p.reply(LightBlockBodiesMsg, req.ReqID, bv, bodies)
+
+	case LightGetReceiptsMsg:
+		var req lightHashesQuery
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		bv, err := pm.acceptLight(p, msg.Code, len(req.Hashes))
+		if err != nil {
+			return err
+		}
+		var (
+			bytes    int
+			receipts []rlp.RawValue
+		)
+		for _, hash := range req.Hashes {
+			if bytes >= softResponseLimit {
+				break
+			}
+			// Retrieve the requested block's receipts, skipping if unknown to us
+			results := core.GetBlockReceipts(pm.chaindb, hash)
+			if results == nil {
+				if header := pm.blockchain.Gpbfeader(hash); header == nil || header.ReceiptHash != types.EmptyRootHash {
+					continue
+				}
+			}
+			if encoded, err := rlp.EncodeToBytes(results); err != nil {
+				glog.V(logger.Error).Infof("failed to encode receipt: %v", err)
+			} else {
+				receipts = append(receipts, encoded)
+				bytes += len(encoded)
+			}
+		}
+		return p.reply(LightReceiptsMsg, req.ReqID, bv, receipts)
+
+	case LightGetProofsMsg:
+		var req lightProofsQuery
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		bv, err := pm.acceptLight(p, msg.Code, len(req.Proofs))
+		if err != nil {
+			return err
+		}
+		// Prove every key, replying with an empty proof if the trie is unknown
+		var (
+			bytes  int
+			proofs [][]rlp.RawValue
+		)
+		for _, query := range req.Proofs {
+			if bytes >= softResponseLimit {
+				break
+			}
+			var proof []rlp.RawValue
+			if t, err := trie.New(query.Root, pm.chaindb); err == nil {
+				proof = t.Prove(query.Key)
+			}
+			for _, node := range proof {
+				bytes += len(node)
+			}
+			proofs = append(proofs, proof)
+		}
+		return p.reply(LightProofsMsg, req.ReqID, bv, proofs)
+
+	case LightGetCodeMsg:
+		var req lightHashesQuery
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		bv, err := pm.acceptLight(p, msg.Code, len(req.Hashes))
+		if err != nil {
+			return err
+		}
+		var (
+			bytes int
+			data  [][]byte
+		)
+		for _, hash := range req.Hashes {
+			if bytes >= softResponseLimit {
+				break
+			}
+			if entry, err := pm.chaindb.Get(hash.Bytes()); err == nil {
+				data = append(data, entry)
+				bytes += len(entry)
+			}
+		}
+		return p.reply(LightCodeMsg, req.ReqID, bv, data)
+
+	case LightBlockHeadersMsg, LightBlockBodiesMsg, LightReceiptsMsg, LightProofsMsg, LightCodeMsg:
+		// A reply arrived to one of our previous requests
+		if !p.serving {
+			return errResp(ErrInvalidMsgCode, "%v from non-serving peer", msg.Code)
+		}
+		var reply lightReply
+		if err := msg.Decode(&reply); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if err := p.deliver(msg.Code, &reply); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+	return nil
+}
+
+// acceptLight charges the cost of a light client request for the given number of
+// items to the client's request buffer, returning the remaining buffer value.
+// Clients exceeding the request limits or their buffer are disconnected.
+func (pm *ProtocolManager) acceptLight(p *lightPeer, code uint64, amount int) (uint64, error) {
+	if p.client == nil {
+		return 0, errResp(ErrInvalidMsgCode, "%v: not serving light clients", code)
+	}
+	if limit := maxLightItems[code]; amount > limit {
+		return 0, errResp(ErrRequestRejected, "%d items requested (limit %d)", amount, limit)
+	}
+	cost, _ := defaultRequestCosts.cost(code, amount)
+	bv, ok := p.client.accept(cost)
+	if !ok {
+		return 0, errResp(ErrRequestRejected, "request cost %d exceeds buffer %d", cost, bv)
+	}
+	return bv, nil
+}
@@ -0,0 +1,262 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package pbf
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pbfcoin/go-pbfcoin/core"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/light"
+	"github.com/pbfcoin/go-pbfcoin/p2p"
+	"github.com/pbfcoin/go-pbfcoin/p2p/discover"
+	"github.com/pbfcoin/go-pbfcoin/params"
+	"github.com/pbfcoin/go-pbfcoin/pbf/downloader"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+	"github.com/pbfcoin/go-pbfcoin/rlp"
+	"github.com/pbfcoin/go-pbfcoin/trie"
+)
+
+// newTestLightClient connects a simulated light client to the light protocol of
+// the given protocol manager, executing the handshake and returning the local
+// side of the connection.
+func newTestLightClient(t *testing.T, pm *ProtocolManager) (*p2p.MsgPipeRW, *lightStatusData, <-chan error) {
+	app, net := p2p.MsgPipe()
+
+	var id discover.NodeID
+	rand.Read(id[:])
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- pm.handleLight(newLightPeer(lpv1, p2p.NewPeer(id, "client", nil), net))
+	}()
+	td, head, genesis := pm.blockchain.Status()
+	if err := p2p.Send(app, LightStatusMsg, &lightStatusData{uint32(lpv1), uint32(NetworkId), td, head, genesis, false, 0, 0, nil}); err != nil {
+		t.Fatalf("status send: %v", err)
+	}
+	msg, err := app.ReadMsg()
+	if err != nil {
+		t.Fatalf("status recv: %v", err)
+	}
+	var status lightStatusData
+	if err := msg.Decode(&status); err != nil {
+		t.Fatalf("status decode: %v", err)
+	}
+	return app, &status, errc
+}
+
+// Tests that light servers announce their flow control parameters and serve
+// Merkle proofs, charging them to the client's buffer.
+func TestLightGetProofs(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, false, 4, nil, nil)
+	pm.lightServ = 1
+	defer pm.Stop()
+
+	app, status, _ := newTestLightClient(t, pm)
+	defer app.Close()
+
+	if !status.Serve || status.BufLimit != defaultFlowParams.BufLimit || len(status.Costs) != len(defaultRequestCosts) {
+		t.Fatalf("server status mismatch: %+v", status)
+	}
+	// Request the proof of the bank account and of a missing one
+	root := pm.blockchain.CurrentBlock().Root()
+	keys := [][]byte{crypto.Sha3(testBankAddress[:]), crypto.Sha3([]byte("missing"))}
+	query := &lightRequest{ReqID: 7, Query: []proofReq{{root, keys[0]}, {root, keys[1]}}}
+	if err := p2p.Send(app, LightGetProofsMsg, query); err != nil {
+		t.Fatalf("failed to send proof request: %v", err)
+	}
+	msg, err := app.ReadMsg()
+	if err != nil {
+		t.Fatalf("failed to read reply: %v", err)
+	}
+	if msg.Code != LightProofsMsg {
+		t.Fatalf("reply code mismatch: have %x, want %x", msg.Code, LightProofsMsg)
+	}
+	var reply lightReply
+	if err := msg.Decode(&reply); err != nil {
+		t.Fatalf("failed to decode reply: %v", err)
+	}
+	cost, _ := defaultRequestCosts.cost(LightGetProofsMsg, 2)
+	if reply.ReqID != 7 || reply.BV > defaultFlowParams.BufLimit-cost {
+		t.Errorf("reply header mismatch: have id %d bv %d, want id 7 bv <= %d", reply.ReqID, reply.BV, defaultFlowParams.BufLimit-cost)
+	}
+	var proofs [][]rlp.RawValue
+	if err := rlp.DecodeBytes(reply.Data, &proofs); err != nil {
+		t.Fatalf("failed to decode proofs: %v", err)
+	}
+	if len(proofs) != 2 {
+		t.Fatalf("proof count mismatch: have %d, want 2", len(proofs))
+	}
+	if value, err := trie.VerifyProof(root, keys[0], proofs[0]); err != nil || value == nil {
+		t.Errorf("bank account proof invalid: %v", err)
+	}
+	if value, err := trie.VerifyProof(root, keys[1], proofs[1]); err != nil || value != nil {
+		t.Errorf("missing account proof invalid: value %x, err %v", value, err)
+	}
+}
+
+// Tests that the largest request of every type fits into a full buffer.
+func TestLightRequestLimitsFitBuffer(t *testing.T) {
+	for code, limit := range maxLightItems {
+		cost, ok := defaultRequestCosts.cost(code, limit)
+		if !ok {
+			t.Errorf("message %#x: no request cost", code)
+			continue
+		}
+		if _, ok := newFlowBuffer(defaultFlowParams).accept(cost); !ok {
+			t.Errorf("message %#x: %d items cost %d, exceeding the buffer of %d", code, limit, cost, defaultFlowParams.BufLimit)
+		}
+	}
+}
+
+// Tests that a request of the maximum size is served with a full buffer, but
+// light clients exceeding their request buffer are disconnected.
+func TestLightFlowControl(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, false, 1, nil, nil)
+	pm.lightServ = 1
+	defer pm.Stop()
+
+	app, _, errc := newTestLightClient(t, pm)
+	defer app.Close()
+
+	proofs := make([]proofReq, downloader.MaxStateFetch)
+	for i := range proofs {
+		proofs[i].Root = pm.blockchain.CurrentBlock().Root()
+	}
+	if err := p2p.Send(app, LightGetProofsMsg, &lightRequest{ReqID: 1, Query: proofs}); err != nil {
+		t.Fatalf("failed to send proof request: %v", err)
+	}
+	msg, err := app.ReadMsg()
+	if err != nil {
+		t.Fatalf("failed to read reply: %v", err)
+	}
+	if msg.Code != LightProofsMsg {
+		t.Fatalf("reply code mismatch: have %x, want %x", msg.Code, LightProofsMsg)
+	}
+	msg.Discard()
+
+	// The buffer recharges far too slowly for a second request right away
+	go p2p.Send(app, LightGetProofsMsg, &lightRequest{ReqID: 2, Query: proofs})
+
+	if err := <-errc; err == nil {
+		t.Fatalf("client exceeding its buffer wasn't disconnected")
+	}
+}
+
+// Tests that header requests above the limits are rejected instead of wrapping
+// around to a negligible cost.
+func TestLightHeadersLimit(t *testing.T) {
+	tests := []getBlockHeadersData{
+		{Origin: hashOrNumber{Number: 0}, Amount: uint64(downloader.MaxHeaderFetch) + 1},
+		{Origin: hashOrNumber{Number: 0}, Amount: math.MaxUint64},
+		{Origin: hashOrNumber{Number: 0}, Amount: 2, Skip: math.MaxUint64},
+	}
+	for i, query := range tests {
+		pm := newTestProtocolManagerMust(t, false, 1, nil, nil)
+		pm.lightServ = 1
+
+		app, _, errc := newTestLightClient(t, pm)
+		go p2p.Send(app, LightGetBlockHeadersMsg, &lightRequest{ReqID: 1, Query: query})
+		if err := <-errc; err == nil {
+			t.Errorf("test %d: client exceeding the limits wasn't disconnected", i)
+		}
+		app.Close()
+		pm.Stop()
+	}
+}
+
+// Tests that light servers limit the number of light clients they serve.
+func TestLightClientLimit(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, false, 0, nil, nil)
+	pm.lightServ = 1
+	defer pm.Stop()
+
+	app1, _, _ := newTestLightClient(t, pm)
+	defer app1.Close()
+	app2, _, errc := newTestLightClient(t, pm)
+	defer app2.Close()
+
+	if err := <-errc; err != errTooManyLightClients {
+		t.Fatalf("second client error mismatch: have %v, want %v", err, errTooManyLightClients)
+	}
+}
+
+// Tests that the on-demand retriever of a light client retrieves state, bodies
+// and receipts from light servers.
+func TestLightOdrRetrieval(t *testing.T) {
+	acc1Key, _ := crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	acc1Addr := crypto.PubkeyToAddress(acc1Key.PublicKey)
+
+	generator := func(i int, block *core.BlockGen) {
+		tx, _ := types.NewTransaction(block.TxNonce(testBankAddress), acc1Addr, big.NewInt(1000), params.TxGas, nil, nil).SignECDSA(testBankKey)
+		block.AddTx(tx)
+	}
+	server := newTestProtocolManagerMust(t, false, 4, generator, nil)
+	server.lightServ = 1
+	defer server.Stop()
+	client := newTestProtocolManagerMust(t, false, 0, nil, nil)
+	defer client.Stop()
+
+	// Connect the two light protocol handlers
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+
+	var id1, id2 discover.NodeID
+	rand.Read(id1[:])
+	rand.Read(id2[:])
+	go server.handleLight(newLightPeer(lpv1, p2p.NewPeer(id1, "client", nil), net))
+	go client.handleLight(newLightPeer(lpv1, p2p.NewPeer(id2, "server", nil), app))
+
+	for i := 0; client.lightPeers.Len() == 0; i++ {
+		if i == 100 {
+			t.Fatalf("light server not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	db, _ := pbfdb.NewMemDatabase()
+	odr := newOdrRetriever(db, newPeerSet(), client.lightPeers, func(string) { t.Errorf("peer dropped") }, make(chan struct{}))
+
+	head := server.blockchain.CurrentBlock()
+	want, _ := server.blockchain.State()
+
+	balance, err := light.NewState(head.Root(), odr).GetBalance(acc1Addr)
+	if err != nil {
+		t.Fatalf("failed to retrieve balance: %v", err)
+	}
+	if balance.Cmp(want.GetBalance(acc1Addr)) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", balance, want.GetBalance(acc1Addr))
+	}
+	statedb, err := light.NewStateDB(head.Root(), odr)
+	if err != nil {
+		t.Fatalf("failed to create on-demand state: %v", err)
+	}
+	if nonce := statedb.GetNonce(testBankAddress); nonce != 4 {
+		t.Errorf("bank nonce mismatch: have %d, want %d", nonce, 4)
+	}
+	receipts, err := light.GetBlockReceipts(odr, head.Header())
+	if err != nil {
+		t.Fatalf("failed to retrieve receipts: %v", err)
+	}
+	if len(receipts) != 1 {
+		t.Errorf("receipt count mismatch: have %d, want 1", len(receipts))
+	}
+}
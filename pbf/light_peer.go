@@ -0,0 +1,331 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package pbf
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/p2p"
+	"github.com/pbfcoin/go-pbfcoin/rlp"
+)
+
+var (
+	errTooManyLightClients = errors.New("too many light clients")
+	errLightPeerClosed     = errors.New("light peer closed")
+	errNotServing          = errors.New("peer doesn't serve the request")
+)
+
+// lightPeer is a remote node speaking the light client protocol, either a light
+// client we serve, or a server we retrieve data from.
+type lightPeer struct {
+	id string
+
+	*p2p.Peer
+	rw p2p.MsgReadWriter
+
+	version int // Protocol version negotiated
+	head    common.Hash
+	td      *big.Int
+
+	serving bool         // Whether the remote peer serves light clients
+	costs   requestCosts // Request costs announced by the remote server
+	server  *flowBuffer  // Estimate of our request buffer at the remote server
+	client  *flowBuffer  // Request buffer of the remote client, if we're serving
+
+	lock     sync.Mutex
+	reqID    uint64                   // Identifier of the last request sent
+	inflight uint64                   // Total cost of the requests in flight
+	pending  map[uint64]*lightPending // Requests in flight, by identifier
+	closed   chan struct{}            // Closed when the peer disconnects
+}
+
+// lightPending is a request in flight to a light server.
+type lightPending struct {
+	code uint64            // Message code of the expected reply
+	cost uint64            // Cost of the request, deducted from the buffer
+	resp chan rlp.RawValue // Channel to deliver the reply data on
+}
+
+func newLightPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *lightPeer {
+	id := p.ID()
+
+	return &lightPeer{
+		Peer:    p,
+		rw:      rw,
+		version: version,
+		id:      fmt.Sprintf("%x", id[:8]),
+		pending: make(map[uint64]*lightPending),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Td retrieves the total difficulty announced by the peer in its handshake.
+func (p *lightPeer) Td() *big.Int {
+	return new(big.Int).Set(p.td)
+}
+
+// request sends a request for the given number of items to a light server and
+// waits for the reply, waiting beforehand if our request buffer at the server is
+// insufficient.
+func (p *lightPeer) request(code uint64, amount int, query interface{}) (rlp.RawValue, error) {
+	cost, ok := p.costs.cost(code, amount)
+	if !p.serving || !ok || cost > p.server.params.BufLimit {
+		return nil, errNotServing
+	}
+	timeout := time.NewTimer(odrRequestTimeout)
+	defer timeout.Stop()
+
+	for {
+		wait := p.server.reserve(cost)
+		if wait == 0 {
+			break
+		}
+		select {
+		case <-time.After(wait):
+		case <-timeout.C:
+			return nil, errOdrTimeout
+		case <-p.closed:
+			return nil, errLightPeerClosed
+		}
+	}
+	// Register the request and send it over, replies always follow their request
+	p.lock.Lock()
+	p.reqID++
+	id, pending := p.reqID, &lightPending{code: code + 1, cost: cost, resp: make(chan rlp.RawValue, 1)}
+	p.pending[id] = pending
+	p.inflight += cost
+	p.lock.Unlock()
+
+	defer func() {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+
+		if _, ok := p.pending[id]; ok {
+			delete(p.pending, id)
+			p.inflight -= cost
+		}
+	}()
+	if err := p2p.Send(p.rw, code, &lightRequest{ReqID: id, Query: query}); err != nil {
+		p.server.refund(cost)
+		return nil, err
+	}
+	select {
+	case data := <-pending.resp:
+		return data, nil
+	case <-timeout.C:
+		return nil, errOdrTimeout
+	case <-p.closed:
+		return nil, errLightPeerClosed
+	}
+}
+
+// deliver hands a reply of a light server to the request waiting for it, and
+// updates our buffer estimate with the value reported by the server. Replies to
+// timed out requests are dropped.
+func (p *lightPeer) deliver(code uint64, reply *lightReply) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pending := p.pending[reply.ReqID]
+	if pending != nil {
+		if pending.code != code {
+			return fmt.Errorf("reply code mismatch: have %x, want %x", code, pending.code)
+		}
+		delete(p.pending, reply.ReqID)
+		p.inflight -= pending.cost
+	}
+	// The server didn't account for the requests still in flight yet
+	if reply.BV > p.inflight {
+		p.server.update(reply.BV - p.inflight)
+	} else {
+		p.server.update(0)
+	}
+	if pending != nil {
+		pending.resp <- reply.Data
+	}
+	return nil
+}
+
+// reply sends the reply to a request of a light client.
+func (p *lightPeer) reply(code uint64, reqID, bv uint64, data interface{}) error {
+	enc, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		return err
+	}
+	return p2p.Send(p.rw, code, &lightReply{ReqID: reqID, BV: bv, Data: enc})
+}
+
+// Handshake executes the light protocol handshake, negotiating the version number,
+// network IDs, difficulties, head and genesis blocks, and whether and with what
+// flow control the peers serve each other.
+func (p *lightPeer) Handshake(network int, td *big.Int, head common.Hash, genesis common.Hash, serve bool) error {
+	status := &lightStatusData{
+		ProtocolVersion: uint32(p.version),
+		NetworkId:       uint32(network),
+		TD:              td,
+		CurrentBlock:    head,
+		GenesisBlock:    genesis,
+		Serve:           serve,
+	}
+	if serve {
+		status.BufLimit, status.MinRecharge = defaultFlowParams.BufLimit, defaultFlowParams.MinRecharge
+		status.Costs = defaultRequestCosts
+	}
+	// Send out own handshake in a new thread
+	errc := make(chan error, 2)
+	var remote lightStatusData // safe to read after two values have been received from errc
+
+	go func() {
+		errc <- p2p.Send(p.rw, LightStatusMsg, status)
+	}()
+	go func() {
+		errc <- p.readStatus(network, &remote, genesis)
+	}()
+	timeout := time.NewTimer(handshakeTimeout)
+	defer timeout.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				return err
+			}
+		case <-timeout.C:
+			return p2p.DiscReadTimeout
+		}
+	}
+	p.td, p.head = remote.TD, remote.CurrentBlock
+	if remote.Serve {
+		p.serving, p.costs = true, remote.Costs
+		p.server = newFlowBuffer(flowParams{BufLimit: remote.BufLimit, MinRecharge: remote.MinRecharge})
+	}
+	if serve {
+		p.client = newFlowBuffer(defaultFlowParams)
+	}
+	return nil
+}
+
+func (p *lightPeer) readStatus(network int, status *lightStatusData, genesis common.Hash) (err error) {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Code != LightStatusMsg {
+		return errResp(ErrNoStatusMsg, "first msg has code %x (!= %x)", msg.Code, LightStatusMsg)
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	// Decode the handshake and make sure everything matches
+	if err := msg.Decode(&status); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	if status.GenesisBlock != genesis {
+		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", status.GenesisBlock, genesis)
+	}
+	if int(status.NetworkId) != network {
+		return errResp(ErrNetworkIdMismatch, "%d (!= %d)", status.NetworkId, network)
+	}
+	if int(status.ProtocolVersion) != p.version {
+		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", status.ProtocolVersion, p.version)
+	}
+	return nil
+}
+
+// String implements fmt.Stringer.
+func (p *lightPeer) String() string {
+	return fmt.Sprintf("Peer %s [%s]", p.id,
+		fmt.Sprintf("%s/%2d", LightProtocolName, p.version),
+	)
+}
+
+// lightPeerSet represents the collection of light protocol peers, both clients
+// and servers.
+type lightPeerSet struct {
+	peers map[string]*lightPeer
+	lock  sync.RWMutex
+}
+
+// newLightPeerSet creates a new light peer set.
+func newLightPeerSet() *lightPeerSet {
+	return &lightPeerSet{
+		peers: make(map[string]*lightPeer),
+	}
+}
+
+// Register injects a new light peer into the working set, or returns an error if
+// the peer is already known, or it is a light client and there are already the
+// maximum allowed number of them.
+func (ps *lightPeerSet) Register(p *lightPeer, maxClients int) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if _, ok := ps.peers[p.id]; ok {
+		return errAlreadyRegistered
+	}
+	if p.client != nil && !p.serving {
+		clients := 0
+		for _, peer := range ps.peers {
+			if peer.client != nil && !peer.serving {
+				clients++
+			}
+		}
+		if clients >= maxClients {
+			return errTooManyLightClients
+		}
+	}
+	ps.peers[p.id] = p
+	return nil
+}
+
+// Unregister removes a light peer from the active set.
+func (ps *lightPeerSet) Unregister(id string) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if _, ok := ps.peers[id]; !ok {
+		return errNotRegistered
+	}
+	delete(ps.peers, id)
+	return nil
+}
+
+// Len returns the current number of light peers in the set.
+func (ps *lightPeerSet) Len() int {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	return len(ps.peers)
+}
+
+// Servers retrieves a list of the light peers serving light clients.
+func (ps *lightPeerSet) Servers() []*lightPeer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*lightPeer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if p.serving {
+			list = append(list, p)
+		}
+	}
+	return list
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package pbf
+
+import (
+	"math/big"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/rlp"
+)
+
+// Constants to match up light protocol versions and messages
+const (
+	lpv1 = 1
+)
+
+// Official short name of the light client protocol used during capability
+// negotiation.
+var LightProtocolName = "lps"
+
+// Supported versions of the light client protocol (first is primary).
+var LightProtocolVersions = []uint{lpv1}
+
+// Number of implemented message corresponding to different light protocol versions.
+var LightProtocolLengths = []uint64{11}
+
+// light protocol message codes
+const (
+	LightStatusMsg          = 0x00
+	LightGetBlockHeadersMsg = 0x01
+	LightBlockHeadersMsg    = 0x02
+	LightGetBlockBodiesMsg  = 0x03
+	LightBlockBodiesMsg     = 0x04
+	LightGetReceiptsMsg     = 0x05
+	LightReceiptsMsg        = 0x06
+	LightGetProofsMsg       = 0x07
+	LightProofsMsg          = 0x08
+	LightGetCodeMsg         = 0x09
+	LightCodeMsg            = 0x0a
+)
+
+// lightStatusData is the network packet for the light protocol status message.
+type lightStatusData struct {
+	ProtocolVersion uint32
+	NetworkId       uint32
+	TD              *big.Int
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+	Serve           bool         // Whether the sender serves light clients
+	BufLimit        uint64       // Request buffer granted to the receiver (if serving)
+	MinRecharge     uint64       // Buffer recharge per millisecond granted to the receiver (if serving)
+	Costs           requestCosts // Costs of the requests served by the sender (if serving)
+}
+
+// lightRequest is the network packet of the light protocol requests, with the
+// query depending on the message code.
+type lightRequest struct {
+	ReqID uint64      // Request identifier echoed in the reply
+	Query interface{} // Request specific query
+}
+
+// lightHeadersQuery is the network packet of a light header request.
+type lightHeadersQuery struct {
+	ReqID uint64
+	Query getBlockHeadersData
+}
+
+// lightHashesQuery is the network packet of light body, receipt and code requests.
+type lightHashesQuery struct {
+	ReqID  uint64
+	Hashes []common.Hash
+}
+
+// lightProofsQuery is the network packet of a light Merkle proof request.
+type lightProofsQuery struct {
+	ReqID  uint64
+	Proofs []proofReq
+}
+
+// proofReq is a request for the Merkle proof of a key in the trie of the given
+// root, be it an account or a storage trie.
+type proofReq struct {
+	Root common.Hash
+	Key  []byte
+}
+
+// lightReply is the network packet of the light protocol replies.
+type lightReply struct {
+	ReqID uint64       // Identifier of the request being answered
+	BV    uint64       // Request buffer value of the client after serving the request
+	Data  rlp.RawValue // Reply specific data
+}
@@ -0,0 +1,389 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package pbf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/light"
+	"github.com/pbfcoin/go-pbfcoin/logger"
+	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/p2p"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+	"github.com/pbfcoin/go-pbfcoin/rlp"
+	"github.com/pbfcoin/go-pbfcoin/trie"
+)
+
+const (
+	odrRequestTimeout = 5 * time.Second // Maximum time to wait for a peer to answer an on-demand request
+	odrMaxAttempts    = 3               // Number of peers to ask before an on-demand retrieval fails
+)
+
+var (
+	errNoOdrPeers        = errors.New("no peers to retrieve data from")
+	errOdrTimeout        = errors.New("on-demand request timed out")
+	errOdrUnavailable    = errors.New("peer doesn't have the requested data")
+	errOdrTerminated     = errors.New("on-demand retrieval terminated")
+	errOdrFailed         = errors.New("on-demand retrieval failed")
+	errOdrHashMismatch   = errors.New("node data hash mismatch")
+	errUnknownOdrRequest = errors.New("unknown on-demand request")
+)
+
+// odrRetriever implements light.OdrBackend, retrieving state, contract code,
+// block bodies and receipts from light servers, or from full peers over pbf/63
+// if no light server can serve them. Since pbf/63 replies don't identify the
+// request they answer, at most one on-demand request is kept in flight per pbf
+// peer.
+type odrRetriever struct {
+	db      pbfdb.Database
+	peers   *peerSet
+	servers *lightPeerSet   // Light servers, preferred as they serve proofs in a single round trip
+	drop    func(id string) // Drops a peer replying with invalid data
+
+	pending map[string]*odrPending // On-demand requests in flight, by peer id
+	idle    chan struct{}          // Closed when a peer finishes a request, waking up waiting retrievals
+	lock    sync.Mutex
+	quit    chan struct{}
+}
+
+// odrPending is an on-demand request in flight, waiting for its reply.
+type odrPending struct {
+	code uint64           // Message code of the expected reply
+	resp chan interface{} // Channel to deliver the decoded reply on, nil once delivered
+}
+
+func newOdrRetriever(db pbfdb.Database, peers *peerSet, servers *lightPeerSet, drop func(id string), quit chan struct{}) *odrRetriever {
+	return &odrRetriever{
+		db:      db,
+		peers:   peers,
+		servers: servers,
+		drop:    drop,
+		pending: make(map[string]*odrPending),
+		idle:    make(chan struct{}),
+		quit:    quit,
+	}
+}
+
+// Database implements light.OdrBackend, returning the local chain database.
+func (r *odrRetriever) Database() pbfdb.Database {
+	return r.db
+}
+
+// Retrieve implements light.OdrBackend, asking different peers until one of them
+// serves valid data. Peers replying with invalid data are dropped.
+func (r *odrRetriever) Retrieve(req light.OdrRequest) error {
+	triedServers, tried := make(map[string]bool), make(map[string]bool)
+	for attempt := 0; attempt < odrMaxAttempts; attempt++ {
+		if server := r.bestServer(triedServers); server != nil {
+			triedServers[server.id] = true
+
+			err := r.retrieveLight(server, req)
+			if done, err := r.result(server, req, err, func() {
+				server.AdjustReputation(p2p.ScoreProtocolError)
+				server.Disconnect(p2p.DiscUselessPeer)
+			}); done {
+				return err
+			}
+			continue
+		}
+		p, err := r.reserve(tried)
+		if err != nil {
+			return err
+		}
+		tried[p.id] = true
+
+		err = r.retrieve(p, req)
+		r.release(p)
+
+		if done, err := r.result(p, req, err, func() { r.drop(p.id) }); done {
+			return err
+		}
+	}
+	return errOdrFailed
+}
+
+// result processes the outcome of a retrieval attempt from a peer, storing the
+// retrieved data on success and dropping peers serving invalid data. It reports
+// whether the retrieval is finished.
+func (r *odrRetriever) result(p fmt.Stringer, req light.OdrRequest, err error, drop func()) (bool, error) {
+	switch err {
+	case nil:
+		req.StoreResult(r.db)
+		return true, nil
+	case errOdrTerminated:
+		return true, err
+	case errOdrTimeout, errOdrUnavailable, errNotServing, errLightPeerClosed:
+		glog.V(logger.Debug).Infof("%v: on-demand request failed: %v", p, err)
+	default:
+		glog.V(logger.Debug).Infof("%v: invalid on-demand reply: %v", p, err)
+		drop()
+	}
+	return false, nil
+}
+
+// bestServer selects the light server with the highest total difficulty which
+// wasn't tried yet.
+func (r *odrRetriever) bestServer(tried map[string]bool) *lightPeer {
+	if r.servers == nil {
+		return nil
+	}
+	var best *lightPeer
+	for _, p := range r.servers.Servers() {
+		if !tried[p.id] && (best == nil || p.td.Cmp(best.td) > 0) {
+			best = p
+		}
+	}
+	return best
+}
+
+// retrieveLight requests the data of an on-demand request from a light server
+// and validates the reply.
+func (r *odrRetriever) retrieveLight(p *lightPeer, req light.OdrRequest) error {
+	switch req := req.(type) {
+	case *light.TrieRequest:
+		data, err := p.request(LightGetProofsMsg, 1, []proofReq{{Root: req.Root, Key: req.Key}})
+		if err != nil {
+			return err
+		}
+		var proofs [][]rlp.RawValue
+		if err := rlp.DecodeBytes(data, &proofs); err != nil {
+			return err
+		}
+		if len(proofs) == 0 || len(proofs[0]) == 0 {
+			return errOdrUnavailable
+		}
+		return req.Validate(proofs[0])
+
+	case *light.NodeDataRequest:
+		data, err := p.request(LightGetCodeMsg, 1, []common.Hash{req.Hash})
+		if err != nil {
+			return err
+		}
+		var entries [][]byte
+		if err := rlp.DecodeBytes(data, &entries); err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return errOdrUnavailable
+		}
+		return req.Validate(entries[0])
+
+	case *light.BodyRequest:
+		data, err := p.request(LightGetBlockBodiesMsg, 1, []common.Hash{req.Header.Hash()})
+		if err != nil {
+			return err
+		}
+		var bodies blockBodiesData
+		if err := rlp.DecodeBytes(data, &bodies); err != nil {
+			return err
+		}
+		if len(bodies) == 0 {
+			return errOdrUnavailable
+		}
+		return req.Validate(&types.Body{Transactions: bodies[0].Transactions, Uncles: bodies[0].Uncles})
+
+	case *light.ReceiptsRequest:
+		data, err := p.request(LightGetReceiptsMsg, 1, []common.Hash{req.Block.Hash()})
+		if err != nil {
+			return err
+		}
+		var receipts [][]*types.Receipt
+		if err := rlp.DecodeBytes(data, &receipts); err != nil {
+			return err
+		}
+		if len(receipts) == 0 {
+			return errOdrUnavailable
+		}
+		return req.Validate(types.Receipts(receipts[0]))
+	}
+	return errUnknownOdrRequest
+}
+
+// retrieve requests the data of an on-demand request from a single peer and
+// validates the reply.
+func (r *odrRetriever) retrieve(p *peer, req light.OdrRequest) error {
+	switch req := req.(type) {
+	case *light.TrieRequest:
+		// Assemble the proof node by node, reusing the ones known locally
+		var proof []rlp.RawValue
+		for {
+			next, err := trie.NextProofNode(req.Root, req.Key, proof)
+			if err != nil {
+				return err
+			}
+			if next == (common.Hash{}) {
+				break
+			}
+			node, _ := r.db.Get(next[:])
+			if len(node) == 0 {
+				if node, err = r.nodeData(p, next); err != nil {
+					return err
+				}
+			}
+			proof = append(proof, node)
+		}
+		return req.Validate(proof)
+
+	case *light.NodeDataRequest:
+		data, err := r.nodeData(p, req.Hash)
+		if err != nil {
+			return err
+		}
+		return req.Validate(data)
+
+	case *light.BodyRequest:
+		reply, err := r.request(p, BlockBodiesMsg, func() error { return p.RequestBodies([]common.Hash{req.Header.Hash()}) })
+		if err != nil {
+			return err
+		}
+		bodies := reply.(blockBodiesData)
+		if len(bodies) == 0 {
+			return errOdrUnavailable
+		}
+		return req.Validate(&types.Body{Transactions: bodies[0].Transactions, Uncles: bodies[0].Uncles})
+
+	case *light.ReceiptsRequest:
+		reply, err := r.request(p, ReceiptsMsg, func() error { return p.RequestReceipts([]common.Hash{req.Block.Hash()}) })
+		if err != nil {
+			return err
+		}
+		receipts := reply.([][]*types.Receipt)
+		if len(receipts) == 0 {
+			return errOdrUnavailable
+		}
+		return req.Validate(types.Receipts(receipts[0]))
+	}
+	return errUnknownOdrRequest
+}
+
+// nodeData requests a single trie node or contract code from a peer.
+func (r *odrRetriever) nodeData(p *peer, hash common.Hash) ([]byte, error) {
+	reply, err := r.request(p, NodeDataMsg, func() error { return p.RequestNodeData([]common.Hash{hash}) })
+	if err != nil {
+		return nil, err
+	}
+	data := reply.([][]byte)
+	if len(data) == 0 {
+		return nil, errOdrUnavailable
+	}
+	if common.BytesToHash(crypto.Sha3(data[0])) != hash {
+		return nil, errOdrHashMismatch
+	}
+	return data[0], nil
+}
+
+// request sends a request to a reserved peer and waits for the reply with the
+// given message code.
+func (r *odrRetriever) request(p *peer, code uint64, send func() error) (interface{}, error) {
+	resp := make(chan interface{}, 1)
+
+	r.lock.Lock()
+	r.pending[p.id].code, r.pending[p.id].resp = code, resp
+	r.lock.Unlock()
+
+	if err := send(); err != nil {
+		return nil, err
+	}
+	timeout := time.NewTimer(odrRequestTimeout)
+	defer timeout.Stop()
+
+	select {
+	case reply := <-resp:
+		return reply, nil
+	case <-timeout.C:
+		return nil, errOdrTimeout
+	case <-r.quit:
+		return nil, errOdrTerminated
+	}
+}
+
+// deliver hands a reply to the on-demand request in flight to the peer, returning
+// false if the peer has no such request, in which case the reply is meant for the
+// downloader or fetcher.
+func (r *odrRetriever) deliver(id string, code uint64, reply interface{}) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	pending := r.pending[id]
+	if pending == nil || pending.resp == nil || pending.code != code {
+		return false
+	}
+	pending.resp <- reply
+	pending.resp = nil
+	return true
+}
+
+// reserve selects the idle pbf/63 peer with the highest total difficulty which
+// wasn't tried yet, waiting for a busy one to become idle if needed.
+func (r *odrRetriever) reserve(tried map[string]bool) (*peer, error) {
+	timeout := time.NewTimer(odrRequestTimeout)
+	defer timeout.Stop()
+
+	for {
+		r.lock.Lock()
+		var (
+			best *peer
+			busy bool
+		)
+		for _, p := range r.peers.AllPeers() {
+			if p.version < pbf63 || tried[p.id] {
+				continue
+			}
+			if r.pending[p.id] != nil {
+				busy = true
+				continue
+			}
+			if best == nil || p.Td().Cmp(best.Td()) > 0 {
+				best = p
+			}
+		}
+		if best != nil {
+			r.pending[best.id] = new(odrPending)
+			r.lock.Unlock()
+			return best, nil
+		}
+		idle := r.idle
+		r.lock.Unlock()
+
+		if !busy {
+			return nil, errNoOdrPeers
+		}
+		select {
+		case <-idle:
+		case <-timeout.C:
+			return nil, errOdrTimeout
+		case <-r.quit:
+			return nil, errOdrTerminated
+		}
+	}
+}
+
+// release marks a reserved peer idle again.
+func (r *odrRetriever) release(p *peer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.pending, p.id)
+	close(r.idle)
+	r.idle = make(chan struct{})
+}
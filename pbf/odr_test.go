@@ -0,0 +1,137 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package pbf
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/light"
+	"github.com/pbfcoin/go-pbfcoin/p2p"
+	"github.com/pbfcoin/go-pbfcoin/p2p/discover"
+	"github.com/pbfcoin/go-pbfcoin/params"
+	"github.com/pbfcoin/go-pbfcoin/pbfdb"
+)
+
+// newTestOdr creates an on-demand retriever connected to a full protocol manager
+// through a test peer, delivering all replies of the full node to the retriever.
+func newTestOdr(t *testing.T, pm *ProtocolManager) (*odrRetriever, func()) {
+	server, _ := newTestPeer("server", pbf63, pm, true)
+
+	var id discover.NodeID
+	rand.Read(id[:])
+	client := newPeer(pbf63, p2p.NewPeer(id, "client", nil), server.app)
+
+	db, _ := pbfdb.NewMemDatabase()
+	peers := newPeerSet()
+	peers.Register(client)
+	odr := newOdrRetriever(db, peers, nil, func(string) { t.Errorf("peer dropped") }, make(chan struct{}))
+
+	go func() {
+		for {
+			msg, err := server.app.ReadMsg()
+			if err != nil {
+				return
+			}
+			var reply interface{}
+			switch msg.Code {
+			case BlockBodiesMsg:
+				var bodies blockBodiesData
+				err = msg.Decode(&bodies)
+				reply = bodies
+			case NodeDataMsg:
+				var data [][]byte
+				err = msg.Decode(&data)
+				reply = data
+			case ReceiptsMsg:
+				var receipts [][]*types.Receipt
+				err = msg.Decode(&receipts)
+				reply = receipts
+			default:
+				msg.Discard()
+				continue
+			}
+			if err != nil {
+				t.Errorf("failed to decode reply: %v", err)
+				return
+			}
+			if !odr.deliver(client.id, msg.Code, reply) {
+				t.Errorf("unexpected reply %d", msg.Code)
+			}
+		}
+	}()
+	return odr, server.close
+}
+
+// Tests that a light client can retrieve state, bodies and receipts from a full
+// node over pbf/63.
+func TestOdrRetrieval(t *testing.T) {
+	acc1Key, _ := crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	acc1Addr := crypto.PubkeyToAddress(acc1Key.PublicKey)
+
+	generator := func(i int, block *core.BlockGen) {
+		tx, _ := types.NewTransaction(block.TxNonce(testBankAddress), acc1Addr, big.NewInt(1000), params.TxGas, nil, nil).SignECDSA(testBankKey)
+		block.AddTx(tx)
+	}
+	pm := newTestProtocolManagerMust(t, false, 4, generator, nil)
+	defer pm.Stop()
+
+	odr, done := newTestOdr(t, pm)
+	defer done()
+
+	head := pm.blockchain.CurrentBlock()
+	want, _ := pm.blockchain.State()
+
+	// Retrieve some accounts through proofs and through the on-demand state database
+	st := light.NewState(head.Root(), odr)
+	for _, addr := range []common.Address{testBankAddress, acc1Addr} {
+		balance, err := st.GetBalance(addr)
+		if err != nil {
+			t.Fatalf("failed to retrieve balance of %x: %v", addr, err)
+		}
+		if balance.Cmp(want.GetBalance(addr)) != 0 {
+			t.Errorf("balance mismatch for %x: have %v, want %v", addr, balance, want.GetBalance(addr))
+		}
+	}
+	statedb, err := light.NewStateDB(head.Root(), odr)
+	if err != nil {
+		t.Fatalf("failed to create on-demand state: %v", err)
+	}
+	if nonce := statedb.GetNonce(testBankAddress); nonce != 4 {
+		t.Errorf("bank nonce mismatch: have %d, want %d", nonce, 4)
+	}
+	// Retrieve the head block and its receipts
+	block, err := light.GetBlock(odr, head.Header())
+	if err != nil {
+		t.Fatalf("failed to retrieve block: %v", err)
+	}
+	if block.Hash() != head.Hash() || len(block.Transactions()) != 1 {
+		t.Errorf("block mismatch: have %x with %d txs, want %x with 1", block.Hash(), len(block.Transactions()), head.Hash())
+	}
+	receipts, err := light.GetBlockReceipts(odr, head.Header())
+	if err != nil {
+		t.Fatalf("failed to retrieve receipts: %v", err)
+	}
+	if len(receipts) != 1 {
+		t.Errorf("receipt count mismatch: have %d, want 1", len(receipts))
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core"
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
@@ -161,7 +162,7 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 		return
 	}
 	// Make sure the peer's TD is higher than our own. If not drop.
-	td := pm.blockchain.GetTd(pm.blockchain.CurrentBlock().Hash())
+	td := pm.localTd()
 	if peer.Td().Cmp(td) <= 0 {
 		return
 	}
@@ -169,10 +170,20 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 	mode := downloader.FullSync
 	if pm.fastSync {
 		mode = downloader.FastSync
+	} else if pm.lightSync {
+		mode = downloader.LightSync
 	}
+	head := pm.blockchain.CurrentHeader()
 	if err := pm.downloader.Synchronise(peer.id, peer.Head(), peer.Td(), mode); err != nil {
 		return
 	}
+	// Light clients don't import blocks, announce the new head header instead
+	if pm.lightSync {
+		if current := pm.blockchain.CurrentHeader(); current.Hash() != head.Hash() {
+			pm.eventMux.Post(core.ChainHeadEvent{Block: types.NewBlockWithHeader(current)})
+		}
+		return
+	}
 	// If fast sync was enabled, and we synced up, disable it
 	if pm.fastSync {
 		// Disable fast sync if we indeed have sompbfing in our chain
@@ -30,6 +30,7 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/errors"
 	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 
 	gometrics "github.com/rcrowley/go-metrics"
 )
@@ -140,6 +141,21 @@ func (self *LDBDatabase) NewIterator() iterator.Iterator {
 	return self.db.NewIterator(nil, nil)
 }
 
+// NewPrefixIterator returns an iterator over the entries with the given key prefix.
+func (self *LDBDatabase) NewPrefixIterator(prefix []byte) Iterator {
+	return self.db.NewIterator(util.BytesPrefix(prefix), nil)
+}
+
+// NewRangeIterator returns an iterator over the entries with keys in [start, limit).
+func (self *LDBDatabase) NewRangeIterator(start, limit []byte) Iterator {
+	return self.db.NewIterator(&util.Range{Start: start, Limit: limit}, nil)
+}
+
+// Compact compacts the database files holding the keys in [start, limit).
+func (self *LDBDatabase) Compact(start, limit []byte) error {
+	return self.db.CompactRange(util.Range{Start: start, Limit: limit})
+}
+
 func (self *LDBDatabase) Close() {
 	// Stop the metrics collection to avoid internal database races
 	self.quitLock.Lock()
@@ -280,6 +296,11 @@ func (b *ldbBatch) Put(key, value []byte) error {
 	return nil
 }
 
+func (b *ldbBatch) Delete(key []byte) error {
+	b.b.Delete(key)
+	return nil
+}
+
 func (b *ldbBatch) Write() error {
 	return b.db.Write(b.b, nil)
 }
@@ -17,8 +17,11 @@
 package pbfdb
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"reflect"
+	"testing"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
 )
@@ -32,3 +35,87 @@ func newDb() *LDBDatabase {
 
 	return db
 }
+
+// testIteration fills a database with some entries sharing prefixes
+// and checks the prefix and range iterators over them.
+func testIteration(t *testing.T, db Database) {
+	keys := []string{"a", "ab", "abc", "abd", "b", "ba", "c"}
+	for _, key := range keys {
+		if err := db.Put([]byte(key), []byte("v"+key)); err != nil {
+			t.Fatalf("failed to put %q: %v", key, err)
+		}
+	}
+	tests := []struct {
+		it   Iterator
+		want []string
+	}{
+		{db.NewPrefixIterator([]byte("ab")), []string{"ab", "abc", "abd"}},
+		{db.NewPrefixIterator([]byte("x")), nil},
+		{db.NewPrefixIterator(nil), keys},
+		{db.NewRangeIterator([]byte("abc"), []byte("ba")), []string{"abc", "abd", "b"}},
+		{db.NewRangeIterator(nil, []byte("ab")), []string{"a"}},
+		{db.NewRangeIterator([]byte("b"), nil), []string{"b", "ba", "c"}},
+	}
+	for i, tt := range tests {
+		var have []string
+		for tt.it.Next() {
+			if value := string(tt.it.Value()); value != "v"+string(tt.it.Key()) {
+				t.Errorf("test %d: value mismatch for %q: have %q", i, tt.it.Key(), value)
+			}
+			have = append(have, string(tt.it.Key()))
+		}
+		if err := tt.it.Error(); err != nil {
+			t.Errorf("test %d: iteration failed: %v", i, err)
+		}
+		tt.it.Release()
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: keys mismatch: have %q, want %q", i, have, tt.want)
+		}
+	}
+}
+
+// testBatchDelete checks that deletions in a batch are only applied on write.
+func testBatchDelete(t *testing.T, db Database) {
+	db.Put([]byte("old"), []byte{1})
+
+	batch := db.NewBatch()
+	batch.Put([]byte("new"), []byte{2})
+	batch.Delete([]byte("old"))
+	if _, err := db.Get([]byte("old")); err != nil {
+		t.Fatalf("deletion applied before batch write")
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	if _, err := db.Get([]byte("old")); err == nil {
+		t.Errorf("deleted entry still present")
+	}
+	if value, err := db.Get([]byte("new")); err != nil || !bytes.Equal(value, []byte{2}) {
+		t.Errorf("new entry mismatch: have %x, %v", value, err)
+	}
+	if err := db.Compact(nil, nil); err != nil {
+		t.Errorf("failed to compact database: %v", err)
+	}
+}
+
+func TestMemDatabaseIteration(t *testing.T) {
+	db, _ := NewMemDatabase()
+	testIteration(t, db)
+}
+
+func TestMemDatabaseBatchDelete(t *testing.T) {
+	db, _ := NewMemDatabase()
+	testBatchDelete(t, db)
+}
+
+func TestLDBDatabaseIteration(t *testing.T) {
+	db := newDb()
+	defer db.Close()
+	testIteration(t, db)
+}
+
+func TestLDBDatabaseBatchDelete(t *testing.T) {
+	db := newDb()
+	defer db.Close()
+	testBatchDelete(t, db)
+}
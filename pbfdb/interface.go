@@ -22,9 +22,36 @@ type Database interface {
 	Delete(key []byte) error
 	Close()
 	NewBatch() Batch
+
+	// NewPrefixIterator returns an iterator over all entries whose key starts
+	// with the given prefix, in ascending key order.
+	NewPrefixIterator(prefix []byte) Iterator
+
+	// NewRangeIterator returns an iterator over all entries with keys in the
+	// range [start, limit), in ascending key order. A nil start denotes the
+	// beginning and a nil limit the end of the key space.
+	NewRangeIterator(start, limit []byte) Iterator
+
+	// Compact compacts the underlying storage for the key range [start, limit).
+	// A nil start denotes the beginning and a nil limit the end of the key space.
+	Compact(start, limit []byte) error
 }
 
 type Batch interface {
 	Put(key, value []byte) error
+	Delete(key []byte) error
 	Write() error
 }
+
+// Iterator iterates over the entries of a database. The iterator is positioned
+// before the first entry, Next has to be called before accessing it. The key
+// and value slices are only valid until the next call to Next.
+//
+// An iterator must be released after use.
+type Iterator interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Error() error
+	Release()
+}
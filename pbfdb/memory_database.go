@@ -17,8 +17,10 @@
 package pbfdb
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
@@ -90,6 +92,46 @@ func (db *MemDatabase) Delete(key []byte) error {
 	return nil
 }
 
+// NewPrefixIterator returns an iterator over the entries with the given key
+// prefix. The iterator works on a snapshot of the database taken at creation.
+func (db *MemDatabase) NewPrefixIterator(prefix []byte) Iterator {
+	return db.newIterator(func(key []byte) bool {
+		return bytes.HasPrefix(key, prefix)
+	})
+}
+
+// NewRangeIterator returns an iterator over the entries with keys in [start,
+// limit). The iterator works on a snapshot of the database taken at creation.
+func (db *MemDatabase) NewRangeIterator(start, limit []byte) Iterator {
+	return db.newIterator(func(key []byte) bool {
+		return bytes.Compare(key, start) >= 0 && (limit == nil || bytes.Compare(key, limit) < 0)
+	})
+}
+
+func (db *MemDatabase) newIterator(include func([]byte) bool) Iterator {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	keys := []string{}
+	for key := range db.db {
+		if include([]byte(key)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = db.db[key]
+	}
+	return &memIterator{keys: keys, values: values, pos: -1}
+}
+
+// Compact is a no-op, there is nothing to compact in memory.
+func (db *MemDatabase) Compact(start, limit []byte) error {
+	return nil
+}
+
 func (db *MemDatabase) Print() {
 	db.lock.RLock()
 	defer db.lock.RUnlock()
@@ -116,7 +158,10 @@ func (db *MemDatabase) NewBatch() Batch {
 	return &memBatch{db: db}
 }
 
-type kv struct{ k, v []byte }
+type kv struct {
+	k, v []byte
+	del  bool
+}
 
 type memBatch struct {
 	db     *MemDatabase
@@ -128,7 +173,15 @@ func (b *memBatch) Put(key, value []byte) error {
 	b.lock.Lock()
 	defer b.lock.Unlock()
 
-	b.writes = append(b.writes, kv{key, common.CopyBytes(value)})
+	b.writes = append(b.writes, kv{k: key, v: common.CopyBytes(value)})
+	return nil
+}
+
+func (b *memBatch) Delete(key []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.writes = append(b.writes, kv{k: common.CopyBytes(key), del: true})
 	return nil
 }
 
@@ -140,7 +193,46 @@ func (b *memBatch) Write() error {
 	defer b.db.lock.Unlock()
 
 	for _, kv := range b.writes {
+		if kv.del {
+			delete(b.db.db, string(kv.k))
+			continue
+		}
 		b.db.db[string(kv.k)] = kv.v
 	}
 	return nil
 }
+
+// memIterator iterates over a sorted snapshot of a memory database.
+type memIterator struct {
+	keys   []string
+	values [][]byte
+	pos    int
+}
+
+func (it *memIterator) Next() bool {
+	if it.pos >= len(it.keys) {
+		return false
+	}
+	it.pos++
+	return it.pos < len(it.keys)
+}
+
+func (it *memIterator) Key() []byte {
+	if it.pos < 0 || it.pos >= len(it.keys) {
+		return nil
+	}
+	return []byte(it.keys[it.pos])
+}
+
+func (it *memIterator) Value() []byte {
+	if it.pos < 0 || it.pos >= len(it.values) {
+		return nil
+	}
+	return it.values[it.pos]
+}
+
+func (it *memIterator) Error() error { return nil }
+
+func (it *memIterator) Release() {
+	it.keys, it.values = nil, nil
+}
@@ -21,6 +21,7 @@ import (
 	"io"
 	"math/big"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
@@ -31,6 +32,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/crypto"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/p2p/discover"
 	"github.com/pbfcoin/go-pbfcoin/pbf"
 	"github.com/pbfcoin/go-pbfcoin/rlp"
 	"github.com/pbfcoin/go-pbfcoin/rpc/codec"
@@ -49,6 +51,12 @@ var (
 	// mapping between methods and handlers
 	AdminMapping = map[string]adminhandler{
 		"admin_addPeer":            (*adminApi).AddPeer,
+		"admin_removePeer":         (*adminApi).RemovePeer,
+		"admin_addTrustedPeer":     (*adminApi).AddTrustedPeer,
+		"admin_removeTrustedPeer":  (*adminApi).RemoveTrustedPeer,
+		"admin_banPeer":            (*adminApi).BanPeer,
+		"admin_unbanPeer":          (*adminApi).UnbanPeer,
+		"admin_bannedPeers":        (*adminApi).BannedPeers,
 		"admin_peers":              (*adminApi).Peers,
 		"admin_nodeInfo":           (*adminApi).NodeInfo,
 		"admin_exportChain":        (*adminApi).ExportChain,
@@ -124,18 +132,107 @@ func (self *adminApi) ApiVersion() string {
 }
 
 func (self *adminApi) AddPeer(req *shared.Request) (interface{}, error) {
-	args := new(AddPeerArgs)
+	args := new(PeerArgs)
 	if err := self.coder.Decode(req.Params, &args); err != nil {
 		return nil, shared.NewDecodeParamError(err.Error())
 	}
 
-	err := self.pbfcoin.AddPeer(args.Url)
+	err := self.pbfcoin.AddPeer(args.Url, args.Persist)
 	if err == nil {
 		return true, nil
 	}
 	return false, err
 }
 
+func (self *adminApi) RemovePeer(req *shared.Request) (interface{}, error) {
+	args := new(PeerArgs)
+	if err := self.coder.Decode(req.Params, &args); err != nil {
+		return nil, shared.NewDecodeParamError(err.Error())
+	}
+
+	err := self.pbfcoin.RemovePeer(args.Url, args.Persist)
+	if err == nil {
+		return true, nil
+	}
+	return false, err
+}
+
+func (self *adminApi) AddTrustedPeer(req *shared.Request) (interface{}, error) {
+	args := new(PeerArgs)
+	if err := self.coder.Decode(req.Params, &args); err != nil {
+		return nil, shared.NewDecodeParamError(err.Error())
+	}
+
+	err := self.pbfcoin.AddTrustedPeer(args.Url, args.Persist)
+	if err == nil {
+		return true, nil
+	}
+	return false, err
+}
+
+func (self *adminApi) RemoveTrustedPeer(req *shared.Request) (interface{}, error) {
+	args := new(PeerArgs)
+	if err := self.coder.Decode(req.Params, &args); err != nil {
+		return nil, shared.NewDecodeParamError(err.Error())
+	}
+
+	err := self.pbfcoin.RemoveTrustedPeer(args.Url, args.Persist)
+	if err == nil {
+		return true, nil
+	}
+	return false, err
+}
+
+// parseNodeID accepts either an enode URL or a hex encoded node id.
+func parseNodeID(node string) (discover.NodeID, error) {
+	if strings.HasPrefix(node, "enode://") {
+		n, err := discover.ParseNode(node)
+		if err != nil {
+			return discover.NodeID{}, fmt.Errorf("invalid node URL: %v", err)
+		}
+		return n.ID, nil
+	}
+	id, err := discover.HexID(node)
+	if err != nil {
+		return discover.NodeID{}, fmt.Errorf("invalid node id: %v", err)
+	}
+	return id, nil
+}
+
+func (self *adminApi) BanPeer(req *shared.Request) (interface{}, error) {
+	args := new(BanPeerArgs)
+	if err := self.coder.Decode(req.Params, &args); err != nil {
+		return nil, shared.NewDecodeParamError(err.Error())
+	}
+	id, err := parseNodeID(args.Node)
+	if err != nil {
+		return false, err
+	}
+	if err := self.pbfcoin.Network().BanNode(id, time.Duration(args.Duration)*time.Second); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (self *adminApi) UnbanPeer(req *shared.Request) (interface{}, error) {
+	args := new(UnbanPeerArgs)
+	if err := self.coder.Decode(req.Params, &args); err != nil {
+		return nil, shared.NewDecodeParamError(err.Error())
+	}
+	id, err := parseNodeID(args.Node)
+	if err != nil {
+		return false, err
+	}
+	if err := self.pbfcoin.Network().UnbanNode(id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (self *adminApi) BannedPeers(req *shared.Request) (interface{}, error) {
+	return self.pbfcoin.Network().BannedNodes(), nil
+}
+
 func (self *adminApi) Peers(req *shared.Request) (interface{}, error) {
 	return self.pbfcoin.Network().PeersInfo(), nil
 }
@@ -23,18 +23,19 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/rpc/shared"
 )
 
-type AddPeerArgs struct {
-	Url string
+type PeerArgs struct {
+	Url     string
+	Persist bool
 }
 
-func (args *AddPeerArgs) UnmarshalJSON(b []byte) (err error) {
+func (args *PeerArgs) UnmarshalJSON(b []byte) (err error) {
 	var obj []interface{}
 	if err := json.Unmarshal(b, &obj); err != nil {
 		return shared.NewDecodeParamError(err.Error())
 	}
 
-	if len(obj) != 1 {
-		return shared.NewDecodeParamError("Expected enode as argument")
+	if len(obj) < 1 || len(obj) > 2 {
+		return shared.NewDecodeParamError("Expected enode and optional persist flag as arguments")
 	}
 
 	urlstr, ok := obj[0].(string)
@@ -43,6 +44,69 @@ func (args *AddPeerArgs) UnmarshalJSON(b []byte) (err error) {
 	}
 	args.Url = urlstr
 
+	if len(obj) == 2 && obj[1] != nil {
+		persist, ok := obj[1].(bool)
+		if !ok {
+			return shared.NewInvalidTypeError("persist", "not a boolean")
+		}
+		args.Persist = persist
+	}
+
+	return nil
+}
+
+type BanPeerArgs struct {
+	Node     string
+	Duration int64
+}
+
+func (args *BanPeerArgs) UnmarshalJSON(b []byte) (err error) {
+	var obj []interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return shared.NewDecodeParamError(err.Error())
+	}
+
+	if len(obj) < 1 {
+		return shared.NewInsufficientParamsError(len(obj), 1)
+	}
+
+	node, ok := obj[0].(string)
+	if !ok {
+		return shared.NewInvalidTypeError("node", "not a string")
+	}
+	args.Node = node
+
+	if len(obj) >= 2 && obj[1] != nil {
+		if n, err := numString(obj[1]); err == nil {
+			args.Duration = n.Int64()
+		} else {
+			return shared.NewInvalidTypeError("duration", "not an integer: "+err.Error())
+		}
+	}
+
+	return nil
+}
+
+type UnbanPeerArgs struct {
+	Node string
+}
+
+func (args *UnbanPeerArgs) UnmarshalJSON(b []byte) (err error) {
+	var obj []interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return shared.NewDecodeParamError(err.Error())
+	}
+
+	if len(obj) != 1 {
+		return shared.NewDecodeParamError("Expected enode or node id as argument")
+	}
+
+	node, ok := obj[0].(string)
+	if !ok {
+		return shared.NewInvalidTypeError("node", "not a string")
+	}
+	args.Node = node
+
 	return nil
 }
 
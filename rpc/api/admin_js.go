@@ -27,6 +27,36 @@ web3._extend({
 			params: 1,
 			inputFormatter: [null]
 		}),
+		new web3._extend.method({
+			name: 'removePeer',
+			call: 'admin_removePeer',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.method({
+			name: 'addTrustedPeer',
+			call: 'admin_addTrustedPeer',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.method({
+			name: 'removeTrustedPeer',
+			call: 'admin_removeTrustedPeer',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.method({
+			name: 'banPeer',
+			call: 'admin_banPeer',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.method({
+			name: 'unbanPeer',
+			call: 'admin_unbanPeer',
+			params: 1,
+			inputFormatter: [null]
+		}),
 		new web3._extend.method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -140,6 +170,10 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'bannedPeers',
+			getter: 'admin_bannedPeers'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
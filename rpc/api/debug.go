@@ -17,12 +17,16 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strings"
 	"time"
 
+	"github.com/pbfcoin/go-pbfcoin/common"
 	"github.com/pbfcoin/go-pbfcoin/core"
 	"github.com/pbfcoin/go-pbfcoin/core/state"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/core/vm"
 	"github.com/pbfcoin/go-pbfcoin/pbf"
 	"github.com/pbfcoin/go-pbfcoin/rlp"
@@ -35,6 +39,9 @@ import (
 
 const (
 	DebugApiVersion = "1.0"
+
+	// default run time limit of Javascript tracers
+	defaultTraceTimeout = 5 * time.Second
 )
 
 var (
@@ -47,6 +54,10 @@ var (
 		"debug_seedHash":     (*debugApi).SeedHash,
 		"debug_spbfead":      (*debugApi).Spbfead,
 		"debug_metrics":      (*debugApi).Metrics,
+
+		"debug_traceTransaction":      (*debugApi).TraceTransaction,
+		"debug_traceTransactionCalls": (*debugApi).TraceTransactionCalls,
+		"debug_traceBlockCalls":       (*debugApi).TraceBlockCalls,
 	}
 )
 
@@ -173,7 +184,7 @@ func (self *debugApi) ProcessBlock(req *shared.Request) (interface{}, error) {
 		processor  = blockchain.Processor()
 	)
 
-	err := core.ValidateHeader(blockchain.AuxValidator(), block.Header(), blockchain.Gpbfeader(block.ParentHash()), true, false)
+	err := core.ValidateHeader(blockchain.Engine(), blockchain, block.Header(), blockchain.Gpbfeader(block.ParentHash()), true, false)
 	if err != nil {
 		return false, err
 	}
@@ -301,3 +312,146 @@ func (self *debugApi) Metrics(req *shared.Request) (interface{}, error) {
 	})
 	return counters, nil
 }
+
+// TraceTransaction replays a mined transaction and returns the executed steps
+// of the VM. Memory, stack and storage capture can be disabled to reduce the
+// size of the result. If a Javascript tracer is given, the result of the
+// tracer is returned instead.
+func (self *debugApi) TraceTransaction(req *shared.Request) (interface{}, error) {
+	args := new(TraceArgs)
+	if err := self.codec.Decode(req.Params, &args); err != nil {
+		return nil, shared.NewDecodeParamError(err.Error())
+	}
+	if args.Tracer != "" {
+		return self.traceTransactionJS(common.HexToHash(args.Hash), args.Tracer, args.Timeout)
+	}
+	logger := vm.NewStructLogger(&args.Config)
+
+	ret, gas, err := self.traceTransaction(common.HexToHash(args.Hash), logger)
+	if err != nil {
+		return nil, err
+	}
+	return NewExecutionResult(ret, gas, logger.StructLogs()), nil
+}
+
+// traceTransactionJS traces a transaction with a Javascript tracer, which is
+// aborted if it runs longer than timeout.
+func (self *debugApi) traceTransactionJS(hash common.Hash, code string, timeout time.Duration) (interface{}, error) {
+	tracer, err := NewJavascriptTracer(code)
+	if err != nil {
+		return nil, err
+	}
+	if timeout == 0 {
+		timeout = defaultTraceTimeout
+	}
+	deadline := time.AfterFunc(timeout, func() {
+		tracer.Stop(errors.New("execution timeout"))
+	})
+	defer deadline.Stop()
+
+	if _, _, err := self.traceTransaction(hash, tracer); err != nil {
+		return nil, err
+	}
+	return tracer.GetResult()
+}
+
+// TraceTransactionCalls replays a mined transaction and returns the tree of
+// message calls it made, including the internal value transfers, contract
+// creations and suicides.
+func (self *debugApi) TraceTransactionCalls(req *shared.Request) (interface{}, error) {
+	args := new(HashArgs)
+	if err := self.codec.Decode(req.Params, &args); err != nil {
+		return nil, shared.NewDecodeParamError(err.Error())
+	}
+	logger := vm.NewCallLogger()
+	if _, _, err := self.traceTransaction(common.HexToHash(args.Hash), logger); err != nil {
+		return nil, err
+	}
+	return NewCallFrameRes(logger.Call()), nil
+}
+
+// TraceBlockCalls replays all transactions of a block and returns the call
+// tree of each of them.
+func (self *debugApi) TraceBlockCalls(req *shared.Request) (interface{}, error) {
+	args := new(BlockNumArg)
+	if err := self.codec.Decode(req.Params, &args); err != nil {
+		return nil, shared.NewDecodeParamError(err.Error())
+	}
+	block := self.xpbf.pbfBlockByNumber(args.BlockNumber)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", args.BlockNumber)
+	}
+	statedb, err := self.parentState(block)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		blockchain = self.pbfcoin.BlockChain()
+		header     = block.Header()
+		gp         = new(core.GasPool).AddGas(block.GasLimit())
+		results    = make([]*TxCallsRes, len(block.Transactions()))
+	)
+	for i, tx := range block.Transactions() {
+		statedb.StartRecord(tx.Hash(), block.Hash(), i)
+
+		logger := vm.NewCallLogger()
+		vmenv := core.NewEnv(statedb, blockchain, tx, header)
+		vmenv.SetTracer(logger)
+		if _, _, err := core.ApplyMessage(vmenv, tx, gp); err != nil {
+			return nil, fmt.Errorf("transaction %x failed: %v", tx.Hash(), err)
+		}
+		// Finalise the state for the following transactions, as the processor does
+		statedb.IntermediateRoot()
+
+		results[i] = &TxCallsRes{TxHash: tx.Hash().Hex(), Calls: NewCallFrameRes(logger.Call())}
+	}
+	return results, nil
+}
+
+// parentState returns the state the transactions of block are executed on.
+func (self *debugApi) parentState(block *types.Block) (*state.StateDB, error) {
+	parent := self.pbfcoin.BlockChain().GetBlock(block.ParentHash())
+	if parent == nil {
+		return nil, fmt.Errorf("block %x not found", block.ParentHash())
+	}
+	return state.New(parent.Root(), self.pbfcoin.ChainDb())
+}
+
+// traceTransaction replays a transaction on the state of its parent block,
+// after executing the transactions preceding it in the block, and feeds the
+// executed steps to tracer. The tracer is only attached to the replayed
+// environment, the global vm.Debug flag isn't touched.
+func (self *debugApi) traceTransaction(hash common.Hash, tracer vm.Tracer) ([]byte, *big.Int, error) {
+	var (
+		chainDb    = self.pbfcoin.ChainDb()
+		blockchain = self.pbfcoin.BlockChain()
+	)
+	tx, blockHash, _, index := core.GetTransaction(chainDb, hash)
+	if tx == nil {
+		return nil, nil, fmt.Errorf("transaction %x not found", hash)
+	}
+	block := blockchain.GetBlock(blockHash)
+	if block == nil {
+		return nil, nil, fmt.Errorf("block %x not found", blockHash)
+	}
+	statedb, err := self.parentState(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		header  = block.Header()
+		gp      = new(core.GasPool).AddGas(block.GasLimit())
+		usedGas = new(big.Int)
+	)
+	for i, prev := range block.Transactions()[:index] {
+		statedb.StartRecord(prev.Hash(), blockHash, i)
+		if _, _, _, err := core.ApplyTransaction(blockchain, gp, statedb, header, prev, usedGas); err != nil {
+			return nil, nil, fmt.Errorf("transaction %x failed: %v", prev.Hash(), err)
+		}
+	}
+	statedb.StartRecord(tx.Hash(), blockHash, int(index))
+
+	vmenv := core.NewEnv(statedb, blockchain, tx, header)
+	vmenv.SetTracer(tracer)
+	return core.ApplyMessage(vmenv, tx, gp)
+}
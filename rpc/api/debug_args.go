@@ -21,7 +21,10 @@ import (
 	"fmt"
 	"math/big"
 	"reflect"
+	"time"
 
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/vm"
 	"github.com/pbfcoin/go-pbfcoin/rpc/shared"
 )
 
@@ -85,3 +88,156 @@ func (args *MetricsArgs) UnmarshalJSON(b []byte) (err error) {
 	}
 	return nil
 }
+
+type TraceArgs struct {
+	Hash    string
+	Config  vm.LogConfig
+	Tracer  string        // Javascript tracer replacing the struct logger if set
+	Timeout time.Duration // maximum run time of a Javascript tracer
+}
+
+func (args *TraceArgs) UnmarshalJSON(b []byte) (err error) {
+	var obj []json.RawMessage
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return shared.NewDecodeParamError(err.Error())
+	}
+	if len(obj) < 1 {
+		return shared.NewInsufficientParamsError(len(obj), 1)
+	}
+	if err := json.Unmarshal(obj[0], &args.Hash); err != nil {
+		return shared.NewInvalidTypeError("hash", "not a string")
+	}
+	if len(obj) > 1 {
+		var config struct {
+			DisableMemory  bool   `json:"disableMemory"`
+			DisableStack   bool   `json:"disableStack"`
+			DisableStorage bool   `json:"disableStorage"`
+			Tracer         string `json:"tracer"`
+			Timeout        string `json:"timeout"`
+		}
+		if err := json.Unmarshal(obj[1], &config); err != nil {
+			return shared.NewDecodeParamError(err.Error())
+		}
+		args.Tracer = config.Tracer
+		if config.Timeout != "" {
+			if args.Timeout, err = time.ParseDuration(config.Timeout); err != nil {
+				return shared.NewInvalidTypeError("timeout", err.Error())
+			}
+		}
+		args.Config = vm.LogConfig{
+			DisableMemory:  config.DisableMemory,
+			DisableStack:   config.DisableStack,
+			DisableStorage: config.DisableStorage,
+		}
+	}
+	return nil
+}
+
+// ExecutionResult is the result of a traced transaction.
+type ExecutionResult struct {
+	Gas         *big.Int       `json:"gas"`
+	ReturnValue string         `json:"returnValue"`
+	StructLogs  []StructLogRes `json:"structLogs"`
+}
+
+// StructLogRes is a single executed step of a traced transaction. The stack
+// and memory are listed as 32 byte words.
+type StructLogRes struct {
+	Pc      uint64            `json:"pc"`
+	Op      string            `json:"op"`
+	Gas     *big.Int          `json:"gas"`
+	GasCost *big.Int          `json:"gasCost"`
+	Depth   int               `json:"depth"`
+	Error   string            `json:"error,omitempty"`
+	Stack   []string          `json:"stack"`
+	Memory  []string          `json:"memory"`
+	Storage map[string]string `json:"storage"`
+}
+
+func NewExecutionResult(ret []byte, gas *big.Int, logs []vm.StructLog) *ExecutionResult {
+	res := &ExecutionResult{
+		Gas:         gas,
+		ReturnValue: common.Bytes2Hex(ret),
+		StructLogs:  make([]StructLogRes, len(logs)),
+	}
+	for i, log := range logs {
+		step := StructLogRes{
+			Pc:      log.Pc,
+			Op:      log.Op.String(),
+			Gas:     log.Gas,
+			GasCost: log.GasCost,
+			Depth:   log.Depth,
+		}
+		if log.Err != nil {
+			step.Error = log.Err.Error()
+		}
+		if log.Stack != nil {
+			step.Stack = make([]string, len(log.Stack))
+			for j, item := range log.Stack {
+				step.Stack[j] = common.Bytes2Hex(common.LeftPadBytes(item.Bytes(), 32))
+			}
+		}
+		if log.Memory != nil {
+			step.Memory = make([]string, 0, (len(log.Memory)+31)/32)
+			for j := 0; j < len(log.Memory); j += 32 {
+				word := log.Memory[j:]
+				if len(word) > 32 {
+					word = word[:32]
+				}
+				step.Memory = append(step.Memory, common.Bytes2Hex(common.RightPadBytes(word, 32)))
+			}
+		}
+		if log.Storage != nil {
+			step.Storage = make(map[string]string, len(log.Storage))
+			for key, value := range log.Storage {
+				step.Storage[common.Bytes2Hex(key.Bytes())] = common.Bytes2Hex(common.LeftPadBytes(value, 32))
+			}
+		}
+		res.StructLogs[i] = step
+	}
+	return res
+}
+
+// CallFrameRes is a message call of a traced transaction with the calls it
+// made nested in Calls.
+type CallFrameRes struct {
+	Type    string          `json:"type"`
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Value   *big.Int        `json:"value"`
+	Gas     *big.Int        `json:"gas"`
+	GasUsed *big.Int        `json:"gasUsed"`
+	Input   string          `json:"input"`
+	Output  string          `json:"output"`
+	Error   string          `json:"error,omitempty"`
+	Calls   []*CallFrameRes `json:"calls,omitempty"`
+}
+
+func NewCallFrameRes(frame *vm.CallFrame) *CallFrameRes {
+	if frame == nil {
+		return nil
+	}
+	res := &CallFrameRes{
+		Type:    frame.Type.String(),
+		From:    frame.From.Hex(),
+		To:      frame.To.Hex(),
+		Value:   frame.Value,
+		Gas:     frame.Gas,
+		GasUsed: frame.GasUsed,
+		Input:   "0x" + common.Bytes2Hex(frame.Input),
+		Output:  "0x" + common.Bytes2Hex(frame.Output),
+	}
+	if frame.Err != nil {
+		res.Error = frame.Err.Error()
+	}
+	for _, call := range frame.Calls {
+		res.Calls = append(res.Calls, NewCallFrameRes(call))
+	}
+	return res
+}
+
+// TxCallsRes is the call tree of a transaction in a traced block.
+type TxCallsRes struct {
+	TxHash string        `json:"txHash"`
+	Calls  *CallFrameRes `json:"calls"`
+}
@@ -62,6 +62,24 @@ web3._extend({
 			call: 'debug_metrics',
 			params: 1,
 			inputFormatter: [null]
+		}),
+		new web3._extend.method({
+			name: 'traceTransaction',
+			call: 'debug_traceTransaction',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.method({
+			name: 'traceTransactionCalls',
+			call: 'debug_traceTransactionCalls',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.method({
+			name: 'traceBlockCalls',
+			call: 'debug_traceBlockCalls',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
 		})
 	],
 	properties:
@@ -18,6 +18,7 @@ package api
 
 import (
 	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/consensus/poa"
 	"github.com/pbfcoin/go-pbfcoin/pbf"
 	"github.com/pbfcoin/go-pbfcoin/rpc/codec"
 	"github.com/pbfcoin/go-pbfcoin/rpc/shared"
@@ -31,8 +32,11 @@ const (
 var (
 	// mapping between methods and handlers
 	MinerMapping = map[string]minerhandler{
+		"miner_discard":      (*minerApi).Discard,
 		"miner_hashrate":     (*minerApi).Hashrate,
 		"miner_makeDAG":      (*minerApi).MakeDAG,
+		"miner_propose":      (*minerApi).Propose,
+		"miner_proposals":    (*minerApi).Proposals,
 		"miner_setExtra":     (*minerApi).SetExtra,
 		"miner_setGasPrice":  (*minerApi).SetGasPrice,
 		"miner_setpbferbase": (*minerApi).Setpbferbase,
@@ -175,3 +179,51 @@ func (self *minerApi) MakeDAG(req *shared.Request) (interface{}, error) {
 	}
 	return false, err
 }
+
+// authority returns the proof-of-authority engine of the node, or an error if
+// the node runs a different consensus engine.
+func (self *minerApi) authority(req *shared.Request) (*poa.PoA, error) {
+	engine, ok := self.pbfcoin.BlockChain().Engine().(*poa.PoA)
+	if !ok {
+		return nil, shared.NewNotAvailableError(req.method, "proof-of-authority consensus not enabled")
+	}
+	return engine, nil
+}
+
+func (self *minerApi) Propose(req *shared.Request) (interface{}, error) {
+	args := new(ProposeArgs)
+	if err := self.codec.Decode(req.Params, &args); err != nil {
+		return false, err
+	}
+	engine, err := self.authority(req)
+	if err != nil {
+		return false, err
+	}
+	engine.Propose(args.Address, args.Authorize)
+	return true, nil
+}
+
+func (self *minerApi) Discard(req *shared.Request) (interface{}, error) {
+	args := new(DiscardArgs)
+	if err := self.codec.Decode(req.Params, &args); err != nil {
+		return false, err
+	}
+	engine, err := self.authority(req)
+	if err != nil {
+		return false, err
+	}
+	engine.Discard(args.Address)
+	return true, nil
+}
+
+func (self *minerApi) Proposals(req *shared.Request) (interface{}, error) {
+	engine, err := self.authority(req)
+	if err != nil {
+		return nil, err
+	}
+	proposals := make(map[string]bool)
+	for address, authorize := range engine.Proposals() {
+		proposals[address.Hex()] = authorize
+	}
+	return proposals, nil
+}
@@ -140,3 +140,52 @@ func (args *MakeDAGArgs) UnmarshalJSON(b []byte) (err error) {
 
 	return nil
 }
+
+type ProposeArgs struct {
+	Address   common.Address
+	Authorize bool
+}
+
+func (args *ProposeArgs) UnmarshalJSON(b []byte) (err error) {
+	var obj []interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return shared.NewDecodeParamError(err.Error())
+	}
+
+	if len(obj) < 2 {
+		return shared.NewInsufficientParamsError(len(obj), 2)
+	}
+
+	addr, ok := obj[0].(string)
+	if !ok {
+		return shared.NewInvalidTypeError("address", "not a string")
+	}
+	args.Address = common.HexToAddress(addr)
+
+	if args.Authorize, ok = obj[1].(bool); !ok {
+		return shared.NewInvalidTypeError("authorize", "not a boolean")
+	}
+	return nil
+}
+
+type DiscardArgs struct {
+	Address common.Address
+}
+
+func (args *DiscardArgs) UnmarshalJSON(b []byte) (err error) {
+	var obj []interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return shared.NewDecodeParamError(err.Error())
+	}
+
+	if len(obj) < 1 {
+		return shared.NewInsufficientParamsError(len(obj), 1)
+	}
+
+	addr, ok := obj[0].(string)
+	if !ok {
+		return shared.NewInvalidTypeError("address", "not a string")
+	}
+	args.Address = common.HexToAddress(addr)
+	return nil
+}
@@ -69,6 +69,18 @@ web3._extend({
 			call: 'miner_makeDAG',
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.method({
+			name: 'propose',
+			call: 'miner_propose',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.method({
+			name: 'discard',
+			call: 'miner_discard',
+			params: 1,
+			inputFormatter: [null]
 		})
 	],
 	properties:
@@ -77,6 +89,10 @@ web3._extend({
 			name: 'hashrate',
 			getter: 'miner_hashrate',
 			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Property({
+			name: 'proposals',
+			getter: 'miner_proposals'
 		})
 	]
 });
@@ -161,23 +161,9 @@ func newHexNum(input interface{}) *hexnum {
 type BlockRes struct {
 	fullTx bool
 
-	BlockNumber     *hexnum           `json:"number"`
-	BlockHash       *hexdata          `json:"hash"`
-	ParentHash      *hexdata          `json:"parentHash"`
-	Nonce           *hexdata          `json:"nonce"`
-	Sha3Uncles      *hexdata          `json:"sha3Uncles"`
-	LogsBloom       *hexdata          `json:"logsBloom"`
-	TransactionRoot *hexdata          `json:"transactionsRoot"`
-	StateRoot       *hexdata          `json:"stateRoot"`
-	ReceiptRoot     *hexdata          `json:"receiptRoot"`
-	Miner           *hexdata          `json:"miner"`
-	Difficulty      *hexnum           `json:"difficulty"`
+	*shared.HeaderRes
 	TotalDifficulty *hexnum           `json:"totalDifficulty"`
 	Size            *hexnum           `json:"size"`
-	ExtraData       *hexdata          `json:"extraData"`
-	GasLimit        *hexnum           `json:"gasLimit"`
-	GasUsed         *hexnum           `json:"gasUsed"`
-	UnixTimestamp   *hexnum           `json:"timestamp"`
 	Transactions    []*TransactionRes `json:"transactions"`
 	Uncles          []*UncleRes       `json:"uncles"`
 }
@@ -185,44 +171,16 @@ type BlockRes struct {
 func (b *BlockRes) MarshalJSON() ([]byte, error) {
 	if b.fullTx {
 		var ext struct {
-			BlockNumber     *hexnum           `json:"number"`
-			BlockHash       *hexdata          `json:"hash"`
-			ParentHash      *hexdata          `json:"parentHash"`
-			Nonce           *hexdata          `json:"nonce"`
-			Sha3Uncles      *hexdata          `json:"sha3Uncles"`
-			LogsBloom       *hexdata          `json:"logsBloom"`
-			TransactionRoot *hexdata          `json:"transactionsRoot"`
-			StateRoot       *hexdata          `json:"stateRoot"`
-			ReceiptRoot     *hexdata          `json:"receiptRoot"`
-			Miner           *hexdata          `json:"miner"`
-			Difficulty      *hexnum           `json:"difficulty"`
+			*shared.HeaderRes
 			TotalDifficulty *hexnum           `json:"totalDifficulty"`
 			Size            *hexnum           `json:"size"`
-			ExtraData       *hexdata          `json:"extraData"`
-			GasLimit        *hexnum           `json:"gasLimit"`
-			GasUsed         *hexnum           `json:"gasUsed"`
-			UnixTimestamp   *hexnum           `json:"timestamp"`
 			Transactions    []*TransactionRes `json:"transactions"`
 			Uncles          []*hexdata        `json:"uncles"`
 		}
 
-		ext.BlockNumber = b.BlockNumber
-		ext.BlockHash = b.BlockHash
-		ext.ParentHash = b.ParentHash
-		ext.Nonce = b.Nonce
-		ext.Sha3Uncles = b.Sha3Uncles
-		ext.LogsBloom = b.LogsBloom
-		ext.TransactionRoot = b.TransactionRoot
-		ext.StateRoot = b.StateRoot
-		ext.ReceiptRoot = b.ReceiptRoot
-		ext.Miner = b.Miner
-		ext.Difficulty = b.Difficulty
+		ext.HeaderRes = b.HeaderRes
 		ext.TotalDifficulty = b.TotalDifficulty
 		ext.Size = b.Size
-		ext.ExtraData = b.ExtraData
-		ext.GasLimit = b.GasLimit
-		ext.GasUsed = b.GasUsed
-		ext.UnixTimestamp = b.UnixTimestamp
 		ext.Transactions = b.Transactions
 		ext.Uncles = make([]*hexdata, len(b.Uncles))
 		for i, u := range b.Uncles {
@@ -231,44 +189,16 @@ func (b *BlockRes) MarshalJSON() ([]byte, error) {
 		return json.Marshal(ext)
 	} else {
 		var ext struct {
-			BlockNumber     *hexnum    `json:"number"`
-			BlockHash       *hexdata   `json:"hash"`
-			ParentHash      *hexdata   `json:"parentHash"`
-			Nonce           *hexdata   `json:"nonce"`
-			Sha3Uncles      *hexdata   `json:"sha3Uncles"`
-			LogsBloom       *hexdata   `json:"logsBloom"`
-			TransactionRoot *hexdata   `json:"transactionsRoot"`
-			StateRoot       *hexdata   `json:"stateRoot"`
-			ReceiptRoot     *hexdata   `json:"receiptRoot"`
-			Miner           *hexdata   `json:"miner"`
-			Difficulty      *hexnum    `json:"difficulty"`
+			*shared.HeaderRes
 			TotalDifficulty *hexnum    `json:"totalDifficulty"`
 			Size            *hexnum    `json:"size"`
-			ExtraData       *hexdata   `json:"extraData"`
-			GasLimit        *hexnum    `json:"gasLimit"`
-			GasUsed         *hexnum    `json:"gasUsed"`
-			UnixTimestamp   *hexnum    `json:"timestamp"`
 			Transactions    []*hexdata `json:"transactions"`
 			Uncles          []*hexdata `json:"uncles"`
 		}
 
-		ext.BlockNumber = b.BlockNumber
-		ext.BlockHash = b.BlockHash
-		ext.ParentHash = b.ParentHash
-		ext.Nonce = b.Nonce
-		ext.Sha3Uncles = b.Sha3Uncles
-		ext.LogsBloom = b.LogsBloom
-		ext.TransactionRoot = b.TransactionRoot
-		ext.StateRoot = b.StateRoot
-		ext.ReceiptRoot = b.ReceiptRoot
-		ext.Miner = b.Miner
-		ext.Difficulty = b.Difficulty
+		ext.HeaderRes = b.HeaderRes
 		ext.TotalDifficulty = b.TotalDifficulty
 		ext.Size = b.Size
-		ext.ExtraData = b.ExtraData
-		ext.GasLimit = b.GasLimit
-		ext.GasUsed = b.GasUsed
-		ext.UnixTimestamp = b.UnixTimestamp
 		ext.Transactions = make([]*hexdata, len(b.Transactions))
 		for i, tx := range b.Transactions {
 			ext.Transactions[i] = tx.Hash
@@ -288,30 +218,16 @@ func NewBlockRes(block *types.Block, td *big.Int, fullTx bool) *BlockRes {
 
 	res := new(BlockRes)
 	res.fullTx = fullTx
-	res.BlockNumber = newHexNum(block.Number())
-	res.BlockHash = newHexData(block.Hash())
-	res.ParentHash = newHexData(block.ParentHash())
-	res.Nonce = newHexData(block.Nonce())
-	res.Sha3Uncles = newHexData(block.UncleHash())
-	res.LogsBloom = newHexData(block.Bloom())
-	res.TransactionRoot = newHexData(block.TxHash())
-	res.StateRoot = newHexData(block.Root())
-	res.ReceiptRoot = newHexData(block.ReceiptHash())
-	res.Miner = newHexData(block.Coinbase())
-	res.Difficulty = newHexNum(block.Difficulty())
+	res.HeaderRes = shared.NewHeaderRes(block.Header())
 	res.TotalDifficulty = newHexNum(td)
 	res.Size = newHexNum(block.Size().Int64())
-	res.ExtraData = newHexData(block.Extra())
-	res.GasLimit = newHexNum(block.GasLimit())
-	res.GasUsed = newHexNum(block.GasUsed())
-	res.UnixTimestamp = newHexNum(block.Time())
 
 	txs := block.Transactions()
 	res.Transactions = make([]*TransactionRes, len(txs))
 	for i, tx := range txs {
 		res.Transactions[i] = NewTransactionRes(tx)
-		res.Transactions[i].BlockHash = res.BlockHash
-		res.Transactions[i].BlockNumber = res.BlockNumber
+		res.Transactions[i].BlockHash = newHexData(block.Hash())
+		res.Transactions[i].BlockNumber = newHexNum(block.Number())
 		res.Transactions[i].TxIndex = newHexNum(i)
 	}
 
@@ -455,7 +371,7 @@ func NewReceiptRes(rec *types.Receipt) *ReceiptRes {
 
 	logs := make([]interface{}, len(rec.Logs))
 	for i, log := range rec.Logs {
-		logs[i] = NewLogRes(log)
+		logs[i] = shared.NewLogRes(log)
 	}
 	v.Logs = &logs
 
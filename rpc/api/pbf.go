@@ -157,7 +157,11 @@ func (self *pbfApi) GetBalance(req *shared.Request) (interface{}, error) {
 		return nil, shared.NewDecodeParamError(err.Error())
 	}
 
-	return self.xpbf.AtStateNum(args.BlockNumber).BalanceAt(args.Address), nil
+	st, err := self.xpbf.AtStateNum(args.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return st.BalanceAt(args.Address), nil
 }
 
 func (self *pbfApi) ProtocolVersion(req *shared.Request) (interface{}, error) {
@@ -194,7 +198,11 @@ func (self *pbfApi) GetStorage(req *shared.Request) (interface{}, error) {
 		return nil, shared.NewDecodeParamError(err.Error())
 	}
 
-	return self.xpbf.AtStateNum(args.BlockNumber).State().SafeGet(args.Address).Storage(), nil
+	st, err := self.xpbf.AtStateNum(args.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return st.State().SafeGet(args.Address).Storage(), nil
 }
 
 func (self *pbfApi) GetStorageAt(req *shared.Request) (interface{}, error) {
@@ -203,7 +211,11 @@ func (self *pbfApi) GetStorageAt(req *shared.Request) (interface{}, error) {
 		return nil, shared.NewDecodeParamError(err.Error())
 	}
 
-	return self.xpbf.AtStateNum(args.BlockNumber).StorageAt(args.Address, args.Key), nil
+	st, err := self.xpbf.AtStateNum(args.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return st.StorageAt(args.Address, args.Key), nil
 }
 
 func (self *pbfApi) GetTransactionCount(req *shared.Request) (interface{}, error) {
@@ -212,7 +224,11 @@ func (self *pbfApi) GetTransactionCount(req *shared.Request) (interface{}, error
 		return nil, shared.NewDecodeParamError(err.Error())
 	}
 
-	count := self.xpbf.AtStateNum(args.BlockNumber).TxCountAt(args.Address)
+	st, err := self.xpbf.AtStateNum(args.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	count := st.TxCountAt(args.Address)
 	return fmt.Sprintf("%#x", count), nil
 }
 
@@ -272,7 +288,11 @@ func (self *pbfApi) GetData(req *shared.Request) (interface{}, error) {
 	if err := self.codec.Decode(req.Params, &args); err != nil {
 		return nil, shared.NewDecodeParamError(err.Error())
 	}
-	v := self.xpbf.AtStateNum(args.BlockNumber).CodeAtBytes(args.Address)
+	st, err := self.xpbf.AtStateNum(args.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	v := st.CodeAtBytes(args.Address)
 	return newHexData(v), nil
 }
 
@@ -416,7 +436,11 @@ func (self *pbfApi) doCall(params json.RawMessage) (string, string, error) {
 		return "", "", err
 	}
 
-	return self.xpbf.AtStateNum(args.BlockNumber).Call(args.From, args.To, args.Value.String(), args.Gas.String(), args.GasPrice.String(), args.Data)
+	st, err := self.xpbf.AtStateNum(args.BlockNumber)
+	if err != nil {
+		return "", "", err
+	}
+	return st.Call(args.From, args.To, args.Value.String(), args.Gas.String(), args.GasPrice.String(), args.Data)
 }
 
 func (self *pbfApi) GetBlockByHash(req *shared.Request) (interface{}, error) {
@@ -834,39 +834,11 @@ func (args *FilterIdArgs) UnmarshalJSON(b []byte) (err error) {
 	return nil
 }
 
-type LogRes struct {
-	Address          *hexdata   `json:"address"`
-	Topics           []*hexdata `json:"topics"`
-	Data             *hexdata   `json:"data"`
-	BlockNumber      *hexnum    `json:"blockNumber"`
-	LogIndex         *hexnum    `json:"logIndex"`
-	BlockHash        *hexdata   `json:"blockHash"`
-	TransactionHash  *hexdata   `json:"transactionHash"`
-	TransactionIndex *hexnum    `json:"transactionIndex"`
-}
-
-func NewLogRes(log *vm.Log) LogRes {
-	var l LogRes
-	l.Topics = make([]*hexdata, len(log.Topics))
-	for j, topic := range log.Topics {
-		l.Topics[j] = newHexData(topic)
-	}
-	l.Address = newHexData(log.Address)
-	l.Data = newHexData(log.Data)
-	l.BlockNumber = newHexNum(log.BlockNumber)
-	l.LogIndex = newHexNum(log.Index)
-	l.TransactionHash = newHexData(log.TxHash)
-	l.TransactionIndex = newHexNum(log.TxIndex)
-	l.BlockHash = newHexData(log.BlockHash)
-
-	return l
-}
-
-func NewLogsRes(logs vm.Logs) (ls []LogRes) {
-	ls = make([]LogRes, len(logs))
+func NewLogsRes(logs vm.Logs) (ls []shared.LogRes) {
+	ls = make([]shared.LogRes, len(logs))
 
 	for i, log := range logs {
-		ls[i] = NewLogRes(log)
+		ls[i] = shared.NewLogRes(log)
 	}
 
 	return
@@ -0,0 +1,281 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/vm"
+	"github.com/robertkrimen/otto"
+)
+
+// JavascriptTracer is a vm.Tracer evaluating a user supplied Javascript object
+// for each executed step. The code has to evaluate to an object with two
+// functions:
+//
+//	step(log, db) is called before every operation executed by the VM
+//	result()      is called once the execution finished, its return value is
+//	              the result of the trace
+//
+// The log passed to step exposes pc, gas, cost, depth and err as plain values
+// and the views op (toNumber, toString, isPush), stack (peek, length), memory
+// (slice, getUint, length) and contract (getCaller, getAddress, getValue,
+// getInput). The db is a read-only view of the state (getBalance, getNonce,
+// getCode, getState, exists). Stack items, values and balances are passed as
+// big.Int objects, byte arrays and hashes as hex strings.
+//
+// The views are only valid during the step call, values which should be kept
+// have to be copied by the tracer.
+type JavascriptTracer struct {
+	vm     *otto.Otto
+	tracer *otto.Object // user supplied tracer object
+	log    *otto.Object // log object passed to step, reused between steps
+	db     *otto.Object // state view passed to step
+
+	// execution state of the current step, accessed by the views
+	env      vm.Environment
+	op       vm.OpCode
+	stack    []*big.Int
+	memory   *vm.Memory
+	contract *vm.Contract
+
+	err error // error raised by the tracer, aborts further steps
+}
+
+// NewJavascriptTracer compiles the given tracer code and verifies that it
+// defines the step and result functions.
+func NewJavascriptTracer(code string) (*JavascriptTracer, error) {
+	jst := &JavascriptTracer{vm: otto.New()}
+	jst.vm.Interrupt = make(chan func(), 1)
+
+	var err error
+	if jst.tracer, err = jst.vm.Object("(" + code + ")"); err != nil {
+		return nil, fmt.Errorf("invalid tracer: %v", err)
+	}
+	for _, name := range []string{"step", "result"} {
+		if fn, err := jst.tracer.Get(name); err != nil || !fn.IsFunction() {
+			return nil, fmt.Errorf("tracer does not define a %s function", name)
+		}
+	}
+	if err := jst.setupViews(); err != nil {
+		return nil, err
+	}
+	return jst, nil
+}
+
+// setupViews creates the log and db objects passed to the step function.
+func (jst *JavascriptTracer) setupViews() error {
+	obj := func(funcs map[string]func(otto.FunctionCall) otto.Value) (*otto.Object, error) {
+		o, err := jst.vm.Object("({})")
+		if err != nil {
+			return nil, err
+		}
+		for name, fn := range funcs {
+			if err := o.Set(name, fn); err != nil {
+				return nil, err
+			}
+		}
+		return o, nil
+	}
+	op, err := obj(map[string]func(otto.FunctionCall) otto.Value{
+		"toNumber": func(otto.FunctionCall) otto.Value { return jst.value(int(jst.op)) },
+		"toString": func(otto.FunctionCall) otto.Value { return jst.value(jst.op.String()) },
+		"isPush":   func(otto.FunctionCall) otto.Value { return jst.value(jst.op >= vm.PUSH1 && jst.op <= vm.PUSH32) },
+	})
+	if err != nil {
+		return err
+	}
+	stack, err := obj(map[string]func(otto.FunctionCall) otto.Value{
+		"length": func(otto.FunctionCall) otto.Value { return jst.value(len(jst.stack)) },
+		"peek":   jst.stackPeek,
+	})
+	if err != nil {
+		return err
+	}
+	memory, err := obj(map[string]func(otto.FunctionCall) otto.Value{
+		"length":  func(otto.FunctionCall) otto.Value { return jst.value(jst.memory.Len()) },
+		"slice":   jst.memorySlice,
+		"getUint": jst.memoryGetUint,
+	})
+	if err != nil {
+		return err
+	}
+	contract, err := obj(map[string]func(otto.FunctionCall) otto.Value{
+		"getCaller":  func(otto.FunctionCall) otto.Value { return jst.value(jst.contract.Caller().Hex()) },
+		"getAddress": func(otto.FunctionCall) otto.Value { return jst.value(jst.contract.Address().Hex()) },
+		"getValue":   func(otto.FunctionCall) otto.Value { return jst.big(jst.contract.Value()) },
+		"getInput":   func(otto.FunctionCall) otto.Value { return jst.value(hexBytes(jst.contract.Input)) },
+	})
+	if err != nil {
+		return err
+	}
+	if jst.log, err = obj(nil); err != nil {
+		return err
+	}
+	jst.log.Set("op", op)
+	jst.log.Set("stack", stack)
+	jst.log.Set("memory", memory)
+	jst.log.Set("contract", contract)
+
+	jst.db, err = obj(map[string]func(otto.FunctionCall) otto.Value{
+		"getBalance": func(call otto.FunctionCall) otto.Value {
+			return jst.big(jst.env.Db().GetBalance(addressArg(call, 0)))
+		},
+		"getNonce": func(call otto.FunctionCall) otto.Value {
+			return jst.value(jst.env.Db().GetNonce(addressArg(call, 0)))
+		},
+		"getCode": func(call otto.FunctionCall) otto.Value {
+			return jst.value(hexBytes(jst.env.Db().GetCode(addressArg(call, 0))))
+		},
+		"getState": func(call otto.FunctionCall) otto.Value {
+			key := common.HexToHash(call.Argument(1).String())
+			return jst.value(jst.env.Db().GetState(addressArg(call, 0), key).Hex())
+		},
+		"exists": func(call otto.FunctionCall) otto.Value {
+			return jst.value(jst.env.Db().Exist(addressArg(call, 0)))
+		},
+	})
+	return err
+}
+
+// CaptureState implements vm.Tracer, calling the step function of the tracer.
+// Once the tracer failed, all further steps are ignored.
+func (jst *JavascriptTracer) CaptureState(env vm.Environment, pc uint64, op vm.OpCode, gas, cost *big.Int, memory *vm.Memory, stack []*big.Int, contract *vm.Contract, depth int, err error) {
+	if jst.err != nil {
+		return
+	}
+	jst.env, jst.op, jst.stack, jst.memory, jst.contract = env, op, stack, memory, contract
+
+	jst.log.Set("pc", pc)
+	jst.log.Set("gas", number(gas))
+	jst.log.Set("cost", number(cost))
+	jst.log.Set("depth", depth)
+	if err != nil {
+		jst.log.Set("err", err.Error())
+	} else {
+		jst.log.Set("err", otto.UndefinedValue())
+	}
+	if _, err := jst.call("step", jst.log, jst.db); err != nil {
+		jst.err = err
+	}
+}
+
+// GetResult calls the result function of the tracer and returns its exported
+// return value, or the error which aborted the trace.
+func (jst *JavascriptTracer) GetResult() (interface{}, error) {
+	if jst.err != nil {
+		return nil, jst.err
+	}
+	result, err := jst.call("result")
+	if err != nil {
+		return nil, err
+	}
+	return result.Export()
+}
+
+// Stop aborts the tracer, the running and all further calls into the
+// tracer fail with err. It is safe to call Stop from any goroutine.
+func (jst *JavascriptTracer) Stop(err error) {
+	select {
+	case jst.vm.Interrupt <- func() { panic(err) }:
+	default:
+	}
+}
+
+// call invokes a function of the tracer object, converting panics raised by
+// the views or an interrupt into an error.
+func (jst *JavascriptTracer) call(name string, args ...interface{}) (ret otto.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r := r.(type) {
+			case error:
+				err = r
+			default:
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	return jst.tracer.Call(name, args...)
+}
+
+// stackPeek returns the n'th item from the top of the stack.
+func (jst *JavascriptTracer) stackPeek(call otto.FunctionCall) otto.Value {
+	n, err := call.Argument(0).ToInteger()
+	if err != nil || n < 0 || int(n) >= len(jst.stack) {
+		jst.throw(fmt.Sprintf("stack peek out of bounds: %v of %d", call.Argument(0), len(jst.stack)))
+	}
+	return jst.big(jst.stack[len(jst.stack)-1-int(n)])
+}
+
+// memorySlice returns the memory between the start and end offsets.
+func (jst *JavascriptTracer) memorySlice(call otto.FunctionCall) otto.Value {
+	start, err1 := call.Argument(0).ToInteger()
+	end, err2 := call.Argument(1).ToInteger()
+	if err1 != nil || err2 != nil || start < 0 || end < start || int(end) > jst.memory.Len() {
+		jst.throw(fmt.Sprintf("memory slice [%v:%v] out of bounds of %d", call.Argument(0), call.Argument(1), jst.memory.Len()))
+	}
+	return jst.value(hexBytes(jst.memory.Data()[start:end]))
+}
+
+// memoryGetUint returns the 32 byte word at the given memory offset.
+func (jst *JavascriptTracer) memoryGetUint(call otto.FunctionCall) otto.Value {
+	offset, err := call.Argument(0).ToInteger()
+	if err != nil || offset < 0 || int(offset)+32 > jst.memory.Len() {
+		jst.throw(fmt.Sprintf("memory word at %v out of bounds of %d", call.Argument(0), jst.memory.Len()))
+	}
+	return jst.big(new(big.Int).SetBytes(jst.memory.Data()[offset : offset+32]))
+}
+
+// value converts a Go value into a Javascript value.
+func (jst *JavascriptTracer) value(v interface{}) otto.Value {
+	val, err := jst.vm.ToValue(v)
+	if err != nil {
+		jst.throw(err.Error())
+	}
+	return val
+}
+
+// big passes a copy of a big integer to Javascript.
+func (jst *JavascriptTracer) big(n *big.Int) otto.Value {
+	return jst.value(new(big.Int).Set(n))
+}
+
+// throw raises a Javascript error, aborting the running tracer function.
+func (jst *JavascriptTracer) throw(msg string) {
+	panic(jst.vm.MakeCustomError("Error", msg))
+}
+
+// addressArg parses the n'th argument of a call as a hex encoded address.
+func addressArg(call otto.FunctionCall, n int) common.Address {
+	return common.HexToAddress(call.Argument(n).String())
+}
+
+// number converts a gas amount into a Javascript number, large values lose
+// precision.
+func number(n *big.Int) float64 {
+	if n == nil {
+		return 0
+	}
+	f, _ := new(big.Rat).SetInt(n).Float64()
+	return f
+}
+
+func hexBytes(b []byte) string {
+	return "0x" + common.Bytes2Hex(b)
+}
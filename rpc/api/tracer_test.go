@@ -0,0 +1,109 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/vm/runtime"
+)
+
+// PUSH1 0x2a PUSH1 0x01 SSTORE PUSH1 0x20 PUSH1 0x00 MSTORE STOP
+var tracerTestCode = common.Hex2Bytes("602a6001556020600052" + "00")
+
+func runTracer(t *testing.T, code string) (interface{}, error) {
+	tracer, err := NewJavascriptTracer(code)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	if _, _, err := runtime.Execute(tracerTestCode, nil, &runtime.Config{Tracer: tracer}); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	return tracer.GetResult()
+}
+
+func TestJavascriptTracer(t *testing.T) {
+	// Count the executed opcodes
+	res, err := runTracer(t, `{
+		ops: {},
+		step: function(log, db) { this.ops[log.op.toString()] = (this.ops[log.op.toString()] || 0) + 1; },
+		result: function() { return this.ops; }
+	}`)
+	if err != nil {
+		t.Fatalf("tracer failed: %v", err)
+	}
+	want := map[string]interface{}{"PUSH1": int64(4), "SSTORE": int64(1), "MSTORE": int64(1), "STOP": int64(1)}
+	ops := make(map[string]interface{})
+	for op, n := range res.(map[string]interface{}) {
+		ops[op] = int64(n.(float64))
+	}
+	if !reflect.DeepEqual(ops, want) {
+		t.Errorf("opcode count mismatch: have %v, want %v", ops, want)
+	}
+
+	// Inspect the stack, memory, contract and state views
+	res, err = runTracer(t, `{
+		res: [],
+		step: function(log, db) {
+			if (log.op.toString() == "SSTORE") {
+				this.res.push(log.stack.peek(0).String(), log.stack.peek(1).String(), log.contract.getValue().String());
+				this.res.push(db.getState(log.contract.getAddress(), "0x01"), db.exists(log.contract.getAddress()));
+			}
+			if (log.op.toString() == "STOP") {
+				this.res.push(log.memory.length(), log.memory.getUint(0).String(), log.memory.slice(31, 32));
+			}
+		},
+		result: function() { return this.res.join(","); }
+	}`)
+	if err != nil {
+		t.Fatalf("tracer failed: %v", err)
+	}
+	if want := "1,42,0," + (common.Hash{}).Hex() + ",true,32,32,0x20"; res != want {
+		t.Errorf("view result mismatch: have %v, want %v", res, want)
+	}
+}
+
+func TestJavascriptTracerErrors(t *testing.T) {
+	if _, err := NewJavascriptTracer(`{step: function() {}}`); err == nil {
+		t.Errorf("tracer without result function accepted")
+	}
+	if _, err := NewJavascriptTracer(`{step: `); err == nil {
+		t.Errorf("invalid tracer code accepted")
+	}
+	// Out of bound accesses abort the trace
+	_, err := runTracer(t, `{step: function(log) { log.stack.peek(10); }, result: function() { return 1; }}`)
+	if err == nil || !strings.Contains(err.Error(), "out of bounds") {
+		t.Errorf("error mismatch: have %v, want out of bounds", err)
+	}
+
+	// Stopped tracers fail with the given error
+	tracer, err := NewJavascriptTracer(`{step: function() {}, result: function() { return 1; }}`)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	tracer.Stop(errors.New("stopped"))
+	if _, _, err := runtime.Execute(tracerTestCode, nil, &runtime.Config{Tracer: tracer}); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if _, err := tracer.GetResult(); err == nil || err.Error() != "stopped" {
+		t.Errorf("error mismatch: have %v, want stopped", err)
+	}
+}
@@ -17,6 +17,10 @@
 package api
 
 import (
+	"fmt"
+	"strconv"
+
+	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/pbf"
 	"github.com/pbfcoin/go-pbfcoin/rpc/codec"
 	"github.com/pbfcoin/go-pbfcoin/rpc/shared"
@@ -30,7 +34,9 @@ const (
 var (
 	// mapping between methods and handlers
 	txpoolMapping = map[string]txpoolhandler{
-		"txpool_status": (*txPoolApi).Status,
+		"txpool_content": (*txPoolApi).Content,
+		"txpool_inspect": (*txPoolApi).Inspect,
+		"txpool_status":  (*txPoolApi).Status,
 	}
 )
 
@@ -90,3 +96,66 @@ func (self *txPoolApi) Status(req *shared.Request) (interface{}, error) {
 		"queued":  queue,
 	}, nil
 }
+
+// Content returns the transactions contained within the transaction pool,
+// grouped by origin account and nonce.
+func (self *txPoolApi) Content(req *shared.Request) (interface{}, error) {
+	pool := self.pbfcoin.TxPool()
+	return map[string]map[string]map[string]*TransactionRes{
+		"pending": groupTransactions(pool.GetTransactions()),
+		"queued":  groupTransactions(pool.GetQueuedTransactions()),
+	}, nil
+}
+
+// Inspect returns a one line summary of every transaction contained within the
+// transaction pool, grouped by origin account and nonce.
+func (self *txPoolApi) Inspect(req *shared.Request) (interface{}, error) {
+	pool := self.pbfcoin.TxPool()
+	return map[string]map[string]map[string]string{
+		"pending": groupSummaries(pool.GetTransactions()),
+		"queued":  groupSummaries(pool.GetQueuedTransactions()),
+	}, nil
+}
+
+// groupTransactions groups transactions by sender address and nonce.
+func groupTransactions(txs types.Transactions) map[string]map[string]*TransactionRes {
+	content := make(map[string]map[string]*TransactionRes)
+	for _, tx := range txs {
+		from, nonce := txKeys(tx)
+		if content[from] == nil {
+			content[from] = make(map[string]*TransactionRes)
+		}
+		content[from][nonce] = NewTransactionRes(tx)
+	}
+	return content
+}
+
+// groupSummaries summarises transactions and groups them by sender address
+// and nonce.
+func groupSummaries(txs types.Transactions) map[string]map[string]string {
+	content := make(map[string]map[string]string)
+	for _, tx := range txs {
+		from, nonce := txKeys(tx)
+		if content[from] == nil {
+			content[from] = make(map[string]string)
+		}
+		content[from][nonce] = summarizeTransaction(tx)
+	}
+	return content
+}
+
+// txKeys returns the sender address and nonce of a transaction as used for
+// grouping the pool content.
+func txKeys(tx *types.Transaction) (string, string) {
+	from, _ := tx.From() // validated by the pool
+	return from.Hex(), strconv.FormatUint(tx.Nonce(), 10)
+}
+
+// summarizeTransaction formats the recipient, value and gas of a transaction.
+func summarizeTransaction(tx *types.Transaction) string {
+	to := "contract creation"
+	if tx.To() != nil {
+		to = tx.To().Hex()
+	}
+	return fmt.Sprintf("%s: %v wei + %v gas × %v wei", to, tx.Value(), tx.Gas(), tx.GasPrice())
+}
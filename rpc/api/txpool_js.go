@@ -24,6 +24,14 @@ web3._extend({
 	],
 	properties:
 	[
+		new web3._extend.Property({
+			name: 'content',
+			getter: 'txpool_content'
+		}),
+		new web3._extend.Property({
+			name: 'inspect',
+			getter: 'txpool_inspect'
+		}),
 		new web3._extend.Property({
 			name: 'status',
 			getter: 'txpool_status'
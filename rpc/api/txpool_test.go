@@ -0,0 +1,54 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
+)
+
+func TestTxPoolGrouping(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	to := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	transfer, _ := types.NewTransaction(3, to, big.NewInt(10), big.NewInt(21000), big.NewInt(50), nil).SignECDSA(key)
+	create, _ := types.NewContractCreation(4, big.NewInt(0), big.NewInt(90000), big.NewInt(60), []byte{0x60}).SignECDSA(key)
+	txs := types.Transactions{transfer, create}
+
+	summaries := groupSummaries(txs)
+	want := map[string]string{
+		"3": to.Hex() + ": 10 wei + 21000 gas × 50 wei",
+		"4": "contract creation: 0 wei + 90000 gas × 60 wei",
+	}
+	if len(summaries) != 1 || len(summaries[from.Hex()]) != len(want) {
+		t.Fatalf("summary grouping mismatch: have %v", summaries)
+	}
+	for nonce, summary := range want {
+		if have := summaries[from.Hex()][nonce]; have != summary {
+			t.Errorf("nonce %s: summary mismatch: have %q, want %q", nonce, have, summary)
+		}
+	}
+	content := groupTransactions(txs)
+	if res := content[from.Hex()]["4"]; res == nil || res.Hash.String() != create.Hash().Hex() {
+		t.Errorf("content mismatch: have %v", content)
+	}
+}
@@ -32,6 +32,9 @@ var (
 	AutoCompletion = map[string][]string{
 		"admin": []string{
 			"addPeer",
+			"addTrustedPeer",
+			"banPeer",
+			"bannedPeers",
 			"datadir",
 			"enableUserAgent",
 			"exportChain",
@@ -42,6 +45,8 @@ var (
 			"peers",
 			"register",
 			"registerUrl",
+			"removePeer",
+			"removeTrustedPeer",
 			"saveInfo",
 			"setGlobalRegistrar",
 			"spbfashReg",
@@ -53,6 +58,7 @@ var (
 			"startRPC",
 			"stopNatSpec",
 			"stopRPC",
+			"unbanPeer",
 			"verbosity",
 		},
 		"db": []string{
@@ -69,6 +75,9 @@ var (
 			"processBlock",
 			"seedHash",
 			"spbfead",
+			"traceBlockCalls",
+			"traceTransaction",
+			"traceTransactionCalls",
 		},
 		"pbf": []string{
 			"accounts",
@@ -109,8 +118,11 @@ var (
 			"syncing",
 		},
 		"miner": []string{
+			"discard",
 			"hashrate",
 			"makeDAG",
+			"propose",
+			"proposals",
 			"setpbferbase",
 			"setExtra",
 			"setGasPrice",
@@ -137,6 +149,8 @@ var (
 			"filter",
 		},
 		"txpool": []string{
+			"content",
+			"inspect",
 			"status",
 		},
 		"web3": []string{
@@ -113,6 +113,7 @@ func handle(id int, conn net.Conn, api shared.pbfcoinApi, c codec.Codec) {
 // ${protocol}:${path}
 // e.g. ipc:/tmp/gpbf.ipc
 //      rpc:localhost:8545
+//      ws:localhost:7576
 func ClientFromEndpoint(endpoint string, c codec.Codec) (pbfcoinClient, error) {
 	if strings.HasPrefix(endpoint, "ipc:") {
 		cfg := IpcConfig{
@@ -146,5 +147,17 @@ func ClientFromEndpoint(endpoint string, c codec.Codec) (pbfcoinClient, error) {
 		return NewHttpClient(cfg, codec.JSON), nil
 	}
 
+	if strings.HasPrefix(endpoint, "ws:") {
+		cfg, err := wsEndpoint(endpoint[3:])
+		if err != nil {
+			return nil, err
+		}
+		client, err := NewWsClient(cfg, codec.JSON)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+
 	return nil, fmt.Errorf("Invalid endpoint")
 }
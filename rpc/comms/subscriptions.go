@@ -0,0 +1,263 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package comms
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core"
+	"github.com/pbfcoin/go-pbfcoin/core/vm"
+	"github.com/pbfcoin/go-pbfcoin/event"
+	"github.com/pbfcoin/go-pbfcoin/pbf/filters"
+	"github.com/pbfcoin/go-pbfcoin/rpc/shared"
+)
+
+const (
+	wsSubscribe    = "eth_subscribe"
+	wsUnsubscribe  = "eth_unsubscribe"
+	wsNotification = "eth_subscription"
+
+	// supported subscription kinds
+	newHeadsSubscription  = "newHeads"
+	logsSubscription      = "logs"
+	pendingTxSubscription = "newPendingTransactions"
+)
+
+// subscriptions manages the event subscriptions of a single client connection.
+type subscriptions struct {
+	mux    *event.TypeMux
+	notify func(interface{})
+
+	mu      sync.Mutex
+	subs    map[string]event.Subscription
+	pending []func() // event loops to start once the subscribe response is queued
+	closed  bool
+}
+
+func newSubscriptions(mux *event.TypeMux, notify func(interface{})) *subscriptions {
+	return &subscriptions{
+		mux:    mux,
+		notify: notify,
+		subs:   make(map[string]event.Subscription),
+	}
+}
+
+// subscribe handles an eth_subscribe call, params are the subscription kind
+// optionally followed by a log filter. It returns the new subscription id.
+func (s *subscriptions) subscribe(params json.RawMessage) (interface{}, error) {
+	if s.mux == nil {
+		return nil, shared.NewNotAvailableError(wsSubscribe, "no event source")
+	}
+	var args []json.RawMessage
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, shared.NewDecodeParamError(err.Error())
+	}
+	if len(args) < 1 {
+		return nil, shared.NewInsufficientParamsError(len(args), 1)
+	}
+	var kind string
+	if err := json.Unmarshal(args[0], &kind); err != nil {
+		return nil, shared.NewInvalidTypeError("kind", "not a string")
+	}
+
+	var (
+		sub    event.Subscription
+		filter *filters.Filter
+	)
+	switch kind {
+	case newHeadsSubscription:
+		sub = s.mux.Subscribe(core.ChainHeadEvent{})
+	case pendingTxSubscription:
+		sub = s.mux.Subscribe(core.TxPreEvent{})
+	case logsSubscription:
+		filter = filters.New(nil)
+		if len(args) > 1 {
+			if err := parseLogFilter(args[1], filter); err != nil {
+				return nil, err
+			}
+		}
+		sub = s.mux.Subscribe(vm.Logs(nil))
+	default:
+		return nil, shared.NewValidationError("kind", fmt.Sprintf("unknown subscription %q", kind))
+	}
+
+	id, err := newSubscriptionId()
+	if err != nil {
+		sub.Unsubscribe()
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		sub.Unsubscribe()
+		return nil, shared.NewNotAvailableError(wsSubscribe, "connection closed")
+	}
+	s.subs[id] = sub
+	s.pending = append(s.pending, func() { go s.loop(id, sub, filter) })
+	return id, nil
+}
+
+// start begins forwarding the events of the subscriptions created since the
+// last call. It must be called after the subscribe responses are queued, so
+// that clients never see a notification for an id they don't know yet.
+func (s *subscriptions) start() {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	for _, start := range pending {
+		start()
+	}
+}
+
+// unsubscribe handles an eth_unsubscribe call. It reports whpbfer the
+// subscription existed.
+func (s *subscriptions) unsubscribe(params json.RawMessage) (interface{}, error) {
+	var args []string
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, shared.NewDecodeParamError(err.Error())
+	}
+	if len(args) < 1 {
+		return nil, shared.NewInsufficientParamsError(len(args), 1)
+	}
+
+	s.mu.Lock()
+	sub, ok := s.subs[args[0]]
+	delete(s.subs, args[0])
+	s.mu.Unlock()
+
+	if ok {
+		sub.Unsubscribe()
+	}
+	return ok, nil
+}
+
+// unsubscribeAll cancels all subscriptions, later subscribe calls are refused.
+func (s *subscriptions) unsubscribeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.pending = nil
+	for id, sub := range s.subs {
+		sub.Unsubscribe()
+		delete(s.subs, id)
+	}
+}
+
+// loop forwards the events of a single subscription until it is unsubscribed.
+func (s *subscriptions) loop(id string, sub event.Subscription, filter *filters.Filter) {
+	for ev := range sub.Chan() {
+		switch ev := ev.Data.(type) {
+		case core.ChainHeadEvent:
+			s.notify(newNotification(id, shared.NewHeaderRes(ev.Block.Header())))
+		case core.TxPreEvent:
+			s.notify(newNotification(id, ev.Tx.Hash().Hex()))
+		case vm.Logs:
+			for _, log := range filter.FilterLogs(ev) {
+				s.notify(newNotification(id, shared.NewLogRes(log)))
+			}
+		}
+	}
+}
+
+func newSubscriptionId() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return common.ToHex(id[:]), nil
+}
+
+// parseLogFilter reads the address and topics criteria of a logs subscription.
+// Both the address and a topic position accept a single value or a list, a
+// null topic matches anything.
+func parseLogFilter(raw json.RawMessage, filter *filters.Filter) error {
+	var args struct {
+		Address interface{}
+		Topics  []interface{}
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return shared.NewDecodeParamError(err.Error())
+	}
+
+	var addresses []common.Address
+	switch addr := args.Address.(type) {
+	case nil:
+	case string:
+		addresses = append(addresses, common.HexToAddress(addr))
+	case []interface{}:
+		for _, a := range addr {
+			str, ok := a.(string)
+			if !ok {
+				return shared.NewInvalidTypeError("address", "is not a string")
+			}
+			addresses = append(addresses, common.HexToAddress(str))
+		}
+	default:
+		return shared.NewInvalidTypeError("address", "is not a string or array")
+	}
+
+	topics := make([][]common.Hash, len(args.Topics))
+	for i, topic := range args.Topics {
+		switch topic := topic.(type) {
+		case nil:
+			topics[i] = []common.Hash{common.Hash{}}
+		case string:
+			topics[i] = []common.Hash{common.HexToHash(topic)}
+		case []interface{}:
+			for _, t := range topic {
+				str, ok := t.(string)
+				if !ok {
+					return shared.NewInvalidTypeError(fmt.Sprintf("topics[%d]", i), "is not a string")
+				}
+				topics[i] = append(topics[i], common.HexToHash(str))
+			}
+		default:
+			return shared.NewInvalidTypeError(fmt.Sprintf("topics[%d]", i), "is not a string or array")
+		}
+	}
+
+	filter.SetAddresses(addresses)
+	filter.SetTopics(topics)
+	return nil
+}
+
+type notification struct {
+	Jsonrpc string             `json:"jsonrpc"`
+	Name    string             `json:"method"`
+	Params  notificationParams `json:"params"`
+}
+
+type notificationParams struct {
+	Subscription string      `json:"subscription"`
+	Result       interface{} `json:"result"`
+}
+
+func newNotification(id string, result interface{}) *notification {
+	return &notification{
+		Jsonrpc: shared.JsonRpcVersion,
+		Name:    wsNotification,
+		Params:  notificationParams{Subscription: id, Result: result},
+	}
+}
@@ -0,0 +1,366 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package comms
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pbfcoin/go-pbfcoin/event"
+	"github.com/pbfcoin/go-pbfcoin/logger"
+	"github.com/pbfcoin/go-pbfcoin/logger/glog"
+	"github.com/pbfcoin/go-pbfcoin/rpc/codec"
+	"github.com/pbfcoin/go-pbfcoin/rpc/shared"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// origin sent by the WebSocket client, allowed by the default server config
+	DefaultWsOrigin = "http://localhost"
+
+	wsSendQueue = 256 // pending messages before a slow client is dropped
+)
+
+var (
+	wsServerMu sync.Mutex
+	wsServer   *stopServer
+	wsHandler  *wsServerHandler
+)
+
+type WsConfig struct {
+	ListenAddress string
+	ListenPort    uint
+	Origins       string // space separated list of allowed origins, "*" allows all
+}
+
+// wsServerHandler accepts WebSocket connections and keeps track of them, so
+// that they can be closed when the server is stopped (hijacked connections
+// are not managed by the http.Server anymore).
+type wsServerHandler struct {
+	codec   codec.Codec
+	api     shared.pbfcoinApi
+	mux     *event.TypeMux
+	origins []string
+
+	mu    sync.Mutex
+	conns map[*wsConn]struct{}
+}
+
+// StartWs starts listening for RPC requests sent via WebSocket. Next to the
+// regular calls offered by api, clients can subscribe to events posted on mux.
+func StartWs(cfg WsConfig, codec codec.Codec, api shared.pbfcoinApi, mux *event.TypeMux) error {
+	wsServerMu.Lock()
+	defer wsServerMu.Unlock()
+
+	addr := fmt.Sprintf("%s:%d", cfg.ListenAddress, cfg.ListenPort)
+	if wsServer != nil {
+		if addr != wsServer.Addr {
+			return fmt.Errorf("WebSocket RPC service already running on %s ", wsServer.Addr)
+		}
+		return nil // WebSocket RPC service already running on given host/port
+	}
+
+	h := &wsServerHandler{
+		codec:   codec,
+		api:     api,
+		mux:     mux,
+		origins: strings.Fields(cfg.Origins),
+		conns:   make(map[*wsConn]struct{}),
+	}
+	s, err := listenHTTP(addr, websocket.Server{Handler: h.serve, Handshake: h.handshake})
+	if err != nil {
+		glog.V(logger.Error).Infof("Can't listen on %s:%d: %v", cfg.ListenAddress, cfg.ListenPort, err)
+		return err
+	}
+	glog.V(logger.Info).Infof("WebSocket RPC service started (ws://%s)\n", addr)
+
+	wsServer, wsHandler = s, h
+	return nil
+}
+
+// StopWs closes all active WebSocket connections and shuts down the server.
+func StopWs() {
+	wsServerMu.Lock()
+	defer wsServerMu.Unlock()
+
+	if wsServer != nil {
+		wsServer.Close()
+		wsHandler.closeAll()
+		wsServer, wsHandler = nil, nil
+	}
+}
+
+// handshake verifies the origin of the connecting client. Clients which don't
+// send an origin (i.e. non browser clients) are always accepted.
+func (h *wsServerHandler) handshake(cfg *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	for _, allowed := range h.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %s not allowed", origin)
+}
+
+func (h *wsServerHandler) serve(ws *websocket.Conn) {
+	// the http.Server read/write timeouts are still active on the hijacked connection
+	ws.SetDeadline(time.Time{})
+	ws.MaxPayloadBytes = maxHttpSizeReqLength
+
+	conn := newWsConn(ws, h)
+	h.mu.Lock()
+	h.conns[conn] = struct{}{}
+	h.mu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Errorf("panic: %v\n", r)
+		}
+		h.mu.Lock()
+		delete(h.conns, conn)
+		h.mu.Unlock()
+		conn.close()
+	}()
+
+	conn.loop()
+}
+
+func (h *wsServerHandler) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for conn := range h.conns {
+		conn.close()
+		delete(h.conns, conn)
+	}
+}
+
+// wsConn is a single WebSocket client connection. Responses and subscription
+// notifications are sent by a dedicated write loop.
+type wsConn struct {
+	id      int
+	ws      *websocket.Conn
+	coder   codec.ApiCoder
+	handler *wsServerHandler
+	subs    *subscriptions
+
+	out       chan interface{}
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+func newWsConn(ws *websocket.Conn, h *wsServerHandler) *wsConn {
+	c := &wsConn{
+		id:      newIpcConnId(),
+		ws:      ws,
+		coder:   h.codec.New(ws),
+		handler: h,
+		out:     make(chan interface{}, wsSendQueue),
+		quit:    make(chan struct{}),
+	}
+	c.subs = newSubscriptions(h.mux, c.send)
+	return c
+}
+
+// loop reads and executes requests until the connection fails.
+func (c *wsConn) loop() {
+	go c.writeLoop()
+
+	glog.V(logger.Debug).Infof("new WebSocket connection with id %06d started", c.id)
+	for {
+		// Receive fails with websocket.ErrFrameTooLarge on oversized requests
+		// without buffering them, the connection is dropped in that case.
+		var payload []byte
+		if err := websocket.Message.Receive(c.ws, &payload); err != nil {
+			glog.V(logger.Debug).Infof("Closed WebSocket Conn %06d recv err - %v\n", c.id, err)
+			return
+		}
+
+		var req shared.Request
+		if err := c.coder.Decode(payload, &req); err == nil {
+			c.send(c.execute(&req))
+			c.subs.start()
+			continue
+		}
+		var reqBatch []*shared.Request
+		if err := c.coder.Decode(payload, &reqBatch); err == nil {
+			responses := make([]*interface{}, 0, len(reqBatch))
+			for _, req := range reqBatch {
+				if res := c.execute(req); req.Id != nil {
+					responses = append(responses, res)
+				}
+			}
+			c.send(responses)
+			c.subs.start()
+			continue
+		}
+		err := fmt.Errorf("Could not decode request")
+		c.send(shared.NewRpcErrorResponse(-1, shared.JsonRpcVersion, -32600, err))
+	}
+}
+
+// execute handles the subscription calls on the connection itself and
+// forwards all others to the API.
+func (c *wsConn) execute(req *shared.Request) *interface{} {
+	var (
+		res interface{}
+		err error
+	)
+	switch req.method {
+	case wsSubscribe:
+		res, err = c.subs.subscribe(req.Params)
+	case wsUnsubscribe:
+		res, err = c.subs.unsubscribe(req.Params)
+	default:
+		res, err = c.handler.api.Execute(req)
+	}
+	return shared.NewRpcResponse(req.Id, req.Jsonrpc, res, err)
+}
+
+// send queues a message for delivery to the client. Notifications are posted
+// from the event mux and subscriptions are only started once their response is
+// queued, a client which doesn't keep up is therefore disconnected instead of
+// blocking either of them.
+func (c *wsConn) send(msg interface{}) {
+	select {
+	case c.out <- msg:
+	case <-c.quit:
+	default:
+		glog.V(logger.Debug).Infof("WebSocket Conn %06d too slow, dropping", c.id)
+		go c.close()
+	}
+}
+
+func (c *wsConn) writeLoop() {
+	for {
+		select {
+		case msg := <-c.out:
+			if glog.V(logger.Detail) {
+				if payload, err := json.Marshal(msg); err == nil {
+					glog.Infof("Sending payload: %s", payload)
+				}
+			}
+			if err := c.coder.WriteResponse(msg); err != nil {
+				glog.V(logger.Debug).Infof("Closed WebSocket Conn %06d send err - %v\n", c.id, err)
+				c.close()
+				return
+			}
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func (c *wsConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.quit)
+		c.subs.unsubscribeAll()
+		c.coder.Close()
+	})
+}
+
+type wsClient struct {
+	url   string
+	codec codec.Codec
+	coder codec.ApiCoder
+}
+
+// Create a new WebSocket client
+func NewWsClient(cfg WsConfig, c codec.Codec) (*wsClient, error) {
+	url := fmt.Sprintf("ws://%s:%d", cfg.ListenAddress, cfg.ListenPort)
+	ws, err := websocket.Dial(url, "", DefaultWsOrigin)
+	if err != nil {
+		return nil, err
+	}
+	return &wsClient{url: url, codec: c, coder: c.New(ws)}, nil
+}
+
+func (self *wsClient) Close() {
+	self.coder.Close()
+}
+
+func (self *wsClient) Send(msg interface{}) error {
+	var err error
+	if err = self.coder.WriteResponse(msg); err != nil {
+		if err = self.reconnect(); err == nil {
+			err = self.coder.WriteResponse(msg)
+		}
+	}
+	return err
+}
+
+func (self *wsClient) Recv() (interface{}, error) {
+	return self.coder.ReadResponse()
+}
+
+func (self *wsClient) SupportedModules() (map[string]string, error) {
+	req := shared.Request{
+		Id:      1,
+		Jsonrpc: "2.0",
+		method:  "modules",
+	}
+
+	if err := self.coder.WriteResponse(req); err != nil {
+		return nil, err
+	}
+
+	res, err := self.coder.ReadResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	if sucRes, ok := res.(*shared.SuccessResponse); ok {
+		data, _ := json.Marshal(sucRes.Result)
+		modules := make(map[string]string)
+		err = json.Unmarshal(data, &modules)
+		if err == nil {
+			return modules, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Invalid response")
+}
+
+func (self *wsClient) reconnect() error {
+	self.coder.Close()
+	ws, err := websocket.Dial(self.url, "", DefaultWsOrigin)
+	if err == nil {
+		self.coder = self.codec.New(ws)
+	}
+	return err
+}
+
+// wsEndpoint splits a host:port WebSocket endpoint into a client config.
+func wsEndpoint(endpoint string) (WsConfig, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return WsConfig{}, err
+	}
+	var p uint
+	if _, err := fmt.Sscan(port, &p); err != nil {
+		return WsConfig{}, fmt.Errorf("invalid port %q", port)
+	}
+	return WsConfig{ListenAddress: host, ListenPort: p}, nil
+}
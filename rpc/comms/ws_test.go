@@ -0,0 +1,190 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package comms
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/core/vm"
+	"github.com/pbfcoin/go-pbfcoin/event"
+	"github.com/pbfcoin/go-pbfcoin/rpc/codec"
+	"github.com/pbfcoin/go-pbfcoin/rpc/shared"
+	"golang.org/x/net/websocket"
+)
+
+type echoApi struct{}
+
+func (echoApi) Name() string       { return "echo" }
+func (echoApi) ApiVersion() string { return "1.0" }
+func (echoApi) methods() []string  { return []string{"echo_ping"} }
+func (echoApi) Execute(req *shared.Request) (interface{}, error) {
+	return "pong", nil
+}
+
+type wsTestMessage struct {
+	Id     interface{}     `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+// startTestWs starts a server on a random port and returns its url.
+func startTestWs(t *testing.T, origins string, mux *event.TypeMux) string {
+	cfg := WsConfig{ListenAddress: "127.0.0.1", ListenPort: 0, Origins: origins}
+	if err := StartWs(cfg, codec.JSON, echoApi{}, mux); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	wsServerMu.Lock()
+	defer wsServerMu.Unlock()
+	return "ws://" + wsServer.l.Addr().String()
+}
+
+func dialTestWs(t *testing.T, mux *event.TypeMux) *websocket.Conn {
+	ws, err := websocket.Dial(startTestWs(t, DefaultWsOrigin, mux), "", DefaultWsOrigin)
+	if err != nil {
+		StopWs()
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	return ws
+}
+
+func wsCall(t *testing.T, ws *websocket.Conn, req string) *wsTestMessage {
+	if err := websocket.Message.Send(ws, req); err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	return wsRecv(t, ws)
+}
+
+func wsRecv(t *testing.T, ws *websocket.Conn) *wsTestMessage {
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	msg := new(wsTestMessage)
+	if err := websocket.JSON.Receive(ws, msg); err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	return msg
+}
+
+func TestWsSubscriptions(t *testing.T) {
+	mux := new(event.TypeMux)
+	ws := dialTestWs(t, mux)
+	defer StopWs()
+	defer ws.Close()
+
+	// Regular calls are forwarded to the API
+	if res := wsCall(t, ws, `{"jsonrpc":"2.0","id":1,"method":"echo_ping","params":[]}`); string(res.Result) != `"pong"` {
+		t.Fatalf("call result mismatch: have %s, want %q", res.Result, "pong")
+	}
+	// Subscribe to new heads and logs of a single address
+	var heads, logs string
+	res := wsCall(t, ws, `{"jsonrpc":"2.0","id":2,"method":"eth_subscribe","params":["newHeads"]}`)
+	if err := json.Unmarshal(res.Result, &heads); err != nil {
+		t.Fatalf("invalid subscription id %s: %v", res.Result, err)
+	}
+	res = wsCall(t, ws, `{"jsonrpc":"2.0","id":3,"method":"eth_subscribe","params":["logs",{"address":"0x0000000000000000000000000000000000000001"}]}`)
+	if err := json.Unmarshal(res.Result, &logs); err != nil {
+		t.Fatalf("invalid subscription id %s: %v", res.Result, err)
+	}
+
+	header := &types.Header{Number: big.NewInt(42)}
+	mux.Post(core.ChainHeadEvent{Block: types.NewBlockWithHeader(header)})
+	if msg := wsRecv(t, ws); msg.Params.Subscription != heads {
+		t.Fatalf("subscription mismatch: have %s, want %s", msg.Params.Subscription, heads)
+	} else if err := json.Unmarshal(msg.Params.Result, new(map[string]string)); err != nil {
+		t.Fatalf("invalid head notification %s: %v", msg.Params.Result, err)
+	}
+
+	mux.Post(vm.Logs{
+		vm.NewLog(common.BytesToAddress([]byte{2}), nil, nil, 42),
+		vm.NewLog(common.BytesToAddress([]byte{1}), nil, []byte{0xff}, 42),
+	})
+	msg := wsRecv(t, ws)
+	if msg.Params.Subscription != logs {
+		t.Fatalf("subscription mismatch: have %s, want %s", msg.Params.Subscription, logs)
+	}
+	var log struct {
+		Address string
+		Data    string
+	}
+	if err := json.Unmarshal(msg.Params.Result, &log); err != nil {
+		t.Fatalf("invalid log notification %s: %v", msg.Params.Result, err)
+	}
+	if log.Address != common.BytesToAddress([]byte{1}).Hex() || log.Data != "0xff" {
+		t.Fatalf("log mismatch: have %+v", log)
+	}
+
+	res = wsCall(t, ws, `{"jsonrpc":"2.0","id":4,"method":"eth_subscribe","params":["newPendingTransactions"]}`)
+	var txs string
+	if err := json.Unmarshal(res.Result, &txs); err != nil {
+		t.Fatalf("invalid subscription id %s: %v", res.Result, err)
+	}
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil)
+	mux.Post(core.TxPreEvent{Tx: tx})
+	msg = wsRecv(t, ws)
+	if msg.Params.Subscription != txs {
+		t.Fatalf("subscription mismatch: have %s, want %s", msg.Params.Subscription, txs)
+	}
+	if string(msg.Params.Result) != `"`+tx.Hash().Hex()+`"` {
+		t.Fatalf("transaction hash mismatch: have %s, want %s", msg.Params.Result, tx.Hash().Hex())
+	}
+
+	// Unsubscribing stops the notifications
+	if res := wsCall(t, ws, `{"jsonrpc":"2.0","id":5,"method":"eth_unsubscribe","params":["`+heads+`"]}`); string(res.Result) != "true" {
+		t.Fatalf("unsubscribe result mismatch: have %s, want true", res.Result)
+	}
+	if res := wsCall(t, ws, `{"jsonrpc":"2.0","id":6,"method":"eth_unsubscribe","params":["`+heads+`"]}`); string(res.Result) != "false" {
+		t.Fatalf("second unsubscribe result mismatch: have %s, want false", res.Result)
+	}
+}
+
+func TestWsOrigin(t *testing.T) {
+	url := startTestWs(t, "http://example.com", nil)
+	defer StopWs()
+
+	if _, err := websocket.Dial(url, "", "http://evil.com"); err == nil {
+		t.Fatalf("connection from disallowed origin accepted")
+	}
+	ws, err := websocket.Dial(url, "", "http://example.com")
+	if err != nil {
+		t.Fatalf("connection from allowed origin rejected: %v", err)
+	}
+	ws.Close()
+}
+
+func TestWsRequestTooLarge(t *testing.T) {
+	ws := dialTestWs(t, nil)
+	defer StopWs()
+	defer ws.Close()
+
+	req := `{"jsonrpc":"2.0","id":1,"method":"echo_ping","params":["` + strings.Repeat("x", maxHttpSizeReqLength) + `"]}`
+	if err := websocket.Message.Send(ws, req); err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	var msg json.RawMessage
+	if err := websocket.JSON.Receive(ws, &msg); err == nil {
+		t.Fatalf("oversized request answered: %s", msg)
+	}
+}
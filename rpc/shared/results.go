@@ -0,0 +1,109 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package shared
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/core/types"
+	"github.com/pbfcoin/go-pbfcoin/core/vm"
+)
+
+// HeaderRes is the JSON representation of a block header. It is used by the
+// block queries and by the newHeads subscription notifications.
+type HeaderRes struct {
+	BlockNumber     string `json:"number"`
+	BlockHash       string `json:"hash"`
+	ParentHash      string `json:"parentHash"`
+	Nonce           string `json:"nonce"`
+	Sha3Uncles      string `json:"sha3Uncles"`
+	LogsBloom       string `json:"logsBloom"`
+	TransactionRoot string `json:"transactionsRoot"`
+	StateRoot       string `json:"stateRoot"`
+	ReceiptRoot     string `json:"receiptRoot"`
+	Miner           string `json:"miner"`
+	Difficulty      string `json:"difficulty"`
+	ExtraData       string `json:"extraData"`
+	GasLimit        string `json:"gasLimit"`
+	GasUsed         string `json:"gasUsed"`
+	UnixTimestamp   string `json:"timestamp"`
+}
+
+func NewHeaderRes(h *types.Header) *HeaderRes {
+	return &HeaderRes{
+		BlockNumber:     hexNum(h.Number),
+		BlockHash:       hexData(h.Hash().Bytes()),
+		ParentHash:      hexData(h.ParentHash.Bytes()),
+		Nonce:           hexData(h.Nonce[:]),
+		Sha3Uncles:      hexData(h.UncleHash.Bytes()),
+		LogsBloom:       hexData(h.Bloom.Bytes()),
+		TransactionRoot: hexData(h.TxHash.Bytes()),
+		StateRoot:       hexData(h.Root.Bytes()),
+		ReceiptRoot:     hexData(h.ReceiptHash.Bytes()),
+		Miner:           hexData(h.Coinbase.Bytes()),
+		Difficulty:      hexNum(h.Difficulty),
+		ExtraData:       hexData(h.Extra),
+		GasLimit:        hexNum(h.GasLimit),
+		GasUsed:         hexNum(h.GasUsed),
+		UnixTimestamp:   hexNum(h.Time),
+	}
+}
+
+// LogRes is the JSON representation of a contract log, used by the filter
+// queries, transaction receipts and the logs subscription notifications.
+type LogRes struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	LogIndex         string   `json:"logIndex"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+}
+
+func NewLogRes(log *vm.Log) LogRes {
+	var l LogRes
+	l.Topics = make([]string, len(log.Topics))
+	for j, topic := range log.Topics {
+		l.Topics[j] = hexData(topic.Bytes())
+	}
+	l.Address = hexData(log.Address.Bytes())
+	l.Data = hexData(log.Data)
+	l.BlockNumber = hexNum(new(big.Int).SetUint64(log.BlockNumber))
+	l.LogIndex = hexNum(big.NewInt(int64(log.Index)))
+	l.TransactionHash = hexData(log.TxHash.Bytes())
+	l.TransactionIndex = hexNum(big.NewInt(int64(log.TxIndex)))
+	l.BlockHash = hexData(log.BlockHash.Bytes())
+
+	return l
+}
+
+// hexData encodes raw bytes, an empty slice is encoded as "0x".
+func hexData(b []byte) string {
+	return "0x" + common.Bytes2Hex(b)
+}
+
+// hexNum encodes a quantity without leading zeroes, nil is encoded as "0x0".
+func hexNum(n *big.Int) string {
+	if n == nil {
+		return "0x0"
+	}
+	return fmt.Sprintf("%#x", n)
+}
@@ -15,29 +15,31 @@ import (
 // also included in the last node and can be retrieved by verifying
 // the proof.
 //
-// The returned proof is nil if the trie does not contain a value for key.
-// For existing keys, the proof will have at least one element.
+// If the trie does not contain a value for key, the proof contains the
+// nodes up to the point where the path of key leaves the trie, proving
+// its absence. The proof of any key in an empty trie is empty.
 func (t *Trie) Prove(key []byte) []rlp.RawValue {
 	// Collect all nodes on the path to key.
 	key = compactHexDecode(key)
 	nodes := []node{}
 	tn := t.root
+walk:
 	for len(key) > 0 {
 		switch n := tn.(type) {
 		case shortNode:
+			nodes = append(nodes, n)
 			if len(key) < len(n.Key) || !bytes.Equal(n.Key, key[:len(n.Key)]) {
 				// The trie doesn't contain the key.
-				return nil
+				break walk
 			}
 			tn = n.Val
 			key = key[len(n.Key):]
-			nodes = append(nodes, n)
 		case fullNode:
 			tn = n[key[0]]
 			key = key[1:]
 			nodes = append(nodes, n)
 		case nil:
-			return nil
+			break walk
 		case hashNode:
 			tn = t.resolveHash(n)
 		default:
@@ -64,10 +66,35 @@ func (t *Trie) Prove(key []byte) []rlp.RawValue {
 }
 
 // VerifyProof checks merkle proofs. The given proof must contain the
-// value for key in a trie with the given root hash. VerifyProof
-// returns an error if the proof contains invalid trie nodes or the
-// wrong value.
+// value for key in a trie with the given root hash, or prove that the
+// trie does not contain key, in which case the returned value is nil.
+// VerifyProof returns an error if the proof contains invalid trie nodes
+// or the wrong value.
 func VerifyProof(rootHash common.Hash, key []byte, proof []rlp.RawValue) (value []byte, err error) {
+	value, next, err := walkProof(rootHash, key, proof)
+	if err == nil && next != nil {
+		return nil, errors.New("unexpected end of proof")
+	}
+	return value, err
+}
+
+// NextProofNode checks the nodes of a partial merkle proof for key and
+// returns the hash of the node following them on the path of key. This
+// allows proofs to be assembled one node at a time from a database
+// indexed by node hash. The zero hash is returned if the proof is
+// already complete.
+func NextProofNode(rootHash common.Hash, key []byte, proof []rlp.RawValue) (common.Hash, error) {
+	_, next, err := walkProof(rootHash, key, proof)
+	return common.BytesToHash(next), err
+}
+
+// walkProof follows the path of key through the proof nodes, checking
+// their hashes. If the proof ends before the path does, the hash of the
+// missing node is returned.
+func walkProof(rootHash common.Hash, key []byte, proof []rlp.RawValue) (value []byte, next []byte, err error) {
+	if (rootHash == common.Hash{} || rootHash == emptyRoot) && len(proof) == 0 {
+		return nil, nil, nil
+	}
 	key = compactHexDecode(key)
 	sha := sha3.NewKeccak256()
 	wantHash := rootHash.Bytes()
@@ -75,27 +102,31 @@ func VerifyProof(rootHash common.Hash, key []byte, proof []rlp.RawValue) (value
 		sha.Reset()
 		sha.Write(buf)
 		if !bytes.Equal(sha.Sum(nil), wantHash) {
-			return nil, fmt.Errorf("bad proof node %d: hash mismatch", i)
+			return nil, nil, fmt.Errorf("bad proof node %d: hash mismatch", i)
 		}
 		n, err := decodeNode(buf)
 		if err != nil {
-			return nil, fmt.Errorf("bad proof node %d: %v", i, err)
+			return nil, nil, fmt.Errorf("bad proof node %d: %v", i, err)
 		}
 		keyrest, cld := get(n, key)
 		switch cld := cld.(type) {
 		case nil:
-			return nil, fmt.Errorf("key mismatch at proof node %d", i)
+			// The path of key leaves the trie, proving its absence.
+			if i != len(proof)-1 {
+				return nil, nil, errors.New("additional nodes at end of proof")
+			}
+			return nil, nil, nil
 		case hashNode:
 			key = keyrest
 			wantHash = cld
 		case valueNode:
 			if i != len(proof)-1 {
-				return nil, errors.New("additional nodes at end of proof")
+				return nil, nil, errors.New("additional nodes at end of proof")
 			}
-			return cld, nil
+			return cld, nil, nil
 		}
 	}
-	return nil, errors.New("unexpected end of proof")
+	return nil, wantHash, nil
 }
 
 func get(tn node, key []byte) ([]byte, node) {
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/pbfcoin/go-pbfcoin/common"
+	"github.com/pbfcoin/go-pbfcoin/crypto"
 	"github.com/pbfcoin/go-pbfcoin/rlp"
 )
 
@@ -67,6 +68,66 @@ func TestVerifyBadProof(t *testing.T) {
 	}
 }
 
+func TestMissingKeyProof(t *testing.T) {
+	trie := new(Trie)
+	updateString(trie, "k", "v")
+
+	for i, key := range []string{"a", "j", "l", "z"} {
+		proof := trie.Prove([]byte(key))
+		if len(proof) != 1 {
+			t.Errorf("test %d: proof should have one element", i)
+		}
+		val, err := VerifyProof(trie.Hash(), []byte(key), proof)
+		if err != nil {
+			t.Fatalf("test %d: failed to verify proof: %v\nraw proof: %x", i, err, proof)
+		}
+		if val != nil {
+			t.Fatalf("test %d: verified value mismatch: have %x, want nil", i, val)
+		}
+	}
+	// Absence in an empty trie is proven without nodes
+	if val, err := VerifyProof(emptyRoot, []byte("k"), nil); val != nil || err != nil {
+		t.Errorf("empty trie proof failed: value %x, err %v", val, err)
+	}
+}
+
+// Tests that proofs can be assembled node by node from a hash indexed database.
+func TestNextProofNode(t *testing.T) {
+	trie, vals := randomTrie(500)
+	root := trie.Hash()
+
+	nodes := make(map[common.Hash][]byte)
+	for _, kv := range vals {
+		for _, node := range trie.Prove(kv.k) {
+			nodes[common.BytesToHash(crypto.Sha3(node))] = node
+		}
+	}
+	for _, kv := range vals {
+		var proof []rlp.RawValue
+		for {
+			next, err := NextProofNode(root, kv.k, proof)
+			if err != nil {
+				t.Fatalf("key %x: failed to walk proof: %v", kv.k, err)
+			}
+			if next == (common.Hash{}) {
+				break
+			}
+			node, ok := nodes[next]
+			if !ok {
+				t.Fatalf("key %x: unknown proof node %x", kv.k, next)
+			}
+			proof = append(proof, node)
+		}
+		val, err := VerifyProof(root, kv.k, proof)
+		if err != nil {
+			t.Fatalf("key %x: failed to verify assembled proof: %v", kv.k, err)
+		}
+		if !bytes.Equal(val, kv.v) {
+			t.Fatalf("key %x: verified value mismatch: have %x, want %x", kv.k, val, kv.v)
+		}
+	}
+}
+
 // mutateByte changes one byte in b.
 func mutateByte(b []byte) {
 	for r := mrand.Intn(len(b)); ; {
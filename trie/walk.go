@@ -0,0 +1,76 @@
+// Copyright 2018 The go-pbfcoin Authors
+// This file is part of the go-pbfcoin library.
+// Go-pbfcoin was modified based on Go-Ethereum. Official Golang implementation of the pbfcoin protocol.
+// The go-pbfcoin library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-pbfcoin library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-pbfcoin library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"fmt"
+
+	"github.com/pbfcoin/go-pbfcoin/common"
+)
+
+// MissingNodeError is returned by Walk if a node referenced by the trie is not
+// present in the database.
+type MissingNodeError struct {
+	Hash common.Hash
+}
+
+func (err *MissingNodeError) Error() string {
+	return fmt.Sprintf("missing trie node %x", err.Hash)
+}
+
+// Walk traverses all nodes of the trie. For every node referenced by hash,
+// i.e. stored in the database under its own key, visit is called first and
+// the node and its children are only loaded if it returns true. The value of
+// every leaf is passed to leaf, an error returned by leaf aborts the walk.
+//
+// Nodes are loaded from the database directly, bypassing the node cache, so
+// that missing nodes are reported as MissingNodeError.
+func (t *Trie) Walk(visit func(hash common.Hash) bool, leaf func(value []byte) error) error {
+	return t.walk(t.root, visit, leaf)
+}
+
+func (t *Trie) walk(n node, visit func(common.Hash) bool, leaf func([]byte) error) error {
+	switch n := n.(type) {
+	case hashNode:
+		hash := common.BytesToHash(n)
+		if !visit(hash) {
+			return nil
+		}
+		enc, err := t.db.Get(n)
+		if err != nil || enc == nil {
+			return &MissingNodeError{Hash: hash}
+		}
+		dec, err := decodeNode(enc)
+		if err != nil {
+			return fmt.Errorf("invalid trie node %x: %v", hash, err)
+		}
+		return t.walk(dec, visit, leaf)
+
+	case shortNode:
+		return t.walk(n.Val, visit, leaf)
+
+	case fullNode:
+		for _, child := range n {
+			if err := t.walk(child, visit, leaf); err != nil {
+				return err
+			}
+		}
+	case valueNode:
+		return leaf(n)
+	}
+	return nil
+}
@@ -19,15 +19,27 @@ package xpbf
 import (
 	"github.com/pbfcoin/go-pbfcoin/common"
 	"github.com/pbfcoin/go-pbfcoin/core/state"
+	"github.com/pbfcoin/go-pbfcoin/light"
 )
 
 type State struct {
 	xpbf  *Xpbf
 	state *state.StateDB
+	light *light.State // proof based account access on light clients, nil otherwise
 }
 
 func NewState(xpbf *Xpbf, statedb *state.StateDB) *State {
-	return &State{xpbf, statedb}
+	return &State{xpbf: xpbf, state: statedb}
+}
+
+// NewLightState creates the state of the given root for a light client, with
+// all the state retrieved from the network on demand.
+func NewLightState(xpbf *Xpbf, root common.Hash, odr light.OdrBackend) (*State, error) {
+	statedb, err := light.NewStateDB(root, odr)
+	if err != nil {
+		return nil, err
+	}
+	return &State{xpbf: xpbf, state: statedb, light: light.NewState(root, odr)}, nil
 }
 
 func (self *State) State() *state.StateDB {
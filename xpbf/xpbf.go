@@ -35,6 +35,7 @@ import (
 	"github.com/pbfcoin/go-pbfcoin/core/types"
 	"github.com/pbfcoin/go-pbfcoin/core/vm"
 	"github.com/pbfcoin/go-pbfcoin/crypto"
+	"github.com/pbfcoin/go-pbfcoin/light"
 	"github.com/pbfcoin/go-pbfcoin/logger"
 	"github.com/pbfcoin/go-pbfcoin/logger/glog"
 	"github.com/pbfcoin/go-pbfcoin/miner"
@@ -113,8 +114,18 @@ func New(pbfcoin *pbf.pbfcoin, frontend Frontend) *Xpbf {
 	if frontend == nil {
 		xpbf.frontend = dummyFrontend{}
 	}
-	state, _ := xpbf.backend.BlockChain().State()
-	xpbf.state = NewState(xpbf, state)
+	if odr := pbfcoin.Odr(); odr != nil {
+		root := pbfcoin.BlockChain().CurrentHeader().Root
+		state, err := NewLightState(xpbf, root, odr)
+		if err != nil {
+			glog.V(logger.Error).Infof("Could not retrieve head state %x: %v", root, err)
+		}
+		xpbf.state = state
+	}
+	if xpbf.state == nil {
+		state, _ := xpbf.backend.BlockChain().State()
+		xpbf.state = NewState(xpbf, state)
+	}
 	go xpbf.start()
 	return xpbf
 }
@@ -202,7 +213,12 @@ func (self *Xpbf) DefaultGasPrice() *big.Int {
 
 func (self *Xpbf) RemoteMining() *miner.RemoteAgent { return self.agent }
 
-func (self *Xpbf) AtStateNum(num int64) *Xpbf {
+// AtStateNum creates an Xpbf operating on the state at the given block number.
+// It returns an error if the state is not available.
+func (self *Xpbf) AtStateNum(num int64) (*Xpbf, error) {
+	if odr := self.backend.Odr(); odr != nil {
+		return self.atLightState(num, odr)
+	}
 	var st *state.StateDB
 	var err error
 	switch num {
@@ -212,17 +228,35 @@ func (self *Xpbf) AtStateNum(num int64) *Xpbf {
 		if block := self.getBlockByHeight(num); block != nil {
 			st, err = state.New(block.Root(), self.backend.ChainDb())
 			if err != nil {
-				return nil
+				return nil, err
 			}
 		} else {
 			st, err = state.New(self.backend.BlockChain().GetBlockByNumber(0).Root(), self.backend.ChainDb())
 			if err != nil {
-				return nil
+				return nil, err
 			}
 		}
 	}
 
-	return self.WithState(st)
+	return self.WithState(st), nil
+}
+
+// atLightState creates an Xpbf for the state at the given block number on a
+// light client. There are no pending blocks on light clients, so the pending
+// state is the one of the head header.
+func (self *Xpbf) atLightState(num int64, odr light.OdrBackend) (*Xpbf, error) {
+	header := self.getHeaderByHeight(num)
+	if header == nil {
+		header = self.backend.BlockChain().GpbfeaderByNumber(0)
+	}
+	xpbf := self.WithState(nil)
+	st, err := NewLightState(xpbf, header.Root, odr)
+	if err != nil {
+		glog.V(logger.Debug).Infof("Could not retrieve state %x: %v", header.Root, err)
+		return nil, err
+	}
+	xpbf.state = st
+	return xpbf, nil
 }
 
 func (self *Xpbf) WithState(statedb *state.StateDB) *Xpbf {
@@ -270,10 +304,19 @@ func (self *Xpbf) UpdateState() (wait chan *big.Int) {
 						wait <- n
 						n = nil
 					}
+					if odr := self.backend.Odr(); odr != nil {
+						st, err := NewLightState(self, event.Block.Root(), odr)
+						if err != nil {
+							glog.V(logger.Error).Infof("Could not retrieve new state: %v", err)
+							continue
+						}
+						self.state = st
+						continue
+					}
 					statedb, err := state.New(event.Block.Root(), self.backend.ChainDb())
 					if err != nil {
-						glog.V(logger.Error).Infoln("Could not create new state: %v", err)
-						return
+						glog.V(logger.Error).Infof("Could not create new state: %v", err)
+						continue
 					}
 					self.state = NewState(self, statedb)
 				}
@@ -290,6 +333,12 @@ func (self *Xpbf) UpdateState() (wait chan *big.Int) {
 func (self *Xpbf) Whisper() *Whisper { return self.whisper }
 
 func (self *Xpbf) getBlockByHeight(height int64) *types.Block {
+	if self.backend.Odr() != nil {
+		if header := self.getHeaderByHeight(height); header != nil {
+			return self.lightBlock(header)
+		}
+		return nil
+	}
 	var num uint64
 
 	switch height {
@@ -308,16 +357,51 @@ func (self *Xpbf) getBlockByHeight(height int64) *types.Block {
 	return self.backend.BlockChain().GetBlockByNumber(num)
 }
 
+// getHeaderByHeight retrieves the header at the given height, where both the
+// pending (-2) and the latest (-1) heights denote the head header.
+func (self *Xpbf) getHeaderByHeight(height int64) *types.Header {
+	switch {
+	case height == -2 || height == -1:
+		return self.backend.BlockChain().CurrentHeader()
+	case height < 0:
+		return nil
+	}
+	return self.backend.BlockChain().GpbfeaderByNumber(uint64(height))
+}
+
+// lightBlock assembles the block of a header on a light client, retrieving its
+// body from the network if needed.
+func (self *Xpbf) lightBlock(header *types.Header) *types.Block {
+	block, err := light.GetBlock(self.backend.Odr(), header)
+	if err != nil {
+		glog.V(logger.Debug).Infof("Could not retrieve block %x: %v", header.Hash(), err)
+		return nil
+	}
+	return block
+}
+
+// getBlockByHash retrieves a block by hash, retrieving its body from the network
+// on light clients.
+func (self *Xpbf) getBlockByHash(hash common.Hash) *types.Block {
+	if self.backend.Odr() != nil {
+		if header := self.backend.BlockChain().Gpbfeader(hash); header != nil {
+			return self.lightBlock(header)
+		}
+		return nil
+	}
+	return self.backend.BlockChain().GetBlock(hash)
+}
+
 func (self *Xpbf) BlockByHash(strHash string) *Block {
 	hash := common.HexToHash(strHash)
-	block := self.backend.BlockChain().GetBlock(hash)
+	block := self.getBlockByHash(hash)
 
 	return NewBlock(block)
 }
 
 func (self *Xpbf) pbfBlockByHash(strHash string) *types.Block {
 	hash := common.HexToHash(strHash)
-	block := self.backend.BlockChain().GetBlock(hash)
+	block := self.getBlockByHash(hash)
 
 	return block
 }
@@ -342,18 +426,48 @@ func (self *Xpbf) Td(hash common.Hash) *big.Int {
 }
 
 func (self *Xpbf) CurrentBlock() *types.Block {
+	// Light clients only have the head header, without its body
+	if self.backend.Odr() != nil {
+		return types.NewBlockWithHeader(self.backend.BlockChain().CurrentHeader())
+	}
 	return self.backend.BlockChain().CurrentBlock()
 }
 
 func (self *Xpbf) GetBlockReceipts(bhash common.Hash) types.Receipts {
+	if odr := self.backend.Odr(); odr != nil {
+		header := self.backend.BlockChain().Gpbfeader(bhash)
+		if header == nil {
+			return nil
+		}
+		receipts, err := light.GetBlockReceipts(odr, header)
+		if err != nil {
+			glog.V(logger.Debug).Infof("Could not retrieve receipts of block %x: %v", bhash, err)
+		}
+		return receipts
+	}
 	return core.GetBlockReceipts(self.backend.ChainDb(), bhash)
 }
 
 func (self *Xpbf) GetTxReceipt(txhash common.Hash) *types.Receipt {
-	return core.GetReceipt(self.backend.ChainDb(), txhash)
+	receipt := core.GetReceipt(self.backend.ChainDb(), txhash)
+	if receipt != nil || self.backend.Odr() == nil {
+		return receipt
+	}
+	// Light clients only know the transactions of the block bodies they retrieved
+	tx, bhash, _, index := core.GetTransaction(self.backend.ChainDb(), txhash)
+	if tx == nil {
+		return nil
+	}
+	if receipts := self.GetBlockReceipts(bhash); index < uint64(len(receipts)) {
+		return receipts[index]
+	}
+	return nil
 }
 
 func (self *Xpbf) GasLimit() *big.Int {
+	if self.backend.Odr() != nil {
+		return self.backend.BlockChain().CurrentHeader().GasLimit
+	}
 	return self.backend.BlockChain().GasLimit()
 }
 
@@ -461,28 +575,60 @@ func (self *Xpbf) NumberToHuman(balance string) string {
 	return common.CurrencyToString(b)
 }
 
+// The account accessors below use proofs on light clients, which retrieve an
+// account in a single request. Should that fail, they fall back to the state
+// database, which retrieves the trie nodes one by one.
+
 func (self *Xpbf) StorageAt(addr, storageAddr string) string {
+	if ls := self.State().light; ls != nil {
+		value, err := ls.GetState(common.HexToAddress(addr), common.HexToHash(storageAddr))
+		if err == nil {
+			return value.Hex()
+		}
+		glog.V(logger.Debug).Infof("Could not retrieve storage of %s: %v", addr, err)
+	}
 	return self.State().state.GetState(common.HexToAddress(addr), common.HexToHash(storageAddr)).Hex()
 }
 
 func (self *Xpbf) BalanceAt(addr string) string {
+	if ls := self.State().light; ls != nil {
+		balance, err := ls.GetBalance(common.HexToAddress(addr))
+		if err == nil {
+			return common.ToHex(balance.Bytes())
+		}
+		glog.V(logger.Debug).Infof("Could not retrieve balance of %s: %v", addr, err)
+	}
 	return common.ToHex(self.State().state.GetBalance(common.HexToAddress(addr)).Bytes())
 }
 
 func (self *Xpbf) TxCountAt(address string) int {
+	if ls := self.State().light; ls != nil {
+		nonce, err := ls.GetNonce(common.HexToAddress(address))
+		if err == nil {
+			return int(nonce)
+		}
+		glog.V(logger.Debug).Infof("Could not retrieve nonce of %s: %v", address, err)
+	}
 	return int(self.State().state.GetNonce(common.HexToAddress(address)))
 }
 
 func (self *Xpbf) CodeAt(address string) string {
-	return common.ToHex(self.State().state.GetCode(common.HexToAddress(address)))
+	return common.ToHex(self.CodeAtBytes(address))
 }
 
 func (self *Xpbf) CodeAtBytes(address string) []byte {
+	if ls := self.State().light; ls != nil {
+		code, err := ls.GetCode(common.HexToAddress(address))
+		if err == nil {
+			return code
+		}
+		glog.V(logger.Debug).Infof("Could not retrieve code of %s: %v", address, err)
+	}
 	return self.State().SafeGet(address).Code()
 }
 
 func (self *Xpbf) IsContract(address string) bool {
-	return len(self.State().SafeGet(address).Code()) > 0
+	return len(self.CodeAtBytes(address)) > 0
 }
 
 func (self *Xpbf) UninstallFilter(id int) bool {
@@ -775,7 +921,7 @@ func (self *Xpbf) PushTx(encodedTx string) (string, error) {
 		return "", err
 	}
 
-	err = self.backend.TxPool().Add(tx)
+	err = self.backend.TxPool().AddLocal(tx)
 	if err != nil {
 		return "", err
 	}
@@ -1017,7 +1163,7 @@ func (self *Xpbf) Transact(fromStr, toStr, nonceStr, valueStr, gasStr, gasPriceS
 	if err != nil {
 		return "", err
 	}
-	if err = self.backend.TxPool().Add(signed); err != nil {
+	if err = self.backend.TxPool().AddLocal(signed); err != nil {
 		return "", err
 	}
 